@@ -2,18 +2,26 @@ package main
 
 import (
 	"context"
+	"io"
 	"log"
 	"net/http"
 	"os"
 	"os/signal"
 	"strconv"
+	"strings"
 	"syscall"
 	"time"
+	_ "time/tzdata"
 
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/fs"
 	httpadapter "github.com/yimsoijoi/7s-backend-challenge/internal/adapters/http"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	redisadapter "github.com/yimsoijoi/7s-backend-challenge/internal/adapters/redis"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
 )
 
 func main() {
@@ -25,26 +33,71 @@ func main() {
 	)
 	defer stop()
 
-	// Infrastructure
-	mongoCfg := infrastructure.MongoConfig{
-		URI:      getEnv("MONGO_URI", "mongodb://localhost:27017"),
-		Database: getEnv("MONGO_DB", "users"),
-		Timeout:  10 * time.Second,
+	if len(os.Args) > 1 {
+		commands := map[string]func(context.Context, []string, io.Writer) error{
+			"migrate": runMigrate,
+			"import":  runImport,
+			"export":  runExport,
+		}
+		if run, ok := commands[os.Args[1]]; ok {
+			if err := run(ctx, os.Args[2:], os.Stdout); err != nil {
+				log.Fatalf("%s failed: %v", os.Args[1], err)
+			}
+			return
+		}
 	}
 
-	mongoClient, mongoDB := infrastructure.NewMongoDatabase(mongoCfg)
-	defer func() {
-		shutdownCtx, cancel := context.WithTimeout(
-			context.Background(),
-			5*time.Second,
-		)
-		defer cancel()
-		_ = mongoClient.Disconnect(shutdownCtx)
-	}()
-
-	err := infrastructure.EnsureMongoIndexes(ctx, mongoDB.Collection("users"))
+	// Infrastructure and Repositories
+	store, err := openStorage(ctx)
 	if err != nil {
-		log.Println("!! MongoDB not indexes")
+		log.Fatal(err)
+	}
+	defer store.Close()
+
+	mongoDB := store.mongoDB
+	userRepo := store.users
+	auditRepo := store.audit
+	outboxRepo := store.outbox
+	webhookRepo := store.webhooks
+	orgRepo := store.orgs
+
+	// Cache user lookups by ID, made on every authenticated request
+	var userCache ports.Cache
+	switch backend := getEnv("USER_CACHE", "none"); backend {
+	case "none":
+	case "memory":
+		size, err := strconv.Atoi(getEnv("USER_CACHE_SIZE", "10000"))
+		if err != nil {
+			log.Fatalf("config USER_CACHE_SIZE failed: %s", err.Error())
+		}
+		userCache = memory.NewCache(size)
+	case "redis":
+		redisClient := infrastructure.NewRedisClient(infrastructure.RedisConfig{
+			URL:     getEnv("REDIS_URL", "redis://localhost:6379/0"),
+			Timeout: 5 * time.Second,
+		})
+		defer redisClient.Close()
+		userCache = redisadapter.NewCache(redisClient, getEnv("REDIS_KEY_PREFIX", ""))
+	default:
+		log.Fatalf("config USER_CACHE failed: unknown cache %q", backend)
+	}
+	var cachedUsers *application.CachedUserRepository
+	if userCache != nil {
+		ttlSeconds, err := strconv.Atoi(getEnv("USER_CACHE_TTL_SECONDS", "30"))
+		if err != nil {
+			log.Fatalf("config USER_CACHE_TTL_SECONDS failed: %s", err.Error())
+		}
+		negativeTTLSeconds, err := strconv.Atoi(getEnv("USER_CACHE_NEGATIVE_TTL_SECONDS", "5"))
+		if err != nil {
+			log.Fatalf("config USER_CACHE_NEGATIVE_TTL_SECONDS failed: %s", err.Error())
+		}
+		cachedUsers = application.NewCachedUserRepository(
+			userRepo,
+			userCache,
+			time.Duration(ttlSeconds)*time.Second,
+			time.Duration(negativeTTLSeconds)*time.Second,
+		)
+		userRepo = cachedUsers
 	}
 
 	ttlMinutes, err := strconv.Atoi(
@@ -59,51 +112,326 @@ func main() {
 		time.Duration(ttlMinutes)*time.Minute,
 	)
 
-	// Repositories
-	userRepo := mongo.NewUserRepository(mongoDB)
+	tenants, err := infrastructure.NewTenantRegistry(
+		getEnv("TENANTS_CONFIG", ""),
+		time.Duration(ttlMinutes)*time.Minute,
+	)
+	if err != nil {
+		log.Fatalf("config TENANTS_CONFIG failed: %s", err.Error())
+	}
+
+	mailer := newMailer()
+
+	attributeSchema, err := infrastructure.NewAttributeSchema(
+		getEnv("USER_ATTRIBUTES_SCHEMA", ""),
+	)
+	if err != nil {
+		log.Fatalf("config USER_ATTRIBUTES_SCHEMA failed: %s", err.Error())
+	}
+
+	var publisher ports.EventPublisher
+	switch backend := getEnv("EVENT_PUBLISHER", "log"); backend {
+	case "log":
+		publisher = infrastructure.NewLogPublisher()
+	case "webhook":
+		url := getEnv("EVENT_WEBHOOK_URL", "")
+		if url == "" {
+			log.Fatalf("config EVENT_WEBHOOK_URL failed: required by the webhook publisher")
+		}
+		publisher = infrastructure.NewWebhookPublisher(url)
+	case "memory":
+		publisher = infrastructure.NewMemoryPublisher()
+	default:
+		log.Fatalf("config EVENT_PUBLISHER failed: unknown publisher %q", backend)
+	}
+
+	var blobStore ports.BlobStore
+	defaultAvatarStorage := "gridfs"
+	if mongoDB == nil {
+		defaultAvatarStorage = "fs"
+	}
+	switch backend := getEnv("AVATAR_STORAGE", defaultAvatarStorage); backend {
+	case "gridfs":
+		if mongoDB == nil {
+			log.Fatalf("config AVATAR_STORAGE failed: gridfs needs STORAGE=mongo")
+		}
+		blobStore = mongo.NewBlobStore(mongoDB)
+	case "fs":
+		blobStore, err = fs.NewBlobStore(getEnv("AVATAR_DIR", "./data/avatars"))
+		if err != nil {
+			log.Fatalf("config AVATAR_DIR failed: %s", err.Error())
+		}
+	default:
+		log.Fatalf("config AVATAR_STORAGE failed: unknown backend %q", backend)
+	}
 
 	// Services
-	userService := application.NewUserService(userRepo, jwtManager)
+	auditService := application.NewAuditService(auditRepo)
+	userService := application.NewUserService(userRepo, jwtManager, mailer, attributeSchema, auditService, tenants)
+	avatarService := application.NewAvatarService(userRepo, blobStore, auditService, getEnv("PUBLIC_BASE_URL", ""))
+	webhookService := application.NewWebhookService(webhookRepo)
+	eventBroker := application.NewEventBroker(1000)
+	transferService := application.NewUserTransferService(userRepo, mailer, attributeSchema, auditService)
+	privacyService := application.NewPrivacyService(userRepo, auditRepo, auditService, webhookRepo, orgRepo, blobStore)
+	orgService := application.NewOrganizationService(orgRepo, userRepo, mailer, auditService)
 
 	// HTTP Handlers
-	handler := httpadapter.NewHandler(userService)
+	handler := httpadapter.NewHandler(
+		userService,
+		avatarService,
+		auditService,
+		webhookService,
+		eventBroker,
+		transferService,
+		privacyService,
+		orgService,
+	)
 
 	mux := http.NewServeMux()
+	admins := splitList(getEnv("ADMIN_USER_IDS", ""))
 
 	// Public
-	mux.HandleFunc("/auth/login", handler.Login)
-	mux.Handle("/users", httpadapter.Logging(http.HandlerFunc(handler.CreateUser)))
+	mux.Handle("/auth/login", httpadapter.Logging(http.HandlerFunc(handler.Login)))
+	mux.Handle("POST /auth/confirm-email", httpadapter.Logging(http.HandlerFunc(handler.ConfirmEmail)))
+	mux.Handle("POST /auth/accept-invitation", httpadapter.Logging(http.HandlerFunc(handler.AcceptInvitation)))
+	mux.Handle("GET /users/{id}/avatar", httpadapter.Logging(http.HandlerFunc(handler.GetAvatar)))
+	mux.Handle("POST /users", httpadapter.Logging(http.HandlerFunc(handler.CreateUser)))
 
 	// Protected
 	mux.Handle(
-		"/users/",
+		"GET /users",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, handler.ListUsers(admins)),
+		),
+	)
+	mux.Handle(
+		"GET /users/search",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, handler.SearchUsers(admins)),
+		),
+	)
+	mux.Handle(
+		"GET /users/{id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, handler.GetUser(admins)),
+		),
+	)
+	mux.Handle(
+		"PUT /users/{id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.UpdateUser)),
+		),
+	)
+	mux.Handle(
+		"PATCH /users/{id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.PatchUser)),
+		),
+	)
+	mux.Handle(
+		"PUT /users/{id}/avatar",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.UploadAvatar)),
+		),
+	)
+	mux.Handle(
+		"DELETE /users/{id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.DeleteUser)),
+		),
+	)
+
+	mux.Handle(
+		"GET /events/users",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, handler.StreamUserEvents(admins)),
+		),
+	)
+	mux.Handle(
+		"GET /users/{id}/data-export",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, handler.ExportUserData(admins)),
+		),
+	)
+
+	mux.Handle(
+		"POST /orgs",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.CreateOrganization)),
+		),
+	)
+	mux.Handle(
+		"GET /orgs",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.ListOrganizations)),
+		),
+	)
+	mux.Handle(
+		"GET /orgs/{id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.GetOrganization)),
+		),
+	)
+	mux.Handle(
+		"GET /orgs/{id}/members",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.ListMembers)),
+		),
+	)
+	mux.Handle(
+		"POST /orgs/{id}/members",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.InviteMember)),
+		),
+	)
+	mux.Handle(
+		"POST /orgs/{id}/accept",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.AcceptMembership)),
+		),
+	)
+	mux.Handle(
+		"PATCH /orgs/{id}/members/{user_id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.UpdateMember)),
+		),
+	)
+	mux.Handle(
+		"DELETE /orgs/{id}/members/{user_id}",
+		httpadapter.Logging(
+			httpadapter.Auth(userService, http.HandlerFunc(handler.RemoveMember)),
+		),
+	)
+
+	// Admin
+
+	mux.Handle(
+		"POST /users/import",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.ImportUsers)),
+			),
+		),
+	)
+	mux.Handle(
+		"GET /users/export",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.ExportUsers)),
+			),
+		),
+	)
+	mux.Handle(
+		"POST /users/{id}/erase",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.EraseUser)),
+			),
+		),
+	)
+	mux.Handle(
+		"POST /users/{id}/restore",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.RestoreUser)),
+			),
+		),
+	)
+	mux.Handle(
+		"POST /users/{id}/suspend",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, handler.ChangeStatus(domain.StatusSuspended)),
+			),
+		),
+	)
+	mux.Handle(
+		"POST /users/{id}/reactivate",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, handler.ChangeStatus(domain.StatusActive)),
+			),
+		),
+	)
+	mux.Handle(
+		"POST /users/{id}/disable",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, handler.ChangeStatus(domain.StatusDisabled)),
+			),
+		),
+	)
+
+	mux.Handle(
+		"GET /audit/events",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.ListAuditEvents)),
+			),
+		),
+	)
+	mux.Handle(
+		"GET /audit/verify",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.VerifyAudit)),
+			),
+		),
+	)
+
+	mux.Handle(
+		"POST /webhooks",
 		httpadapter.Logging(
-			httpadapter.Auth(jwtManager, http.HandlerFunc(handler.GetUser)),
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.CreateWebhook)),
+			),
 		),
 	)
 	mux.Handle(
-		"/users/",
+		"GET /webhooks",
 		httpadapter.Logging(
-			httpadapter.Auth(jwtManager, http.HandlerFunc(handler.ListUsers)),
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.ListWebhooks)),
+			),
 		),
 	)
 	mux.Handle(
-		"/users/",
+		"GET /webhooks/{id}",
 		httpadapter.Logging(
-			httpadapter.Auth(jwtManager, http.HandlerFunc(handler.UpdateUser)),
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.GetWebhook)),
+			),
 		),
 	)
 	mux.Handle(
-		"/users/",
+		"DELETE /webhooks/{id}",
 		httpadapter.Logging(
-			httpadapter.Auth(jwtManager, http.HandlerFunc(handler.DeleteUser)),
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.DeleteWebhook)),
+			),
+		),
+	)
+	mux.Handle(
+		"GET /webhooks/{id}/deliveries",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.ListWebhookDeliveries)),
+			),
+		),
+	)
+	mux.Handle(
+		"POST /webhooks/{id}/deliveries/{delivery}/redeliver",
+		httpadapter.Logging(
+			httpadapter.Auth(userService,
+				httpadapter.Admin(admins, http.HandlerFunc(handler.RedeliverWebhook)),
+			),
 		),
 	)
 
 	// HTTP Server
 	server := &http.Server{
 		Addr:         getEnv("REST_PORT", ":8080"),
-		Handler:      mux,
+		Handler:      httpadapter.Tenant(tenants, mux),
 		ReadTimeout:  5 * time.Second,
 		WriteTimeout: 10 * time.Second,
 		IdleTimeout:  120 * time.Second,
@@ -128,6 +456,58 @@ func main() {
 		}
 	}()
 
+	// Purge soft-deleted users after the retention period
+	retentionHours, err := strconv.Atoi(
+		getEnv("DELETED_USER_RETENTION_HOURS", "720"),
+	)
+	if err != nil {
+		log.Fatalf("config DELETED_USER_RETENTION_HOURS failed: %s", err.Error())
+	}
+
+	purger := application.NewPurger(
+		userRepo,
+		orgRepo,
+		blobStore,
+		auditService,
+		time.Duration(retentionHours)*time.Hour,
+		time.Hour,
+	)
+	go purger.Run(ctx)
+
+	// Publish outbox events and queue them for webhook subscribers. SSE
+	// clients get them from the relay too, unless the change stream
+	// watcher follows the users collection instead. The watcher also
+	// keeps the user cache in step with writes from other instances.
+	relayed := []ports.EventPublisher{publisher, application.NewWebhookQueue(webhookRepo)}
+	if name := getEnv("USER_CHANGE_STREAM", ""); name != "" {
+		if mongoDB == nil {
+			log.Fatalf("config USER_CHANGE_STREAM failed: change streams need STORAGE=mongo")
+		}
+		var sink ports.EventPublisher = eventBroker
+		if cachedUsers != nil {
+			sink = infrastructure.NewFanoutPublisher(cachedUsers, eventBroker)
+		}
+		watcher := mongo.NewUserChangeWatcher(mongoDB, name, sink)
+		go watcher.Run(ctx)
+	} else {
+		relayed = append(relayed, eventBroker)
+	}
+
+	relay := application.NewOutboxRelay(
+		outboxRepo,
+		infrastructure.NewFanoutPublisher(relayed...),
+		time.Second,
+	)
+	go relay.Run(ctx)
+
+	// Send webhook deliveries
+	dispatcher := application.NewWebhookDispatcher(
+		webhookRepo,
+		infrastructure.NewWebhookSender(10*time.Second),
+		time.Second,
+	)
+	go dispatcher.Run(ctx)
+
 	// Start Server
 	go func() {
 		log.Println("HTTP server started on :8080")
@@ -156,9 +536,39 @@ func main() {
 
 // Helpers
 
+func mongoConfig() infrastructure.MongoConfig {
+	return infrastructure.MongoConfig{
+		URI:      getEnv("MONGO_URI", "mongodb://localhost:27017"),
+		Database: getEnv("MONGO_DB", "users"),
+		Timeout:  10 * time.Second,
+	}
+}
+
+func newMailer() ports.Mailer {
+	if addr := getEnv("SMTP_ADDR", ""); addr != "" {
+		return infrastructure.NewSMTPMailer(infrastructure.SMTPConfig{
+			Addr:     addr,
+			Username: getEnv("SMTP_USERNAME", ""),
+			Password: getEnv("SMTP_PASSWORD", ""),
+			From:     getEnv("SMTP_FROM", "no-reply@localhost"),
+		})
+	}
+	return infrastructure.NewLogMailer()
+}
+
 func getEnv(key, fallback string) string {
 	if v := os.Getenv(key); v != "" {
 		return v
 	}
 	return fallback
 }
+
+func splitList(v string) []string {
+	var out []string
+	for _, s := range strings.Split(v, ",") {
+		if s = strings.TrimSpace(s); s != "" {
+			out = append(out, s)
+		}
+	}
+	return out
+}
@@ -0,0 +1,102 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"text/tabwriter"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+)
+
+const migrateUsage = `usage: server migrate <command> [-to version]
+
+commands:
+  status  list migrations and when they were applied
+  up      apply pending migrations, up to -to if given
+  down    roll back the last migration, or every one above -to`
+
+// runMigrate runs the migrate subcommand against the MongoDB database the
+// server is configured with. SQL storages migrate themselves at startup.
+func runMigrate(ctx context.Context, args []string, out io.Writer) error {
+	if len(args) == 0 {
+		return errors.New(migrateUsage)
+	}
+	if storage := getEnv("STORAGE", "mongo"); storage != "mongo" {
+		return fmt.Errorf("STORAGE=%s has no migrate command, its migrations run at startup", storage)
+	}
+
+	cmd := args[0]
+	if cmd != "status" && cmd != "up" && cmd != "down" {
+		return fmt.Errorf("unknown command %q\n%s", cmd, migrateUsage)
+	}
+	flags := flag.NewFlagSet("migrate "+cmd, flag.ContinueOnError)
+	flags.SetOutput(out)
+	to := flags.Int("to", -1, "target version")
+	if err := flags.Parse(args[1:]); err != nil {
+		return err
+	}
+
+	client, db := infrastructure.NewMongoDatabase(mongoConfig())
+	defer func() {
+		shutdownCtx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		_ = client.Disconnect(shutdownCtx)
+	}()
+	m := mongo.NewMigrator(db)
+
+	switch cmd {
+	case "status":
+		status, err := m.Status(ctx)
+		if err != nil {
+			return err
+		}
+		w := tabwriter.NewWriter(out, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tAPPLIED AT")
+		for _, s := range status {
+			applied := "pending"
+			if s.AppliedAt != nil {
+				applied = s.AppliedAt.Format(time.RFC3339)
+			}
+			fmt.Fprintf(w, "%d\t%s\t%s\n", s.Version, s.Name, applied)
+		}
+		return w.Flush()
+	case "up":
+		if *to < 0 {
+			*to = 0
+		}
+		return m.Up(ctx, *to)
+	case "down":
+		if *to < 0 {
+			last, err := lastApplied(ctx, m)
+			if err != nil {
+				return err
+			}
+			if last == 0 {
+				fmt.Fprintln(out, "no migrations applied")
+				return nil
+			}
+			*to = last - 1
+		}
+		return m.Down(ctx, *to)
+	}
+	return nil
+}
+
+func lastApplied(ctx context.Context, m *mongo.Migrator) (int, error) {
+	status, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+	last := 0
+	for _, s := range status {
+		if s.AppliedAt != nil {
+			last = s.Version
+		}
+	}
+	return last, nil
+}
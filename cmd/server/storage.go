@@ -0,0 +1,104 @@
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	sqladapter "github.com/yimsoijoi/7s-backend-challenge/internal/adapters/sql"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+)
+
+// storage holds the repositories of the configured STORAGE. mongoDB is
+// only set for STORAGE=mongo.
+type storage struct {
+	name     string
+	mongoDB  *mongodriver.Database
+	users    ports.UserRepository
+	audit    ports.AuditRepository
+	outbox   ports.OutboxRepository
+	webhooks ports.WebhookRepository
+	orgs     ports.OrganizationRepository
+	close    func()
+}
+
+// Close disconnects from the database.
+func (s *storage) Close() {
+	if s.close != nil {
+		s.close()
+	}
+}
+
+// openStorage connects to the database STORAGE names and migrates it.
+func openStorage(ctx context.Context) (*storage, error) {
+	s := &storage{name: getEnv("STORAGE", "mongo")}
+
+	switch s.name {
+	case "mongo":
+		var mongoClient *mongodriver.Client
+		mongoClient, s.mongoDB = infrastructure.NewMongoDatabase(mongoConfig())
+		s.close = func() {
+			shutdownCtx, cancel := context.WithTimeout(
+				context.Background(),
+				5*time.Second,
+			)
+			defer cancel()
+			_ = mongoClient.Disconnect(shutdownCtx)
+		}
+
+		if err := mongo.NewMigrator(s.mongoDB).Up(ctx, 0); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("mongo migrate failed: %w", err)
+		}
+
+		s.users = mongo.NewUserRepository(s.mongoDB)
+		s.audit = mongo.NewAuditRepository(s.mongoDB)
+		s.outbox = mongo.NewOutboxRepository(s.mongoDB)
+		s.webhooks = mongo.NewWebhookRepository(s.mongoDB)
+		s.orgs = mongo.NewOrganizationRepository(s.mongoDB)
+	case "postgres", "sqlite":
+		dsn := getEnv("SQL_DSN", "")
+		if dsn == "" && s.name == "sqlite" {
+			dsn = "file:./data/users.db?_pragma=busy_timeout(5000)&_pragma=journal_mode(WAL)"
+		}
+		if dsn == "" {
+			return nil, errors.New("config SQL_DSN failed: required by the postgres storage")
+		}
+
+		sqlDB := infrastructure.NewSQLDatabase(infrastructure.SQLConfig{
+			Driver:  s.name,
+			DSN:     dsn,
+			Timeout: 10 * time.Second,
+		})
+		s.close = func() { _ = sqlDB.Close() }
+
+		if err := sqladapter.Migrate(ctx, sqlDB); err != nil {
+			s.Close()
+			return nil, fmt.Errorf("%s migrate failed: %w", s.name, err)
+		}
+
+		s.users = sqladapter.NewUserRepository(sqlDB)
+		s.audit = sqladapter.NewAuditRepository(sqlDB)
+		s.outbox = sqladapter.NewOutboxRepository(sqlDB)
+		s.webhooks = sqladapter.NewWebhookRepository(sqlDB)
+		s.orgs = sqladapter.NewOrganizationRepository(sqlDB)
+	case "memory":
+		log.Println("Using in-memory storage, data is lost on shutdown")
+
+		outbox := memory.NewOutboxRepository()
+		s.users = memory.NewUserRepository(outbox)
+		s.audit = memory.NewAuditRepository()
+		s.outbox = outbox
+		s.webhooks = memory.NewWebhookRepository()
+		s.orgs = memory.NewOrganizationRepository()
+	default:
+		return nil, fmt.Errorf("config STORAGE failed: unknown storage %q", s.name)
+	}
+	return s, nil
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+const importUsage = `usage: server import [-tenant id] [-format csv|ndjson] [-invite] [-dry-run] <file|->`
+
+const exportUsage = `usage: server export [-tenant id] [-format csv|ndjson] [-o file]`
+
+// runImport imports users from a file, or stdin for "-", into a tenant of
+// the configured storage and prints the report as JSON. The format
+// defaults to the file's extension.
+func runImport(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("import", flag.ContinueOnError)
+	flags.SetOutput(out)
+	tenant := flags.String("tenant", domain.DefaultTenant, "tenant to import into")
+	name := flags.String("format", "", "csv or ndjson")
+	invite := flags.Bool("invite", false, "invite users without a password hash")
+	dryRun := flags.Bool("dry-run", false, "validate without creating users")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 1 {
+		return errors.New(importUsage)
+	}
+	path := flags.Arg(0)
+	ctx, err := withTenant(ctx, *tenant)
+	if err != nil {
+		return err
+	}
+
+	if *name == "" {
+		*name = strings.TrimPrefix(filepath.Ext(path), ".")
+	}
+	format, err := domain.ParseTransferFormat(*name)
+	if err != nil {
+		return err
+	}
+
+	in := io.Reader(os.Stdin)
+	if path != "-" {
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		defer f.Close()
+		in = f
+	}
+
+	transfer, closeStorage, err := newTransferService(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	report, err := transfer.Import(ctx, in, format, domain.ImportOptions{Invite: *invite, DryRun: *dryRun})
+	if err != nil {
+		return err
+	}
+	enc := json.NewEncoder(out)
+	enc.SetIndent("", "  ")
+	return enc.Encode(report)
+}
+
+// runExport writes every user of a tenant of the configured storage to a
+// file, or to out when -o is not given.
+func runExport(ctx context.Context, args []string, out io.Writer) error {
+	flags := flag.NewFlagSet("export", flag.ContinueOnError)
+	flags.SetOutput(out)
+	tenant := flags.String("tenant", domain.DefaultTenant, "tenant to export")
+	name := flags.String("format", "ndjson", "csv or ndjson")
+	path := flags.String("o", "", "output file")
+	if err := flags.Parse(args); err != nil {
+		return err
+	}
+	if flags.NArg() != 0 {
+		return errors.New(exportUsage)
+	}
+	ctx, err := withTenant(ctx, *tenant)
+	if err != nil {
+		return err
+	}
+	format, err := domain.ParseTransferFormat(*name)
+	if err != nil {
+		return err
+	}
+
+	transfer, closeStorage, err := newTransferService(ctx)
+	if err != nil {
+		return err
+	}
+	defer closeStorage()
+
+	if *path == "" {
+		if n, err := transfer.Export(ctx, out, format); err != nil {
+			return fmt.Errorf("stopped after %d users: %w", n, err)
+		}
+		return nil
+	}
+
+	f, err := os.Create(*path)
+	if err != nil {
+		return err
+	}
+	defer f.Close()
+
+	n, err := transfer.Export(ctx, f, format)
+	if err != nil {
+		return fmt.Errorf("stopped after %d users: %w", n, err)
+	}
+	if err := f.Close(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "exported %d users to %s\n", n, *path)
+	return nil
+}
+
+// withTenant scopes ctx to the tenant, which must be in TENANTS_CONFIG.
+func withTenant(ctx context.Context, id string) (context.Context, error) {
+	tenants, err := infrastructure.NewTenantRegistry(getEnv("TENANTS_CONFIG", ""), 0)
+	if err != nil {
+		return nil, fmt.Errorf("config TENANTS_CONFIG failed: %w", err)
+	}
+	if _, err := tenants.Tenant(id); err != nil {
+		return nil, err
+	}
+	return domain.WithTenant(ctx, id), nil
+}
+
+// newTransferService builds the transfer service over the configured
+// storage, mailer and attribute schema, as the server does.
+func newTransferService(ctx context.Context) (ports.UserTransferService, func(), error) {
+	attributeSchema, err := infrastructure.NewAttributeSchema(getEnv("USER_ATTRIBUTES_SCHEMA", ""))
+	if err != nil {
+		return nil, nil, fmt.Errorf("config USER_ATTRIBUTES_SCHEMA failed: %w", err)
+	}
+
+	if getEnv("STORAGE", "mongo") == "memory" {
+		return nil, nil, errors.New("STORAGE=memory is lost when the command exits")
+	}
+	store, err := openStorage(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	transfer := application.NewUserTransferService(
+		store.users,
+		newMailer(),
+		attributeSchema,
+		application.NewAuditService(store.audit),
+	)
+	return transfer, store.Close, nil
+}
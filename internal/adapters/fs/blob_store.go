@@ -0,0 +1,137 @@
+package fs
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// metaSuffix names the sidecar file holding a blob's BlobInfo.
+const metaSuffix = ".meta.json"
+
+// BlobStore keeps blobs as files under a root directory.
+type BlobStore struct {
+	root string
+}
+
+func NewBlobStore(root string) (ports.BlobStore, error) {
+	if err := os.MkdirAll(root, 0o755); err != nil {
+		return nil, err
+	}
+	return &BlobStore{root: root}, nil
+}
+
+func (s *BlobStore) Put(_ context.Context, key, contentType string, data []byte) (*ports.BlobInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	info := &ports.BlobInfo{
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ETag:        hex.EncodeToString(sum[:]),
+	}
+
+	if err := writeAtomic(path, data); err != nil {
+		return nil, err
+	}
+	stat, err := os.Stat(path)
+	if err != nil {
+		return nil, err
+	}
+	info.UpdatedAt = stat.ModTime()
+
+	meta, err := json.Marshal(info)
+	if err != nil {
+		return nil, err
+	}
+	if err := writeAtomic(path+metaSuffix, meta); err != nil {
+		return nil, err
+	}
+
+	return info, nil
+}
+
+func (s *BlobStore) Get(_ context.Context, key string) (io.ReadCloser, *ports.BlobInfo, error) {
+	path, err := s.path(key)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	meta, err := os.ReadFile(path + metaSuffix)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	var info ports.BlobInfo
+	if err := json.Unmarshal(meta, &info); err != nil {
+		return nil, nil, err
+	}
+
+	f, err := os.Open(path)
+	if errors.Is(err, os.ErrNotExist) {
+		return nil, nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return f, &info, nil
+}
+
+func (s *BlobStore) Delete(_ context.Context, key string) error {
+	path, err := s.path(key)
+	if err != nil {
+		return err
+	}
+
+	for _, p := range []string{path + metaSuffix, path} {
+		if err := os.Remove(p); err != nil && !errors.Is(err, os.ErrNotExist) {
+			return err
+		}
+	}
+	return nil
+}
+
+// path maps key to a file under root, refusing keys that escape it.
+func (s *BlobStore) path(key string) (string, error) {
+	clean := filepath.Clean("/" + key)
+	if key == "" || strings.HasSuffix(key, metaSuffix) || clean != "/"+key {
+		return "", errors.New("invalid blob key")
+	}
+	return filepath.Join(s.root, filepath.FromSlash(clean)), nil
+}
+
+func writeAtomic(path string, data []byte) error {
+	tmp, err := os.CreateTemp(filepath.Dir(path), ".tmp-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(tmp.Name())
+
+	if _, err := tmp.Write(data); err != nil {
+		tmp.Close()
+		return err
+	}
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+	return os.Rename(tmp.Name(), path)
+}
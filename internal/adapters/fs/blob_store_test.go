@@ -0,0 +1,44 @@
+package fs_test
+
+import (
+	"context"
+	"io"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/fs"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestBlobStore_PutGetDelete(t *testing.T) {
+	store, err := fs.NewBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	ctx := context.Background()
+
+	info, err := store.Put(ctx, "avatars/u1/original", "image/png", []byte("png-bytes"))
+	assert.NoError(t, err)
+	assert.Equal(t, int64(9), info.Size)
+	assert.NotEmpty(t, info.ETag)
+
+	rc, got, err := store.Get(ctx, "avatars/u1/original")
+	assert.NoError(t, err)
+	data, _ := io.ReadAll(rc)
+	rc.Close()
+	assert.Equal(t, "png-bytes", string(data))
+	assert.Equal(t, "image/png", got.ContentType)
+	assert.Equal(t, info.ETag, got.ETag)
+
+	assert.NoError(t, store.Delete(ctx, "avatars/u1/original"))
+
+	_, _, err = store.Get(ctx, "avatars/u1/original")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestBlobStore_RejectsEscapingKeys(t *testing.T) {
+	store, err := fs.NewBlobStore(t.TempDir())
+	assert.NoError(t, err)
+
+	_, err = store.Put(context.Background(), "../outside", "text/plain", []byte("x"))
+	assert.Error(t, err)
+}
@@ -0,0 +1,39 @@
+package http
+
+import (
+	"net/http"
+)
+
+func (h *Handler) ListAuditEvents(w http.ResponseWriter, r *http.Request) {
+	q, err := parseAuditQuery(r.URL.Query())
+	if err == nil {
+		err = q.Normalize()
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	events, err := h.auditService.Find(r.Context(), q)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	// A full page may have more behind it; pass next_after_seq back as after_seq.
+	resp := map[string]interface{}{"events": events}
+	if len(events) > 0 && len(events) == q.Limit {
+		resp["next_after_seq"] = events[len(events)-1].Seq
+	}
+	respondJSON(w, http.StatusOK, resp)
+}
+
+func (h *Handler) VerifyAudit(w http.ResponseWriter, r *http.Request) {
+	result, err := h.auditService.Verify(r.Context())
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, result)
+}
@@ -0,0 +1,87 @@
+package http
+
+import (
+	"bytes"
+	"errors"
+	"io"
+	"net/http"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// avatarCacheControl lets browsers and proxies cache avatars; the avatar URL
+// on the user carries a content version, so a new upload changes the URL.
+const avatarCacheControl = "public, max-age=86400"
+
+func (h *Handler) UploadAvatar(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	if id != r.Header.Get("user-id") {
+		http.Error(w, "cannot update another user's data", http.StatusBadRequest)
+		return
+	}
+
+	// Leave room for the multipart envelope around the file.
+	r.Body = http.MaxBytesReader(w, r.Body, domain.MaxAvatarBytes+1<<20)
+
+	file, _, err := r.FormFile("avatar")
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "avatar too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		http.Error(w, "expected multipart form with an \"avatar\" file", http.StatusBadRequest)
+		return
+	}
+	defer file.Close()
+
+	user, err := h.avatarService.Upload(r.Context(), id, file)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(user.Version))
+	respondJSON(w, http.StatusOK, user)
+}
+
+func (h *Handler) GetAvatar(w http.ResponseWriter, r *http.Request) {
+	size, err := domain.ParseAvatarSize(r.URL.Query().Get("size"))
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Avatar URLs name the user's tenant, as <img> requests carry no
+	// X-Tenant-ID header.
+	ctx := r.Context()
+	if tenant := r.URL.Query().Get("tenant"); tenant != "" {
+		ctx = domain.WithTenant(ctx, tenant)
+	}
+
+	rc, info, err := h.avatarService.Open(ctx, r.PathValue("id"), size)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+	defer rc.Close()
+
+	data, err := io.ReadAll(rc)
+	if err != nil {
+		http.Error(w, "internal error", http.StatusInternalServerError)
+		return
+	}
+
+	w.Header().Set("Content-Type", info.ContentType)
+	w.Header().Set("Cache-Control", avatarCacheControl)
+	w.Header().Set("ETag", `"`+info.ETag+`"`)
+	w.Header().Set("X-Content-Type-Options", "nosniff")
+
+	// ServeContent answers If-None-Match / If-Modified-Since and Range requests.
+	http.ServeContent(w, r, "", info.UpdatedAt, bytes.NewReader(data))
+}
@@ -0,0 +1,46 @@
+package http
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/png"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestGetAvatar_OtherTenant(t *testing.T) {
+	users := memory.NewUserRepository(memory.NewOutboxRepository())
+	avatars := application.NewAvatarService(users, &mocks.BlobStoreMock{}, &mocks.AuditServiceMock{}, "")
+	h := NewHandler(nil, avatars, nil, nil, nil, nil, nil, nil)
+
+	ctx := domain.WithTenant(context.Background(), "acme")
+	u := &domain.User{Name: "Ann", Email: "ann@test.com", Status: domain.StatusActive}
+	assert.NoError(t, users.Create(ctx, u))
+
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, image.NewRGBA(image.Rect(0, 0, 8, 8))))
+	u, err := avatars.Upload(ctx, u.ID, &buf)
+	assert.NoError(t, err)
+
+	get := func(url string) int {
+		// Like an <img> tag: no credentials, no X-Tenant-ID.
+		req := httptest.NewRequest(http.MethodGet, url, nil)
+		req.SetPathValue("id", u.ID)
+		rec := httptest.NewRecorder()
+		h.GetAvatar(rec, req)
+		return rec.Code
+	}
+
+	assert.Equal(t, http.StatusOK, get(u.AvatarURL))
+	assert.Equal(t, http.StatusNotFound, get(strings.Replace(u.AvatarURL, "tenant=acme", "tenant=other", 1)))
+	assert.Equal(t, http.StatusNotFound, get("/users/"+u.ID+"/avatar"))
+}
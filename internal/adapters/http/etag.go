@@ -0,0 +1,35 @@
+package http
+
+import (
+	"errors"
+	"net/http"
+	"strconv"
+	"strings"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func etag(version int64) string {
+	return `"` + strconv.FormatInt(version, 10) + `"`
+}
+
+// ifMatch returns the version from the If-Match header,
+// or 0 when the header is absent or "*". If-Match compares strongly, so a
+// weak ETag never matches: it fails with domain.ErrVersionConflict.
+func ifMatch(r *http.Request) (int64, error) {
+	v := strings.TrimSpace(r.Header.Get("If-Match"))
+	if v == "" || v == "*" {
+		return 0, nil
+	}
+	if strings.HasPrefix(v, "W/") {
+		return 0, domain.ErrVersionConflict
+	}
+
+	v = strings.Trim(v, `"`)
+
+	version, err := strconv.ParseInt(v, 10, 64)
+	if err != nil || version <= 0 {
+		return 0, errors.New("invalid If-Match")
+	}
+	return version, nil
+}
@@ -0,0 +1,95 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// sseHeartbeat keeps idle connections open through proxies.
+var sseHeartbeat = 15 * time.Second
+
+// StreamUserEvents returns the Server-Sent Events handler for user changes.
+// Admins see every user's events, optionally narrowed with ?user_id=;
+// everyone else only sees their own. Nobody sees another tenant's events.
+// Resume with the Last-Event-ID header
+// (sent by EventSource on reconnect) or ?last_event_id=. When the events
+// since then are gone, a "reset" event tells the client to reload.
+func (h *Handler) StreamUserEvents(adminIDs []string) http.HandlerFunc {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		callerID := r.Header.Get("user-id")
+		tenant := domain.TenantID(r.Context())
+		userID := callerID
+		if _, ok := admins[callerID]; ok {
+			userID = r.URL.Query().Get("user_id")
+		}
+
+		lastID := r.Header.Get("Last-Event-ID")
+		if lastID == "" {
+			lastID = r.URL.Query().Get("last_event_id")
+		}
+
+		events, err := h.eventStream.Subscribe(r.Context(), lastID)
+		reset := errors.Is(err, domain.ErrEventsLost)
+		if reset {
+			events, err = h.eventStream.Subscribe(r.Context(), "")
+		}
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		// The server's write timeout would cut the stream.
+		rc := http.NewResponseController(w)
+		_ = rc.SetWriteDeadline(time.Time{})
+
+		w.Header().Set("Content-Type", "text/event-stream")
+		w.Header().Set("Cache-Control", "no-cache")
+		w.Header().Set("X-Accel-Buffering", "no")
+		w.WriteHeader(http.StatusOK)
+
+		if reset {
+			fmt.Fprint(w, "event: reset\ndata: {}\n\n")
+		}
+		if err := rc.Flush(); err != nil {
+			return
+		}
+
+		heartbeat := time.NewTicker(sseHeartbeat)
+		defer heartbeat.Stop()
+
+		for {
+			select {
+			case e, ok := <-events:
+				if !ok {
+					// Too slow or shutting down; the client reconnects and resumes.
+					return
+				}
+				if e.Tenant() != tenant || (userID != "" && e.UserID != userID) {
+					continue
+				}
+				data, err := json.Marshal(e)
+				if err != nil {
+					return
+				}
+				fmt.Fprintf(w, "id: %s\nevent: %s\ndata: %s\n\n", e.ID, e.Type, data)
+			case <-heartbeat.C:
+				fmt.Fprint(w, ": ping\n\n")
+			case <-r.Context().Done():
+				return
+			}
+			if err := rc.Flush(); err != nil {
+				return
+			}
+		}
+	}
+}
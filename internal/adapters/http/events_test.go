@@ -0,0 +1,125 @@
+package http
+
+import (
+	"bufio"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// streamLines connects to the SSE handler as userID and returns its lines.
+func streamLines(t *testing.T, h http.Handler, userID, lastEventID string) (<-chan string, context.CancelFunc) {
+	return tenantStreamLines(t, h, domain.DefaultTenant, userID, lastEventID)
+}
+
+// tenantStreamLines is streamLines for a user of the given tenant.
+func tenantStreamLines(
+	t *testing.T,
+	h http.Handler,
+	tenant, userID, lastEventID string,
+) (<-chan string, context.CancelFunc) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		r.Header.Set("user-id", userID)
+		h.ServeHTTP(w, r.WithContext(domain.WithTenant(r.Context(), tenant)))
+	}))
+	t.Cleanup(srv.Close)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	req, _ := http.NewRequestWithContext(ctx, http.MethodGet, srv.URL, nil)
+	if lastEventID != "" {
+		req.Header.Set("Last-Event-ID", lastEventID)
+	}
+	resp, err := http.DefaultClient.Do(req)
+	if !assert.NoError(t, err) {
+		cancel()
+		return nil, cancel
+	}
+	assert.Equal(t, "text/event-stream", resp.Header.Get("Content-Type"))
+
+	lines := make(chan string, 64)
+	go func() {
+		defer resp.Body.Close()
+		defer close(lines)
+		sc := bufio.NewScanner(resp.Body)
+		for sc.Scan() {
+			lines <- sc.Text()
+		}
+	}()
+	return lines, cancel
+}
+
+func nextLine(t *testing.T, lines <-chan string) string {
+	for {
+		select {
+		case l := <-lines:
+			if l == "" || strings.HasPrefix(l, "data:") {
+				continue
+			}
+			return l
+		case <-time.After(2 * time.Second):
+			t.Fatal("timed out waiting for event")
+			return ""
+		}
+	}
+}
+
+func TestStreamUserEvents_FiltersForNonAdmins(t *testing.T) {
+	broker := application.NewEventBroker(10)
+	h := &Handler{eventStream: broker}
+
+	lines, cancel := streamLines(t, h.StreamUserEvents([]string{"admin"}), "u1", "")
+	defer cancel()
+
+	// Give the handler time to subscribe.
+	time.Sleep(50 * time.Millisecond)
+	_ = broker.Publish(context.Background(), domain.Event{ID: "e1", Type: domain.EventUserUpdated, UserID: "u2"})
+	_ = broker.Publish(context.Background(), domain.Event{ID: "e2", Type: domain.EventUserUpdated, UserID: "u1"})
+
+	assert.Equal(t, "id: e2", nextLine(t, lines))
+	assert.Equal(t, "event: user.updated", nextLine(t, lines))
+}
+
+func TestStreamUserEvents_ResumeAndReset(t *testing.T) {
+	broker := application.NewEventBroker(2)
+	for _, id := range []string{"e1", "e2", "e3"} {
+		_ = broker.Publish(context.Background(), domain.Event{ID: id, Type: domain.EventUserCreated, UserID: "u1"})
+	}
+	h := &Handler{eventStream: broker}
+
+	lines, cancel := streamLines(t, h.StreamUserEvents([]string{"admin"}), "admin", "e2")
+	assert.Equal(t, "id: e3", nextLine(t, lines))
+	cancel()
+
+	lines, cancel = streamLines(t, h.StreamUserEvents([]string{"admin"}), "admin", "e1")
+	defer cancel()
+	assert.Equal(t, "event: reset", nextLine(t, lines))
+}
+
+func TestStreamUserEvents_OnlyTheCallersTenant(t *testing.T) {
+	broker := application.NewEventBroker(10)
+	for _, e := range []domain.Event{
+		{ID: "e1", Type: domain.EventUserCreated, TenantID: "acme", UserID: "u1"},
+		{ID: "e2", Type: domain.EventUserCreated, TenantID: "globex", UserID: "u2"},
+		{ID: "e3", Type: domain.EventUserUpdated, TenantID: "acme", UserID: "u1"},
+	} {
+		_ = broker.Publish(context.Background(), e)
+	}
+	h := &Handler{eventStream: broker}
+
+	lines, cancel := tenantStreamLines(t, h.StreamUserEvents([]string{"admin"}), "acme", "admin", "e1")
+	defer cancel()
+	assert.Equal(t, "id: e3", nextLine(t, lines))
+
+	time.Sleep(50 * time.Millisecond)
+	_ = broker.Publish(context.Background(), domain.Event{ID: "e4", Type: domain.EventUserUpdated, TenantID: "globex", UserID: "u2"})
+	_ = broker.Publish(context.Background(), domain.Event{ID: "e5", Type: domain.EventUserDeleted, TenantID: "acme", UserID: "u1"})
+	assert.Equal(t, "event: user.updated", nextLine(t, lines))
+	assert.Equal(t, "id: e5", nextLine(t, lines))
+}
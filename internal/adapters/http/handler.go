@@ -2,21 +2,47 @@ package http
 
 import (
 	"encoding/json"
+	"errors"
+	"log"
+	"mime"
 	"net/http"
+	"slices"
 	"strings"
 
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
 )
 
 type Handler struct {
-	userService ports.UserService
+	userService    ports.UserService
+	avatarService  ports.AvatarService
+	auditService   ports.AuditService
+	webhookService ports.WebhookService
+	eventStream    ports.EventStream
+	transfer       ports.UserTransferService
+	privacy        ports.PrivacyService
+	orgService     ports.OrganizationService
 }
 
 func NewHandler(
 	userSvc ports.UserService,
+	avatarSvc ports.AvatarService,
+	auditSvc ports.AuditService,
+	webhookSvc ports.WebhookService,
+	events ports.EventStream,
+	transfer ports.UserTransferService,
+	privacy ports.PrivacyService,
+	orgSvc ports.OrganizationService,
 ) *Handler {
 	return &Handler{
-		userService: userSvc,
+		userService:    userSvc,
+		avatarService:  avatarSvc,
+		auditService:   auditSvc,
+		webhookService: webhookSvc,
+		eventStream:    events,
+		transfer:       transfer,
+		privacy:        privacy,
+		orgService:     orgSvc,
 	}
 }
 
@@ -41,6 +67,10 @@ func (h *Handler) Login(w http.ResponseWriter, r *http.Request) {
 		strings.TrimSpace(req.Email),
 		req.Password,
 	)
+	if errors.Is(err, domain.ErrAccountInactive) {
+		http.Error(w, err.Error(), http.StatusForbidden)
+		return
+	}
 	if err != nil {
 		http.Error(w, "invalid credentials", http.StatusUnauthorized)
 		return
@@ -75,37 +105,177 @@ func (h *Handler) CreateUser(w http.ResponseWriter, r *http.Request) {
 		req.Password,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusConflict)
+		writeUserError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusCreated)
 }
 
-func (h *Handler) GetUser(w http.ResponseWriter, r *http.Request) {
-	id := strings.TrimPrefix(r.URL.Path, "/users/")
-	if id == "" {
-		http.Error(w, "missing id", http.StatusBadRequest)
+func (h *Handler) ConfirmEmail(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token string `json:"token"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
 		return
 	}
 
-	user, err := h.userService.GetByID(r.Context(), id)
+	err := h.userService.ConfirmEmailChange(r.Context(), strings.TrimSpace(req.Token))
+	if errors.Is(err, domain.ErrInvalidToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
 	if err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		http.Error(w, "internal error", http.StatusInternalServerError)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, user)
+	w.WriteHeader(http.StatusNoContent)
 }
 
-func (h *Handler) ListUsers(w http.ResponseWriter, r *http.Request) {
-	users, err := h.userService.List(r.Context())
+// AcceptInvitation sets the password of an imported user from the token
+// they were mailed, activating the account.
+func (h *Handler) AcceptInvitation(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Token    string `json:"token"`
+		Password string `json:"password"`
+	}
+
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil || req.Token == "" {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	err := h.userService.AcceptInvitation(r.Context(), strings.TrimSpace(req.Token), req.Password)
+	if errors.Is(err, domain.ErrInvalidToken) {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
 	if err != nil {
-		http.Error(w, "internal error", http.StatusInternalServerError)
+		writeUserError(w, err)
 		return
 	}
 
-	respondJSON(w, http.StatusOK, users)
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// GetUser shows admins any user and everyone else the users ListUsers
+// would show them; the others are not found.
+func (h *Handler) GetUser(adminIDs []string) http.HandlerFunc {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := strings.TrimPrefix(r.URL.Path, "/users/")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+
+		callerID := r.Header.Get("user-id")
+		if _, ok := admins[callerID]; !ok {
+			visible, err := h.orgService.VisibleUsers(r.Context(), callerID, "")
+			if err != nil {
+				writeUserError(w, err)
+				return
+			}
+			if !slices.Contains(visible, id) {
+				writeUserError(w, domain.ErrNotFound)
+				return
+			}
+		}
+
+		user, err := h.userService.GetByID(r.Context(), id)
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		w.Header().Set("ETag", etag(user.Version))
+		respondJSON(w, http.StatusOK, user)
+	}
+}
+
+// ListUsers shows admins every user and everyone else the users sharing
+// an organization with them. ?org_id= narrows the listing to the members
+// of one organization the caller belongs to.
+func (h *Handler) ListUsers(adminIDs []string) http.HandlerFunc {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		q, err := parseUserQuery(r.URL.Query())
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if q.Filter.UserIDs, err = h.visibleUsers(r, admins); err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		page, err := h.userService.List(r.Context(), q)
+		if errors.Is(err, domain.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, page)
+	}
+}
+
+// SearchUsers finds the users ListUsers would show.
+func (h *Handler) SearchUsers(adminIDs []string) http.HandlerFunc {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		v := r.URL.Query()
+
+		q := domain.UserSearch{Text: v.Get("q")}
+
+		var err error
+		if q.Limit, err = intParam(v, "limit"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if q.Offset, err = intParam(v, "offset"); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if q.UserIDs, err = h.visibleUsers(r, admins); err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		page, err := h.userService.Search(r.Context(), q)
+		if errors.Is(err, domain.ErrInvalidQuery) {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+		if err != nil {
+			http.Error(w, "internal error", http.StatusInternalServerError)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, page)
+	}
 }
 
 func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
@@ -122,6 +292,17 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 
 	if id != r.Header.Get("user-id") {
 		http.Error(w, "cannot update another user's data", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatch(r)
+	if errors.Is(err, domain.ErrVersionConflict) {
+		writeUserError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
 	}
 
 	var req struct {
@@ -134,20 +315,90 @@ func (h *Handler) UpdateUser(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	err := h.userService.Update(
+	user, err := h.userService.Update(
 		r.Context(),
 		id,
 		strings.TrimSpace(req.Name),
 		strings.TrimSpace(req.Email),
+		version,
 	)
 	if err != nil {
-		http.Error(w, err.Error(), http.StatusBadRequest)
+		writeUserError(w, err)
 		return
 	}
 
+	w.Header().Set("ETag", etag(user.Version))
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) PatchUser(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPatch {
+		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	id := strings.TrimPrefix(r.URL.Path, "/users/")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	if id != r.Header.Get("user-id") {
+		http.Error(w, "cannot update another user's data", http.StatusBadRequest)
+		return
+	}
+
+	version, err := ifMatch(r)
+	if errors.Is(err, domain.ErrVersionConflict) {
+		writeUserError(w, err)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var patch domain.UserPatch
+
+	mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type"))
+	switch mediaType {
+	case mergePatchType, "application/json":
+		patch, err = parseMergePatch(r.Body)
+	case jsonPatchType:
+		current, getErr := h.userService.GetByID(r.Context(), id)
+		if getErr != nil {
+			writeUserError(w, getErr)
+			return
+		}
+		// "test" ops are evaluated against this version, so pin it.
+		if version == 0 {
+			version = current.Version
+		}
+		patch, err = applyJSONPatch(current, r.Body)
+	default:
+		w.Header().Set("Accept-Patch", mergePatchType+", "+jsonPatchType)
+		http.Error(w, "unsupported patch format", http.StatusUnsupportedMediaType)
+		return
+	}
+	if errors.Is(err, errPatchTestFailed) {
+		http.Error(w, err.Error(), http.StatusConflict)
+		return
+	}
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	user, err := h.userService.Patch(r.Context(), id, patch, version)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.Header().Set("ETag", etag(user.Version))
+	respondJSON(w, http.StatusOK, user)
+}
+
 func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 	if r.Method != http.MethodDelete {
 		http.Error(w, "method not allowed", http.StatusMethodNotAllowed)
@@ -162,16 +413,91 @@ func (h *Handler) DeleteUser(w http.ResponseWriter, r *http.Request) {
 
 	if id != r.Header.Get("user-id") {
 		http.Error(w, "cannot delete another user's data", http.StatusBadRequest)
+		return
 	}
 
 	if err := h.userService.Delete(r.Context(), id); err != nil {
-		http.Error(w, "not found", http.StatusNotFound)
+		writeUserError(w, err)
 		return
 	}
 
 	w.WriteHeader(http.StatusNoContent)
 }
 
+func (h *Handler) RestoreUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	if err := h.userService.Restore(r.Context(), id); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ChangeStatus returns an admin handler moving the user to the given status.
+func (h *Handler) ChangeStatus(to domain.UserStatus) http.HandlerFunc {
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+
+		var req struct {
+			Reason string `json:"reason"`
+		}
+		if r.ContentLength != 0 {
+			if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+				http.Error(w, "invalid request body", http.StatusBadRequest)
+				return
+			}
+		}
+
+		user, err := h.userService.ChangeStatus(r.Context(), id, to, req.Reason)
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		respondJSON(w, http.StatusOK, user)
+	}
+}
+
+// writeUserError answers with the status matching an error from the user
+// services. Errors that are not the client's fault are logged and hidden.
+func writeUserError(w http.ResponseWriter, err error) {
+	switch {
+	case errors.Is(err, domain.ErrInvalidID),
+		errors.Is(err, domain.ErrInvalidPatch),
+		errors.Is(err, domain.ErrInvalidAvatar),
+		errors.Is(err, domain.ErrInvalidImport),
+		errors.Is(err, domain.ErrWeakPassword),
+		errors.Is(err, domain.ErrUnknownTenant),
+		errors.Is(err, domain.ErrInvalidOrganization),
+		errors.Is(err, domain.ErrInvalidWebhook),
+		errors.Is(err, domain.ErrInvalidQuery):
+		http.Error(w, err.Error(), http.StatusBadRequest)
+	case errors.Is(err, domain.ErrForbidden):
+		http.Error(w, err.Error(), http.StatusForbidden)
+	case errors.Is(err, domain.ErrNotFound):
+		http.Error(w, "not found", http.StatusNotFound)
+	case errors.Is(err, domain.ErrVersionConflict):
+		http.Error(w, "user was modified, reload and retry", http.StatusPreconditionFailed)
+	case errors.Is(err, domain.ErrAlreadyExists),
+		errors.Is(err, domain.ErrInvalidStatus),
+		errors.Is(err, domain.ErrLastOwner):
+		http.Error(w, err.Error(), http.StatusConflict)
+	default:
+		log.Printf("internal error: %v", err)
+		http.Error(w, "internal error", http.StatusInternalServerError)
+	}
+}
+
 func respondJSON(w http.ResponseWriter, status int, payload interface{}) {
 	w.Header().Set("Content-Type", "application/json")
 	w.WriteHeader(status)
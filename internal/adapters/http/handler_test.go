@@ -0,0 +1,139 @@
+package http
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	jwtmocks "github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure/mocks"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestWriteUserError(t *testing.T) {
+	tests := []struct {
+		err  error
+		code int
+	}{
+		{domain.ErrInvalidID, http.StatusBadRequest},
+		{fmt.Errorf("%w: name cannot be empty", domain.ErrInvalidPatch), http.StatusBadRequest},
+		{domain.ErrNotFound, http.StatusNotFound},
+		{domain.ErrVersionConflict, http.StatusPreconditionFailed},
+		{domain.ErrEmailTaken, http.StatusConflict},
+		{domain.ErrInvalidStatus, http.StatusConflict},
+		{fmt.Errorf("%w: header has no email column", domain.ErrInvalidImport), http.StatusBadRequest},
+		{fmt.Errorf("%w: needs a digit", domain.ErrWeakPassword), http.StatusBadRequest},
+		{domain.ErrUnknownTenant, http.StatusBadRequest},
+		{fmt.Errorf("%w: url must be http or https", domain.ErrInvalidWebhook), http.StatusBadRequest},
+		{fmt.Errorf("%w: limit must be a number", domain.ErrInvalidQuery), http.StatusBadRequest},
+		{errors.New("connection reset"), http.StatusInternalServerError},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.err.Error(), func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			writeUserError(rec, tt.err)
+			assert.Equal(t, tt.code, rec.Code)
+		})
+	}
+
+	rec := httptest.NewRecorder()
+	writeUserError(rec, errors.New("dial tcp 10.0.0.1:27017: connection refused"))
+	assert.Equal(t, "internal error\n", rec.Body.String())
+}
+
+func TestDeleteUser_OtherUser(t *testing.T) {
+	// No user service: the request must be refused before reaching it.
+	h := NewHandler(nil, nil, nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodDelete, "/users/6ad56e6e50aaf258e2a3207f", nil)
+	req.Header.Set("user-id", "6ad56e6e50aaf258e2a32080")
+	rec := httptest.NewRecorder()
+	h.DeleteUser(rec, req)
+
+	assert.Equal(t, http.StatusBadRequest, rec.Code)
+}
+
+func TestCreateUser_Errors(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			if email == "taken@test.com" {
+				return &domain.User{Email: email}, nil
+			}
+			return nil, domain.ErrNotFound
+		},
+		CreateFn: func(ctx context.Context, user *domain.User) error {
+			return errors.New("dial tcp 10.0.0.1:27017: connection refused")
+		},
+	}
+	users := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{},
+		&mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+	h := NewHandler(users, nil, nil, nil, nil, nil, nil, nil)
+
+	tests := []struct {
+		email string
+		code  int
+		body  string
+	}{
+		{"taken@test.com", http.StatusConflict, "email already exists\n"},
+		{"new@test.com", http.StatusInternalServerError, "internal error\n"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.email, func(t *testing.T) {
+			body := `{"name":"Alice","email":"` + tt.email + `","password":"secret123"}`
+			rec := httptest.NewRecorder()
+			h.CreateUser(rec, httptest.NewRequest(http.MethodPost, "/users", strings.NewReader(body)))
+
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, tt.body, rec.Body.String())
+		})
+	}
+}
+
+func TestIfMatch(t *testing.T) {
+	tests := []struct {
+		header  string
+		version int64
+		err     error
+	}{
+		{"", 0, nil},
+		{"*", 0, nil},
+		{`"3"`, 3, nil},
+		{`W/"3"`, 0, domain.ErrVersionConflict},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.header, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPut, "/users/u1", nil)
+			req.Header.Set("If-Match", tt.header)
+			version, err := ifMatch(req)
+			assert.Equal(t, tt.version, version)
+			assert.Equal(t, tt.err, err)
+		})
+	}
+
+	req := httptest.NewRequest(http.MethodPut, "/users/u1", nil)
+	req.Header.Set("If-Match", `"abc"`)
+	_, err := ifMatch(req)
+	assert.Error(t, err)
+}
+
+func TestPatchUser_WeakETag(t *testing.T) {
+	// No user service: the request must be refused before reaching it.
+	h := NewHandler(nil, nil, nil, nil, nil, nil, nil, nil)
+
+	req := httptest.NewRequest(http.MethodPatch, "/users/u1", strings.NewReader(`{"name":"Jane"}`))
+	req.Header.Set("user-id", "u1")
+	req.Header.Set("Content-Type", "application/merge-patch+json")
+	req.Header.Set("If-Match", `W/"1"`)
+	rec := httptest.NewRecorder()
+	h.PatchUser(rec, req)
+
+	assert.Equal(t, http.StatusPreconditionFailed, rec.Code)
+}
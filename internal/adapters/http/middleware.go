@@ -1,31 +1,111 @@
 package http
 
 import (
+	"crypto/rand"
+	"encoding/hex"
+	"errors"
 	"log"
+	"net"
 	"net/http"
+	"strings"
 	"time"
 
-	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
 )
 
+// Logging logs each request and attaches its domain.RequestMeta. The
+// request ID is taken from X-Request-ID when the client sends one.
 func Logging(next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
 		start := time.Now()
+
+		requestID := r.Header.Get("X-Request-ID")
+		if requestID == "" {
+			requestID = newRequestID()
+		}
+		w.Header().Set("X-Request-ID", requestID)
+
+		ip, _, err := net.SplitHostPort(r.RemoteAddr)
+		if err != nil {
+			ip = r.RemoteAddr
+		}
+
+		ctx := domain.WithRequestMeta(r.Context(), domain.RequestMeta{IP: ip, RequestID: requestID})
+		next.ServeHTTP(w, r.WithContext(ctx))
+		log.Printf("%s %s %s %s", requestID, r.Method, r.URL.Path, time.Since(start))
+	})
+}
+
+func newRequestID() string {
+	b := make([]byte, 8)
+	_, _ = rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// Tenant scopes the request to the tenant named by the X-Tenant-ID header
+// or, failing that, to the one serving the request's host. A header naming
+// an unknown tenant is rejected. Requests matching neither are left
+// unscoped: Auth then takes the tenant from the token, and anonymous
+// requests are served by the default tenant.
+func Tenant(tenants ports.TenantRegistry, next http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		var tenant *domain.Tenant
+		if id := r.Header.Get("X-Tenant-ID"); id != "" {
+			t, err := tenants.Tenant(id)
+			if err != nil {
+				http.Error(w, err.Error(), http.StatusBadRequest)
+				return
+			}
+			tenant = t
+		} else if t, err := tenants.TenantForHost(r.Host); err == nil {
+			tenant = t
+		}
+
+		if tenant != nil {
+			r = r.WithContext(domain.WithTenant(r.Context(), tenant.ID))
+		}
 		next.ServeHTTP(w, r)
-		log.Printf("%s %s %s", r.Method, r.URL.Path, time.Since(start))
 	})
 }
 
-func Auth(jwt infrastructure.JWTManager, next http.Handler) http.Handler {
+// Auth lets through requests bearing a valid token, scoped to the tenant
+// of its user.
+func Auth(users ports.UserService, next http.Handler) http.Handler {
 	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
-		token := r.Header.Get("Authorization")
-		userID, err := jwt.Validate(token)
+		token := strings.TrimPrefix(r.Header.Get("Authorization"), "Bearer ")
+		user, err := users.Authenticate(r.Context(), token)
+		if errors.Is(err, domain.ErrAccountInactive) {
+			http.Error(w, err.Error(), http.StatusForbidden)
+			return
+		}
 		if err != nil {
 			http.Error(w, "unauthorized", http.StatusUnauthorized)
 			return
 		}
 
-		r.Header.Add("user-id", userID)
+		meta := domain.RequestMetaFrom(r.Context())
+		meta.ActorID = user.ID
+
+		r.Header.Set("user-id", user.ID)
+		ctx := domain.WithTenant(domain.WithRequestMeta(r.Context(), meta), user.TenantID)
+		next.ServeHTTP(w, r.WithContext(ctx))
+	})
+}
+
+// Admin only lets through authenticated users listed as administrators.
+// It must be wrapped by Auth.
+func Admin(adminIDs []string, next http.Handler) http.Handler {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if _, ok := admins[r.Header.Get("user-id")]; !ok {
+			http.Error(w, "forbidden", http.StatusForbidden)
+			return
+		}
 		next.ServeHTTP(w, r)
 	})
 }
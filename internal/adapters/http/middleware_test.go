@@ -0,0 +1,62 @@
+package http
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestTenant(t *testing.T) {
+	tenants := &mocks.TenantRegistryMock{
+		TenantFn: func(id string) (*domain.Tenant, error) {
+			if id != "acme" && id != "globex" {
+				return nil, domain.ErrUnknownTenant
+			}
+			return &domain.Tenant{ID: id}, nil
+		},
+		TenantForHostFn: func(host string) (*domain.Tenant, error) {
+			if host != "acme.example.com" {
+				return nil, domain.ErrUnknownTenant
+			}
+			return &domain.Tenant{ID: "acme"}, nil
+		},
+	}
+
+	tests := []struct {
+		name   string
+		host   string
+		header string
+		code   int
+		tenant string
+	}{
+		{"header", "example.com", "globex", http.StatusOK, "globex"},
+		{"header wins over host", "acme.example.com", "globex", http.StatusOK, "globex"},
+		{"host", "acme.example.com", "", http.StatusOK, "acme"},
+		{"neither", "example.com", "", http.StatusOK, ""},
+		{"unknown header", "acme.example.com", "initech", http.StatusBadRequest, ""},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			var tenant string
+			next := http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				tenant, _ = domain.TenantFrom(r.Context())
+			})
+
+			req := httptest.NewRequest(http.MethodGet, "/users", nil)
+			req.Host = tt.host
+			if tt.header != "" {
+				req.Header.Set("X-Tenant-ID", tt.header)
+			}
+			rec := httptest.NewRecorder()
+			Tenant(tenants, next).ServeHTTP(rec, req)
+
+			assert.Equal(t, tt.code, rec.Code)
+			assert.Equal(t, tt.tenant, tenant)
+		})
+	}
+}
@@ -0,0 +1,159 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func (h *Handler) CreateOrganization(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Name string `json:"name"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	org, err := h.orgService.Create(r.Context(), r.Header.Get("user-id"), req.Name)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusCreated, org)
+}
+
+// ListOrganizations lists the caller's organizations, including those
+// they are invited to.
+func (h *Handler) ListOrganizations(w http.ResponseWriter, r *http.Request) {
+	orgs, err := h.orgService.List(r.Context(), r.Header.Get("user-id"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"organizations": orgs})
+}
+
+func (h *Handler) GetOrganization(w http.ResponseWriter, r *http.Request) {
+	org, err := h.orgService.Get(r.Context(), r.Header.Get("user-id"), r.PathValue("id"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, org)
+}
+
+// ListMembers lists the members of an organization:
+//
+//	?team=backend
+func (h *Handler) ListMembers(w http.ResponseWriter, r *http.Request) {
+	members, err := h.orgService.Members(
+		r.Context(),
+		r.Header.Get("user-id"),
+		r.PathValue("id"),
+		r.URL.Query().Get("team"),
+	)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"members": members})
+}
+
+func (h *Handler) InviteMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Email string         `json:"email"`
+		Role  domain.OrgRole `json:"role"`
+		Teams []string       `json:"teams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+	if req.Role == "" {
+		req.Role = domain.OrgRoleMember
+	}
+
+	err := h.orgService.Invite(
+		r.Context(),
+		r.Header.Get("user-id"),
+		r.PathValue("id"),
+		req.Email,
+		req.Role,
+		req.Teams,
+	)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	// The same answer whether or not the email is registered.
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// AcceptMembership accepts the caller's invitation to the organization.
+func (h *Handler) AcceptMembership(w http.ResponseWriter, r *http.Request) {
+	m, err := h.orgService.Accept(r.Context(), r.Header.Get("user-id"), r.PathValue("id"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, m)
+}
+
+// UpdateMember changes the fields present in the body:
+//
+//	{"role": "admin", "teams": ["backend"]}
+func (h *Handler) UpdateMember(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		Role  *domain.OrgRole `json:"role"`
+		Teams *[]string       `json:"teams"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	m, err := h.orgService.ChangeMember(
+		r.Context(),
+		r.Header.Get("user-id"),
+		r.PathValue("id"),
+		r.PathValue("user_id"),
+		domain.MembershipPatch{Role: req.Role, Teams: req.Teams},
+	)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, m)
+}
+
+// RemoveMember takes a member out; members may remove themselves.
+func (h *Handler) RemoveMember(w http.ResponseWriter, r *http.Request) {
+	err := h.orgService.Remove(r.Context(), r.Header.Get("user-id"), r.PathValue("id"), r.PathValue("user_id"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// visibleUsers restricts a user listing to what the caller may see. It
+// returns nil, no restriction, for admins not asking about one
+// organization.
+func (h *Handler) visibleUsers(r *http.Request, admins map[string]struct{}) ([]string, error) {
+	callerID := r.Header.Get("user-id")
+	orgID := r.URL.Query().Get("org_id")
+	if _, ok := admins[callerID]; ok && orgID == "" {
+		return nil, nil
+	}
+	return h.orgService.VisibleUsers(r.Context(), callerID, orgID)
+}
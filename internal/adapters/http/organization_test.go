@@ -0,0 +1,157 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	jwtmocks "github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure/mocks"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+// newOrganizationMux serves the organization routes and user lookups
+// without authentication; requests name their caller in user-id.
+func newOrganizationMux(t *testing.T, names ...string) (*http.ServeMux, map[string]string) {
+	users := memory.NewUserRepository(memory.NewOutboxRepository())
+	ids := map[string]string{}
+	for _, name := range names {
+		u := &domain.User{Name: name, Email: name + "@test.com", Status: domain.StatusActive}
+		assert.NoError(t, users.Create(context.Background(), u))
+		ids[name] = u.ID
+	}
+
+	audit := &mocks.AuditServiceMock{}
+	userSvc := application.NewUserService(users, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, audit, &mocks.TenantRegistryMock{})
+	orgSvc := application.NewOrganizationService(memory.NewOrganizationRepository(), users, &mocks.MailerMock{}, audit)
+	h := NewHandler(userSvc, nil, nil, nil, nil, nil, nil, orgSvc)
+
+	mux := http.NewServeMux()
+	mux.Handle("GET /users", h.ListUsers([]string{ids["admin"]}))
+	mux.Handle("GET /users/{id}", h.GetUser([]string{ids["admin"]}))
+	mux.HandleFunc("POST /orgs", h.CreateOrganization)
+	mux.HandleFunc("GET /orgs", h.ListOrganizations)
+	mux.HandleFunc("GET /orgs/{id}/members", h.ListMembers)
+	mux.HandleFunc("POST /orgs/{id}/members", h.InviteMember)
+	mux.HandleFunc("POST /orgs/{id}/accept", h.AcceptMembership)
+	mux.HandleFunc("PATCH /orgs/{id}/members/{user_id}", h.UpdateMember)
+	mux.HandleFunc("DELETE /orgs/{id}/members/{user_id}", h.RemoveMember)
+	return mux, ids
+}
+
+func serve(mux http.Handler, method, target, callerID, body string) *httptest.ResponseRecorder {
+	req := httptest.NewRequest(method, target, strings.NewReader(body))
+	req.Header.Set("user-id", callerID)
+	rec := httptest.NewRecorder()
+	mux.ServeHTTP(rec, req)
+	return rec
+}
+
+func TestOrganizationEndpoints(t *testing.T) {
+	mux, ids := newOrganizationMux(t, "ann", "bob")
+	ann, bob := ids["ann"], ids["bob"]
+
+	rec := serve(mux, http.MethodPost, "/orgs", ann, `{"name":"Acme"}`)
+	assert.Equal(t, http.StatusCreated, rec.Code)
+	var org domain.Organization
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&org))
+	members := "/orgs/" + org.ID + "/members"
+
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodPost, "/orgs", ann, `{"name":""}`).Code)
+
+	rec = serve(mux, http.MethodPost, members, ann, `{"email":"bob@test.com","teams":["backend"]}`)
+	assert.Equal(t, http.StatusAccepted, rec.Code)
+	assert.Empty(t, rec.Body.String())
+	rec = serve(mux, http.MethodGet, members, ann, "")
+	assert.Contains(t, rec.Body.String(), `"user_id":"`+bob+`","role":"member","teams":["backend"],"state":"invited"`)
+
+	// Unknown emails and repeated invitations answer alike.
+	assert.Equal(t, http.StatusAccepted, serve(mux, http.MethodPost, members, ann, `{"email":"nobody@test.com"}`).Code)
+	assert.Equal(t, http.StatusAccepted, serve(mux, http.MethodPost, members, ann, `{"email":"bob@test.com"}`).Code)
+	assert.Equal(t, http.StatusForbidden, serve(mux, http.MethodGet, members, bob, "").Code)
+	assert.Equal(t, http.StatusOK, serve(mux, http.MethodPost, "/orgs/"+org.ID+"/accept", bob, "").Code)
+
+	rec = serve(mux, http.MethodGet, members+"?team=backend", bob, "")
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"user_id":"`+bob+`"`)
+	assert.NotContains(t, rec.Body.String(), `"user_id":"`+ann+`"`)
+
+	assert.Equal(t, http.StatusForbidden, serve(mux, http.MethodPatch, members+"/"+ann, bob, `{"role":"member"}`).Code)
+	assert.Equal(t, http.StatusBadRequest, serve(mux, http.MethodPatch, members+"/"+bob, ann, `{"role":"boss"}`).Code)
+	assert.Equal(t, http.StatusConflict, serve(mux, http.MethodDelete, members+"/"+ann, ann, "").Code)
+
+	rec = serve(mux, http.MethodPatch, members+"/"+bob, ann, `{"role":"admin"}`)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Contains(t, rec.Body.String(), `"role":"admin"`)
+
+	assert.Equal(t, http.StatusNoContent, serve(mux, http.MethodDelete, members+"/"+bob, bob, "").Code)
+	assert.Equal(t, http.StatusNotFound, serve(mux, http.MethodGet, members, bob, "").Code)
+}
+
+func TestListUsers_MembershipAware(t *testing.T) {
+	mux, ids := newOrganizationMux(t, "admin", "ann", "bob", "carol")
+	ann, bob, carol := ids["ann"], ids["bob"], ids["carol"]
+
+	rec := serve(mux, http.MethodPost, "/orgs", ann, `{"name":"Acme"}`)
+	var org domain.Organization
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&org))
+	serve(mux, http.MethodPost, "/orgs/"+org.ID+"/members", ann, `{"email":"bob@test.com"}`)
+	serve(mux, http.MethodPost, "/orgs/"+org.ID+"/accept", bob, "")
+
+	list := func(callerID, query string) (int, []string) {
+		rec := serve(mux, http.MethodGet, "/users?sort=name&order=asc"+query, callerID, "")
+		var page domain.UserPage
+		_ = json.NewDecoder(rec.Body).Decode(&page)
+		var names []string
+		for _, u := range page.Users {
+			names = append(names, u.Name)
+		}
+		return rec.Code, names
+	}
+
+	code, names := list(ids["admin"], "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"admin", "ann", "bob", "carol"}, names)
+
+	code, names = list(bob, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"ann", "bob"}, names)
+
+	code, names = list(carol, "")
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"carol"}, names)
+
+	code, names = list(ann, "&org_id="+org.ID)
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, []string{"ann", "bob"}, names)
+
+	code, _ = list(carol, "&org_id="+org.ID)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func TestGetUser_MembershipAware(t *testing.T) {
+	mux, ids := newOrganizationMux(t, "admin", "ann", "bob", "carol")
+	ann, bob, carol := ids["ann"], ids["bob"], ids["carol"]
+
+	rec := serve(mux, http.MethodPost, "/orgs", ann, `{"name":"Acme"}`)
+	var org domain.Organization
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&org))
+	serve(mux, http.MethodPost, "/orgs/"+org.ID+"/members", ann, `{"email":"bob@test.com"}`)
+	serve(mux, http.MethodPost, "/orgs/"+org.ID+"/accept", bob, "")
+
+	get := func(callerID, id string) int {
+		return serve(mux, http.MethodGet, "/users/"+id, callerID, "").Code
+	}
+
+	assert.Equal(t, http.StatusOK, get(ids["admin"], carol))
+	assert.Equal(t, http.StatusOK, get(bob, ann))
+	assert.Equal(t, http.StatusOK, get(carol, carol))
+	assert.Equal(t, http.StatusNotFound, get(carol, ann))
+	assert.Equal(t, http.StatusNotFound, get(ann, carol))
+}
@@ -0,0 +1,407 @@
+package http
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"reflect"
+	"slices"
+	"strconv"
+	"strings"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+const (
+	mergePatchType = "application/merge-patch+json"
+	jsonPatchType  = "application/json-patch+json"
+)
+
+var errPatchTestFailed = errors.New("patch test failed")
+
+var pointerUnescape = strings.NewReplacer("~1", "/", "~0", "~")
+
+// Patchable top-level fields. Required ones cannot be removed or nulled.
+var (
+	requiredFields = []string{"name", "email"}
+	optionalFields = []string{"display_name", "locale", "timezone", "phone", "avatar_url"}
+)
+
+// parseMergePatch reads an RFC 7396 merge patch of the editable user fields.
+func parseMergePatch(body io.Reader) (domain.UserPatch, error) {
+	var patch domain.UserPatch
+
+	var doc map[string]json.RawMessage
+	if err := json.NewDecoder(body).Decode(&doc); err != nil {
+		return patch, fmt.Errorf("%w: body must be a JSON object", domain.ErrInvalidPatch)
+	}
+
+	for key, raw := range doc {
+		if key == "attributes" {
+			var attrs map[string]interface{}
+			if err := json.Unmarshal(raw, &attrs); err != nil || attrs == nil {
+				return patch, fmt.Errorf("%w: attributes must be an object", domain.ErrInvalidPatch)
+			}
+			patch.Attributes = attrs
+			continue
+		}
+
+		field, err := patchField(&patch, key)
+		if err != nil {
+			return patch, err
+		}
+
+		var v string
+		if string(raw) == "null" {
+			if isRequired(key) {
+				return patch, fmt.Errorf("%w: %s cannot be removed", domain.ErrInvalidPatch, key)
+			}
+		} else if err := json.Unmarshal(raw, &v); err != nil {
+			return patch, fmt.Errorf("%w: %s must be a string", domain.ErrInvalidPatch, key)
+		}
+		v = strings.TrimSpace(v)
+		*field = &v
+	}
+
+	return patch, nil
+}
+
+type jsonPatchOp struct {
+	Op    string          `json:"op"`
+	Path  string          `json:"path"`
+	From  string          `json:"from"`
+	Value json.RawMessage `json:"value"`
+}
+
+// applyJSONPatch applies RFC 6902 operations to the editable fields of u
+// and returns what changed. A failed "test" op returns errPatchTestFailed.
+// Paths may point into attributes, e.g. /attributes/team, and their
+// arrays, e.g. /attributes/tags/0 or /attributes/tags/- to append.
+func applyJSONPatch(u *domain.User, body io.Reader) (domain.UserPatch, error) {
+	var patch domain.UserPatch
+
+	var ops []jsonPatchOp
+	if err := json.NewDecoder(body).Decode(&ops); err != nil {
+		return patch, fmt.Errorf("%w: body must be a JSON array", domain.ErrInvalidPatch)
+	}
+
+	current, err := editableDoc(u)
+	if err != nil {
+		return patch, err
+	}
+	doc, _ := editableDoc(u)
+
+	for _, op := range ops {
+		path, err := parsePointer(op.Path)
+		if err != nil {
+			return patch, err
+		}
+
+		switch op.Op {
+		case "add", "replace", "test":
+			var v interface{}
+			if err := json.Unmarshal(op.Value, &v); err != nil {
+				return patch, fmt.Errorf("%w: missing value for %s", domain.ErrInvalidPatch, op.Path)
+			}
+			if op.Op == "test" {
+				got, ok := pointerGet(doc, path)
+				if !ok || !reflect.DeepEqual(got, v) {
+					return patch, fmt.Errorf("%w: %s", errPatchTestFailed, op.Path)
+				}
+				continue
+			}
+			if op.Op == "replace" {
+				// Replacing is removing, then adding in the same place.
+				if _, err := pointerRemove(doc, path); err != nil {
+					return patch, err
+				}
+			}
+			err = pointerAdd(doc, path, v)
+		case "remove":
+			_, err = pointerRemove(doc, path)
+		case "copy", "move":
+			from, ferr := parsePointer(op.From)
+			if ferr != nil {
+				return patch, ferr
+			}
+			v, ok := pointerGet(doc, from)
+			if !ok {
+				return patch, fmt.Errorf("%w: %s does not exist", domain.ErrInvalidPatch, op.From)
+			}
+			if op.Op == "move" {
+				if _, err := pointerRemove(doc, from); err != nil {
+					return patch, err
+				}
+			} else {
+				v = cloneJSON(v)
+			}
+			err = pointerAdd(doc, path, v)
+		default:
+			return patch, fmt.Errorf("%w: unsupported op %q", domain.ErrInvalidPatch, op.Op)
+		}
+		if err != nil {
+			return patch, err
+		}
+	}
+
+	return diffDoc(current, doc)
+}
+
+// editableDoc is the JSON view of u that JSON Patch operates on.
+func editableDoc(u *domain.User) (map[string]interface{}, error) {
+	attrs := map[string]interface{}{}
+	if u.Attributes != nil {
+		b, err := json.Marshal(u.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &attrs); err != nil {
+			return nil, err
+		}
+	}
+
+	doc := map[string]interface{}{
+		"name":       u.Name,
+		"email":      u.Email,
+		"attributes": attrs,
+	}
+	for key, v := range map[string]string{
+		"display_name": u.DisplayName,
+		"locale":       u.Locale,
+		"timezone":     u.Timezone,
+		"phone":        u.Phone,
+		"avatar_url":   u.AvatarURL,
+	} {
+		if v != "" {
+			doc[key] = v
+		}
+	}
+
+	return doc, nil
+}
+
+// diffDoc turns the patched document back into a UserPatch.
+func diffDoc(from, to map[string]interface{}) (domain.UserPatch, error) {
+	var patch domain.UserPatch
+
+	for _, key := range slices.Concat(requiredFields, optionalFields) {
+		raw, ok := to[key]
+		if !ok && isRequired(key) {
+			return patch, fmt.Errorf("%w: %s cannot be removed", domain.ErrInvalidPatch, key)
+		}
+
+		v, isString := raw.(string)
+		if ok && !isString {
+			return patch, fmt.Errorf("%w: %s must be a string", domain.ErrInvalidPatch, key)
+		}
+		v = strings.TrimSpace(v)
+
+		if old, _ := from[key].(string); old == v {
+			continue
+		}
+		field, _ := patchField(&patch, key)
+		*field = &v
+	}
+
+	attrs, ok := to["attributes"].(map[string]interface{})
+	if _, present := to["attributes"]; present && !ok {
+		return patch, fmt.Errorf("%w: attributes must be an object", domain.ErrInvalidPatch)
+	}
+	old, _ := from["attributes"].(map[string]interface{})
+	patch.Attributes = domain.DiffAttributes(old, attrs)
+
+	return patch, nil
+}
+
+func parsePointer(path string) ([]string, error) {
+	rest, ok := strings.CutPrefix(path, "/")
+	if !ok {
+		return nil, fmt.Errorf("%w: invalid path %q", domain.ErrInvalidPatch, path)
+	}
+
+	tokens := strings.Split(rest, "/")
+	for i, t := range tokens {
+		tokens[i] = pointerUnescape.Replace(t)
+	}
+
+	key := tokens[0]
+	switch {
+	case key == "attributes":
+	case isRequired(key) || isOptional(key):
+		if len(tokens) > 1 {
+			return nil, fmt.Errorf("%w: invalid path %q", domain.ErrInvalidPatch, path)
+		}
+	default:
+		return nil, fmt.Errorf("%w: unknown path %q", domain.ErrInvalidPatch, path)
+	}
+
+	return tokens, nil
+}
+
+// pointerGet returns the value at path, walking objects by key and arrays
+// by index.
+func pointerGet(doc map[string]interface{}, path []string) (interface{}, bool) {
+	var v interface{} = doc
+	for _, t := range path {
+		switch c := v.(type) {
+		case map[string]interface{}:
+			next, ok := c[t]
+			if !ok {
+				return nil, false
+			}
+			v = next
+		case []interface{}:
+			i, ok := arrayIndex(t, len(c)-1)
+			if !ok {
+				return nil, false
+			}
+			v = c[i]
+		default:
+			return nil, false
+		}
+	}
+	return v, true
+}
+
+// pointerAdd adds v at path as the "add" op does: it sets an object
+// member, or inserts into an array before the index, "-" appending.
+func pointerAdd(doc map[string]interface{}, path []string, v interface{}) error {
+	return pointerUpdate(doc, path, func(parent interface{}, t string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			c[t] = v
+			return c, nil
+		case []interface{}:
+			i, ok := len(c), t == "-"
+			if !ok {
+				if i, ok = arrayIndex(t, len(c)); !ok {
+					return nil, fmt.Errorf("%w: invalid array index in /%s", domain.ErrInvalidPatch, strings.Join(path, "/"))
+				}
+			}
+			return slices.Insert(c, i, v), nil
+		}
+		return nil, fmt.Errorf("%w: parent of /%s does not exist", domain.ErrInvalidPatch, strings.Join(path, "/"))
+	})
+}
+
+// pointerRemove removes the value at path, shifting later array elements
+// down, and returns it.
+func pointerRemove(doc map[string]interface{}, path []string) (interface{}, error) {
+	var removed interface{}
+	err := pointerUpdate(doc, path, func(parent interface{}, t string) (interface{}, error) {
+		switch c := parent.(type) {
+		case map[string]interface{}:
+			if v, ok := c[t]; ok {
+				removed = v
+				delete(c, t)
+				return c, nil
+			}
+		case []interface{}:
+			if i, ok := arrayIndex(t, len(c)-1); ok {
+				removed = c[i]
+				return slices.Delete(c, i, i+1), nil
+			}
+		}
+		return nil, fmt.Errorf("%w: /%s does not exist", domain.ErrInvalidPatch, strings.Join(path, "/"))
+	})
+	return removed, err
+}
+
+// pointerUpdate walks to the parent of path's last token and stores what
+// fn makes of it in its place, as inserting into or removing from an
+// array yields a new slice.
+func pointerUpdate(doc map[string]interface{}, path []string, fn func(parent interface{}, token string) (interface{}, error)) error {
+	var update func(v interface{}, path []string) (interface{}, error)
+	update = func(v interface{}, path []string) (interface{}, error) {
+		if len(path) == 1 {
+			return fn(v, path[0])
+		}
+		switch c := v.(type) {
+		case map[string]interface{}:
+			if child, ok := c[path[0]]; ok {
+				child, err := update(child, path[1:])
+				if err != nil {
+					return nil, err
+				}
+				c[path[0]] = child
+				return c, nil
+			}
+		case []interface{}:
+			if i, ok := arrayIndex(path[0], len(c)-1); ok {
+				child, err := update(c[i], path[1:])
+				if err != nil {
+					return nil, err
+				}
+				c[i] = child
+				return c, nil
+			}
+		}
+		return nil, fmt.Errorf("%w: parent of /%s does not exist", domain.ErrInvalidPatch, strings.Join(path, "/"))
+	}
+
+	// The document is an object, which is updated in place.
+	_, err := update(doc, path)
+	return err
+}
+
+// arrayIndex parses an RFC 6901 array index, digits without leading
+// zeros, no greater than max.
+func arrayIndex(t string, max int) (int, bool) {
+	if t == "" || (len(t) > 1 && t[0] == '0') || strings.Trim(t, "0123456789") != "" {
+		return 0, false
+	}
+	i, err := strconv.Atoi(t)
+	if err != nil || i > max {
+		return 0, false
+	}
+	return i, true
+}
+
+// cloneJSON deep-copies a decoded JSON value, so a copied value is not
+// changed through its source.
+func cloneJSON(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		c := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			c[k] = cloneJSON(e)
+		}
+		return c
+	case []interface{}:
+		c := make([]interface{}, len(v))
+		for i, e := range v {
+			c[i] = cloneJSON(e)
+		}
+		return c
+	}
+	return v
+}
+
+func patchField(p *domain.UserPatch, key string) (**string, error) {
+	switch key {
+	case "name":
+		return &p.Name, nil
+	case "email":
+		return &p.Email, nil
+	case "display_name":
+		return &p.DisplayName, nil
+	case "locale":
+		return &p.Locale, nil
+	case "timezone":
+		return &p.Timezone, nil
+	case "phone":
+		return &p.Phone, nil
+	case "avatar_url":
+		return &p.AvatarURL, nil
+	}
+	return nil, fmt.Errorf("%w: %s cannot be patched", domain.ErrInvalidPatch, key)
+}
+
+func isRequired(key string) bool {
+	return slices.Contains(requiredFields, key)
+}
+
+func isOptional(key string) bool {
+	return slices.Contains(optionalFields, key)
+}
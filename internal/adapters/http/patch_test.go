@@ -0,0 +1,120 @@
+package http
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestParseMergePatch(t *testing.T) {
+	patch, err := parseMergePatch(strings.NewReader(`{"name":" Johnny "}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "Johnny", *patch.Name)
+	assert.Nil(t, patch.Email)
+
+	_, err = parseMergePatch(strings.NewReader(`{"email":null}`))
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+
+	_, err = parseMergePatch(strings.NewReader(`{"password":"x"}`))
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+}
+
+func TestApplyJSONPatch(t *testing.T) {
+	user := &domain.User{Name: "John", Email: "john@test.com"}
+
+	patch, err := applyJSONPatch(user, strings.NewReader(`[
+		{"op":"test","path":"/email","value":"john@test.com"},
+		{"op":"replace","path":"/email","value":"johnny@test.com"}
+	]`))
+
+	assert.NoError(t, err)
+	assert.Nil(t, patch.Name)
+	assert.Equal(t, "johnny@test.com", *patch.Email)
+
+	_, err = applyJSONPatch(user, strings.NewReader(`[{"op":"test","path":"/name","value":"Jane"}]`))
+	assert.ErrorIs(t, err, errPatchTestFailed)
+
+	_, err = applyJSONPatch(user, strings.NewReader(`[{"op":"remove","path":"/name"}]`))
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+}
+
+func TestParseMergePatch_Profile(t *testing.T) {
+	patch, err := parseMergePatch(strings.NewReader(`{
+		"display_name": "JJ",
+		"phone": null,
+		"attributes": {"team": "core", "legacy": null}
+	}`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "JJ", *patch.DisplayName)
+	assert.Equal(t, "", *patch.Phone)
+	assert.Equal(t, map[string]interface{}{"team": "core", "legacy": nil}, patch.Attributes)
+}
+
+func TestApplyJSONPatch_Attributes(t *testing.T) {
+	user := &domain.User{
+		Name:  "John",
+		Email: "john@test.com",
+		Profile: domain.Profile{
+			Attributes: map[string]interface{}{"team": "core", "prefs": map[string]interface{}{"theme": "dark"}},
+		},
+	}
+
+	patch, err := applyJSONPatch(user, strings.NewReader(`[
+		{"op":"replace","path":"/attributes/prefs/theme","value":"light"},
+		{"op":"remove","path":"/attributes/team"},
+		{"op":"add","path":"/locale","value":"th-TH"}
+	]`))
+
+	assert.NoError(t, err)
+	assert.Nil(t, patch.Name)
+	assert.Equal(t, "th-TH", *patch.Locale)
+	assert.Equal(t, map[string]interface{}{
+		"team":  nil,
+		"prefs": map[string]interface{}{"theme": "light"},
+	}, patch.Attributes)
+}
+
+func TestApplyJSONPatch_Arrays(t *testing.T) {
+	user := &domain.User{
+		Name:  "John",
+		Email: "john@test.com",
+		Profile: domain.Profile{
+			Attributes: map[string]interface{}{
+				"tags":  []interface{}{"a", "b"},
+				"links": []interface{}{map[string]interface{}{"url": "https://a.test"}},
+			},
+		},
+	}
+
+	patch, err := applyJSONPatch(user, strings.NewReader(`[
+		{"op":"add","path":"/attributes/tags/-","value":"d"},
+		{"op":"add","path":"/attributes/tags/0","value":"z"},
+		{"op":"replace","path":"/attributes/tags/2","value":"c"},
+		{"op":"remove","path":"/attributes/tags/1"},
+		{"op":"test","path":"/attributes/tags","value":["z","c","d"]},
+		{"op":"copy","from":"/attributes/links/0","path":"/attributes/links/-"},
+		{"op":"replace","path":"/attributes/links/1/url","value":"https://b.test"}
+	]`))
+
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"tags": []interface{}{"z", "c", "d"},
+		"links": []interface{}{
+			map[string]interface{}{"url": "https://a.test"},
+			map[string]interface{}{"url": "https://b.test"},
+		},
+	}, patch.Attributes)
+
+	for _, path := range []string{"/attributes/tags/2", "/attributes/tags/01", "/attributes/tags/x", "/attributes/tags/-"} {
+		_, err = applyJSONPatch(user, strings.NewReader(`[{"op":"remove","path":"`+path+`"}]`))
+		assert.ErrorIs(t, err, domain.ErrInvalidPatch, path)
+	}
+	_, err = applyJSONPatch(user, strings.NewReader(`[{"op":"add","path":"/attributes/tags/3","value":"x"}]`))
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+	_, err = applyJSONPatch(user, strings.NewReader(`[{"op":"test","path":"/attributes/tags/5","value":"a"}]`))
+	assert.ErrorIs(t, err, errPatchTestFailed)
+}
@@ -0,0 +1,68 @@
+package http
+
+import (
+	"encoding/json"
+	"fmt"
+	"net/http"
+)
+
+// ExportUserData returns the handler answering a subject access request
+// with everything held about the user as a JSON download. Users may only
+// export their own data; admins may export anyone's.
+func (h *Handler) ExportUserData(adminIDs []string) http.HandlerFunc {
+	admins := make(map[string]struct{}, len(adminIDs))
+	for _, id := range adminIDs {
+		admins[id] = struct{}{}
+	}
+
+	return func(w http.ResponseWriter, r *http.Request) {
+		id := r.PathValue("id")
+		if id == "" {
+			http.Error(w, "missing id", http.StatusBadRequest)
+			return
+		}
+
+		callerID := r.Header.Get("user-id")
+		if _, ok := admins[callerID]; !ok && id != callerID {
+			http.Error(w, "cannot export another user's data", http.StatusForbidden)
+			return
+		}
+
+		archive, err := h.privacy.Export(r.Context(), id)
+		if err != nil {
+			writeUserError(w, err)
+			return
+		}
+
+		w.Header().Set("Content-Disposition", fmt.Sprintf("attachment; filename=%q", "user-"+id+".json"))
+		respondJSON(w, http.StatusOK, archive)
+	}
+}
+
+// EraseUser permanently erases a user, deleted or not, and answers with
+// the erasure report.
+func (h *Handler) EraseUser(w http.ResponseWriter, r *http.Request) {
+	id := r.PathValue("id")
+	if id == "" {
+		http.Error(w, "missing id", http.StatusBadRequest)
+		return
+	}
+
+	var req struct {
+		Reason string `json:"reason"`
+	}
+	if r.ContentLength != 0 {
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, "invalid request body", http.StatusBadRequest)
+			return
+		}
+	}
+
+	report, err := h.privacy.Erase(r.Context(), id, req.Reason)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
@@ -0,0 +1,64 @@
+package http
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestExportAndEraseUserData(t *testing.T) {
+	users := memory.NewUserRepository(memory.NewOutboxRepository())
+	audit := memory.NewAuditRepository()
+	privacy := application.NewPrivacyService(users, audit, application.NewAuditService(audit), memory.NewWebhookRepository(), memory.NewOrganizationRepository(), &mocks.BlobStoreMock{})
+	h := NewHandler(nil, nil, nil, nil, nil, nil, privacy, nil)
+
+	u := &domain.User{Name: "Ann", Email: "ann@test.com", Status: domain.StatusActive}
+	assert.NoError(t, users.Create(context.Background(), u))
+	export := h.ExportUserData([]string{"admin"})
+
+	get := func(callerID string) *httptest.ResponseRecorder {
+		req := httptest.NewRequest(http.MethodGet, "/users/"+u.ID+"/data-export", nil)
+		req.SetPathValue("id", u.ID)
+		req.Header.Set("user-id", callerID)
+		rec := httptest.NewRecorder()
+		export(rec, req)
+		return rec
+	}
+
+	assert.Equal(t, http.StatusForbidden, get("someone-else").Code)
+	assert.Equal(t, http.StatusOK, get("admin").Code)
+	rec := get(u.ID)
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, `attachment; filename="user-`+u.ID+`.json"`, rec.Header().Get("Content-Disposition"))
+	var archive domain.SubjectArchive
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&archive))
+	if assert.NotNil(t, archive.User) {
+		assert.Equal(t, "ann@test.com", archive.User.Email)
+	}
+
+	// Deleted users' data is held, and exported, until they are purged.
+	assert.NoError(t, users.Delete(context.Background(), u.ID))
+	assert.Equal(t, http.StatusOK, get("admin").Code)
+
+	req := httptest.NewRequest(http.MethodPost, "/users/"+u.ID+"/erase", strings.NewReader(`{"reason":"GDPR request"}`))
+	req.SetPathValue("id", u.ID)
+	rec = httptest.NewRecorder()
+	h.EraseUser(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report domain.ErasureReport
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.True(t, report.UserDeleted)
+	assert.True(t, strings.HasPrefix(report.Pseudonym, "erased-"))
+
+	assert.Equal(t, http.StatusNotFound, get(u.ID).Code)
+}
@@ -0,0 +1,112 @@
+package http
+
+import (
+	"fmt"
+	"net/url"
+	"strconv"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// parseUserQuery reads listing options from the query string:
+//
+//	?limit=20&offset=40 or ?limit=20&cursor=<next_cursor>
+//	&sort=created_at|name|email&order=asc|desc
+//	&status=pending|active|suspended|disabled
+//	&name=<prefix>&email=<prefix>
+//	&created_after=<RFC3339>&created_until=<RFC3339>
+func parseUserQuery(v url.Values) (domain.UserQuery, error) {
+	q := domain.UserQuery{
+		SortBy: domain.UserSortField(v.Get("sort")),
+		Order:  domain.SortOrder(v.Get("order")),
+		Cursor: v.Get("cursor"),
+		Filter: domain.UserFilter{
+			Status:      domain.UserStatus(v.Get("status")),
+			NamePrefix:  v.Get("name"),
+			EmailPrefix: v.Get("email"),
+		},
+	}
+
+	var err error
+	if q.Limit, err = intParam(v, "limit"); err != nil {
+		return q, err
+	}
+	if q.Offset, err = intParam(v, "offset"); err != nil {
+		return q, err
+	}
+	if q.Filter.CreatedAfter, err = timeParam(v, "created_after"); err != nil {
+		return q, err
+	}
+	if q.Filter.CreatedUntil, err = timeParam(v, "created_until"); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+// parseAuditQuery reads audit filters from the query string:
+//
+//	?actor=<user id>&target=<user id>&action=<action>
+//	&from=<RFC3339>&until=<RFC3339>&after_seq=<seq>&limit=20
+func parseAuditQuery(v url.Values) (domain.AuditQuery, error) {
+	q := domain.AuditQuery{
+		ActorID:  v.Get("actor"),
+		TargetID: v.Get("target"),
+		Action:   domain.AuditAction(v.Get("action")),
+	}
+
+	var err error
+	if q.Limit, err = intParam(v, "limit"); err != nil {
+		return q, err
+	}
+	afterSeq, err := intParam(v, "after_seq")
+	if err != nil {
+		return q, err
+	}
+	q.AfterSeq = int64(afterSeq)
+	if q.From, err = timeParam(v, "from"); err != nil {
+		return q, err
+	}
+	if q.Until, err = timeParam(v, "until"); err != nil {
+		return q, err
+	}
+
+	return q, nil
+}
+
+func intParam(v url.Values, key string) (int, error) {
+	s := v.Get(key)
+	if s == "" {
+		return 0, nil
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil {
+		return 0, fmt.Errorf("invalid %s", key)
+	}
+	return n, nil
+}
+
+func boolParam(v url.Values, key string) (bool, error) {
+	s := v.Get(key)
+	if s == "" {
+		return false, nil
+	}
+	b, err := strconv.ParseBool(s)
+	if err != nil {
+		return false, fmt.Errorf("invalid %s", key)
+	}
+	return b, nil
+}
+
+func timeParam(v url.Values, key string) (time.Time, error) {
+	s := v.Get(key)
+	if s == "" {
+		return time.Time{}, nil
+	}
+	t, err := time.Parse(time.RFC3339, s)
+	if err != nil {
+		return time.Time{}, fmt.Errorf("invalid %s", key)
+	}
+	return t, nil
+}
@@ -0,0 +1,100 @@
+package http
+
+import (
+	"errors"
+	"log"
+	"mime"
+	"net/http"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// maxImportBytes bounds an import upload.
+const maxImportBytes = 256 << 20
+
+// transferTypes are the Content-Types of the transfer formats.
+var transferTypes = map[domain.TransferFormat]string{
+	domain.FormatCSV:    "text/csv",
+	domain.FormatNDJSON: "application/x-ndjson",
+}
+
+// ImportUsers creates users from a CSV or NDJSON body and answers with a
+// report of what was created and which rows failed. The format is taken
+// from ?format=, or else from the Content-Type. ?invite=true creates rows
+// without a password hash as pending users and mails them an invitation;
+// ?dry_run=true only validates.
+func (h *Handler) ImportUsers(w http.ResponseWriter, r *http.Request) {
+	v := r.URL.Query()
+
+	name := v.Get("format")
+	if name == "" {
+		switch mediaType, _, _ := mime.ParseMediaType(r.Header.Get("Content-Type")); mediaType {
+		case "text/csv":
+			name = string(domain.FormatCSV)
+		case "application/x-ndjson", "application/ndjson":
+			name = string(domain.FormatNDJSON)
+		}
+	}
+	format, err := domain.ParseTransferFormat(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	var opts domain.ImportOptions
+	if opts.Invite, err = boolParam(v, "invite"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if opts.DryRun, err = boolParam(v, "dry_run"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	// Large files outlast the server's timeouts.
+	rc := http.NewResponseController(w)
+	_ = rc.SetReadDeadline(time.Time{})
+	_ = rc.SetWriteDeadline(time.Time{})
+	r.Body = http.MaxBytesReader(w, r.Body, maxImportBytes)
+
+	report, err := h.transfer.Import(r.Context(), r.Body, format, opts)
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		http.Error(w, "import too large", http.StatusRequestEntityTooLarge)
+		return
+	}
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, report)
+}
+
+// ExportUsers streams every user as CSV or NDJSON, picked with ?format=.
+// Passwords are never exported.
+func (h *Handler) ExportUsers(w http.ResponseWriter, r *http.Request) {
+	name := r.URL.Query().Get("format")
+	if name == "" {
+		name = string(domain.FormatNDJSON)
+	}
+	format, err := domain.ParseTransferFormat(name)
+	if err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	rc := http.NewResponseController(w)
+	_ = rc.SetWriteDeadline(time.Time{})
+
+	w.Header().Set("Content-Type", transferTypes[format])
+	w.Header().Set("Content-Disposition", `attachment; filename="users.`+string(format)+`"`)
+
+	// The status is sent with the first row, so a failure later on can
+	// only cut the download short.
+	n, err := h.transfer.Export(r.Context(), w, format)
+	if err != nil {
+		log.Printf("export users: stopped after %d: %v", n, err)
+	}
+}
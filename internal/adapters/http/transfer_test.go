@@ -0,0 +1,79 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func newTransferHandler() *Handler {
+	repo := memory.NewUserRepository(memory.NewOutboxRepository())
+	transfer := application.NewUserTransferService(repo, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+	return NewHandler(nil, nil, nil, nil, nil, transfer, nil, nil)
+}
+
+func TestImportExportUsers(t *testing.T) {
+	h := newTransferHandler()
+
+	// The format comes from the Content-Type when ?format= is missing.
+	body := "name,email\nAnn,ann@test.com\nBob,not-an-email\n"
+	req := httptest.NewRequest(http.MethodPost, "/users/import?invite=true", strings.NewReader(body))
+	req.Header.Set("Content-Type", "text/csv; charset=utf-8")
+	rec := httptest.NewRecorder()
+	h.ImportUsers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	var report domain.ImportReport
+	assert.NoError(t, json.NewDecoder(rec.Body).Decode(&report))
+	assert.Equal(t, 2, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 1, report.Invited)
+	if assert.Len(t, report.Errors, 1) {
+		assert.Equal(t, 3, report.Errors[0].Line)
+	}
+
+	req = httptest.NewRequest(http.MethodGet, "/users/export?format=csv", nil)
+	rec = httptest.NewRecorder()
+	h.ExportUsers(rec, req)
+
+	assert.Equal(t, http.StatusOK, rec.Code)
+	assert.Equal(t, "text/csv", rec.Header().Get("Content-Type"))
+	assert.Equal(t, `attachment; filename="users.csv"`, rec.Header().Get("Content-Disposition"))
+	lines := strings.Split(strings.TrimSpace(rec.Body.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[1], "ann@test.com,pending")
+	}
+}
+
+func TestImportUsers_BadRequest(t *testing.T) {
+	h := newTransferHandler()
+
+	tests := map[string]struct {
+		target      string
+		contentType string
+		body        string
+	}{
+		"unknown format":  {"/users/import?format=xml", "", "name,email\n"},
+		"no format":       {"/users/import", "application/octet-stream", "name,email\n"},
+		"bad flag":        {"/users/import?format=csv&invite=maybe", "", "name,email\n"},
+		"missing columns": {"/users/import?format=csv", "", "name\nAnn\n"},
+	}
+	for name, tt := range tests {
+		t.Run(name, func(t *testing.T) {
+			req := httptest.NewRequest(http.MethodPost, tt.target, strings.NewReader(tt.body))
+			req.Header.Set("Content-Type", tt.contentType)
+			rec := httptest.NewRecorder()
+			h.ImportUsers(rec, req)
+
+			assert.Equal(t, http.StatusBadRequest, rec.Code)
+		})
+	}
+}
@@ -0,0 +1,98 @@
+package http
+
+import (
+	"encoding/json"
+	"net/http"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func (h *Handler) CreateWebhook(w http.ResponseWriter, r *http.Request) {
+	var req struct {
+		URL    string             `json:"url"`
+		Events []domain.EventType `json:"events"`
+	}
+	if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+		http.Error(w, "invalid request body", http.StatusBadRequest)
+		return
+	}
+
+	sub, err := h.webhookService.Subscribe(r.Context(), req.URL, req.Events)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	// The secret is only ever returned here.
+	respondJSON(w, http.StatusCreated, struct {
+		*domain.WebhookSubscription
+		Secret string `json:"secret"`
+	}{sub, sub.Secret})
+}
+
+func (h *Handler) ListWebhooks(w http.ResponseWriter, r *http.Request) {
+	subs, err := h.webhookService.ListSubscriptions(r.Context())
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"webhooks": subs})
+}
+
+func (h *Handler) GetWebhook(w http.ResponseWriter, r *http.Request) {
+	sub, err := h.webhookService.GetSubscription(r.Context(), r.PathValue("id"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, sub)
+}
+
+func (h *Handler) DeleteWebhook(w http.ResponseWriter, r *http.Request) {
+	if err := h.webhookService.Unsubscribe(r.Context(), r.PathValue("id")); err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	w.WriteHeader(http.StatusNoContent)
+}
+
+// ListWebhookDeliveries is the delivery log of one subscription:
+//
+//	?status=pending|delivered|dead&limit=20&offset=0
+func (h *Handler) ListWebhookDeliveries(w http.ResponseWriter, r *http.Request) {
+	q := domain.WebhookDeliveryQuery{
+		SubscriptionID: r.PathValue("id"),
+		Status:         domain.WebhookDeliveryStatus(r.URL.Query().Get("status")),
+	}
+
+	var err error
+	if q.Limit, err = intParam(r.URL.Query(), "limit"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+	if q.Offset, err = intParam(r.URL.Query(), "offset"); err != nil {
+		http.Error(w, err.Error(), http.StatusBadRequest)
+		return
+	}
+
+	deliveries, err := h.webhookService.Deliveries(r.Context(), q)
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusOK, map[string]interface{}{"deliveries": deliveries})
+}
+
+func (h *Handler) RedeliverWebhook(w http.ResponseWriter, r *http.Request) {
+	d, err := h.webhookService.Redeliver(r.Context(), r.PathValue("id"), r.PathValue("delivery"))
+	if err != nil {
+		writeUserError(w, err)
+		return
+	}
+
+	respondJSON(w, http.StatusAccepted, d)
+}
@@ -0,0 +1,96 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// AuditRepository keeps the audit chains in memory, ordered by tenant and
+// sequence number.
+type AuditRepository struct {
+	mu     sync.RWMutex
+	events []domain.AuditEvent
+}
+
+func NewAuditRepository() ports.AuditRepository {
+	return &AuditRepository{}
+}
+
+func (r *AuditRepository) Append(ctx context.Context, e *domain.AuditEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, stored := range r.events {
+		if stored.TenantID == e.TenantID && stored.Seq == e.Seq {
+			return domain.ErrAlreadyExists
+		}
+	}
+	r.events = append(r.events, *e)
+	return nil
+}
+
+func (r *AuditRepository) Last(ctx context.Context) (*domain.AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := domain.TenantID(ctx)
+	var last *domain.AuditEvent
+	for _, e := range r.events {
+		if e.TenantID == tenant && (last == nil || e.Seq > last.Seq) {
+			found := e
+			last = &found
+		}
+	}
+	if last == nil {
+		return nil, domain.ErrNotFound
+	}
+	return last, nil
+}
+
+func (r *AuditRepository) Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	tenant := domain.TenantID(ctx)
+	events := []*domain.AuditEvent{}
+	for _, e := range r.events {
+		if e.TenantID != tenant ||
+			e.Seq <= q.AfterSeq ||
+			(q.ActorID != "" && e.ActorID != q.ActorID) ||
+			(q.TargetID != "" && e.TargetID != q.TargetID) ||
+			(q.Action != "" && e.Action != q.Action) ||
+			(!q.From.IsZero() && e.Timestamp.Before(q.From)) ||
+			(!q.Until.IsZero() && e.Timestamp.After(q.Until)) {
+			continue
+		}
+		found := e
+		events = append(events, &found)
+	}
+
+	slices.SortFunc(events, func(a, b *domain.AuditEvent) int { return cmp.Compare(a.Seq, b.Seq) })
+	if q.Limit > 0 && len(events) > q.Limit {
+		events = events[:q.Limit]
+	}
+	return events, nil
+}
+
+func (r *AuditRepository) Redact(ctx context.Context, userID, pseudonym string) ([]*domain.AuditEvent, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := domain.TenantID(ctx)
+	redacted := []*domain.AuditEvent{}
+	for i := range r.events {
+		if r.events[i].TenantID == tenant && r.events[i].Redact(userID, pseudonym) {
+			found := r.events[i]
+			redacted = append(redacted, &found)
+		}
+	}
+	slices.SortFunc(redacted, func(a, b *domain.AuditEvent) int { return cmp.Compare(a.Seq, b.Seq) })
+	return redacted, nil
+}
@@ -0,0 +1,99 @@
+package memory
+
+import (
+	"container/list"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// Cache is an in-process LRU cache. Once it holds size entries, adding
+// one evicts the least recently used. Expired entries are dropped when
+// read or evicted.
+type Cache struct {
+	mu      sync.Mutex
+	size    int
+	order   *list.List // front is the most recently used
+	entries map[string]*list.Element
+}
+
+type cacheEntry struct {
+	key       string
+	value     []byte
+	expiresAt time.Time
+}
+
+// NewCache returns a cache holding at most size entries.
+func NewCache(size int) *Cache {
+	return &Cache{
+		size:    max(size, 1),
+		order:   list.New(),
+		entries: map[string]*list.Element{},
+	}
+}
+
+var _ ports.Cache = (*Cache)(nil)
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	el, ok := c.entries[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	e := el.Value.(*cacheEntry)
+	if !time.Now().Before(e.expiresAt) {
+		c.remove(el)
+		return nil, domain.ErrNotFound
+	}
+	c.order.MoveToFront(el)
+	return slices.Clone(e.value), nil
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	e := &cacheEntry{key: key, value: slices.Clone(value), expiresAt: time.Now().Add(ttl)}
+	if el, ok := c.entries[key]; ok {
+		el.Value = e
+		c.order.MoveToFront(el)
+		return nil
+	}
+
+	c.entries[key] = c.order.PushFront(e)
+	for c.order.Len() > c.size {
+		c.remove(c.order.Back())
+	}
+	return nil
+}
+
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, key := range keys {
+		if el, ok := c.entries[key]; ok {
+			c.remove(el)
+		}
+	}
+	return nil
+}
+
+// Len returns the number of entries, expired ones included.
+func (c *Cache) Len() int {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.order.Len()
+}
+
+// remove drops el. The caller holds the lock.
+func (c *Cache) remove(el *list.Element) {
+	c.order.Remove(el)
+	delete(c.entries, el.Value.(*cacheEntry).key)
+}
@@ -0,0 +1,58 @@
+package memory_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	ctx := context.Background()
+	cache := memory.NewCache(2)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("2"), time.Minute))
+	_, err := cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.NoError(t, cache.Set(ctx, "c", []byte("3"), time.Minute))
+
+	_, err = cache.Get(ctx, "b")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	v, err := cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+	assert.Equal(t, 2, cache.Len())
+}
+
+func TestCache_Expires(t *testing.T) {
+	ctx := context.Background()
+	cache := memory.NewCache(10)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte{}, 20*time.Millisecond))
+	v, err := cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+
+	time.Sleep(30 * time.Millisecond)
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Zero(t, cache.Len())
+}
+
+func TestCache_Delete(t *testing.T) {
+	ctx := context.Background()
+	cache := memory.NewCache(10)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "b", []byte("2"), time.Minute))
+	assert.NoError(t, cache.Delete(ctx, "a", "missing"))
+
+	_, err := cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	_, err = cache.Get(ctx, "b")
+	assert.NoError(t, err)
+}
@@ -0,0 +1,40 @@
+package memory_test
+
+import (
+	"testing"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/porttest"
+)
+
+func TestUserRepository_Conformance(t *testing.T) {
+	porttest.UserRepository(t, func(t *testing.T) ports.UserRepository {
+		return memory.NewUserRepository(memory.NewOutboxRepository())
+	})
+}
+
+func TestCachedUserRepository_Conformance(t *testing.T) {
+	porttest.UserRepository(t, func(t *testing.T) ports.UserRepository {
+		return application.NewCachedUserRepository(
+			memory.NewUserRepository(nil),
+			memory.NewCache(100),
+			time.Minute,
+			time.Minute,
+		)
+	})
+}
+
+func TestOrganizationRepository_Conformance(t *testing.T) {
+	porttest.OrganizationRepository(t, func(t *testing.T) ports.OrganizationRepository {
+		return memory.NewOrganizationRepository()
+	})
+}
+
+func TestAuditRepository_Conformance(t *testing.T) {
+	porttest.AuditRepository(t, func(t *testing.T) ports.AuditRepository {
+		return memory.NewAuditRepository()
+	})
+}
@@ -0,0 +1,178 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizationRepository keeps organizations and memberships in memory.
+type OrganizationRepository struct {
+	mu   sync.Mutex
+	orgs map[string]domain.Organization
+	// members holds the memberships by organization ID, then user ID.
+	members map[string]map[string]domain.Membership
+}
+
+func NewOrganizationRepository() ports.OrganizationRepository {
+	return &OrganizationRepository{
+		orgs:    map[string]domain.Organization{},
+		members: map[string]map[string]domain.Membership{},
+	}
+}
+
+func (r *OrganizationRepository) Create(ctx context.Context, org *domain.Organization, owner *domain.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	org.ID = primitive.NewObjectID().Hex()
+	org.TenantID = domain.TenantID(ctx)
+	owner.OrganizationID = org.ID
+	r.orgs[org.ID] = *org
+	r.members[org.ID] = map[string]domain.Membership{owner.UserID: cloneMembership(owner)}
+	return nil
+}
+
+func (r *OrganizationRepository) FindByID(ctx context.Context, id string) (*domain.Organization, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	org, ok := r.org(ctx, id)
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return &org, nil
+}
+
+func (r *OrganizationRepository) AddMember(ctx context.Context, m *domain.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.org(ctx, m.OrganizationID); !ok {
+		return domain.ErrNotFound
+	}
+	members := r.members[m.OrganizationID]
+	if _, ok := members[m.UserID]; ok {
+		return domain.ErrAlreadyExists
+	}
+	members[m.UserID] = cloneMembership(m)
+	return nil
+}
+
+func (r *OrganizationRepository) FindMember(ctx context.Context, orgID, userID string) (*domain.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.org(ctx, orgID); !ok {
+		return nil, domain.ErrNotFound
+	}
+	m, ok := r.members[orgID][userID]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	c := cloneMembership(&m)
+	return &c, nil
+}
+
+func (r *OrganizationRepository) ListMembers(ctx context.Context, orgID string) ([]*domain.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.org(ctx, orgID); !ok {
+		return nil, domain.ErrNotFound
+	}
+	members := []*domain.Membership{}
+	for _, m := range r.members[orgID] {
+		c := cloneMembership(&m)
+		members = append(members, &c)
+	}
+	slices.SortFunc(members, func(a, b *domain.Membership) int { return cmp.Compare(a.UserID, b.UserID) })
+	return members, nil
+}
+
+func (r *OrganizationRepository) MembershipsOf(ctx context.Context, userID string) ([]*domain.Membership, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	memberships := []*domain.Membership{}
+	for orgID, members := range r.members {
+		if _, ok := r.org(ctx, orgID); !ok {
+			continue
+		}
+		if m, ok := members[userID]; ok {
+			c := cloneMembership(&m)
+			memberships = append(memberships, &c)
+		}
+	}
+	slices.SortFunc(memberships, func(a, b *domain.Membership) int {
+		return cmp.Compare(a.OrganizationID, b.OrganizationID)
+	})
+	return memberships, nil
+}
+
+func (r *OrganizationRepository) UpdateMember(ctx context.Context, m *domain.Membership) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.org(ctx, m.OrganizationID); !ok {
+		return domain.ErrNotFound
+	}
+	members := r.members[m.OrganizationID]
+	if _, ok := members[m.UserID]; !ok {
+		return domain.ErrNotFound
+	}
+	members[m.UserID] = cloneMembership(m)
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.org(ctx, orgID); !ok {
+		return domain.ErrNotFound
+	}
+	members := r.members[orgID]
+	if _, ok := members[userID]; !ok {
+		return domain.ErrNotFound
+	}
+	delete(members, userID)
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveUser(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var n int64
+	for orgID, members := range r.members {
+		if _, ok := r.org(ctx, orgID); !ok {
+			continue
+		}
+		if _, ok := members[userID]; ok {
+			delete(members, userID)
+			n++
+		}
+	}
+	return n, nil
+}
+
+// org returns the organization with id if it belongs to ctx's tenant.
+func (r *OrganizationRepository) org(ctx context.Context, id string) (domain.Organization, bool) {
+	org, ok := r.orgs[id]
+	if !ok || org.TenantID != domain.TenantID(ctx) {
+		return domain.Organization{}, false
+	}
+	return org, true
+}
+
+func cloneMembership(m *domain.Membership) domain.Membership {
+	c := *m
+	c.Teams = slices.Clone(m.Teams)
+	return c
+}
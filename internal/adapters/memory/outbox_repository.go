@@ -0,0 +1,92 @@
+package memory
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxRepository holds the events written by the in-memory
+// UserRepository. Delivered messages are dropped.
+type OutboxRepository struct {
+	mu       sync.Mutex
+	messages []*domain.OutboxMessage
+}
+
+func NewOutboxRepository() *OutboxRepository {
+	return &OutboxRepository{}
+}
+
+func (r *OutboxRepository) add(e domain.Event) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	e.ID = primitive.NewObjectID().Hex()
+	r.messages = append(r.messages, &domain.OutboxMessage{Event: e, NextAttemptAt: e.OccurredAt})
+}
+
+// stripData removes the user snapshot from the user's waiting events.
+func (r *OutboxRepository) stripData(userID string) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, msg := range r.messages {
+		if msg.UserID == userID {
+			msg.Data = nil
+		}
+	}
+}
+
+// Claim picks the oldest due message and pushes its next attempt past the lease.
+func (r *OutboxRepository) Claim(ctx context.Context, lease time.Duration) (*domain.OutboxMessage, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var next *domain.OutboxMessage
+	for _, msg := range r.messages {
+		if msg.NextAttemptAt.After(now) {
+			continue
+		}
+		if next == nil || msg.NextAttemptAt.Before(next.NextAttemptAt) {
+			next = msg
+		}
+	}
+	if next == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	next.Attempts++
+	next.NextAttemptAt = now.Add(lease)
+	claimed := *next
+	return &claimed, nil
+}
+
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for i, msg := range r.messages {
+		if msg.ID == id {
+			r.messages = append(r.messages[:i], r.messages[i+1:]...)
+			return nil
+		}
+	}
+	return nil
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, msg := range r.messages {
+		if msg.ID == id {
+			msg.LastError = reason
+			msg.NextAttemptAt = retryAt
+		}
+	}
+	return nil
+}
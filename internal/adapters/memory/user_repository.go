@@ -0,0 +1,580 @@
+package memory
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strings"
+	"sync"
+	"time"
+	"unicode"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository keeps users in memory with the semantics of the MongoDB
+// repository: users are scoped by tenant, emails are unique among all of a
+// tenant's stored users, including soft-deleted ones, listings never carry
+// passwords, and IDs are
+// ObjectIDs so they sort in creation order. Every change is recorded in
+// the outbox, when one is given, while the change is still invisible to
+// readers.
+type UserRepository struct {
+	mu     sync.RWMutex
+	users  map[string]*domain.User
+	outbox *OutboxRepository
+}
+
+// NewUserRepository returns an empty repository. outbox may be nil.
+func NewUserRepository(outbox *OutboxRepository) ports.UserRepository {
+	return &UserRepository{
+		users:  map[string]*domain.User{},
+		outbox: outbox,
+	}
+}
+
+func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
+	tenant := domain.TenantID(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.emailTaken(tenant, u.Email, "") {
+		return domain.ErrEmailTaken
+	}
+
+	created := cloneUser(u)
+	if _, err := primitive.ObjectIDFromHex(created.ID); err != nil {
+		created.ID = primitive.NewObjectID().Hex()
+	}
+	created.TenantID = tenant
+	created.Version = 1
+
+	if err := r.record(domain.EventUserCreated, created.ID, created); err != nil {
+		return err
+	}
+	r.users[created.ID] = created
+
+	u.ID = created.ID
+	u.TenantID = created.TenantID
+	u.Version = created.Version
+	return nil
+}
+
+func (r *UserRepository) CreateMany(ctx context.Context, users []*domain.User) error {
+	tenant := domain.TenantID(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	emails := map[string]bool{}
+	for _, u := range users {
+		if emails[u.Email] || r.emailTaken(tenant, u.Email, "") {
+			return domain.ErrEmailTaken
+		}
+		emails[u.Email] = true
+	}
+
+	created := make([]*domain.User, len(users))
+	for i, u := range users {
+		c := cloneUser(u)
+		if _, err := primitive.ObjectIDFromHex(c.ID); err != nil {
+			c.ID = primitive.NewObjectID().Hex()
+		}
+		c.TenantID = tenant
+		c.Version = 1
+		created[i] = c
+	}
+	for _, c := range created {
+		if err := r.record(domain.EventUserCreated, c.ID, c); err != nil {
+			return err
+		}
+	}
+
+	for i, c := range created {
+		r.users[c.ID] = c
+		users[i].ID = c.ID
+		users[i].TenantID = c.TenantID
+		users[i].Version = c.Version
+	}
+	return nil
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, domain.ErrInvalidID
+	}
+	return r.findOne(ctx, func(u *domain.User) bool { return u.ID == id })
+}
+
+func (r *UserRepository) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, domain.ErrInvalidID
+	}
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	u, ok := r.users[id]
+	if !ok || u.TenantID != domain.TenantID(ctx) {
+		return nil, domain.ErrNotFound
+	}
+	return cloneUser(u), nil
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.findOne(ctx, func(u *domain.User) bool { return u.Email == email })
+}
+
+func (r *UserRepository) FindByEmailChangeToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	return r.findOne(ctx, func(u *domain.User) bool {
+		return u.PendingEmail != nil && u.PendingEmail.TokenHash == tokenHash
+	})
+}
+
+func (r *UserRepository) FindByInvitationToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	return r.findOne(ctx, func(u *domain.User) bool {
+		return u.Invitation != nil && u.Invitation.TokenHash == tokenHash
+	})
+}
+
+func (r *UserRepository) FindAll(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error) {
+	dir := -1
+	if q.Order == domain.SortAsc {
+		dir = 1
+	}
+
+	var after func(u *domain.User) bool
+	if q.Cursor != "" {
+		c, err := domain.DecodeCursor(q.Cursor, q)
+		if err != nil {
+			return nil, err
+		}
+		after = func(u *domain.User) bool {
+			return dir*compareUsers(q.SortBy, u, cursorUser(c)) > 0
+		}
+	}
+
+	r.mu.RLock()
+	matched := r.filter(ctx, func(u *domain.User) bool { return matchesFilter(u, q.Filter) })
+	r.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b *domain.User) int {
+		return dir * compareUsers(q.SortBy, a, b)
+	})
+
+	page := &domain.UserPage{Total: int64(len(matched))}
+	if after != nil {
+		i := slices.IndexFunc(matched, after)
+		if i < 0 {
+			i = len(matched)
+		}
+		matched = matched[i:]
+	}
+
+	// One extra user tells us whether there is a next page.
+	users := window(matched, q.Offset, q.Limit+1)
+	page.Users = users
+	if len(users) > q.Limit {
+		page.Users = users[:q.Limit]
+		page.NextCursor = domain.NewCursor(q, page.Users[q.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+// Search mimics the text index: a user matches when a word of the search
+// equals a word of the name (weighted 3) or the email (weighted 1),
+// ignoring case. Stemming is not imitated. Without a match it falls back
+// to a case-insensitive prefix match on name or email.
+func (r *UserRepository) Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error) {
+	terms := words(q.Text)
+
+	r.mu.RLock()
+	scores := map[string]int{}
+	matched := r.filter(ctx, func(u *domain.User) bool {
+		if !inUserIDs(q.UserIDs, u.ID) {
+			return false
+		}
+		score := 0
+		for _, w := range words(u.Name) {
+			if slices.Contains(terms, w) {
+				score += 3
+			}
+		}
+		for _, w := range words(u.Email) {
+			if slices.Contains(terms, w) {
+				score++
+			}
+		}
+		scores[u.ID] = score
+		return score > 0
+	})
+	r.mu.RUnlock()
+
+	if len(matched) > 0 {
+		slices.SortFunc(matched, func(a, b *domain.User) int {
+			return cmp.Or(cmp.Compare(scores[b.ID], scores[a.ID]), cmp.Compare(a.ID, b.ID))
+		})
+		return &domain.UserPage{Users: window(matched, q.Offset, q.Limit), Total: int64(len(matched))}, nil
+	}
+
+	prefix := strings.ToLower(q.Text)
+	r.mu.RLock()
+	matched = r.filter(ctx, func(u *domain.User) bool {
+		return inUserIDs(q.UserIDs, u.ID) && (strings.HasPrefix(strings.ToLower(u.Name), prefix) ||
+			strings.HasPrefix(strings.ToLower(u.Email), prefix))
+	})
+	r.mu.RUnlock()
+
+	slices.SortFunc(matched, func(a, b *domain.User) int {
+		return compareUsers(domain.SortByName, a, b)
+	})
+	return &domain.UserPage{Users: window(matched, q.Offset, q.Limit), Total: int64(len(matched))}, nil
+}
+
+// Each works on a snapshot taken when it is called, so fn may write to
+// the repository.
+func (r *UserRepository) Each(ctx context.Context, fn func(*domain.User) error) error {
+	r.mu.RLock()
+	users := r.filter(ctx, func(*domain.User) bool { return true })
+	r.mu.RUnlock()
+
+	slices.SortFunc(users, func(a, b *domain.User) int { return strings.Compare(a.ID, b.ID) })
+	for _, u := range users {
+		if err := ctx.Err(); err != nil {
+			return err
+		}
+		if err := fn(u); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Update only applies when the stored version still equals u.Version,
+// and bumps it on success. Like the MongoDB repository it leaves the
+// password, creation time and deletion state alone.
+func (r *UserRepository) Update(ctx context.Context, u *domain.User) error {
+	return r.update(ctx, u, false)
+}
+
+func (r *UserRepository) UpdateWithPassword(ctx context.Context, u *domain.User) error {
+	return r.update(ctx, u, true)
+}
+
+func (r *UserRepository) update(ctx context.Context, u *domain.User, withPassword bool) error {
+	if !primitive.IsValidObjectID(u.ID) {
+		return domain.ErrInvalidID
+	}
+	tenant := domain.TenantID(ctx)
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	stored, ok := r.users[u.ID]
+	if !ok || stored.DeletedAt != nil || stored.TenantID != tenant {
+		return domain.ErrNotFound
+	}
+	if stored.Version != u.Version {
+		return domain.ErrVersionConflict
+	}
+	if r.emailTaken(tenant, u.Email, u.ID) {
+		return domain.ErrEmailTaken
+	}
+
+	updated := cloneUser(u)
+	if !withPassword {
+		updated.Password = stored.Password
+	}
+	updated.TenantID = stored.TenantID
+	updated.CreatedAt = stored.CreatedAt
+	updated.DeletedAt = nil
+	updated.Version++
+
+	if err := r.record(domain.EventUserUpdated, u.ID, updated); err != nil {
+		return err
+	}
+	r.users[u.ID] = updated
+
+	u.Version++
+	return nil
+}
+
+// Delete is a soft delete: the user is hidden from every finder until
+// restored or purged. The email stays reserved in the meantime.
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt != nil || u.TenantID != domain.TenantID(ctx) {
+		return domain.ErrNotFound
+	}
+
+	if err := r.record(domain.EventUserDeleted, id, u); err != nil {
+		return err
+	}
+	now := time.Now()
+	u.DeletedAt = &now
+	return nil
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok || u.DeletedAt == nil || u.TenantID != domain.TenantID(ctx) {
+		return domain.ErrNotFound
+	}
+
+	if err := r.record(domain.EventUserRestored, id, u); err != nil {
+		return err
+	}
+	u.DeletedAt = nil
+	return nil
+}
+
+// Purge permanently removes users of every tenant soft-deleted before the
+// given time.
+func (r *UserRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	purged := []domain.PurgedUser{}
+	for id, u := range r.users {
+		if u.DeletedAt != nil && u.DeletedAt.Before(deletedBefore) {
+			delete(r.users, id)
+			purged = append(purged, domain.PurgedUser{ID: id, TenantID: u.TenantID})
+		}
+	}
+	return purged, nil
+}
+
+func (r *UserRepository) Erase(ctx context.Context, id string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrInvalidID
+	}
+
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	u, ok := r.users[id]
+	if !ok || u.TenantID != domain.TenantID(ctx) {
+		return domain.ErrNotFound
+	}
+
+	if r.outbox != nil {
+		r.outbox.stripData(id)
+	}
+	if err := r.record(domain.EventUserErased, id, u); err != nil {
+		return err
+	}
+	delete(r.users, id)
+	return nil
+}
+
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	tenant := domain.TenantID(ctx)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	var n int64
+	for _, u := range r.users {
+		if u.DeletedAt == nil && u.TenantID == tenant {
+			n++
+		}
+	}
+	return n, nil
+}
+
+func (r *UserRepository) findOne(ctx context.Context, match func(u *domain.User) bool) (*domain.User, error) {
+	tenant := domain.TenantID(ctx)
+
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, u := range r.users {
+		if u.DeletedAt == nil && u.TenantID == tenant && match(u) {
+			return cloneUser(u), nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+// filter returns copies of the tenant's live users matching match,
+// without passwords. The caller holds the lock.
+func (r *UserRepository) filter(ctx context.Context, match func(u *domain.User) bool) []*domain.User {
+	tenant := domain.TenantID(ctx)
+	users := []*domain.User{}
+	for _, u := range r.users {
+		if u.DeletedAt == nil && u.TenantID == tenant && match(u) {
+			c := cloneUser(u)
+			c.Password = ""
+			users = append(users, c)
+		}
+	}
+	return users
+}
+
+// emailTaken reports whether another user of the tenant, deleted or not,
+// has email. The caller holds the lock.
+func (r *UserRepository) emailTaken(tenant, email, exceptID string) bool {
+	for _, u := range r.users {
+		if u.TenantID == tenant && u.Email == email && u.ID != exceptID {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds the event for a change of u to the outbox, with a snapshot
+// of u for creates and updates. The caller holds the lock and applies the
+// change only if record succeeds.
+func (r *UserRepository) record(t domain.EventType, id string, u *domain.User) error {
+	if r.outbox == nil {
+		return nil
+	}
+	snapshot := u
+	if t != domain.EventUserCreated && t != domain.EventUserUpdated {
+		snapshot = nil
+	}
+	event, err := domain.NewUserEvent(t, u.TenantID, id, snapshot)
+	if err != nil {
+		return err
+	}
+	r.outbox.add(event)
+	return nil
+}
+
+func matchesFilter(u *domain.User, f domain.UserFilter) bool {
+	switch f.Status {
+	case "":
+	case domain.StatusActive:
+		// Users stored before statuses existed are active.
+		if u.Status != domain.StatusActive && u.Status != "" {
+			return false
+		}
+	default:
+		if u.Status != f.Status {
+			return false
+		}
+	}
+	if f.NamePrefix != "" && !strings.HasPrefix(strings.ToLower(u.Name), strings.ToLower(f.NamePrefix)) {
+		return false
+	}
+	if f.EmailPrefix != "" && !strings.HasPrefix(strings.ToLower(u.Email), strings.ToLower(f.EmailPrefix)) {
+		return false
+	}
+	if !f.CreatedAfter.IsZero() && u.CreatedAt.Before(f.CreatedAfter) {
+		return false
+	}
+	if !f.CreatedUntil.IsZero() && !u.CreatedAt.Before(f.CreatedUntil) {
+		return false
+	}
+	return inUserIDs(f.UserIDs, u.ID)
+}
+
+// inUserIDs reports whether id is in ids, or ids is empty.
+func inUserIDs(ids []string, id string) bool {
+	return len(ids) == 0 || slices.Contains(ids, id)
+}
+
+// compareUsers orders by the sort field and then by ID, ascending.
+func compareUsers(by domain.UserSortField, a, b *domain.User) int {
+	var c int
+	switch by {
+	case domain.SortByName:
+		c = strings.Compare(a.Name, b.Name)
+	case domain.SortByEmail:
+		c = strings.Compare(a.Email, b.Email)
+	default:
+		c = a.CreatedAt.Compare(b.CreatedAt)
+	}
+	return cmp.Or(c, strings.Compare(a.ID, b.ID))
+}
+
+// cursorUser is a stand-in user at the cursor's position.
+func cursorUser(c domain.Cursor) *domain.User {
+	u := &domain.User{ID: c.ID}
+	switch c.SortBy {
+	case domain.SortByName:
+		u.Name = c.Value
+	case domain.SortByEmail:
+		u.Email = c.Value
+	default:
+		u.CreatedAt = c.CreatedAt()
+	}
+	return u
+}
+
+func window(users []*domain.User, offset, limit int) []*domain.User {
+	if offset >= len(users) {
+		return []*domain.User{}
+	}
+	users = users[offset:]
+	if limit < len(users) {
+		users = users[:limit]
+	}
+	return users
+}
+
+func words(s string) []string {
+	return strings.FieldsFunc(strings.ToLower(s), func(r rune) bool {
+		return !unicode.IsLetter(r) && !unicode.IsDigit(r)
+	})
+}
+
+// cloneUser copies u deeply enough that neither side sees the other's changes.
+func cloneUser(u *domain.User) *domain.User {
+	c := *u
+	if u.DeletedAt != nil {
+		t := *u.DeletedAt
+		c.DeletedAt = &t
+	}
+	if u.StatusChangedAt != nil {
+		t := *u.StatusChangedAt
+		c.StatusChangedAt = &t
+	}
+	if u.PendingEmail != nil {
+		p := *u.PendingEmail
+		c.PendingEmail = &p
+	}
+	if u.Invitation != nil {
+		i := *u.Invitation
+		c.Invitation = &i
+	}
+	if u.Attributes != nil {
+		c.Attributes = cloneValue(u.Attributes).(map[string]interface{})
+	}
+	return &c
+}
+
+func cloneValue(v interface{}) interface{} {
+	switch v := v.(type) {
+	case map[string]interface{}:
+		m := make(map[string]interface{}, len(v))
+		for k, e := range v {
+			m[k] = cloneValue(e)
+		}
+		return m
+	case []interface{}:
+		s := make([]interface{}, len(v))
+		for i, e := range v {
+			s[i] = cloneValue(e)
+		}
+		return s
+	}
+	return v
+}
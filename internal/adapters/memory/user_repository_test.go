@@ -0,0 +1,194 @@
+package memory_test
+
+import (
+	"context"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/memory"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestUserRepository_Create(t *testing.T) {
+	ctx := context.Background()
+	outbox := memory.NewOutboxRepository()
+	repo := memory.NewUserRepository(outbox)
+
+	u := &domain.User{Name: "Alice", Email: "alice@test.com", Password: "hash"}
+	assert.NoError(t, repo.Create(ctx, u))
+	assert.Len(t, u.ID, 24)
+	assert.Equal(t, int64(1), u.Version)
+
+	err := repo.Create(ctx, &domain.User{Name: "Other", Email: "alice@test.com"})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	// The email stays reserved while the user is soft-deleted.
+	assert.NoError(t, repo.Delete(ctx, u.ID))
+	err = repo.Create(ctx, &domain.User{Name: "Other", Email: "alice@test.com"})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+
+	msg, err := outbox.Claim(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.EventUserCreated, msg.Type)
+	assert.Equal(t, u.ID, msg.UserID)
+}
+
+func TestUserRepository_CreateConcurrently(t *testing.T) {
+	repo := memory.NewUserRepository(nil)
+
+	var (
+		wg      sync.WaitGroup
+		mu      sync.Mutex
+		created int
+	)
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			if repo.Create(context.Background(), &domain.User{Email: "same@test.com"}) == nil {
+				mu.Lock()
+				created++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	assert.Equal(t, 1, created)
+}
+
+func TestUserRepository_FindAll(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUserRepository(nil)
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Carol", "alice", "Bob", "Dave"} {
+		assert.NoError(t, repo.Create(ctx, &domain.User{
+			Name:      name,
+			Email:     fmt.Sprintf("%s@test.com", name),
+			Password:  "hash",
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	t.Run("pages by cursor without passwords", func(t *testing.T) {
+		q := domain.UserQuery{SortBy: domain.SortByName, Order: domain.SortAsc, Limit: 2}
+
+		page, err := repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), page.Total)
+		assert.Equal(t, []string{"Bob", "Carol"}, names(page))
+		assert.Empty(t, page.Users[0].Password)
+		assert.NotEmpty(t, page.NextCursor)
+
+		q.Cursor = page.NextCursor
+		page, err = repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Dave", "alice"}, names(page))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("newest first by default", func(t *testing.T) {
+		q := domain.UserQuery{}
+		assert.NoError(t, q.Normalize())
+
+		page, err := repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Dave", "Bob", "alice", "Carol"}, names(page))
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		q := domain.UserQuery{Filter: domain.UserFilter{
+			NamePrefix:   "A",
+			CreatedUntil: base.Add(2 * time.Hour),
+		}}
+		assert.NoError(t, q.Normalize())
+
+		page, err := repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, names(page))
+		assert.Equal(t, int64(1), page.Total)
+	})
+}
+
+func TestUserRepository_Search(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUserRepository(nil)
+	assert.NoError(t, repo.Create(ctx, &domain.User{Name: "John Smith", Email: "js@test.com"}))
+	assert.NoError(t, repo.Create(ctx, &domain.User{Name: "Jane Doe", Email: "john@test.com"}))
+
+	page, err := repo.Search(ctx, domain.UserSearch{Text: "john", Limit: 10})
+	assert.NoError(t, err)
+	// A name match outranks an email match.
+	assert.Equal(t, []string{"John Smith", "Jane Doe"}, names(page))
+
+	page, err = repo.Search(ctx, domain.UserSearch{Text: "ja", Limit: 10})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"Jane Doe"}, names(page))
+}
+
+func TestUserRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUserRepository(nil)
+	u := &domain.User{Name: "Alice", Email: "alice@test.com", Password: "hash"}
+	assert.NoError(t, repo.Create(ctx, u))
+	assert.NoError(t, repo.Create(ctx, &domain.User{Name: "Bob", Email: "bob@test.com"}))
+
+	stale := *u
+
+	u.Name = "Alicia"
+	u.Password = "ignored"
+	assert.NoError(t, repo.Update(ctx, u))
+	assert.Equal(t, int64(2), u.Version)
+
+	stored, err := repo.FindByID(ctx, u.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, "Alicia", stored.Name)
+	assert.Equal(t, "hash", stored.Password)
+
+	// Changes to the returned copy do not leak into the repository.
+	stored.Name = "Mallory"
+	again, _ := repo.FindByID(ctx, u.ID)
+	assert.Equal(t, "Alicia", again.Name)
+
+	assert.ErrorIs(t, repo.Update(ctx, &stale), domain.ErrVersionConflict)
+
+	u.Email = "bob@test.com"
+	assert.ErrorIs(t, repo.Update(ctx, u), domain.ErrEmailTaken)
+}
+
+func TestUserRepository_DeleteRestorePurge(t *testing.T) {
+	ctx := context.Background()
+	repo := memory.NewUserRepository(nil)
+	u := &domain.User{Name: "Alice", Email: "alice@test.com"}
+	assert.NoError(t, repo.Create(ctx, u))
+
+	assert.ErrorIs(t, repo.Restore(ctx, u.ID), domain.ErrNotFound)
+
+	assert.NoError(t, repo.Delete(ctx, u.ID))
+	_, err := repo.FindByID(ctx, u.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	count, _ := repo.Count(ctx)
+	assert.Equal(t, int64(0), count)
+
+	assert.NoError(t, repo.Restore(ctx, u.ID))
+	_, err = repo.FindByID(ctx, u.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Delete(ctx, u.ID))
+	purged, err := repo.Purge(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PurgedUser{{ID: u.ID, TenantID: domain.DefaultTenant}}, purged)
+	assert.ErrorIs(t, repo.Restore(ctx, u.ID), domain.ErrNotFound)
+}
+
+func names(page *domain.UserPage) []string {
+	out := []string{}
+	for _, u := range page.Users {
+		out = append(out, u.Name)
+	}
+	return out
+}
@@ -0,0 +1,214 @@
+package memory
+
+import (
+	"bytes"
+	"cmp"
+	"context"
+	"slices"
+	"sync"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// WebhookRepository keeps subscriptions and deliveries in memory, with
+// their tenant.
+type WebhookRepository struct {
+	mu         sync.Mutex
+	subs       map[string]domain.WebhookSubscription
+	deliveries map[string]domain.WebhookDelivery
+}
+
+func NewWebhookRepository() ports.WebhookRepository {
+	return &WebhookRepository{
+		subs:       map[string]domain.WebhookSubscription{},
+		deliveries: map[string]domain.WebhookDelivery{},
+	}
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, s *domain.WebhookSubscription) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s.ID = primitive.NewObjectID().Hex()
+	s.TenantID = domain.TenantID(ctx)
+	stored := *s
+	stored.EventTypes = slices.Clone(s.EventTypes)
+	r.subs[s.ID] = stored
+	return nil
+}
+
+func (r *WebhookRepository) FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	s, ok := r.subs[id]
+	if !ok || s.TenantID != domain.TenantID(ctx) {
+		return nil, domain.ErrNotFound
+	}
+	s.EventTypes = slices.Clone(s.EventTypes)
+	return &s, nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := domain.TenantID(ctx)
+	subs := []*domain.WebhookSubscription{}
+	for _, s := range r.subs {
+		if s.TenantID != tenant {
+			continue
+		}
+		s.EventTypes = slices.Clone(s.EventTypes)
+		subs = append(subs, &s)
+	}
+	slices.SortFunc(subs, func(a, b *domain.WebhookSubscription) int { return cmp.Compare(a.ID, b.ID) })
+	return subs, nil
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if s, ok := r.subs[id]; !ok || s.TenantID != domain.TenantID(ctx) {
+		return domain.ErrNotFound
+	}
+	delete(r.subs, id)
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.deliveries {
+		if existing.SubscriptionID == d.SubscriptionID && existing.EventID == d.EventID {
+			return domain.ErrAlreadyExists
+		}
+	}
+	d.ID = primitive.NewObjectID().Hex()
+	r.deliveries[d.ID] = cloneDelivery(d)
+	return nil
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	d, ok := r.deliveries[id]
+	if !ok || d.TenantID != domain.TenantID(ctx) {
+		return nil, domain.ErrNotFound
+	}
+	found := cloneDelivery(&d)
+	return &found, nil
+}
+
+func (r *WebhookRepository) ListDeliveries(
+	ctx context.Context,
+	q domain.WebhookDeliveryQuery,
+) ([]*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := domain.TenantID(ctx)
+	deliveries := []*domain.WebhookDelivery{}
+	for _, d := range r.deliveries {
+		if d.TenantID != tenant ||
+			(q.SubscriptionID != "" && d.SubscriptionID != q.SubscriptionID) ||
+			(q.Status != "" && d.Status != q.Status) {
+			continue
+		}
+		found := cloneDelivery(&d)
+		deliveries = append(deliveries, &found)
+	}
+
+	// Newest first, like the ObjectID order in MongoDB.
+	slices.SortFunc(deliveries, func(a, b *domain.WebhookDelivery) int { return cmp.Compare(b.ID, a.ID) })
+	if q.Offset >= len(deliveries) {
+		return []*domain.WebhookDelivery{}, nil
+	}
+	deliveries = deliveries[q.Offset:]
+	if q.Limit > 0 && len(deliveries) > q.Limit {
+		deliveries = deliveries[:q.Limit]
+	}
+	return deliveries, nil
+}
+
+// ClaimDelivery picks the pending delivery that is due first and pushes
+// its next attempt past the lease.
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	var next *domain.WebhookDelivery
+	for _, d := range r.deliveries {
+		if d.Status != domain.DeliveryPending || d.NextAttemptAt.After(now) {
+			continue
+		}
+		if next == nil || d.NextAttemptAt.Before(next.NextAttemptAt) ||
+			(d.NextAttemptAt.Equal(next.NextAttemptAt) && d.ID < next.ID) {
+			next = &d
+		}
+	}
+	if next == nil {
+		return nil, domain.ErrNotFound
+	}
+
+	next.NextAttemptAt = now.Add(lease)
+	r.deliveries[next.ID] = *next
+	claimed := cloneDelivery(next)
+	return &claimed, nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.deliveries[d.ID]; !ok {
+		return domain.ErrNotFound
+	}
+	r.deliveries[d.ID] = cloneDelivery(d)
+	return nil
+}
+
+func (r *WebhookRepository) RedactDeliveries(ctx context.Context, userID string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	tenant := domain.TenantID(ctx)
+	field := []byte(domain.EventUserField(userID))
+	var n int64
+	for id, d := range r.deliveries {
+		if d.TenantID != tenant || !bytes.Contains(d.Payload, field) {
+			continue
+		}
+		payload, err := domain.WithoutData(d.Payload)
+		if err != nil {
+			return n, err
+		}
+		if !bytes.Equal(payload, d.Payload) {
+			d.Payload = payload
+			r.deliveries[id] = d
+			n++
+		}
+	}
+	return n, nil
+}
+
+func cloneDelivery(d *domain.WebhookDelivery) domain.WebhookDelivery {
+	c := *d
+	c.Payload = slices.Clone(d.Payload)
+	c.Attempts = slices.Clone(d.Attempts)
+	if c.Attempts == nil {
+		c.Attempts = []domain.WebhookAttempt{}
+	}
+	if d.DeliveredAt != nil {
+		t := *d.DeliveredAt
+		c.DeliveredAt = &t
+	}
+	return c
+}
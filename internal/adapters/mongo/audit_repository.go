@@ -0,0 +1,216 @@
+package mongo
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ColAuditEvents = "audit_events"
+)
+
+type AuditRepository struct {
+	col *mongo.Collection
+}
+
+func NewAuditRepository(db *mongo.Database) ports.AuditRepository {
+	return &AuditRepository{col: db.Collection(ColAuditEvents)}
+}
+
+// auditDocument stores changes as the exact JSON that was hashed, so the
+// chain still verifies after a round trip through BSON. Its _id is left
+// to the driver; events stored before tenants existed keep their
+// sequence number as _id.
+type auditDocument struct {
+	TenantID     string    `bson:"tenant_id"`
+	Seq          int64     `bson:"seq"`
+	Action       string    `bson:"action"`
+	ActorID      string    `bson:"actor_id,omitempty"`
+	TargetID     string    `bson:"target_id,omitempty"`
+	Changes      string    `bson:"changes,omitempty"`
+	Reason       string    `bson:"reason,omitempty"`
+	IP           string    `bson:"ip,omitempty"`
+	RequestID    string    `bson:"request_id,omitempty"`
+	Timestamp    time.Time `bson:"timestamp"`
+	PrevHash     string    `bson:"prev_hash"`
+	Hash         string    `bson:"hash"`
+	Redacted     bool      `bson:"redacted,omitempty"`
+	OriginalHash string    `bson:"original_hash,omitempty"`
+}
+
+// Append relies on the unique index on tenant and sequence number, so two
+// writers racing for the same position cannot both succeed.
+func (r *AuditRepository) Append(ctx context.Context, e *domain.AuditEvent) error {
+	doc, err := newAuditDocument(e)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.col.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrAlreadyExists
+	}
+	return err
+}
+
+func newAuditDocument(e *domain.AuditEvent) (*auditDocument, error) {
+	doc := &auditDocument{
+		TenantID:     e.TenantID,
+		Seq:          e.Seq,
+		Action:       string(e.Action),
+		ActorID:      e.ActorID,
+		TargetID:     e.TargetID,
+		Reason:       e.Reason,
+		IP:           e.IP,
+		RequestID:    e.RequestID,
+		Timestamp:    e.Timestamp,
+		PrevHash:     e.PrevHash,
+		Hash:         e.Hash,
+		Redacted:     e.Redacted,
+		OriginalHash: e.OriginalHash,
+	}
+	if e.Changes != nil {
+		b, err := json.Marshal(e.Changes)
+		if err != nil {
+			return nil, err
+		}
+		doc.Changes = string(b)
+	}
+	return doc, nil
+}
+
+func (r *AuditRepository) Last(ctx context.Context) (*domain.AuditEvent, error) {
+	var doc auditDocument
+	err := r.col.FindOne(ctx, bson.M{"tenant_id": domain.TenantID(ctx)}, options.FindOne().
+		SetSort(bson.D{{Key: "seq", Value: -1}})).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain()
+}
+
+func (r *AuditRepository) Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error) {
+	filter := bson.M{"tenant_id": domain.TenantID(ctx)}
+	if q.AfterSeq > 0 {
+		filter["seq"] = bson.M{"$gt": q.AfterSeq}
+	}
+	if q.ActorID != "" {
+		filter["actor_id"] = q.ActorID
+	}
+	if q.TargetID != "" {
+		filter["target_id"] = q.TargetID
+	}
+	if q.Action != "" {
+		filter["action"] = string(q.Action)
+	}
+	ts := bson.M{}
+	if !q.From.IsZero() {
+		ts["$gte"] = q.From
+	}
+	if !q.Until.IsZero() {
+		ts["$lte"] = q.Until
+	}
+	if len(ts) > 0 {
+		filter["timestamp"] = ts
+	}
+
+	opts := options.Find().SetSort(bson.D{{Key: "seq", Value: 1}})
+	if q.Limit > 0 {
+		opts.SetLimit(int64(q.Limit))
+	}
+
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	events := []*domain.AuditEvent{}
+	for cur.Next(ctx) {
+		var doc auditDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		e, err := doc.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, cur.Err()
+}
+
+// Redact applies domain.AuditEvent.Redact to the events in Go, as they
+// have to be rehashed, and replaces them one by one. A failure part way
+// leaves the rest for a retry to redact.
+func (r *AuditRepository) Redact(ctx context.Context, userID, pseudonym string) ([]*domain.AuditEvent, error) {
+	tenant := domain.TenantID(ctx)
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": tenant, "$or": bson.A{
+		bson.M{"actor_id": userID},
+		bson.M{"target_id": userID},
+	}}, options.Find().SetSort(bson.D{{Key: "seq", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	redacted := []*domain.AuditEvent{}
+	for cur.Next(ctx) {
+		var stored auditDocument
+		if err := cur.Decode(&stored); err != nil {
+			return nil, err
+		}
+		e, err := stored.toDomain()
+		if err != nil {
+			return nil, err
+		}
+		if !e.Redact(userID, pseudonym) {
+			continue
+		}
+		doc, err := newAuditDocument(e)
+		if err != nil {
+			return nil, err
+		}
+		_, err = r.col.ReplaceOne(ctx, bson.M{"tenant_id": tenant, "seq": e.Seq}, doc)
+		if err != nil {
+			return nil, err
+		}
+		redacted = append(redacted, e)
+	}
+	return redacted, cur.Err()
+}
+
+func (d *auditDocument) toDomain() (*domain.AuditEvent, error) {
+	e := &domain.AuditEvent{
+		Seq:          d.Seq,
+		TenantID:     d.TenantID,
+		Action:       domain.AuditAction(d.Action),
+		ActorID:      d.ActorID,
+		TargetID:     d.TargetID,
+		Reason:       d.Reason,
+		IP:           d.IP,
+		RequestID:    d.RequestID,
+		Timestamp:    d.Timestamp.UTC(),
+		PrevHash:     d.PrevHash,
+		Hash:         d.Hash,
+		Redacted:     d.Redacted,
+		OriginalHash: d.OriginalHash,
+	}
+	if d.Changes != "" {
+		if err := json.Unmarshal([]byte(d.Changes), &e.Changes); err != nil {
+			return nil, err
+		}
+	}
+	return e, nil
+}
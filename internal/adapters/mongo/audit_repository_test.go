@@ -0,0 +1,91 @@
+package mongo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestAuditRepository_Append(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := mongo.NewAuditRepository(mt.DB)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		err := repo.Append(context.Background(), &domain.AuditEvent{Seq: 1, Action: domain.AuditUserDeleted})
+		assert.NoError(t, err)
+	})
+
+	mt.Run("sequence taken", func(mt *mtest.T) {
+		repo := mongo.NewAuditRepository(mt.DB)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "duplicate key error",
+		}))
+
+		err := repo.Append(context.Background(), &domain.AuditEvent{Seq: 1, Action: domain.AuditUserDeleted})
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+	})
+}
+
+func TestAuditRepository_Last(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("empty log", func(mt *mtest.T) {
+		repo := mongo.NewAuditRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColAuditEvents
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch))
+
+		_, err := repo.Last(context.Background())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestAuditRepository_Find_HashSurvivesRoundTrip(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := mongo.NewAuditRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColAuditEvents
+
+		event := domain.AuditEvent{
+			Seq:       7,
+			TenantID:  domain.DefaultTenant,
+			Action:    domain.AuditUserUpdated,
+			ActorID:   "admin",
+			TargetID:  "u1",
+			Changes:   domain.AuditDiff(&domain.User{Name: "Old"}, &domain.User{Name: "New"}),
+			Timestamp: time.Now().UTC().Truncate(time.Millisecond),
+			PrevHash:  "abc",
+		}
+		event.Hash = event.ComputeHash()
+
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{
+			{Key: "_id", Value: event.Seq},
+			{Key: "tenant_id", Value: domain.DefaultTenant},
+			{Key: "seq", Value: event.Seq},
+			{Key: "action", Value: string(event.Action)},
+			{Key: "actor_id", Value: event.ActorID},
+			{Key: "target_id", Value: event.TargetID},
+			{Key: "changes", Value: `{"name":{"before":"Old","after":"New"}}`},
+			{Key: "timestamp", Value: event.Timestamp},
+			{Key: "prev_hash", Value: event.PrevHash},
+			{Key: "hash", Value: event.Hash},
+		}))
+
+		events, err := repo.Find(context.Background(), domain.AuditQuery{TargetID: "u1", Limit: 10})
+
+		assert.NoError(t, err)
+		assert.Len(t, events, 1)
+		assert.Equal(t, event, *events[0])
+		assert.Equal(t, event.Hash, events[0].ComputeHash())
+	})
+}
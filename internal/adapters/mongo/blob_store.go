@@ -0,0 +1,154 @@
+package mongo
+
+import (
+	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"io"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/gridfs"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	BucketBlobs = "blobs"
+)
+
+// BlobStore keeps blobs in a GridFS bucket, using the key as filename.
+type BlobStore struct {
+	db     *mongo.Database
+	bucket string
+}
+
+func NewBlobStore(db *mongo.Database) ports.BlobStore {
+	return &BlobStore{db: db, bucket: BucketBlobs}
+}
+
+type blobFile struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	Length     int64              `bson:"length"`
+	UploadDate time.Time          `bson:"uploadDate"`
+	Metadata   struct {
+		ContentType string `bson:"content_type"`
+		ETag        string `bson:"etag"`
+	} `bson:"metadata"`
+}
+
+// Put uploads a new revision and then removes older ones, so readers
+// always find a complete file.
+func (s *BlobStore) Put(ctx context.Context, key, contentType string, data []byte) (*ports.BlobInfo, error) {
+	b, err := s.open(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	sum := sha256.Sum256(data)
+	etag := hex.EncodeToString(sum[:])
+
+	id, err := b.UploadFromStream(key, bytes.NewReader(data), options.GridFSUpload().
+		SetMetadata(bson.M{"content_type": contentType, "etag": etag}))
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.deleteWhere(ctx, b, bson.M{"filename": key, "_id": bson.M{"$ne": id}}); err != nil {
+		return nil, err
+	}
+
+	return &ports.BlobInfo{
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ETag:        etag,
+		UpdatedAt:   time.Now(),
+	}, nil
+}
+
+func (s *BlobStore) Get(ctx context.Context, key string) (io.ReadCloser, *ports.BlobInfo, error) {
+	b, err := s.open(ctx)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	cur, err := b.FindContext(ctx, bson.M{"filename": key}, options.GridFSFind().
+		SetSort(bson.D{{Key: "uploadDate", Value: -1}}).
+		SetLimit(1))
+	if err != nil {
+		return nil, nil, err
+	}
+	defer cur.Close(ctx)
+
+	if !cur.Next(ctx) {
+		if err := cur.Err(); err != nil {
+			return nil, nil, err
+		}
+		return nil, nil, domain.ErrNotFound
+	}
+
+	var f blobFile
+	if err := cur.Decode(&f); err != nil {
+		return nil, nil, err
+	}
+
+	stream, err := b.OpenDownloadStream(f.ID)
+	if err == gridfs.ErrFileNotFound {
+		return nil, nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, nil, err
+	}
+
+	return stream, &ports.BlobInfo{
+		ContentType: f.Metadata.ContentType,
+		Size:        f.Length,
+		ETag:        f.Metadata.ETag,
+		UpdatedAt:   f.UploadDate,
+	}, nil
+}
+
+func (s *BlobStore) Delete(ctx context.Context, key string) error {
+	b, err := s.open(ctx)
+	if err != nil {
+		return err
+	}
+	return s.deleteWhere(ctx, b, bson.M{"filename": key})
+}
+
+func (s *BlobStore) deleteWhere(ctx context.Context, b *gridfs.Bucket, filter bson.M) error {
+	cur, err := b.FindContext(ctx, filter)
+	if err != nil {
+		return err
+	}
+	defer cur.Close(ctx)
+
+	for cur.Next(ctx) {
+		var f blobFile
+		if err := cur.Decode(&f); err != nil {
+			return err
+		}
+		if err := b.DeleteContext(ctx, f.ID); err != nil && err != gridfs.ErrFileNotFound {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// open returns a bucket bound to ctx's deadline. Buckets hold deadlines as
+// state, so one is created per call.
+func (s *BlobStore) open(ctx context.Context) (*gridfs.Bucket, error) {
+	b, err := gridfs.NewBucket(s.db, options.GridFSBucket().SetName(s.bucket))
+	if err != nil {
+		return nil, err
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		_ = b.SetReadDeadline(deadline)
+		_ = b.SetWriteDeadline(deadline)
+	}
+	return b, nil
+}
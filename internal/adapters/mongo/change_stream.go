@@ -0,0 +1,251 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ColChangeStreamTokens = "change_stream_tokens"
+)
+
+// codeChangeStreamHistoryLost is returned when a resume token is older
+// than the oldest oplog entry.
+const codeChangeStreamHistoryLost = 286
+
+// errStreamInvalidated means the users collection was dropped or renamed.
+var errStreamInvalidated = errors.New("change stream invalidated")
+
+// UserChangeWatcher follows the users collection through a change stream
+// and publishes a domain event for every change, whoever made it. Deletes
+// carry no document, so the tenant of a deleted user is taken from the
+// pre-image migration 9 has the collection record. The resume token is stored after each published event, so a restarted
+// watcher continues where it stopped. Delivery is at-least-once.
+type UserChangeWatcher struct {
+	users  *mongo.Collection
+	tokens *mongo.Collection
+	name   string
+	sink   ports.EventPublisher
+	retry  time.Duration
+}
+
+// NewUserChangeWatcher creates a watcher that stores its resume token
+// under name. Watchers that must not share a position need their own name.
+func NewUserChangeWatcher(db *mongo.Database, name string, sink ports.EventPublisher) *UserChangeWatcher {
+	return &UserChangeWatcher{
+		users:  db.Collection(ColUser, options.Collection().SetRegistry(registry)),
+		tokens: db.Collection(ColChangeStreamTokens),
+		name:   name,
+		sink:   sink,
+		retry:  5 * time.Second,
+	}
+}
+
+type changeEvent struct {
+	ID struct {
+		Data string `bson:"_data"`
+	} `bson:"_id"`
+	OperationType string              `bson:"operationType"`
+	ClusterTime   primitive.Timestamp `bson:"clusterTime"`
+	DocumentKey   struct {
+		ID primitive.ObjectID `bson:"_id"`
+	} `bson:"documentKey"`
+	FullDocument             *userDocument `bson:"fullDocument"`
+	FullDocumentBeforeChange *userDocument `bson:"fullDocumentBeforeChange"`
+	UpdateDescription        struct {
+		UpdatedFields bson.M   `bson:"updatedFields"`
+		RemovedFields []string `bson:"removedFields"`
+	} `bson:"updateDescription"`
+}
+
+type tokenDocument struct {
+	ID        string    `bson:"_id"`
+	Token     bson.Raw  `bson:"token"`
+	UpdatedAt time.Time `bson:"updated_at"`
+}
+
+// Run watches until ctx is cancelled, reopening the stream after errors.
+func (w *UserChangeWatcher) Run(ctx context.Context) {
+	for {
+		err := w.Watch(ctx)
+		if ctx.Err() != nil {
+			log.Println("Stopping user change watcher")
+			return
+		}
+		log.Printf("user change stream failed: %v", err)
+
+		select {
+		case <-time.After(w.retry):
+		case <-ctx.Done():
+			log.Println("Stopping user change watcher")
+			return
+		}
+	}
+}
+
+// Watch opens the change stream at the stored resume token and publishes
+// changes until ctx ends or an error occurs. A token that is no longer in
+// the oplog is discarded and the stream starts from now; changes made in
+// between are not seen.
+func (w *UserChangeWatcher) Watch(ctx context.Context) error {
+	token, err := w.loadToken(ctx)
+	if err != nil {
+		return err
+	}
+
+	opts := streamOptions()
+	if token != nil {
+		opts.SetResumeAfter(token)
+	}
+
+	cs, err := w.users.Watch(ctx, mongo.Pipeline{}, opts)
+	var serr mongo.ServerError
+	if errors.As(err, &serr) && serr.HasErrorCode(codeChangeStreamHistoryLost) {
+		log.Printf("user change stream %q: resume token expired, starting from now", w.name)
+		if err := w.resetToken(ctx); err != nil {
+			return err
+		}
+		cs, err = w.users.Watch(ctx, mongo.Pipeline{}, streamOptions())
+	}
+	if err != nil {
+		return err
+	}
+	defer cs.Close(context.Background())
+
+	for cs.Next(ctx) {
+		var change changeEvent
+		if err := cs.Decode(&change); err != nil {
+			return err
+		}
+
+		if change.OperationType == "invalidate" {
+			// A token from after an invalidate cannot be resumed from.
+			if err := w.resetToken(ctx); err != nil {
+				return err
+			}
+			return errStreamInvalidated
+		}
+
+		event, err := toChangeEvent(change)
+		if err != nil {
+			return err
+		}
+		if event != nil {
+			if err := w.sink.Publish(ctx, *event); err != nil {
+				return fmt.Errorf("publish %s: %w", event.Type, err)
+			}
+		}
+
+		if err := w.saveToken(ctx, cs.ResumeToken()); err != nil {
+			return err
+		}
+	}
+	return cs.Err()
+}
+
+func streamOptions() *options.ChangeStreamOptions {
+	return options.ChangeStream().
+		SetFullDocument(options.UpdateLookup).
+		SetFullDocumentBeforeChange(options.WhenAvailable)
+}
+
+func (w *UserChangeWatcher) loadToken(ctx context.Context) (bson.Raw, error) {
+	var doc tokenDocument
+	err := w.tokens.FindOne(ctx, bson.M{"_id": w.name}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.Token, nil
+}
+
+func (w *UserChangeWatcher) saveToken(ctx context.Context, token bson.Raw) error {
+	_, err := w.tokens.ReplaceOne(
+		ctx,
+		bson.M{"_id": w.name},
+		tokenDocument{ID: w.name, Token: token, UpdatedAt: time.Now()},
+		options.Replace().SetUpsert(true),
+	)
+	return err
+}
+
+func (w *UserChangeWatcher) resetToken(ctx context.Context) error {
+	_, err := w.tokens.DeleteOne(ctx, bson.M{"_id": w.name})
+	return err
+}
+
+// toChangeEvent maps a change to the event the repository would have
+// written to the outbox. Soft deletes and restores are updates of
+// deleted_at; any change to a deleted user and a hard delete (purge) are
+// reported as user.deleted as well.
+// The event ID is the change's resume token, so a change seen twice keeps
+// its ID. It returns nil for changes that carry no user event, and for
+// changes whose tenant is unknown because neither the document nor its
+// pre-image could be read: no tenant's caches or subscribers can be told.
+func toChangeEvent(c changeEvent) (*domain.Event, error) {
+	id := c.DocumentKey.ID.Hex()
+
+	var (
+		t      domain.EventType
+		tenant string
+		user   *domain.User
+	)
+	switch c.OperationType {
+	case "insert":
+		t = domain.EventUserCreated
+	case "update", "replace":
+		_, deleted := c.UpdateDescription.UpdatedFields["deleted_at"]
+		switch {
+		case deleted, c.FullDocument != nil && c.FullDocument.DeletedAt != nil:
+			t = domain.EventUserDeleted
+		case slices.Contains(c.UpdateDescription.RemovedFields, "deleted_at"):
+			t = domain.EventUserRestored
+		default:
+			t = domain.EventUserUpdated
+		}
+	case "delete":
+		t = domain.EventUserDeleted
+	default:
+		return nil, nil
+	}
+
+	// Like the outbox, only creates and updates carry a snapshot. The full
+	// document is missing for deletes and when the user was deleted before
+	// it was looked up; the tenant then comes from the pre-image.
+	switch {
+	case c.FullDocument != nil:
+		u := toDomain(c.FullDocument)
+		tenant = u.TenantID
+		if t == domain.EventUserCreated || t == domain.EventUserUpdated {
+			user = u
+		}
+	case c.FullDocumentBeforeChange != nil:
+		tenant = toDomain(c.FullDocumentBeforeChange).TenantID
+	default:
+		log.Printf("user change stream: dropping %s of user %s, its tenant is unknown", t, id)
+		return nil, nil
+	}
+
+	event, err := domain.NewUserEvent(t, tenant, id, user)
+	if err != nil {
+		return nil, err
+	}
+	event.ID = c.ID.Data
+	if c.ClusterTime.T != 0 {
+		event.OccurredAt = time.Unix(int64(c.ClusterTime.T), 0).UTC()
+	}
+	return &event, nil
+}
@@ -0,0 +1,140 @@
+package mongo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestUserChangeWatcher_Watch(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("publishes changes and saves the resume token", func(mt *mtest.T) {
+		var events []domain.Event
+		sink := &mocks.EventPublisherMock{PublishFn: func(_ context.Context, e domain.Event) error {
+			events = append(events, e)
+			return nil
+		}}
+		watcher := mongo.NewUserChangeWatcher(mt.DB, "test", sink)
+
+		inserted, updated, deleted, unknown := primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID(), primitive.NewObjectID()
+		ns := mt.DB.Name() + ".users"
+		mt.AddMockResponses(
+			// no stored token
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".change_stream_tokens", mtest.FirstBatch),
+			mtest.CreateCursorResponse(1, ns, mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: bson.D{{Key: "_data", Value: "t1"}}},
+					{Key: "operationType", Value: "insert"},
+					{Key: "clusterTime", Value: primitive.Timestamp{T: uint32(time.Now().Unix())}},
+					{Key: "documentKey", Value: bson.D{{Key: "_id", Value: inserted}}},
+					{Key: "fullDocument", Value: bson.D{
+						{Key: "_id", Value: inserted},
+						{Key: "name", Value: "Alice"},
+						{Key: "email", Value: "alice@test.com"},
+						{Key: "version", Value: int64(1)},
+					}},
+				},
+				bson.D{
+					{Key: "_id", Value: bson.D{{Key: "_data", Value: "t2"}}},
+					{Key: "operationType", Value: "update"},
+					{Key: "documentKey", Value: bson.D{{Key: "_id", Value: updated}}},
+					{Key: "updateDescription", Value: bson.D{
+						{Key: "updatedFields", Value: bson.D{{Key: "deleted_at", Value: time.Now()}}},
+						{Key: "removedFields", Value: bson.A{}},
+					}},
+					{Key: "fullDocumentBeforeChange", Value: preImage(updated, "acme")},
+				},
+				bson.D{
+					{Key: "_id", Value: bson.D{{Key: "_data", Value: "t3"}}},
+					{Key: "operationType", Value: "delete"},
+					{Key: "documentKey", Value: bson.D{{Key: "_id", Value: deleted}}},
+					{Key: "fullDocumentBeforeChange", Value: preImage(deleted, "acme")},
+				},
+				// no pre-image: the tenant is unknown
+				bson.D{
+					{Key: "_id", Value: bson.D{{Key: "_data", Value: "t4"}}},
+					{Key: "operationType", Value: "delete"},
+					{Key: "documentKey", Value: bson.D{{Key: "_id", Value: unknown}}},
+				},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			mtest.CreateCommandErrorResponse(mtest.CommandError{Code: 2, Message: "stop"}),
+		)
+
+		err := watcher.Watch(context.Background())
+		assert.Error(t, err)
+
+		if assert.Len(t, events, 3) {
+			assert.Equal(t, "t1", events[0].ID)
+			assert.Equal(t, domain.EventUserCreated, events[0].Type)
+			assert.Equal(t, inserted.Hex(), events[0].UserID)
+			assert.Contains(t, string(events[0].Data), `"name":"Alice"`)
+			assert.Equal(t, domain.DefaultTenant, events[0].TenantID)
+
+			assert.Equal(t, domain.EventUserDeleted, events[1].Type)
+			assert.Equal(t, updated.Hex(), events[1].UserID)
+			assert.Equal(t, "acme", events[1].TenantID)
+			assert.Empty(t, events[1].Data)
+
+			assert.Equal(t, domain.EventUserDeleted, events[2].Type)
+			assert.Equal(t, deleted.Hex(), events[2].UserID)
+			assert.Equal(t, "acme", events[2].TenantID)
+		}
+
+		var saved []string
+		for _, e := range mt.GetAllStartedEvents() {
+			if e.CommandName == "update" {
+				saved = append(saved, e.Command.Lookup("updates", "0", "u", "token", "_data").StringValue())
+			}
+		}
+		assert.Equal(t, []string{"t1", "t2", "t3", "t4"}, saved)
+	})
+
+	mt.Run("does not save the token when publishing fails", func(mt *mtest.T) {
+		sink := &mocks.EventPublisherMock{PublishFn: func(context.Context, domain.Event) error {
+			return assert.AnError
+		}}
+		watcher := mongo.NewUserChangeWatcher(mt.DB, "test", sink)
+
+		oid := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, mt.DB.Name()+".change_stream_tokens", mtest.FirstBatch),
+			mtest.CreateCursorResponse(1, mt.DB.Name()+".users", mtest.FirstBatch,
+				bson.D{
+					{Key: "_id", Value: bson.D{{Key: "_data", Value: "t1"}}},
+					{Key: "operationType", Value: "delete"},
+					{Key: "documentKey", Value: bson.D{{Key: "_id", Value: oid}}},
+					{Key: "fullDocumentBeforeChange", Value: preImage(oid, "acme")},
+				},
+			),
+		)
+
+		err := watcher.Watch(context.Background())
+		assert.ErrorIs(t, err, assert.AnError)
+
+		for _, e := range mt.GetAllStartedEvents() {
+			assert.NotEqual(t, "update", e.CommandName)
+		}
+	})
+}
+
+func preImage(id primitive.ObjectID, tenant string) bson.D {
+	return bson.D{
+		{Key: "_id", Value: id},
+		{Key: "schema_version", Value: 2},
+		{Key: "tenant_id", Value: tenant},
+		{Key: "email", Value: "bob@test.com"},
+	}
+}
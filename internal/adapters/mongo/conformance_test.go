@@ -0,0 +1,69 @@
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/porttest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+// newTestDB connects to the MongoDB at MONGO_TEST_URI, skipping t when
+// none is set. The function it returns gives each subtest a migrated
+// database of its own, dropped when the subtest ends.
+func newTestDB(t *testing.T) func(t *testing.T) *mongodriver.Database {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+	client, err := mongodriver.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	return func(t *testing.T) *mongodriver.Database {
+		db := client.Database("conformance_" + primitive.NewObjectID().Hex())
+		t.Cleanup(func() { db.Drop(context.Background()) })
+
+		if err := mongo.NewMigrator(db).Up(context.Background(), 0); err != nil {
+			t.Fatal(err)
+		}
+		return db
+	}
+}
+
+// TestUserRepository_Conformance needs a replica set, for transactions,
+// at MONGO_TEST_URI.
+func TestUserRepository_Conformance(t *testing.T) {
+	newDB := newTestDB(t)
+	porttest.UserRepository(t, func(t *testing.T) ports.UserRepository {
+		return mongo.NewUserRepository(newDB(t))
+	})
+}
+
+// TestOrganizationRepository_Conformance needs a replica set at
+// MONGO_TEST_URI, as creating an organization is transactional.
+func TestOrganizationRepository_Conformance(t *testing.T) {
+	newDB := newTestDB(t)
+	porttest.OrganizationRepository(t, func(t *testing.T) ports.OrganizationRepository {
+		return mongo.NewOrganizationRepository(newDB(t))
+	})
+}
+
+// TestAuditRepository_Conformance needs a MongoDB at MONGO_TEST_URI.
+func TestAuditRepository_Conformance(t *testing.T) {
+	newDB := newTestDB(t)
+	porttest.AuditRepository(t, func(t *testing.T) ports.AuditRepository {
+		return mongo.NewAuditRepository(newDB(t))
+	})
+}
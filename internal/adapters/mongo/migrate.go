@@ -0,0 +1,765 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"os"
+	"slices"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ColMigrations     = "migrations"
+	ColMigrationLocks = "migration_locks"
+)
+
+const (
+	codeNamespaceNotFound = 26
+	codeIndexNotFound     = 27
+)
+
+// migration is one versioned step. Index builds and data changes cannot
+// run in a transaction, so a step that was interrupted runs again: up and
+// down must be safe to repeat. down is nil for a step that cannot be
+// rolled back.
+type migration struct {
+	version int
+	name    string
+	up      func(ctx context.Context, db *mongo.Database) error
+	down    func(ctx context.Context, db *mongo.Database) error
+}
+
+// migrations are applied in order and never edited once released; changes
+// get a new migration.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create user indexes",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			col := db.Collection(ColUser)
+			if _, err := col.Indexes().CreateMany(ctx, userIndexes()); err != nil {
+				return err
+			}
+			// The index earlier versions built was on a field users are
+			// not stored under.
+			return dropIndexes(ctx, col, "createdAt_-1")
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection(ColUser), indexNames(userIndexes())...)
+		},
+	},
+	{
+		version: 2,
+		name:    "create audit, outbox and webhook indexes",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for col, indexes := range eventIndexes() {
+				if _, err := db.Collection(col).Indexes().CreateMany(ctx, indexes); err != nil {
+					return fmt.Errorf("%s: %w", col, err)
+				}
+			}
+			return nil
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			for col, indexes := range eventIndexes() {
+				if err := dropIndexes(ctx, db.Collection(col), indexNames(indexes)...); err != nil {
+					return fmt.Errorf("%s: %w", col, err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 3,
+		name:    "backfill user schema version",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			col := db.Collection(ColUser)
+			_, err := col.UpdateMany(ctx,
+				bson.M{
+					"schema_version": bson.M{"$exists": false},
+					"created_at":     bson.M{"$exists": false},
+					"createdAt":      bson.M{"$exists": true},
+				},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"created_at": "$createdAt"}}}},
+			)
+			if err != nil {
+				return err
+			}
+			_, err = col.UpdateMany(ctx,
+				bson.M{"schema_version": bson.M{"$exists": false}},
+				bson.M{
+					"$set":   bson.M{"schema_version": 1},
+					"$unset": bson.M{"createdAt": ""},
+				},
+			)
+			return err
+		},
+		// Documents without schema_version are read as version 0 and
+		// upgraded on read, which still works once createdAt is gone.
+		down: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(ColUser).UpdateMany(ctx,
+				bson.M{"schema_version": 1},
+				bson.M{"$unset": bson.M{"schema_version": ""}},
+			)
+			return err
+		},
+	},
+	{
+		version: 4,
+		name:    "index invitation tokens",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(ColUser).Indexes().CreateOne(ctx, invitationIndex())
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection(ColUser), *invitationIndex().Options.Name)
+		},
+	},
+	{
+		version: 5,
+		name:    "scope users by tenant",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			col := db.Collection(ColUser)
+			_, err := col.UpdateMany(ctx,
+				bson.M{"schema_version": 1},
+				bson.M{"$set": bson.M{"schema_version": 2, "tenant_id": domain.DefaultTenant}},
+			)
+			if err != nil {
+				return err
+			}
+			// Emails become unique per tenant.
+			if _, err := col.Indexes().CreateOne(ctx, tenantEmailIndex()); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, col, "email_1")
+		},
+		// Rolling back would merge the tenants, so it is refused once
+		// there are users outside the default one.
+		down: func(ctx context.Context, db *mongo.Database) error {
+			col := db.Collection(ColUser)
+			n, err := col.CountDocuments(ctx, bson.M{"tenant_id": bson.M{"$nin": bson.A{domain.DefaultTenant, nil}}})
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%d users belong to tenants other than %q", n, domain.DefaultTenant)
+			}
+			if _, err := col.Indexes().CreateOne(ctx, userIndexes()[0]); err != nil {
+				return err
+			}
+			if err := dropIndexes(ctx, col, *tenantEmailIndex().Options.Name); err != nil {
+				return err
+			}
+			_, err = col.UpdateMany(ctx,
+				bson.M{"schema_version": 2},
+				bson.M{
+					"$set":   bson.M{"schema_version": 1},
+					"$unset": bson.M{"tenant_id": ""},
+				},
+			)
+			return err
+		},
+	},
+	{
+		version: 6,
+		name:    "create organization and membership indexes",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			_, err := db.Collection(ColMemberships).Indexes().CreateMany(ctx, membershipIndexes())
+			return err
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return dropIndexes(ctx, db.Collection(ColMemberships), indexNames(membershipIndexes())...)
+		},
+	},
+	{
+		version: 7,
+		name:    "scope audit events by tenant",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			col := db.Collection(ColAuditEvents)
+			// The sequence number moves out of _id, which no longer
+			// identifies an event once every tenant numbers from 1.
+			_, err := col.UpdateMany(ctx,
+				bson.M{"seq": bson.M{"$exists": false}},
+				mongo.Pipeline{{{Key: "$set", Value: bson.M{"seq": "$_id", "tenant_id": domain.DefaultTenant}}}},
+			)
+			if err != nil {
+				return err
+			}
+			if _, err := col.Indexes().CreateMany(ctx, auditTenantIndexes()); err != nil {
+				return err
+			}
+			return dropIndexes(ctx, col, "actor_id_1__id_1", "target_id_1__id_1")
+		},
+		// Rolling back would merge the chains, so it is refused once there
+		// are events outside the default tenant. Events appended since
+		// have generated _ids and are stored again under their sequence
+		// number.
+		down: func(ctx context.Context, db *mongo.Database) error {
+			col := db.Collection(ColAuditEvents)
+			n, err := col.CountDocuments(ctx, bson.M{"tenant_id": bson.M{"$nin": bson.A{domain.DefaultTenant, nil}}})
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%d audit events belong to tenants other than %q", n, domain.DefaultTenant)
+			}
+
+			cur, err := col.Find(ctx, bson.M{"_id": bson.M{"$type": "objectId"}})
+			if err != nil {
+				return err
+			}
+			var docs []bson.M
+			if err := cur.All(ctx, &docs); err != nil {
+				return err
+			}
+			for _, doc := range docs {
+				id := doc["_id"]
+				doc["_id"] = doc["seq"]
+				if _, err := col.InsertOne(ctx, doc); err != nil && !mongo.IsDuplicateKeyError(err) {
+					return err
+				}
+				if _, err := col.DeleteOne(ctx, bson.M{"_id": id}); err != nil {
+					return err
+				}
+			}
+
+			if _, err := col.Indexes().CreateMany(ctx, eventIndexes()[ColAuditEvents]); err != nil {
+				return err
+			}
+			if err := dropIndexes(ctx, col, indexNames(auditTenantIndexes())...); err != nil {
+				return err
+			}
+			_, err = col.UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"seq": "", "tenant_id": ""}})
+			return err
+		},
+	},
+	{
+		version: 8,
+		name:    "scope webhooks by tenant",
+		up: func(ctx context.Context, db *mongo.Database) error {
+			for _, col := range []string{ColWebhookSubscriptions, ColWebhookDeliveries} {
+				_, err := db.Collection(col).UpdateMany(ctx,
+					bson.M{"tenant_id": bson.M{"$exists": false}},
+					bson.M{"$set": bson.M{"tenant_id": domain.DefaultTenant}},
+				)
+				if err != nil {
+					return fmt.Errorf("%s: %w", col, err)
+				}
+			}
+			return nil
+		},
+		// Rolling back would send every tenant's events to every
+		// subscription, so it is refused once other tenants subscribed.
+		down: func(ctx context.Context, db *mongo.Database) error {
+			subs := db.Collection(ColWebhookSubscriptions)
+			n, err := subs.CountDocuments(ctx, bson.M{"tenant_id": bson.M{"$nin": bson.A{domain.DefaultTenant, nil}}})
+			if err != nil {
+				return err
+			}
+			if n > 0 {
+				return fmt.Errorf("%d webhook subscriptions belong to tenants other than %q", n, domain.DefaultTenant)
+			}
+			for _, col := range []string{ColWebhookSubscriptions, ColWebhookDeliveries} {
+				_, err := db.Collection(col).UpdateMany(ctx, bson.M{}, bson.M{"$unset": bson.M{"tenant_id": ""}})
+				if err != nil {
+					return fmt.Errorf("%s: %w", col, err)
+				}
+			}
+			return nil
+		},
+	},
+	{
+		version: 9,
+		name:    "record user pre-images for change streams",
+		// Change streams report deletes without the document; the
+		// pre-image tells the watcher which tenant the user was in.
+		up: func(ctx context.Context, db *mongo.Database) error {
+			return setUserPreImages(ctx, db, true)
+		},
+		down: func(ctx context.Context, db *mongo.Database) error {
+			return setUserPreImages(ctx, db, false)
+		},
+	},
+}
+
+// Index names are those the server would pick, so databases indexed before
+// migrations existed keep their indexes.
+func userIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			Keys: bson.D{{Key: "email", Value: 1}},
+			Options: options.Index().
+				SetName("email_1").
+				SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "created_at", Value: -1},
+				{Key: "_id", Value: -1},
+			},
+			Options: options.Index().
+				SetName("created_at_-1__id_-1"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "name", Value: 1},
+				{Key: "_id", Value: 1},
+			},
+			Options: options.Index().
+				SetName("name_1__id_1"),
+		},
+		{
+			Keys: bson.D{{Key: "pending_email.token_hash", Value: 1}},
+			Options: options.Index().
+				SetName("pending_email.token_hash_1").
+				SetSparse(true),
+		},
+		{
+			Keys: bson.D{{Key: "deleted_at", Value: 1}},
+			Options: options.Index().
+				SetName("deleted_at_1").
+				SetSparse(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "name", Value: "text"},
+				{Key: "email", Value: "text"},
+			},
+			Options: options.Index().
+				SetName("user_search").
+				SetWeights(bson.D{
+					{Key: "name", Value: 3},
+					{Key: "email", Value: 1},
+				}),
+		},
+	}
+}
+
+func invitationIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{{Key: "invitation.token_hash", Value: 1}},
+		Options: options.Index().
+			SetName("invitation.token_hash_1").
+			SetSparse(true),
+	}
+}
+
+func tenantEmailIndex() mongo.IndexModel {
+	return mongo.IndexModel{
+		Keys: bson.D{
+			{Key: "tenant_id", Value: 1},
+			{Key: "email", Value: 1},
+		},
+		Options: options.Index().
+			SetName("tenant_id_1_email_1").
+			SetUnique(true),
+	}
+}
+
+func membershipIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			// A user holds at most one membership per organization.
+			Keys: bson.D{
+				{Key: "organization_id", Value: 1},
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().
+				SetName("organization_id_1_user_id_1").
+				SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "user_id", Value: 1},
+			},
+			Options: options.Index().
+				SetName("tenant_id_1_user_id_1"),
+		},
+	}
+}
+
+func auditTenantIndexes() []mongo.IndexModel {
+	return []mongo.IndexModel{
+		{
+			// Each tenant's chain has one event per position.
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "seq", Value: 1},
+			},
+			Options: options.Index().
+				SetName("tenant_id_1_seq_1").
+				SetUnique(true),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "actor_id", Value: 1},
+				{Key: "seq", Value: 1},
+			},
+			Options: options.Index().
+				SetName("tenant_id_1_actor_id_1_seq_1"),
+		},
+		{
+			Keys: bson.D{
+				{Key: "tenant_id", Value: 1},
+				{Key: "target_id", Value: 1},
+				{Key: "seq", Value: 1},
+			},
+			Options: options.Index().
+				SetName("tenant_id_1_target_id_1_seq_1"),
+		},
+	}
+}
+
+func eventIndexes() map[string][]mongo.IndexModel {
+	return map[string][]mongo.IndexModel{
+		ColAuditEvents: {
+			{
+				Keys: bson.D{
+					{Key: "actor_id", Value: 1},
+					{Key: "_id", Value: 1},
+				},
+				Options: options.Index().
+					SetName("actor_id_1__id_1"),
+			},
+			{
+				Keys: bson.D{
+					{Key: "target_id", Value: 1},
+					{Key: "_id", Value: 1},
+				},
+				Options: options.Index().
+					SetName("target_id_1__id_1"),
+			},
+			{
+				Keys: bson.D{{Key: "timestamp", Value: 1}},
+				Options: options.Index().
+					SetName("timestamp_1"),
+			},
+		},
+		ColOutbox: {
+			{
+				Keys: bson.D{
+					{Key: "delivered_at", Value: 1},
+					{Key: "next_attempt_at", Value: 1},
+				},
+				Options: options.Index().
+					SetName("delivered_at_1_next_attempt_at_1"),
+			},
+			{
+				// Delivered messages are kept for a week for debugging.
+				Keys: bson.D{{Key: "delivered_at", Value: 1}},
+				Options: options.Index().
+					SetName("delivered_ttl").
+					SetExpireAfterSeconds(7 * 24 * 60 * 60),
+			},
+		},
+		ColWebhookDeliveries: {
+			{
+				// An event is queued at most once per subscription.
+				Keys: bson.D{
+					{Key: "subscription_id", Value: 1},
+					{Key: "event_id", Value: 1},
+				},
+				Options: options.Index().
+					SetName("subscription_id_1_event_id_1").
+					SetUnique(true),
+			},
+			{
+				Keys: bson.D{
+					{Key: "status", Value: 1},
+					{Key: "next_attempt_at", Value: 1},
+				},
+				Options: options.Index().
+					SetName("status_1_next_attempt_at_1"),
+			},
+			{
+				Keys: bson.D{
+					{Key: "subscription_id", Value: 1},
+					{Key: "_id", Value: -1},
+				},
+				Options: options.Index().
+					SetName("subscription_id_1__id_-1"),
+			},
+		},
+	}
+}
+
+func setUserPreImages(ctx context.Context, db *mongo.Database, enabled bool) error {
+	return db.RunCommand(ctx, bson.D{
+		{Key: "collMod", Value: ColUser},
+		{Key: "changeStreamPreAndPostImages", Value: bson.M{"enabled": enabled}},
+	}).Err()
+}
+
+func indexNames(indexes []mongo.IndexModel) []string {
+	names := make([]string, len(indexes))
+	for i, idx := range indexes {
+		names[i] = *idx.Options.Name
+	}
+	return names
+}
+
+// dropIndexes drops the named indexes that exist.
+func dropIndexes(ctx context.Context, col *mongo.Collection, names ...string) error {
+	for _, name := range names {
+		_, err := col.Indexes().DropOne(ctx, name)
+		var cmdErr mongo.CommandError
+		if errors.As(err, &cmdErr) && (cmdErr.Code == codeNamespaceNotFound || cmdErr.Code == codeIndexNotFound) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("drop index %s: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// MigrationStatus is a migration known to this build or recorded as
+// applied. AppliedAt is nil while it is pending.
+type MigrationStatus struct {
+	Version   int
+	Name      string
+	AppliedAt *time.Time
+}
+
+type migrationDocument struct {
+	Version   int       `bson:"_id"`
+	Name      string    `bson:"name"`
+	AppliedAt time.Time `bson:"applied_at"`
+}
+
+const migrationLockID = "migrations"
+
+var errMigrationLockLost = errors.New("migration lock lost")
+
+// Migrator applies and rolls back migrations, recording applied ones in
+// the migrations collection. Up and Down hold a lock for as long as they
+// run, so replicas starting together apply each migration once; the others
+// wait for the lock and then find nothing left to do. The lock is leased
+// and renewed while held, so one left behind by a crashed process expires.
+type Migrator struct {
+	db      *mongo.Database
+	records *mongo.Collection
+	locks   *mongo.Collection
+	owner   string
+	lease   time.Duration
+	poll    time.Duration
+}
+
+func NewMigrator(db *mongo.Database) *Migrator {
+	host, _ := os.Hostname()
+	return &Migrator{
+		db:      db,
+		records: db.Collection(ColMigrations),
+		locks:   db.Collection(ColMigrationLocks),
+		owner:   fmt.Sprintf("%s/%d/%s", host, os.Getpid(), primitive.NewObjectID().Hex()),
+		lease:   time.Minute,
+		poll:    time.Second,
+	}
+}
+
+// Latest is the version of the last migration in this build.
+func Latest() int {
+	return migrations[len(migrations)-1].version
+}
+
+// Up applies the pending migrations up to and including version to, or
+// all of them when to is 0.
+func (m *Migrator) Up(ctx context.Context, to int) error {
+	if to == 0 {
+		to = Latest()
+	}
+	return m.locked(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range migrations {
+			if mig.version > to {
+				break
+			}
+			if _, ok := applied[mig.version]; ok {
+				continue
+			}
+			if err := mig.up(ctx, m.db); err != nil {
+				return fmt.Errorf("migration %d (%s): %w", mig.version, mig.name, err)
+			}
+			_, err := m.records.InsertOne(ctx, migrationDocument{
+				Version:   mig.version,
+				Name:      mig.name,
+				AppliedAt: time.Now().UTC(),
+			})
+			if err != nil {
+				return fmt.Errorf("record migration %d: %w", mig.version, err)
+			}
+			log.Printf("Applied MongoDB migration %d: %s", mig.version, mig.name)
+		}
+		return nil
+	})
+}
+
+// Down rolls back the applied migrations above version to, newest first.
+func (m *Migrator) Down(ctx context.Context, to int) error {
+	if to < 0 {
+		return fmt.Errorf("invalid target version %d", to)
+	}
+	return m.locked(ctx, func(ctx context.Context) error {
+		applied, err := m.applied(ctx)
+		if err != nil {
+			return err
+		}
+		for _, mig := range slices.Backward(migrations) {
+			if mig.version <= to {
+				break
+			}
+			if _, ok := applied[mig.version]; !ok {
+				continue
+			}
+			if mig.down == nil {
+				return fmt.Errorf("migration %d (%s) cannot be rolled back", mig.version, mig.name)
+			}
+			if err := mig.down(ctx, m.db); err != nil {
+				return fmt.Errorf("roll back migration %d (%s): %w", mig.version, mig.name, err)
+			}
+			if _, err := m.records.DeleteOne(ctx, bson.M{"_id": mig.version}); err != nil {
+				return fmt.Errorf("unrecord migration %d: %w", mig.version, err)
+			}
+			log.Printf("Rolled back MongoDB migration %d: %s", mig.version, mig.name)
+		}
+		return nil
+	})
+}
+
+// Status lists the migrations of this build, then any applied by a newer
+// one, in version order.
+func (m *Migrator) Status(ctx context.Context) ([]MigrationStatus, error) {
+	applied, err := m.applied(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := make([]MigrationStatus, 0, len(migrations))
+	for _, mig := range migrations {
+		s := MigrationStatus{Version: mig.version, Name: mig.name}
+		if doc, ok := applied[mig.version]; ok {
+			s.AppliedAt = &doc.AppliedAt
+			delete(applied, mig.version)
+		}
+		status = append(status, s)
+	}
+	for _, doc := range applied {
+		status = append(status, MigrationStatus{Version: doc.Version, Name: doc.Name, AppliedAt: &doc.AppliedAt})
+	}
+	slices.SortFunc(status, func(a, b MigrationStatus) int { return a.Version - b.Version })
+	return status, nil
+}
+
+func (m *Migrator) applied(ctx context.Context) (map[int]migrationDocument, error) {
+	cur, err := m.records.Find(ctx, bson.M{})
+	if err != nil {
+		return nil, err
+	}
+	var docs []migrationDocument
+	if err := cur.All(ctx, &docs); err != nil {
+		return nil, err
+	}
+
+	applied := make(map[int]migrationDocument, len(docs))
+	for _, doc := range docs {
+		doc.AppliedAt = doc.AppliedAt.UTC()
+		applied[doc.Version] = doc
+	}
+	return applied, nil
+}
+
+// locked runs fn holding the migration lock. fn's context is canceled if
+// the lock is lost.
+func (m *Migrator) locked(ctx context.Context, fn func(ctx context.Context) error) error {
+	if err := m.lock(ctx); err != nil {
+		return err
+	}
+	defer m.unlock(context.WithoutCancel(ctx))
+
+	ctx, cancel := context.WithCancelCause(ctx)
+	defer cancel(nil)
+	go m.renew(ctx, cancel)
+
+	err := fn(ctx)
+	if err != nil && errors.Is(context.Cause(ctx), errMigrationLockLost) {
+		return fmt.Errorf("%w: %w", errMigrationLockLost, err)
+	}
+	return err
+}
+
+// lock takes the lock once it is free or expired, polling until then.
+func (m *Migrator) lock(ctx context.Context) error {
+	logged := false
+	for {
+		now := time.Now()
+		_, err := m.locks.UpdateOne(ctx,
+			bson.M{"_id": migrationLockID, "expires_at": bson.M{"$lt": now}},
+			bson.M{"$set": bson.M{
+				"owner":       m.owner,
+				"acquired_at": now,
+				"expires_at":  now.Add(m.lease),
+			}},
+			options.Update().SetUpsert(true),
+		)
+		// The upsert collides with a lock that is still held.
+		if !mongo.IsDuplicateKeyError(err) {
+			return err
+		}
+		if !logged {
+			log.Println("Waiting for the MongoDB migration lock")
+			logged = true
+		}
+
+		select {
+		case <-time.After(m.poll):
+		case <-ctx.Done():
+			return fmt.Errorf("wait for migration lock: %w", ctx.Err())
+		}
+	}
+}
+
+// renew extends the lease until ctx is done, canceling it when the lock
+// was taken over.
+func (m *Migrator) renew(ctx context.Context, cancel context.CancelCauseFunc) {
+	t := time.NewTicker(m.lease / 3)
+	defer t.Stop()
+	for {
+		select {
+		case <-t.C:
+		case <-ctx.Done():
+			return
+		}
+
+		res, err := m.locks.UpdateOne(ctx,
+			bson.M{"_id": migrationLockID, "owner": m.owner},
+			bson.M{"$set": bson.M{"expires_at": time.Now().Add(m.lease)}},
+		)
+		if err != nil {
+			log.Printf("renew migration lock failed: %v", err)
+			continue
+		}
+		if res.MatchedCount == 0 {
+			cancel(errMigrationLockLost)
+			return
+		}
+	}
+}
+
+func (m *Migrator) unlock(ctx context.Context) {
+	_, err := m.locks.DeleteOne(ctx, bson.M{"_id": migrationLockID, "owner": m.owner})
+	if err != nil {
+		log.Printf("release migration lock failed: %v", err)
+	}
+}
@@ -0,0 +1,165 @@
+package mongo_test
+
+import (
+	"context"
+	"os"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	mongodriver "go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+func TestMigrator_Status(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("applied and pending", func(mt *mtest.T) {
+		m := mongo.NewMigrator(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColMigrations
+		appliedAt := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		mt.AddMockResponses(mtest.CreateCursorResponse(
+			0,
+			namespace,
+			mtest.FirstBatch,
+			bson.D{{Key: "_id", Value: 1}, {Key: "name", Value: "create user indexes"}, {Key: "applied_at", Value: appliedAt}},
+			bson.D{{Key: "_id", Value: 99}, {Key: "name", Value: "from a newer build"}, {Key: "applied_at", Value: appliedAt}},
+		))
+
+		status, err := m.Status(context.Background())
+
+		assert.NoError(t, err)
+		if assert.Len(t, status, mongo.Latest()+1) {
+			assert.Equal(t, 1, status[0].Version)
+			if assert.NotNil(t, status[0].AppliedAt) {
+				assert.Equal(t, appliedAt, *status[0].AppliedAt)
+			}
+			assert.Nil(t, status[1].AppliedAt)
+			assert.Equal(t, 99, status[len(status)-1].Version)
+			assert.Equal(t, "from a newer build", status[len(status)-1].Name)
+		}
+	})
+}
+
+func TestMigrator_Up(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("nothing pending", func(mt *mtest.T) {
+		m := mongo.NewMigrator(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColMigrations
+
+		var applied []bson.D
+		for v := 1; v <= mongo.Latest(); v++ {
+			applied = append(applied, bson.D{{Key: "_id", Value: v}, {Key: "applied_at", Value: time.Now()}})
+		}
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), // take lock
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, applied...),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}), // release lock
+		)
+
+		assert.NoError(t, m.Up(context.Background(), 0))
+
+		var commands []string
+		for e := mt.GetStartedEvent(); e != nil; e = mt.GetStartedEvent() {
+			commands = append(commands, e.CommandName)
+		}
+		assert.Equal(t, []string{"update", "find", "delete"}, commands)
+	})
+
+	mt.Run("waits while the lock is held", func(mt *mtest.T) {
+		m := mongo.NewMigrator(mt.DB)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Code:    11000,
+			Message: "duplicate key",
+		}))
+
+		ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+		defer cancel()
+
+		err := m.Up(ctx, 0)
+		assert.ErrorIs(t, err, context.DeadlineExceeded)
+	})
+}
+
+// TestMigrator_UpDown needs a MongoDB at MONGO_TEST_URI.
+func TestMigrator_UpDown(t *testing.T) {
+	uri := os.Getenv("MONGO_TEST_URI")
+	if uri == "" {
+		t.Skip("MONGO_TEST_URI not set")
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+	defer cancel()
+	client, err := mongodriver.Connect(ctx, options.Client().ApplyURI(uri))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { client.Disconnect(context.Background()) })
+
+	db := client.Database("migrate_" + primitive.NewObjectID().Hex())
+	t.Cleanup(func() { db.Drop(context.Background()) })
+
+	// A user and an index from before migrations existed.
+	legacyCreatedAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+	users := db.Collection(mongo.ColUser)
+	_, err = users.InsertOne(ctx, bson.M{"name": "John", "email": "john@test.com", "createdAt": legacyCreatedAt})
+	assert.NoError(t, err)
+	_, err = users.Indexes().CreateOne(ctx, mongodriver.IndexModel{Keys: bson.D{{Key: "createdAt", Value: -1}}})
+	assert.NoError(t, err)
+
+	// An audit event from before tenants existed.
+	audit := db.Collection(mongo.ColAuditEvents)
+	_, err = audit.InsertOne(ctx, bson.M{"_id": int64(1), "action": "user.deleted", "prev_hash": "", "hash": "h1"})
+	assert.NoError(t, err)
+
+	m := mongo.NewMigrator(db)
+	assert.NoError(t, m.Up(ctx, 0))
+	assert.NoError(t, m.Up(ctx, 0), "a second run has nothing to do")
+
+	status, err := m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.NotNil(t, s.AppliedAt, "migration %d", s.Version)
+	}
+
+	var doc bson.M
+	assert.NoError(t, users.FindOne(ctx, bson.M{}).Decode(&doc))
+	assert.EqualValues(t, 2, doc["schema_version"])
+	assert.Equal(t, domain.DefaultTenant, doc["tenant_id"])
+	assert.Equal(t, primitive.NewDateTimeFromTime(legacyCreatedAt), doc["created_at"])
+	assert.NotContains(t, doc, "createdAt")
+	assert.NotContains(t, indexNames(ctx, t, users), "createdAt_-1")
+	assert.Contains(t, indexNames(ctx, t, users), "created_at_-1__id_-1")
+	assert.Contains(t, indexNames(ctx, t, users), "tenant_id_1_email_1")
+	assert.NotContains(t, indexNames(ctx, t, users), "email_1")
+
+	var event bson.M
+	assert.NoError(t, audit.FindOne(ctx, bson.M{}).Decode(&event))
+	assert.EqualValues(t, 1, event["seq"])
+	assert.Equal(t, domain.DefaultTenant, event["tenant_id"])
+	assert.Contains(t, indexNames(ctx, t, audit), "tenant_id_1_seq_1")
+
+	assert.NoError(t, m.Down(ctx, 0))
+	status, err = m.Status(ctx)
+	assert.NoError(t, err)
+	for _, s := range status {
+		assert.Nil(t, s.AppliedAt, "migration %d", s.Version)
+	}
+	assert.Equal(t, []string{"_id_"}, indexNames(ctx, t, users))
+}
+
+func indexNames(ctx context.Context, t *testing.T, col *mongodriver.Collection) []string {
+	specs, err := col.Indexes().ListSpecifications(ctx)
+	assert.NoError(t, err)
+	names := make([]string, len(specs))
+	for i, s := range specs {
+		names[i] = s.Name
+	}
+	return names
+}
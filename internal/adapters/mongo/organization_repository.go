@@ -0,0 +1,231 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ColOrganizations = "organizations"
+	ColMemberships   = "memberships"
+)
+
+// OrganizationRepository keeps memberships in a collection of their own,
+// unique per organization and user. Both collections carry tenant_id and
+// every query matches on it.
+type OrganizationRepository struct {
+	orgs    *mongo.Collection
+	members *mongo.Collection
+}
+
+func NewOrganizationRepository(db *mongo.Database) ports.OrganizationRepository {
+	return &OrganizationRepository{
+		orgs:    db.Collection(ColOrganizations),
+		members: db.Collection(ColMemberships),
+	}
+}
+
+type organizationDocument struct {
+	ID        primitive.ObjectID `bson:"_id"`
+	TenantID  string             `bson:"tenant_id"`
+	Name      string             `bson:"name"`
+	CreatedAt time.Time          `bson:"created_at"`
+	UpdatedAt time.Time          `bson:"updated_at"`
+}
+
+type membershipDocument struct {
+	OrganizationID string    `bson:"organization_id"`
+	UserID         string    `bson:"user_id"`
+	TenantID       string    `bson:"tenant_id"`
+	Role           string    `bson:"role"`
+	Teams          []string  `bson:"teams,omitempty"`
+	State          string    `bson:"state"`
+	InvitedBy      string    `bson:"invited_by,omitempty"`
+	CreatedAt      time.Time `bson:"created_at"`
+	UpdatedAt      time.Time `bson:"updated_at"`
+}
+
+func (r *OrganizationRepository) Create(ctx context.Context, org *domain.Organization, owner *domain.Membership) error {
+	doc := organizationDocument{
+		ID:        primitive.NewObjectID(),
+		TenantID:  domain.TenantID(ctx),
+		Name:      org.Name,
+		CreatedAt: org.CreatedAt,
+		UpdatedAt: org.UpdatedAt,
+	}
+	m := *owner
+	m.OrganizationID = doc.ID.Hex()
+
+	sess, err := r.orgs.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		if _, err := r.orgs.InsertOne(sc, doc); err != nil {
+			return nil, err
+		}
+		_, err := r.members.InsertOne(sc, toMembershipDocument(&m, doc.TenantID))
+		return nil, err
+	})
+	if err != nil {
+		return err
+	}
+
+	org.ID = doc.ID.Hex()
+	org.TenantID = doc.TenantID
+	owner.OrganizationID = org.ID
+	return nil
+}
+
+func (r *OrganizationRepository) FindByID(ctx context.Context, id string) (*domain.Organization, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	var doc organizationDocument
+	err = r.orgs.FindOne(ctx, bson.M{"_id": oid, "tenant_id": domain.TenantID(ctx)}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &domain.Organization{
+		ID:        doc.ID.Hex(),
+		TenantID:  doc.TenantID,
+		Name:      doc.Name,
+		CreatedAt: doc.CreatedAt.UTC(),
+		UpdatedAt: doc.UpdatedAt.UTC(),
+	}, nil
+}
+
+func (r *OrganizationRepository) AddMember(ctx context.Context, m *domain.Membership) error {
+	if _, err := r.FindByID(ctx, m.OrganizationID); err != nil {
+		return err
+	}
+	_, err := r.members.InsertOne(ctx, toMembershipDocument(m, domain.TenantID(ctx)))
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *OrganizationRepository) FindMember(ctx context.Context, orgID, userID string) (*domain.Membership, error) {
+	var doc membershipDocument
+	err := r.members.FindOne(ctx, memberFilter(ctx, orgID, userID)).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain(), nil
+}
+
+func (r *OrganizationRepository) ListMembers(ctx context.Context, orgID string) ([]*domain.Membership, error) {
+	if _, err := r.FindByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return r.memberships(ctx,
+		bson.M{"organization_id": orgID, "tenant_id": domain.TenantID(ctx)},
+		bson.D{{Key: "user_id", Value: 1}},
+	)
+}
+
+func (r *OrganizationRepository) MembershipsOf(ctx context.Context, userID string) ([]*domain.Membership, error) {
+	return r.memberships(ctx,
+		bson.M{"user_id": userID, "tenant_id": domain.TenantID(ctx)},
+		bson.D{{Key: "organization_id", Value: 1}},
+	)
+}
+
+func (r *OrganizationRepository) memberships(ctx context.Context, filter bson.M, sort bson.D) ([]*domain.Membership, error) {
+	cur, err := r.members.Find(ctx, filter, options.Find().SetSort(sort))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	memberships := []*domain.Membership{}
+	for cur.Next(ctx) {
+		var doc membershipDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, doc.toDomain())
+	}
+	return memberships, cur.Err()
+}
+
+func (r *OrganizationRepository) UpdateMember(ctx context.Context, m *domain.Membership) error {
+	doc := toMembershipDocument(m, domain.TenantID(ctx))
+	res, err := r.members.ReplaceOne(ctx, memberFilter(ctx, m.OrganizationID, m.UserID), doc)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	res, err := r.members.DeleteOne(ctx, memberFilter(ctx, orgID, userID))
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *OrganizationRepository) RemoveUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.members.DeleteMany(ctx, bson.M{"user_id": userID, "tenant_id": domain.TenantID(ctx)})
+	if err != nil {
+		return 0, err
+	}
+	return res.DeletedCount, nil
+}
+
+func memberFilter(ctx context.Context, orgID, userID string) bson.M {
+	return bson.M{"organization_id": orgID, "user_id": userID, "tenant_id": domain.TenantID(ctx)}
+}
+
+func toMembershipDocument(m *domain.Membership, tenant string) membershipDocument {
+	return membershipDocument{
+		OrganizationID: m.OrganizationID,
+		UserID:         m.UserID,
+		TenantID:       tenant,
+		Role:           string(m.Role),
+		Teams:          m.Teams,
+		State:          string(m.State),
+		InvitedBy:      m.InvitedBy,
+		CreatedAt:      m.CreatedAt,
+		UpdatedAt:      m.UpdatedAt,
+	}
+}
+
+func (d *membershipDocument) toDomain() *domain.Membership {
+	return &domain.Membership{
+		OrganizationID: d.OrganizationID,
+		UserID:         d.UserID,
+		Role:           domain.OrgRole(d.Role),
+		Teams:          d.Teams,
+		State:          domain.MembershipState(d.State),
+		InvitedBy:      d.InvitedBy,
+		CreatedAt:      d.CreatedAt.UTC(),
+		UpdatedAt:      d.UpdatedAt.UTC(),
+	}
+}
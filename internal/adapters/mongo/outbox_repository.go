@@ -0,0 +1,135 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ColOutbox = "outbox"
+)
+
+type OutboxRepository struct {
+	col *mongo.Collection
+}
+
+func NewOutboxRepository(db *mongo.Database) ports.OutboxRepository {
+	return &OutboxRepository{col: db.Collection(ColOutbox)}
+}
+
+type outboxDocument struct {
+	ID            primitive.ObjectID `bson:"_id"`
+	Type          string             `bson:"type"`
+	TenantID      string             `bson:"tenant_id,omitempty"`
+	UserID        string             `bson:"user_id"`
+	OccurredAt    time.Time          `bson:"occurred_at"`
+	Data          string             `bson:"data,omitempty"`
+	Attempts      int                `bson:"attempts"`
+	LastError     string             `bson:"last_error,omitempty"`
+	NextAttemptAt time.Time          `bson:"next_attempt_at"`
+	DeliveredAt   *time.Time         `bson:"delivered_at,omitempty"`
+}
+
+// insertOutbox stores the events as due immediately. ctx should carry the
+// session of the transaction making the changes they describe.
+func insertOutbox(ctx context.Context, col *mongo.Collection, events ...domain.Event) error {
+	if len(events) == 0 {
+		return nil
+	}
+
+	docs := make([]interface{}, len(events))
+	for i, e := range events {
+		docs[i] = outboxDocument{
+			ID:            primitive.NewObjectID(),
+			Type:          string(e.Type),
+			TenantID:      e.TenantID,
+			UserID:        e.UserID,
+			OccurredAt:    e.OccurredAt,
+			Data:          string(e.Data),
+			NextAttemptAt: e.OccurredAt,
+		}
+	}
+	_, err := col.InsertMany(ctx, docs)
+	return err
+}
+
+// Claim picks the oldest due message and pushes its next attempt past the
+// lease in one atomic update, so concurrent relays never share a message.
+func (r *OutboxRepository) Claim(ctx context.Context, lease time.Duration) (*domain.OutboxMessage, error) {
+	now := time.Now()
+
+	var doc outboxDocument
+	err := r.col.FindOneAndUpdate(
+		ctx,
+		bson.M{"delivered_at": nil, "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{
+			"$set": bson.M{"next_attempt_at": now.Add(lease)},
+			"$inc": bson.M{"attempts": 1},
+		},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg := &domain.OutboxMessage{
+		Event: domain.Event{
+			ID:         doc.ID.Hex(),
+			Type:       domain.EventType(doc.Type),
+			TenantID:   doc.TenantID,
+			UserID:     doc.UserID,
+			OccurredAt: doc.OccurredAt,
+		},
+		Attempts:      doc.Attempts,
+		LastError:     doc.LastError,
+		NextAttemptAt: doc.NextAttemptAt,
+	}
+	if doc.Data != "" {
+		msg.Data = []byte(doc.Data)
+	}
+	return msg, nil
+}
+
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNotFound
+	}
+
+	_, err = r.col.UpdateOne(
+		ctx,
+		bson.M{"_id": oid},
+		bson.M{
+			"$set":   bson.M{"delivered_at": time.Now()},
+			"$unset": bson.M{"last_error": ""},
+		},
+	)
+	return err
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNotFound
+	}
+
+	_, err = r.col.UpdateOne(
+		ctx,
+		bson.M{"_id": oid, "delivered_at": nil},
+		bson.M{"$set": bson.M{"last_error": reason, "next_attempt_at": retryAt}},
+	)
+	return err
+}
@@ -7,12 +7,74 @@ import (
 	"go.mongodb.org/mongo-driver/bson/primitive"
 )
 
+// userSchemaVersion is the shape of the user documents Create writes.
+// Documents of older shapes are upgraded when read, see upgrade. Update
+// only sets fields every shape shares and keeps a document's version.
+//
+//  0. Documents written before schema_version existed. The oldest of
+//     them have their creation time in createdAt.
+//  1. Creation time in created_at.
+//  2. Owned by the tenant in tenant_id.
+const userSchemaVersion = 2
+
 type userDocument struct {
-	ID        primitive.ObjectID `bson:"_id,omitempty"`
-	Name      string             `bson:"name"`
-	Email     string             `bson:"email"`
-	Password  string             `bson:"password"`
-	CreatedAt time.Time          `bson:"created_at"`
+	ID            primitive.ObjectID `bson:"_id,omitempty"`
+	SchemaVersion int                `bson:"schema_version"`
+	TenantID      string             `bson:"tenant_id"`
+
+	Name      string     `bson:"name"`
+	Email     string     `bson:"email"`
+	Password  string     `bson:"password"`
+	CreatedAt time.Time  `bson:"created_at"`
+	UpdatedAt time.Time  `bson:"updated_at"`
+	DeletedAt *time.Time `bson:"deleted_at,omitempty"`
+	Version   int64      `bson:"version"`
+
+	PendingEmail *emailChangeDocument `bson:"pending_email,omitempty"`
+	Invitation   *invitationDocument  `bson:"invitation,omitempty"`
+
+	Status          string     `bson:"status,omitempty"`
+	StatusReason    string     `bson:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `bson:"status_changed_at,omitempty"`
+
+	DisplayName string                 `bson:"display_name,omitempty"`
+	Locale      string                 `bson:"locale,omitempty"`
+	Timezone    string                 `bson:"timezone,omitempty"`
+	Phone       string                 `bson:"phone,omitempty"`
+	AvatarURL   string                 `bson:"avatar_url,omitempty"`
+	Attributes  map[string]interface{} `bson:"attributes,omitempty"`
+
+	// LegacyCreatedAt is only read, from schema version 0.
+	LegacyCreatedAt *time.Time `bson:"createdAt,omitempty"`
+}
+
+// upgrade brings d to userSchemaVersion, one version at a time.
+func (d *userDocument) upgrade() {
+	if d.SchemaVersion < 1 {
+		if d.CreatedAt.IsZero() && d.LegacyCreatedAt != nil {
+			d.CreatedAt = *d.LegacyCreatedAt
+		}
+		d.LegacyCreatedAt = nil
+		d.SchemaVersion = 1
+	}
+	if d.SchemaVersion < 2 {
+		// Users stored before tenants existed belong to the default one.
+		if d.TenantID == "" {
+			d.TenantID = domain.DefaultTenant
+		}
+		d.SchemaVersion = 2
+	}
+}
+
+type emailChangeDocument struct {
+	Email     string    `bson:"email"`
+	TokenHash string    `bson:"token_hash"`
+	ExpiresAt time.Time `bson:"expires_at"`
+}
+
+type invitationDocument struct {
+	TokenHash string    `bson:"token_hash"`
+	ExpiresAt time.Time `bson:"expires_at"`
 }
 
 func toDocument(u *domain.User) (*userDocument, error) {
@@ -22,20 +84,91 @@ func toDocument(u *domain.User) (*userDocument, error) {
 	}
 
 	return &userDocument{
-		ID:        oid,
-		Name:      u.Name,
-		Email:     u.Email,
-		Password:  u.Password,
-		CreatedAt: u.CreatedAt,
+		ID:              oid,
+		SchemaVersion:   userSchemaVersion,
+		TenantID:        u.TenantID,
+		Name:            u.Name,
+		Email:           u.Email,
+		Password:        u.Password,
+		CreatedAt:       u.CreatedAt,
+		UpdatedAt:       u.UpdatedAt,
+		DeletedAt:       u.DeletedAt,
+		Version:         u.Version,
+		PendingEmail:    toEmailChangeDocument(u.PendingEmail),
+		Invitation:      toInvitationDocument(u.Invitation),
+		Status:          string(u.Status),
+		StatusReason:    u.StatusReason,
+		StatusChangedAt: u.StatusChangedAt,
+		DisplayName:     u.DisplayName,
+		Locale:          u.Locale,
+		Timezone:        u.Timezone,
+		Phone:           u.Phone,
+		AvatarURL:       u.AvatarURL,
+		Attributes:      u.Attributes,
 	}, nil
 }
 
+// toDomain upgrades d and maps it to a user.
 func toDomain(d *userDocument) *domain.User {
-	return &domain.User{
+	d.upgrade()
+
+	u := &domain.User{
 		ID:        d.ID.Hex(),
+		TenantID:  d.TenantID,
 		Name:      d.Name,
 		Email:     d.Email,
 		Password:  d.Password,
 		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+		DeletedAt: d.DeletedAt,
+		Version:   d.Version,
+
+		Status:          domain.UserStatus(d.Status),
+		StatusReason:    d.StatusReason,
+		StatusChangedAt: d.StatusChangedAt,
+
+		Profile: domain.Profile{
+			DisplayName: d.DisplayName,
+			Locale:      d.Locale,
+			Timezone:    d.Timezone,
+			Phone:       d.Phone,
+			AvatarURL:   d.AvatarURL,
+			Attributes:  d.Attributes,
+		},
+	}
+	if d.PendingEmail != nil {
+		u.PendingEmail = &domain.EmailChange{
+			Email:     d.PendingEmail.Email,
+			TokenHash: d.PendingEmail.TokenHash,
+			ExpiresAt: d.PendingEmail.ExpiresAt,
+		}
+	}
+	if d.Invitation != nil {
+		u.Invitation = &domain.Invitation{
+			TokenHash: d.Invitation.TokenHash,
+			ExpiresAt: d.Invitation.ExpiresAt,
+		}
+	}
+	return u
+}
+
+func toEmailChangeDocument(c *domain.EmailChange) *emailChangeDocument {
+	if c == nil {
+		return nil
+	}
+	return &emailChangeDocument{
+		Email:     c.Email,
+		TokenHash: c.TokenHash,
+		ExpiresAt: c.ExpiresAt,
+	}
+}
+
+func toInvitationDocument(i *domain.Invitation) *invitationDocument {
+	if i == nil {
+		return nil
+	}
+	return &invitationDocument{
+		TokenHash: i.TokenHash,
+		ExpiresAt: i.ExpiresAt,
 	}
 }
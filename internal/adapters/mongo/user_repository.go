@@ -2,10 +2,17 @@ package mongo
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"reflect"
+	"regexp"
+	"time"
 
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsoncodec"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo"
 	"go.mongodb.org/mongo-driver/mongo/options"
@@ -15,88 +22,670 @@ const (
 	ColUser = "users"
 )
 
+// UserRepository records an event in the outbox collection for every
+// change, in the same transaction. This needs a replica set. Users are
+// stored with their tenant_id and every query but Purge matches on it.
 type UserRepository struct {
-	col *mongo.Collection
+	col    *mongo.Collection
+	outbox *mongo.Collection
 }
 
 func NewUserRepository(db *mongo.Database) ports.UserRepository {
-	return &UserRepository{col: db.Collection(ColUser)}
+	return &UserRepository{
+		col:    db.Collection(ColUser, options.Collection().SetRegistry(registry)),
+		outbox: db.Collection(ColOutbox),
+	}
 }
 
+// registry decodes nested documents and arrays in free-form fields such as
+// attributes into plain maps and slices instead of bson.D.
+var registry = func() *bsoncodec.Registry {
+	r := bson.NewRegistry()
+	r.RegisterTypeMapEntry(bsontype.EmbeddedDocument, reflect.TypeOf(map[string]interface{}{}))
+	r.RegisterTypeMapEntry(bsontype.Array, reflect.TypeOf([]interface{}{}))
+	return r
+}()
+
 func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
+	tenant := domain.TenantID(ctx)
 	doc, err := toDocument(u)
 	if err != nil {
 		return err
 	}
+	doc.TenantID = tenant
+	doc.Version = 1
+
+	created := *u
+	created.ID = doc.ID.Hex()
+	created.TenantID = tenant
+	created.Version = doc.Version
+	event, err := domain.NewUserEvent(domain.EventUserCreated, tenant, created.ID, &created)
+	if err != nil {
+		return err
+	}
 
-	_, err = r.col.InsertOne(ctx, doc)
+	err = r.withOutbox(ctx, func(ctx context.Context) (*domain.Event, error) {
+		_, err := r.col.InsertOne(ctx, doc)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, domain.ErrEmailTaken
+		}
+		return &event, err
+	})
 	if err != nil {
 		return err
 	}
 
-	u.ID = doc.ID.Hex()
+	u.ID = created.ID
+	u.TenantID = created.TenantID
+	u.Version = created.Version
+
+	return nil
+}
+
+// CreateMany inserts the users in one transaction, with their events.
+func (r *UserRepository) CreateMany(ctx context.Context, users []*domain.User) error {
+	tenant := domain.TenantID(ctx)
+	docs := make([]interface{}, len(users))
+	events := make([]domain.Event, len(users))
+	created := make([]domain.User, len(users))
+	for i, u := range users {
+		doc, err := toDocument(u)
+		if err != nil {
+			return err
+		}
+		doc.TenantID = tenant
+		doc.Version = 1
+		docs[i] = doc
+
+		created[i] = *u
+		created[i].ID = doc.ID.Hex()
+		created[i].TenantID = tenant
+		created[i].Version = doc.Version
+		events[i], err = domain.NewUserEvent(domain.EventUserCreated, tenant, created[i].ID, &created[i])
+		if err != nil {
+			return err
+		}
+	}
+
+	err := r.withOutboxEvents(ctx, func(ctx context.Context) ([]domain.Event, error) {
+		_, err := r.col.InsertMany(ctx, docs)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, domain.ErrEmailTaken
+		}
+		return events, err
+	})
+	if err != nil {
+		return err
+	}
 
+	for i, u := range users {
+		u.ID = created[i].ID
+		u.TenantID = tenant
+		u.Version = created[i].Version
+	}
 	return nil
 }
 
 func (r *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
-	oid, _ := primitive.ObjectIDFromHex(id)
-	var u domain.User
-	err := r.col.FindOne(ctx, bson.M{"_id": oid}).Decode(&u)
-	return &u, err
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrInvalidID
+	}
+	return r.findOne(ctx, bson.M{"_id": oid})
+}
+
+func (r *UserRepository) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrInvalidID
+	}
+
+	var doc userDocument
+	err = r.col.FindOne(ctx, bson.M{"_id": oid, "tenant_id": domain.TenantID(ctx)}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomain(&doc), nil
 }
 
 func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
-	var u domain.User
-	err := r.col.FindOne(ctx, bson.M{"email": email}).Decode(&u)
-	return &u, err
+	return r.findOne(ctx, bson.M{"email": email})
 }
 
-func (r *UserRepository) FindAll(
-	ctx context.Context,
-	// Pagination is not used for now.
-	// The number of users is expected to be small.
-	// We rely on an index on `createdAt` to keep this query fast.
-	// Add pagination if the user list becomes large.
-) ([]*domain.User, error) {
+func (r *UserRepository) FindByEmailChangeToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	return r.findOne(ctx, bson.M{"pending_email.token_hash": tokenHash})
+}
 
-	filter := bson.M{}
+func (r *UserRepository) FindByInvitationToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	return r.findOne(ctx, bson.M{"invitation.token_hash": tokenHash})
+}
+
+// findOne returns the tenant's live user matching filter, or
+// domain.ErrNotFound.
+func (r *UserRepository) findOne(ctx context.Context, filter bson.M) (*domain.User, error) {
+	filter["tenant_id"] = domain.TenantID(ctx)
+	filter["deleted_at"] = nil
+
+	var doc userDocument
+	err := r.col.FindOne(ctx, filter).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return toDomain(&doc), nil
+}
+
+func (r *UserRepository) FindAll(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error) {
+	filter := userFilter(ctx, q.Filter)
+
+	dir := -1
+	if q.Order == domain.SortAsc {
+		dir = 1
+	}
 
+	find := filter
+	if q.Cursor != "" {
+		c, err := domain.DecodeCursor(q.Cursor, q)
+		if err != nil {
+			return nil, err
+		}
+		after, err := afterCursor(c, dir)
+		if err != nil {
+			return nil, err
+		}
+		find = bson.M{"$and": bson.A{filter, after}}
+	}
+
+	// One extra document tells us whether there is a next page.
 	opts := options.Find().
-		SetSort(bson.D{{Key: "createdAt", Value: -1}}).
+		SetSort(bson.D{
+			{Key: string(q.SortBy), Value: dir},
+			{Key: "_id", Value: dir},
+		}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit) + 1).
 		SetProjection(bson.M{
 			"password": 0,
 		})
 
-	cur, err := r.col.Find(ctx, filter, opts)
+	cur, err := r.col.Find(ctx, find, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := decodeUsers(ctx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := r.col.CountDocuments(ctx, filter)
 	if err != nil {
 		return nil, err
 	}
+
+	page := &domain.UserPage{Users: users, Total: total}
+	if len(users) > q.Limit {
+		page.Users = users[:q.Limit]
+		page.NextCursor = domain.NewCursor(q, page.Users[q.Limit-1]).Encode()
+	}
+
+	return page, nil
+}
+
+// Each reads users through one cursor, so it holds no more than a batch
+// in memory.
+func (r *UserRepository) Each(ctx context.Context, fn func(*domain.User) error) error {
+	cur, err := r.col.Find(ctx, bson.M{"tenant_id": domain.TenantID(ctx), "deleted_at": nil}, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"password": 0}))
+	if err != nil {
+		return err
+	}
 	defer cur.Close(ctx)
 
-	var users []*domain.User
 	for cur.Next(ctx) {
-		var u domain.User
-		if err := cur.Decode(&u); err != nil {
-			return nil, err
+		var doc userDocument
+		if err := cur.Decode(&doc); err != nil {
+			return err
 		}
-		users = append(users, &u)
+		if err := fn(toDomain(&doc)); err != nil {
+			return err
+		}
+	}
+	return cur.Err()
+}
+
+// Search ranks matches from the text index by relevance. The text index only
+// matches whole words, so when it finds nothing we fall back to a
+// case-insensitive prefix match on name or email.
+func (r *UserRepository) Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error) {
+	score := bson.M{"$meta": "textScore"}
+	text := options.Find().
+		SetSort(bson.D{{Key: "score", Value: score}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"score": score, "password": 0})
+
+	filter := bson.M{
+		"$text":      bson.M{"$search": q.Text},
+		"tenant_id":  domain.TenantID(ctx),
+		"deleted_at": nil,
 	}
-	return users, nil
+	if len(q.UserIDs) > 0 {
+		filter["_id"] = idIn(q.UserIDs)
+	}
+	page, err := r.searchPage(ctx, filter, text, q)
+	if err != nil || page.Total > 0 {
+		return page, err
+	}
+
+	prefix := options.Find().
+		SetSort(bson.D{{Key: "name", Value: 1}, {Key: "_id", Value: 1}}).
+		SetProjection(bson.M{"password": 0})
+
+	delete(filter, "$text")
+	filter["$or"] = bson.A{
+		bson.M{"name": prefixRegex(q.Text)},
+		bson.M{"email": prefixRegex(q.Text)},
+	}
+	return r.searchPage(ctx, filter, prefix, q)
 }
 
+func (r *UserRepository) searchPage(
+	ctx context.Context,
+	filter bson.M,
+	opts *options.FindOptions,
+	q domain.UserSearch,
+) (*domain.UserPage, error) {
+	opts.SetSkip(int64(q.Offset)).SetLimit(int64(q.Limit))
+
+	cur, err := r.col.Find(ctx, filter, opts)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := decodeUsers(ctx, cur)
+	if err != nil {
+		return nil, err
+	}
+
+	total, err := r.col.CountDocuments(ctx, filter)
+	if err != nil {
+		return nil, err
+	}
+
+	return &domain.UserPage{Users: users, Total: total}, nil
+}
+
+// Update only applies when the stored version still equals u.Version,
+// and bumps it on success. It fails with domain.ErrNotFound when the user
+// does not exist or is deleted.
 func (r *UserRepository) Update(ctx context.Context, u *domain.User) error {
-	oid, _ := primitive.ObjectIDFromHex(u.ID)
-	_, err := r.col.UpdateOne(ctx, bson.M{"_id": oid}, bson.M{"$set": bson.M{"name": u.Name, "email": u.Email}})
-	return err
+	return r.update(ctx, u, false)
+}
+
+func (r *UserRepository) UpdateWithPassword(ctx context.Context, u *domain.User) error {
+	return r.update(ctx, u, true)
+}
+
+func (r *UserRepository) update(ctx context.Context, u *domain.User, withPassword bool) error {
+	oid, err := primitive.ObjectIDFromHex(u.ID)
+	if err != nil {
+		return domain.ErrInvalidID
+	}
+
+	var version interface{} = u.Version
+	if u.Version == 0 {
+		// Documents written before versioning have no version field.
+		version = bson.M{"$in": bson.A{0, nil}}
+	}
+
+	set := bson.M{
+		"name":       u.Name,
+		"email":      u.Email,
+		"updated_at": u.UpdatedAt,
+	}
+	if withPassword {
+		set["password"] = u.Password
+	}
+	unset := bson.M{}
+
+	optional := map[string]interface{}{
+		"pending_email":     toEmailChangeDocument(u.PendingEmail),
+		"invitation":        toInvitationDocument(u.Invitation),
+		"status":            string(u.Status),
+		"status_reason":     u.StatusReason,
+		"status_changed_at": u.StatusChangedAt,
+		"display_name":      u.DisplayName,
+		"locale":            u.Locale,
+		"timezone":          u.Timezone,
+		"phone":             u.Phone,
+		"avatar_url":        u.AvatarURL,
+		"attributes":        u.Attributes,
+	}
+	for field, v := range optional {
+		if isEmpty(v) {
+			unset[field] = ""
+		} else {
+			set[field] = v
+		}
+	}
+
+	update := bson.M{
+		"$set": set,
+		"$inc": bson.M{"version": 1},
+	}
+	if len(unset) > 0 {
+		update["$unset"] = unset
+	}
+
+	tenant := domain.TenantID(ctx)
+	updated := *u
+	updated.TenantID = tenant
+	updated.Version++
+	event, err := domain.NewUserEvent(domain.EventUserUpdated, tenant, u.ID, &updated)
+	if err != nil {
+		return err
+	}
+
+	err = r.withOutbox(ctx, func(ctx context.Context) (*domain.Event, error) {
+		res, err := r.col.UpdateOne(
+			ctx,
+			bson.M{"_id": oid, "tenant_id": tenant, "deleted_at": nil, "version": version},
+			update,
+		)
+		if mongo.IsDuplicateKeyError(err) {
+			return nil, domain.ErrEmailTaken
+		}
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount == 0 {
+			return nil, r.missingOrConflict(ctx, tenant, oid)
+		}
+		return &event, nil
+	})
+	if err != nil {
+		return err
+	}
+
+	u.TenantID = tenant
+	u.Version++
+
+	return nil
 }
 
+// Delete is a soft delete: the user is hidden from every finder until
+// restored or purged. The email stays reserved in the meantime.
 func (r *UserRepository) Delete(ctx context.Context, id string) error {
-	oid, _ := primitive.ObjectIDFromHex(id)
-	_, err := r.col.DeleteOne(ctx, bson.M{"_id": oid})
-	return err
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrInvalidID
+	}
+
+	tenant := domain.TenantID(ctx)
+	event, err := domain.NewUserEvent(domain.EventUserDeleted, tenant, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.withOutbox(ctx, func(ctx context.Context) (*domain.Event, error) {
+		res, err := r.col.UpdateOne(
+			ctx,
+			bson.M{"_id": oid, "tenant_id": tenant, "deleted_at": nil},
+			bson.M{"$set": bson.M{"deleted_at": time.Now()}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount == 0 {
+			return nil, domain.ErrNotFound
+		}
+		return &event, nil
+	})
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrInvalidID
+	}
+
+	tenant := domain.TenantID(ctx)
+	event, err := domain.NewUserEvent(domain.EventUserRestored, tenant, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.withOutbox(ctx, func(ctx context.Context) (*domain.Event, error) {
+		res, err := r.col.UpdateOne(
+			ctx,
+			bson.M{"_id": oid, "tenant_id": tenant, "deleted_at": bson.M{"$ne": nil}},
+			bson.M{"$unset": bson.M{"deleted_at": ""}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		if res.MatchedCount == 0 {
+			return nil, domain.ErrNotFound
+		}
+		return &event, nil
+	})
+}
+
+// Purge permanently removes users soft-deleted before the given time, in
+// every tenant. Users are removed one by one with the filter checked
+// again, so one restored in the meantime is kept and not reported.
+func (r *UserRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+	filter := bson.M{"deleted_at": bson.M{"$lt": deletedBefore}}
+	cur, err := r.col.Find(ctx, filter, options.Find().SetProjection(bson.M{"_id": 1, "tenant_id": 1}))
+	if err != nil {
+		return nil, err
+	}
+	var found []struct {
+		ID       primitive.ObjectID `bson:"_id"`
+		TenantID string             `bson:"tenant_id"`
+	}
+	if err := cur.All(ctx, &found); err != nil {
+		return nil, err
+	}
+
+	purged := []domain.PurgedUser{}
+	for _, u := range found {
+		res, err := r.col.DeleteOne(ctx, bson.M{"_id": u.ID, "deleted_at": filter["deleted_at"]})
+		if err != nil {
+			return purged, err
+		}
+		if res.DeletedCount > 0 {
+			purged = append(purged, domain.PurgedUser{ID: u.ID.Hex(), TenantID: u.TenantID})
+		}
+	}
+	return purged, nil
+}
+
+func (r *UserRepository) Erase(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrInvalidID
+	}
+
+	tenant := domain.TenantID(ctx)
+	event, err := domain.NewUserEvent(domain.EventUserErased, tenant, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return r.withOutbox(ctx, func(ctx context.Context) (*domain.Event, error) {
+		res, err := r.col.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": tenant})
+		if err != nil {
+			return nil, err
+		}
+		if res.DeletedCount == 0 {
+			return nil, domain.ErrNotFound
+		}
+		_, err = r.outbox.UpdateMany(
+			ctx,
+			bson.M{"user_id": id, "data": bson.M{"$exists": true}},
+			bson.M{"$unset": bson.M{"data": ""}},
+		)
+		if err != nil {
+			return nil, err
+		}
+		return &event, nil
+	})
 }
 
 func (r *UserRepository) Count(ctx context.Context) (int64, error) {
-	return r.col.CountDocuments(ctx, bson.M{})
+	return r.col.CountDocuments(ctx, bson.M{"tenant_id": domain.TenantID(ctx), "deleted_at": nil})
+}
+
+// withOutbox runs write in a transaction together with storing the event
+// it returns in the outbox, so an event exists exactly when the change was
+// committed. A nil event means write changed nothing. write may be retried.
+func (r *UserRepository) withOutbox(ctx context.Context, write func(ctx context.Context) (*domain.Event, error)) error {
+	return r.withOutboxEvents(ctx, func(ctx context.Context) ([]domain.Event, error) {
+		event, err := write(ctx)
+		if err != nil || event == nil {
+			return nil, err
+		}
+		return []domain.Event{*event}, nil
+	})
+}
+
+// withOutboxEvents is withOutbox for writes that make several changes.
+func (r *UserRepository) withOutboxEvents(ctx context.Context, write func(ctx context.Context) ([]domain.Event, error)) error {
+	sess, err := r.col.Database().Client().StartSession()
+	if err != nil {
+		return err
+	}
+	defer sess.EndSession(ctx)
+
+	_, err = sess.WithTransaction(ctx, func(sc mongo.SessionContext) (interface{}, error) {
+		events, err := write(sc)
+		if err != nil {
+			return nil, err
+		}
+		return nil, insertOutbox(sc, r.outbox, events...)
+	})
+	return err
+}
+
+// missingOrConflict tells why an update by ID and version matched nothing.
+func (r *UserRepository) missingOrConflict(ctx context.Context, tenant string, oid primitive.ObjectID) error {
+	n, err := r.col.CountDocuments(ctx, bson.M{"_id": oid, "tenant_id": tenant, "deleted_at": nil})
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return domain.ErrVersionConflict
+}
+
+func isEmpty(v interface{}) bool {
+	switch v := v.(type) {
+	case string:
+		return v == ""
+	case map[string]interface{}:
+		return len(v) == 0
+	case *emailChangeDocument:
+		return v == nil
+	case *invitationDocument:
+		return v == nil
+	case *time.Time:
+		return v == nil
+	}
+	return v == nil
+}
+
+func decodeUsers(ctx context.Context, cur *mongo.Cursor) ([]*domain.User, error) {
+	defer cur.Close(ctx)
+
+	users := []*domain.User{}
+	for cur.Next(ctx) {
+		var doc userDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		users = append(users, toDomain(&doc))
+	}
+	return users, cur.Err()
+}
+
+// idIn matches the users with the given IDs. IDs that are not ObjectIDs
+// match no user.
+func idIn(ids []string) bson.M {
+	oids := bson.A{}
+	for _, id := range ids {
+		if oid, err := primitive.ObjectIDFromHex(id); err == nil {
+			oids = append(oids, oid)
+		}
+	}
+	return bson.M{"$in": oids}
+}
+
+func userFilter(ctx context.Context, f domain.UserFilter) bson.M {
+	filter := bson.M{"tenant_id": domain.TenantID(ctx), "deleted_at": nil}
+
+	switch f.Status {
+	case "":
+	case domain.StatusActive:
+		// Users stored before statuses existed are active.
+		filter["status"] = bson.M{"$in": bson.A{domain.StatusActive, nil}}
+	default:
+		filter["status"] = f.Status
+	}
+	if f.NamePrefix != "" {
+		filter["name"] = prefixRegex(f.NamePrefix)
+	}
+	if f.EmailPrefix != "" {
+		filter["email"] = prefixRegex(f.EmailPrefix)
+	}
+
+	created := bson.M{}
+	if !f.CreatedAfter.IsZero() {
+		created["$gte"] = f.CreatedAfter
+	}
+	if !f.CreatedUntil.IsZero() {
+		created["$lt"] = f.CreatedUntil
+	}
+	if len(created) > 0 {
+		filter["created_at"] = created
+	}
+	if len(f.UserIDs) > 0 {
+		filter["_id"] = idIn(f.UserIDs)
+	}
+
+	return filter
+}
+
+func prefixRegex(prefix string) primitive.Regex {
+	return primitive.Regex{Pattern: "^" + regexp.QuoteMeta(prefix), Options: "i"}
+}
+
+// afterCursor matches documents strictly after the cursor in (sort field, _id) order.
+func afterCursor(c domain.Cursor, dir int) (bson.M, error) {
+	oid, err := primitive.ObjectIDFromHex(c.ID)
+	if err != nil {
+		return nil, fmt.Errorf("%w: malformed cursor", domain.ErrInvalidQuery)
+	}
+
+	var value interface{} = c.Value
+	if c.SortBy == domain.SortByCreatedAt {
+		value = c.CreatedAt()
+	}
+
+	op := "$lt"
+	if dir > 0 {
+		op = "$gt"
+	}
+
+	field := string(c.SortBy)
+	return bson.M{"$or": bson.A{
+		bson.M{field: bson.M{op: value}},
+		bson.M{field: value, "_id": bson.M{op: oid}},
+	}}, nil
 }
@@ -3,11 +3,13 @@ package mongo_test
 import (
 	"context"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/bsontype"
 	"go.mongodb.org/mongo-driver/bson/primitive"
 	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
 )
@@ -19,7 +21,9 @@ func TestUserRepository_Create(t *testing.T) {
 		repo := mongo.NewUserRepository(mt.DB)
 
 		mt.AddMockResponses(
-			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(), // insert user
+			mtest.CreateSuccessResponse(), // insert outbox event
+			mtest.CreateSuccessResponse(), // commit
 		)
 
 		user := &domain.User{
@@ -31,6 +35,19 @@ func TestUserRepository_Create(t *testing.T) {
 
 		assert.NoError(t, err)
 		assert.NotEmpty(t, user.ID) // ✔ THIS is correct
+
+		insert := mt.GetStartedEvent()
+		for insert != nil && insert.CommandName != "insert" {
+			insert = mt.GetStartedEvent()
+		}
+		if assert.NotNil(t, insert) {
+			doc := insert.Command.Lookup("documents", "0").Document()
+			assert.Equal(t, int32(2), doc.Lookup("schema_version").Int32())
+			assert.Equal(t, domain.DefaultTenant, doc.Lookup("tenant_id").StringValue())
+			assert.Equal(t, bsontype.DateTime, doc.Lookup("created_at").Type)
+			_, err := doc.LookupErr("createdAt")
+			assert.Error(t, err)
+		}
 	})
 }
 
@@ -68,11 +85,32 @@ func TestUserRepository_Delete(t *testing.T) {
 
 	mt.Run("success", func(mt *mtest.T) {
 		repo := mongo.NewUserRepository(mt.DB)
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 1},
+				bson.E{Key: "nModified", Value: 1},
+			),
+			mtest.CreateSuccessResponse(), // insert outbox event
+			mtest.CreateSuccessResponse(), // commit
+		)
 
 		err := repo.Delete(context.Background(), primitive.NewObjectID().Hex())
 		assert.NoError(t, err)
 	})
+
+	mt.Run("missing user", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 0},
+				bson.E{Key: "nModified", Value: 0},
+			),
+			mtest.CreateSuccessResponse(), // abort
+		)
+
+		err := repo.Delete(context.Background(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
 }
 
 func TestUserRepository_Update(t *testing.T) {
@@ -81,16 +119,70 @@ func TestUserRepository_Update(t *testing.T) {
 	mt.Run("success", func(mt *mtest.T) {
 		repo := mongo.NewUserRepository(mt.DB)
 
-		mt.AddMockResponses(mtest.CreateSuccessResponse())
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 1},
+				bson.E{Key: "nModified", Value: 1},
+			),
+			mtest.CreateSuccessResponse(), // insert outbox event
+			mtest.CreateSuccessResponse(), // commit
+		)
 
 		user := &domain.User{
-			ID:    primitive.NewObjectID().Hex(),
-			Name:  "Updated",
-			Email: "updated@test.com",
+			ID:      primitive.NewObjectID().Hex(),
+			Name:    "Updated",
+			Email:   "updated@test.com",
+			Version: 2,
 		}
 
 		err := repo.Update(context.Background(), user)
 		assert.NoError(t, err)
+		assert.Equal(t, int64(3), user.Version)
+	})
+
+	mt.Run("version conflict", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 0},
+				bson.E{Key: "nModified", Value: 0},
+			),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch, bson.D{{Key: "n", Value: int64(1)}}),
+			mtest.CreateSuccessResponse(), // abort
+		)
+
+		user := &domain.User{
+			ID:      primitive.NewObjectID().Hex(),
+			Version: 2,
+		}
+
+		err := repo.Update(context.Background(), user)
+		assert.ErrorIs(t, err, domain.ErrVersionConflict)
+		assert.Equal(t, int64(2), user.Version)
+	})
+
+	mt.Run("missing user", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 0},
+				bson.E{Key: "nModified", Value: 0},
+			),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateSuccessResponse(), // abort
+		)
+
+		user := &domain.User{
+			ID:      primitive.NewObjectID().Hex(),
+			Version: 2,
+		}
+
+		err := repo.Update(context.Background(), user)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
 	})
 }
 
@@ -100,18 +192,90 @@ func TestUserRepository_FindAll(t *testing.T) {
 	mt.Run("multiple users", func(mt *mtest.T) {
 		repo := mongo.NewUserRepository(mt.DB)
 		namespace := mt.DB.Name() + "." + mongo.ColUser
-		mt.AddMockResponses(mtest.CreateCursorResponse(
-			1,
-			namespace,
-			mtest.FirstBatch,
-			bson.D{{Key: "name", Value: "User1"}},
-			bson.D{{Key: "name", Value: "User2"}},
-		))
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "name", Value: "User1"}},
+				bson.D{{Key: "name", Value: "User2"}},
+			),
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "n", Value: int64(2)}},
+			),
+		)
+
+		page, err := repo.FindAll(context.Background(), domain.UserQuery{
+			SortBy: domain.SortByCreatedAt,
+			Order:  domain.SortDesc,
+			Limit:  20,
+		})
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 2)
+		assert.Equal(t, int64(2), page.Total)
+		assert.Empty(t, page.NextCursor)
+	})
+
+	mt.Run("next cursor when more results", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		last := primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "name", Value: "Ann"}},
+				bson.D{{Key: "_id", Value: last}, {Key: "name", Value: "Bob"}},
+				bson.D{{Key: "_id", Value: primitive.NewObjectID()}, {Key: "name", Value: "Cid"}},
+			),
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "n", Value: int64(7)}},
+			),
+		)
 
-		users, err := repo.FindAll(context.Background())
+		q := domain.UserQuery{
+			SortBy: domain.SortByName,
+			Order:  domain.SortAsc,
+			Limit:  2,
+		}
+		page, err := repo.FindAll(context.Background(), q)
 
 		assert.NoError(t, err)
-		assert.Len(t, users, 2)
+		assert.Len(t, page.Users, 2)
+		assert.Equal(t, int64(7), page.Total)
+
+		c, err := domain.DecodeCursor(page.NextCursor, q)
+		assert.NoError(t, err)
+		assert.Equal(t, last.Hex(), c.ID)
+		assert.Equal(t, "Bob", c.Value)
+	})
+
+	mt.Run("rejects cursor for another sort", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+
+		cursor := domain.Cursor{
+			SortBy: domain.SortByName,
+			Order:  domain.SortAsc,
+			Value:  "Bob",
+			ID:     primitive.NewObjectID().Hex(),
+		}.Encode()
+
+		_, err := repo.FindAll(context.Background(), domain.UserQuery{
+			SortBy: domain.SortByCreatedAt,
+			Order:  domain.SortDesc,
+			Limit:  2,
+			Cursor: cursor,
+		})
+
+		assert.ErrorIs(t, err, domain.ErrInvalidQuery)
 	})
 }
 
@@ -163,4 +327,223 @@ func TestUserRepository_FindByID(t *testing.T) {
 		assert.NoError(t, err)
 		assert.Equal(t, "john@test.com", user.Email)
 	})
+
+	mt.Run("legacy document", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		oid := primitive.NewObjectID()
+		createdAt := time.Date(2023, 5, 1, 12, 0, 0, 0, time.UTC)
+		mt.AddMockResponses(mtest.CreateCursorResponse(
+			1,
+			namespace,
+			mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: oid},
+				{Key: "email", Value: "john@test.com"},
+				{Key: "name", Value: "John"},
+				{Key: "createdAt", Value: createdAt},
+			},
+		))
+
+		user, err := repo.FindByID(context.Background(), oid.Hex())
+
+		assert.NoError(t, err)
+		if assert.NotNil(t, user) {
+			assert.Equal(t, createdAt, user.CreatedAt.UTC())
+		}
+	})
+
+	mt.Run("not found", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		mt.AddMockResponses(mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch))
+
+		_, err := repo.FindByID(context.Background(), primitive.NewObjectID().Hex())
+
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	mt.Run("invalid id", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+
+		_, err := repo.FindByID(context.Background(), "not-an-id")
+		assert.ErrorIs(t, err, domain.ErrInvalidID)
+		assert.ErrorIs(t, repo.Update(context.Background(), &domain.User{ID: "not-an-id"}), domain.ErrInvalidID)
+		assert.ErrorIs(t, repo.Delete(context.Background(), "not-an-id"), domain.ErrInvalidID)
+		assert.ErrorIs(t, repo.Restore(context.Background(), "not-an-id"), domain.ErrInvalidID)
+	})
+}
+
+func TestUserRepository_Search(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("text match", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "name", Value: "John Smith"}, {Key: "score", Value: 1.5}},
+			),
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "n", Value: int64(1)}},
+			),
+		)
+
+		page, err := repo.Search(context.Background(), domain.UserSearch{Text: "smith", Limit: 10})
+
+		assert.NoError(t, err)
+		assert.Len(t, page.Users, 1)
+		assert.Equal(t, "John Smith", page.Users[0].Name)
+	})
+
+	mt.Run("falls back to prefix", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch),
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "name", Value: "Johanna"}},
+			),
+			mtest.CreateCursorResponse(
+				0,
+				namespace,
+				mtest.FirstBatch,
+				bson.D{{Key: "n", Value: int64(1)}},
+			),
+		)
+
+		page, err := repo.Search(context.Background(), domain.UserSearch{Text: "joh", Limit: 10})
+
+		assert.NoError(t, err)
+		assert.Equal(t, int64(1), page.Total)
+		assert.Equal(t, "Johanna", page.Users[0].Name)
+	})
+}
+
+func TestUserRepository_Restore(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 1},
+				bson.E{Key: "nModified", Value: 1},
+			),
+			mtest.CreateSuccessResponse(), // insert outbox event
+			mtest.CreateSuccessResponse(), // commit
+		)
+
+		err := repo.Restore(context.Background(), primitive.NewObjectID().Hex())
+		assert.NoError(t, err)
+	})
+
+	mt.Run("not deleted", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(
+				bson.E{Key: "n", Value: 0},
+				bson.E{Key: "nModified", Value: 0},
+			),
+			mtest.CreateSuccessResponse(), // abort
+		)
+
+		err := repo.Restore(context.Background(), primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
+
+func TestUserRepository_Purge(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("reports the users it removed", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		alice, bob := primitive.NewObjectID(), primitive.NewObjectID()
+		mt.AddMockResponses(
+			mtest.CreateCursorResponse(0, namespace, mtest.FirstBatch,
+				bson.D{{Key: "_id", Value: alice}, {Key: "tenant_id", Value: "acme"}},
+				bson.D{{Key: "_id", Value: bob}, {Key: "tenant_id", Value: "globex"}},
+			),
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 1}),
+			// Restored before its turn came.
+			mtest.CreateSuccessResponse(bson.E{Key: "n", Value: 0}),
+		)
+
+		purged, err := repo.Purge(context.Background(), time.Now())
+
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.PurgedUser{{ID: alice.Hex(), TenantID: "acme"}}, purged)
+	})
+}
+
+func TestUserRepository_FindByID_Attributes(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("nested attributes decode as maps", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		namespace := mt.DB.Name() + "." + mongo.ColUser
+		oid := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateCursorResponse(
+			0,
+			namespace,
+			mtest.FirstBatch,
+			bson.D{
+				{Key: "_id", Value: oid},
+				{Key: "locale", Value: "th-TH"},
+				{Key: "attributes", Value: bson.D{
+					{Key: "prefs", Value: bson.D{{Key: "theme", Value: "dark"}}},
+					{Key: "tags", Value: bson.A{"a", "b"}},
+				}},
+			},
+		))
+
+		user, err := repo.FindByID(context.Background(), oid.Hex())
+
+		assert.NoError(t, err)
+		assert.Equal(t, "th-TH", user.Locale)
+		assert.Equal(t, map[string]interface{}{"theme": "dark"}, user.Attributes["prefs"])
+		assert.Equal(t, []interface{}{"a", "b"}, user.Attributes["tags"])
+	})
+}
+
+func TestUserRepository_Create_WritesOutboxInTransaction(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := mongo.NewUserRepository(mt.DB)
+		mt.AddMockResponses(
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+			mtest.CreateSuccessResponse(),
+		)
+
+		err := repo.Create(context.Background(), &domain.User{Name: "John", Email: "john@test.com"})
+		assert.NoError(t, err)
+
+		var commands []string
+		var outbox bson.Raw
+		for _, e := range mt.GetAllStartedEvents() {
+			commands = append(commands, e.CommandName)
+			if e.CommandName == "insert" && e.Command.Lookup("insert").StringValue() == mongo.ColOutbox {
+				outbox = e.Command
+			}
+		}
+		assert.Equal(t, []string{"insert", "insert", "commitTransaction"}, commands)
+		assert.NotNil(t, outbox)
+
+		doc := outbox.Lookup("documents").Array().Index(0).Value().Document()
+		assert.Equal(t, string(domain.EventUserCreated), doc.Lookup("type").StringValue())
+		assert.NotContains(t, doc.Lookup("data").StringValue(), "password")
+	})
 }
@@ -0,0 +1,353 @@
+package mongo
+
+import (
+	"context"
+	"errors"
+	"regexp"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo"
+	"go.mongodb.org/mongo-driver/mongo/options"
+)
+
+const (
+	ColWebhookSubscriptions = "webhook_subscriptions"
+	ColWebhookDeliveries    = "webhook_deliveries"
+)
+
+type WebhookRepository struct {
+	subs       *mongo.Collection
+	deliveries *mongo.Collection
+}
+
+func NewWebhookRepository(db *mongo.Database) ports.WebhookRepository {
+	return &WebhookRepository{
+		subs:       db.Collection(ColWebhookSubscriptions),
+		deliveries: db.Collection(ColWebhookDeliveries),
+	}
+}
+
+type subscriptionDocument struct {
+	ID         primitive.ObjectID `bson:"_id"`
+	TenantID   string             `bson:"tenant_id"`
+	URL        string             `bson:"url"`
+	EventTypes []string           `bson:"events,omitempty"`
+	Secret     string             `bson:"secret"`
+	Active     bool               `bson:"active"`
+	CreatedAt  time.Time          `bson:"created_at"`
+	UpdatedAt  time.Time          `bson:"updated_at"`
+}
+
+type deliveryDocument struct {
+	ID             primitive.ObjectID `bson:"_id"`
+	TenantID       string             `bson:"tenant_id"`
+	SubscriptionID string             `bson:"subscription_id"`
+	EventID        string             `bson:"event_id"`
+	EventType      string             `bson:"event_type"`
+	Payload        string             `bson:"payload"`
+	Status         string             `bson:"status"`
+	Tries          int                `bson:"tries"`
+	Attempts       []attemptDocument  `bson:"attempts"`
+	NextAttemptAt  time.Time          `bson:"next_attempt_at"`
+	CreatedAt      time.Time          `bson:"created_at"`
+	DeliveredAt    *time.Time         `bson:"delivered_at,omitempty"`
+}
+
+type attemptDocument struct {
+	At         time.Time `bson:"at"`
+	StatusCode int       `bson:"status_code,omitempty"`
+	Error      string    `bson:"error,omitempty"`
+	DurationMS int64     `bson:"duration_ms"`
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, s *domain.WebhookSubscription) error {
+	doc := subscriptionDocument{
+		ID:        primitive.NewObjectID(),
+		TenantID:  domain.TenantID(ctx),
+		URL:       s.URL,
+		Secret:    s.Secret,
+		Active:    s.Active,
+		CreatedAt: s.CreatedAt,
+		UpdatedAt: s.UpdatedAt,
+	}
+	for _, t := range s.EventTypes {
+		doc.EventTypes = append(doc.EventTypes, string(t))
+	}
+
+	if _, err := r.subs.InsertOne(ctx, doc); err != nil {
+		return err
+	}
+
+	s.ID = doc.ID.Hex()
+	s.TenantID = doc.TenantID
+	return nil
+}
+
+func (r *WebhookRepository) FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	var doc subscriptionDocument
+	err = r.subs.FindOne(ctx, bson.M{"_id": oid, "tenant_id": domain.TenantID(ctx)}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain(), nil
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	cur, err := r.subs.Find(ctx, bson.M{"tenant_id": domain.TenantID(ctx)}, options.Find().SetSort(bson.D{{Key: "_id", Value: 1}}))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	subs := []*domain.WebhookSubscription{}
+	for cur.Next(ctx) {
+		var doc subscriptionDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		subs = append(subs, doc.toDomain())
+	}
+	return subs, cur.Err()
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return domain.ErrNotFound
+	}
+
+	res, err := r.subs.DeleteOne(ctx, bson.M{"_id": oid, "tenant_id": domain.TenantID(ctx)})
+	if err != nil {
+		return err
+	}
+	if res.DeletedCount == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	doc := toDeliveryDocument(d)
+	doc.ID = primitive.NewObjectID()
+
+	_, err := r.deliveries.InsertOne(ctx, doc)
+	if mongo.IsDuplicateKeyError(err) {
+		return domain.ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	d.ID = doc.ID.Hex()
+	return nil
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	oid, err := primitive.ObjectIDFromHex(id)
+	if err != nil {
+		return nil, domain.ErrNotFound
+	}
+
+	var doc deliveryDocument
+	err = r.deliveries.FindOne(ctx, bson.M{"_id": oid, "tenant_id": domain.TenantID(ctx)}).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain(), nil
+}
+
+func (r *WebhookRepository) ListDeliveries(
+	ctx context.Context,
+	q domain.WebhookDeliveryQuery,
+) ([]*domain.WebhookDelivery, error) {
+	filter := bson.M{"tenant_id": domain.TenantID(ctx)}
+	if q.SubscriptionID != "" {
+		filter["subscription_id"] = q.SubscriptionID
+	}
+	if q.Status != "" {
+		filter["status"] = string(q.Status)
+	}
+
+	cur, err := r.deliveries.Find(ctx, filter, options.Find().
+		SetSort(bson.D{{Key: "_id", Value: -1}}).
+		SetSkip(int64(q.Offset)).
+		SetLimit(int64(q.Limit)))
+	if err != nil {
+		return nil, err
+	}
+	defer cur.Close(ctx)
+
+	deliveries := []*domain.WebhookDelivery{}
+	for cur.Next(ctx) {
+		var doc deliveryDocument
+		if err := cur.Decode(&doc); err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, doc.toDomain())
+	}
+	return deliveries, cur.Err()
+}
+
+// ClaimDelivery pushes the next attempt past the lease in the same update
+// that finds the delivery, so concurrent dispatchers never share one.
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	now := time.Now()
+
+	var doc deliveryDocument
+	err := r.deliveries.FindOneAndUpdate(
+		ctx,
+		bson.M{"status": string(domain.DeliveryPending), "next_attempt_at": bson.M{"$lte": now}},
+		bson.M{"$set": bson.M{"next_attempt_at": now.Add(lease)}},
+		options.FindOneAndUpdate().
+			SetSort(bson.D{{Key: "next_attempt_at", Value: 1}, {Key: "_id", Value: 1}}).
+			SetReturnDocument(options.After),
+	).Decode(&doc)
+	if errors.Is(err, mongo.ErrNoDocuments) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	return doc.toDomain(), nil
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	doc := toDeliveryDocument(d)
+	oid, err := primitive.ObjectIDFromHex(d.ID)
+	if err != nil {
+		return domain.ErrNotFound
+	}
+	doc.ID = oid
+
+	res, err := r.deliveries.ReplaceOne(ctx, bson.M{"_id": oid}, doc)
+	if err != nil {
+		return err
+	}
+	if res.MatchedCount == 0 {
+		return domain.ErrNotFound
+	}
+	return nil
+}
+
+// RedactDeliveries finds the deliveries by searching payloads for the
+// user's ID, as deliveries do not keep it in a field.
+func (r *WebhookRepository) RedactDeliveries(ctx context.Context, userID string) (int64, error) {
+	cur, err := r.deliveries.Find(
+		ctx,
+		bson.M{
+			"tenant_id": domain.TenantID(ctx),
+			"payload":   primitive.Regex{Pattern: regexp.QuoteMeta(domain.EventUserField(userID))},
+		},
+		options.Find().SetProjection(bson.M{"payload": 1}),
+	)
+	if err != nil {
+		return 0, err
+	}
+	defer cur.Close(ctx)
+
+	var n int64
+	for cur.Next(ctx) {
+		var doc struct {
+			ID      primitive.ObjectID `bson:"_id"`
+			Payload string             `bson:"payload"`
+		}
+		if err := cur.Decode(&doc); err != nil {
+			return n, err
+		}
+		payload, err := domain.WithoutData([]byte(doc.Payload))
+		if err != nil {
+			return n, err
+		}
+		if string(payload) == doc.Payload {
+			continue
+		}
+		_, err = r.deliveries.UpdateOne(ctx, bson.M{"_id": doc.ID}, bson.M{"$set": bson.M{"payload": string(payload)}})
+		if err != nil {
+			return n, err
+		}
+		n++
+	}
+	return n, cur.Err()
+}
+
+func (d *subscriptionDocument) toDomain() *domain.WebhookSubscription {
+	s := &domain.WebhookSubscription{
+		ID:        d.ID.Hex(),
+		TenantID:  d.TenantID,
+		URL:       d.URL,
+		Secret:    d.Secret,
+		Active:    d.Active,
+		CreatedAt: d.CreatedAt,
+		UpdatedAt: d.UpdatedAt,
+	}
+	for _, t := range d.EventTypes {
+		s.EventTypes = append(s.EventTypes, domain.EventType(t))
+	}
+	return s
+}
+
+func toDeliveryDocument(d *domain.WebhookDelivery) deliveryDocument {
+	doc := deliveryDocument{
+		TenantID:       d.TenantID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      string(d.EventType),
+		Payload:        string(d.Payload),
+		Status:         string(d.Status),
+		Tries:          d.Tries,
+		Attempts:       []attemptDocument{},
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+	for _, a := range d.Attempts {
+		doc.Attempts = append(doc.Attempts, attemptDocument{
+			At:         a.At,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMS: a.Duration.Milliseconds(),
+		})
+	}
+	return doc
+}
+
+func (d *deliveryDocument) toDomain() *domain.WebhookDelivery {
+	out := &domain.WebhookDelivery{
+		ID:             d.ID.Hex(),
+		TenantID:       d.TenantID,
+		SubscriptionID: d.SubscriptionID,
+		EventID:        d.EventID,
+		EventType:      domain.EventType(d.EventType),
+		Payload:        []byte(d.Payload),
+		Status:         domain.WebhookDeliveryStatus(d.Status),
+		Tries:          d.Tries,
+		Attempts:       []domain.WebhookAttempt{},
+		NextAttemptAt:  d.NextAttemptAt,
+		CreatedAt:      d.CreatedAt,
+		DeliveredAt:    d.DeliveredAt,
+	}
+	for _, a := range d.Attempts {
+		out.Attempts = append(out.Attempts, domain.WebhookAttempt{
+			At:         a.At,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			Duration:   time.Duration(a.DurationMS) * time.Millisecond,
+		})
+	}
+	return out
+}
@@ -0,0 +1,78 @@
+package mongo_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/mongo"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"go.mongodb.org/mongo-driver/bson"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+	"go.mongodb.org/mongo-driver/mongo/integration/mtest"
+)
+
+func TestWebhookRepository_CreateDelivery(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("success", func(mt *mtest.T) {
+		repo := mongo.NewWebhookRepository(mt.DB)
+		mt.AddMockResponses(mtest.CreateSuccessResponse())
+
+		d := &domain.WebhookDelivery{SubscriptionID: "s1", EventID: "e1", Status: domain.DeliveryPending}
+		err := repo.CreateDelivery(context.Background(), d)
+
+		assert.NoError(t, err)
+		assert.NotEmpty(t, d.ID)
+	})
+
+	mt.Run("already queued", func(mt *mtest.T) {
+		repo := mongo.NewWebhookRepository(mt.DB)
+		mt.AddMockResponses(mtest.CreateWriteErrorsResponse(mtest.WriteError{
+			Index:   0,
+			Code:    11000,
+			Message: "duplicate key error",
+		}))
+
+		err := repo.CreateDelivery(context.Background(), &domain.WebhookDelivery{SubscriptionID: "s1", EventID: "e1"})
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+	})
+}
+
+func TestWebhookRepository_ClaimDelivery(t *testing.T) {
+	mt := mtest.New(t, mtest.NewOptions().ClientType(mtest.Mock))
+
+	mt.Run("due delivery", func(mt *mtest.T) {
+		repo := mongo.NewWebhookRepository(mt.DB)
+		oid := primitive.NewObjectID()
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: bson.D{
+			{Key: "_id", Value: oid},
+			{Key: "subscription_id", Value: "s1"},
+			{Key: "event_type", Value: "user.created"},
+			{Key: "payload", Value: `{"id":"e1"}`},
+			{Key: "status", Value: "pending"},
+			{Key: "tries", Value: 2},
+			{Key: "attempts", Value: bson.A{
+				bson.D{{Key: "at", Value: time.Now()}, {Key: "status_code", Value: 500}, {Key: "duration_ms", Value: int64(12)}},
+			}},
+		}}))
+
+		d, err := repo.ClaimDelivery(context.Background(), time.Minute)
+
+		assert.NoError(t, err)
+		assert.Equal(t, oid.Hex(), d.ID)
+		assert.Equal(t, `{"id":"e1"}`, string(d.Payload))
+		assert.Equal(t, 2, d.Tries)
+		assert.Equal(t, 500, d.Attempts[0].StatusCode)
+		assert.Equal(t, 12*time.Millisecond, d.Attempts[0].Duration)
+	})
+
+	mt.Run("nothing due", func(mt *mtest.T) {
+		repo := mongo.NewWebhookRepository(mt.DB)
+		mt.AddMockResponses(mtest.CreateSuccessResponse(bson.E{Key: "value", Value: nil}))
+
+		_, err := repo.ClaimDelivery(context.Background(), time.Minute)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+}
@@ -0,0 +1,46 @@
+package redis
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// Cache keeps values in Redis, or anything speaking its protocol, so that
+// every instance shares them. Keys are prefixed to share a database with
+// other applications.
+type Cache struct {
+	client redis.UniversalClient
+	prefix string
+}
+
+func NewCache(client redis.UniversalClient, prefix string) ports.Cache {
+	return &Cache{client: client, prefix: prefix}
+}
+
+func (c *Cache) Get(ctx context.Context, key string) ([]byte, error) {
+	v, err := c.client.Get(ctx, c.prefix+key).Bytes()
+	if errors.Is(err, redis.Nil) {
+		return nil, domain.ErrNotFound
+	}
+	return v, err
+}
+
+func (c *Cache) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	return c.client.Set(ctx, c.prefix+key, value, ttl).Err()
+}
+
+func (c *Cache) Delete(ctx context.Context, keys ...string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	prefixed := make([]string, len(keys))
+	for i, k := range keys {
+		prefixed[i] = c.prefix + k
+	}
+	return c.client.Del(ctx, prefixed...).Err()
+}
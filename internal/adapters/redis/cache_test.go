@@ -0,0 +1,57 @@
+package redis_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/alicebob/miniredis/v2"
+	goredis "github.com/redis/go-redis/v9"
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/adapters/redis"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestCache(t *testing.T) {
+	ctx := context.Background()
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr()})
+	defer client.Close()
+
+	cache := redis.NewCache(client, "app:")
+
+	_, err := cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	assert.NoError(t, cache.Set(ctx, "a", []byte("1"), time.Minute))
+	assert.NoError(t, cache.Set(ctx, "empty", []byte{}, time.Second))
+	assert.True(t, server.Exists("app:a"))
+	assert.Equal(t, time.Minute, server.TTL("app:a"))
+
+	v, err := cache.Get(ctx, "a")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("1"), v)
+	v, err = cache.Get(ctx, "empty")
+	assert.NoError(t, err)
+	assert.Empty(t, v)
+
+	server.FastForward(2 * time.Second)
+	_, err = cache.Get(ctx, "empty")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	assert.NoError(t, cache.Delete(ctx, "a", "missing"))
+	_, err = cache.Get(ctx, "a")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.NoError(t, cache.Delete(ctx))
+}
+
+func TestCache_ServerDown(t *testing.T) {
+	server := miniredis.RunT(t)
+	client := goredis.NewClient(&goredis.Options{Addr: server.Addr(), MaxRetries: -1})
+	defer client.Close()
+	server.Close()
+
+	_, err := redis.NewCache(client, "").Get(context.Background(), "a")
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,191 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+type AuditRepository struct {
+	db *sql.DB
+	d  dialect
+}
+
+func NewAuditRepository(db *sql.DB) ports.AuditRepository {
+	return &AuditRepository{db: db, d: dialectOf(db)}
+}
+
+const auditColumns = `tenant_id, seq, action, actor_id, target_id, changes, reason, ip, request_id, timestamp, prev_hash, hash, redacted, original_hash`
+
+// Append relies on the tenant and sequence number being the primary key,
+// so two writers racing for the same position cannot both succeed. Changes are
+// stored as the JSON that was hashed.
+func (r *AuditRepository) Append(ctx context.Context, e *domain.AuditEvent) error {
+	changes, err := auditChanges(e.Changes)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, r.d.rebind(`INSERT INTO audit_events (`+auditColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`),
+		e.TenantID, e.Seq, string(e.Action), nullString(e.ActorID), nullString(e.TargetID), changes,
+		nullString(e.Reason), nullString(e.IP), nullString(e.RequestID),
+		r.d.time(e.Timestamp), e.PrevHash, e.Hash, e.Redacted, nullString(e.OriginalHash))
+	if isUniqueViolation(err) {
+		return domain.ErrAlreadyExists
+	}
+	return err
+}
+
+func (r *AuditRepository) Last(ctx context.Context) (*domain.AuditEvent, error) {
+	row := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT `+auditColumns+` FROM audit_events WHERE tenant_id = ? ORDER BY seq DESC LIMIT 1`),
+		domain.TenantID(ctx))
+	e, err := scanAuditEvent(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return e, err
+}
+
+func (r *AuditRepository) Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error) {
+	conds := []string{"tenant_id = ?", "seq > ?"}
+	args := []any{domain.TenantID(ctx), q.AfterSeq}
+	if q.ActorID != "" {
+		conds = append(conds, "actor_id = ?")
+		args = append(args, q.ActorID)
+	}
+	if q.TargetID != "" {
+		conds = append(conds, "target_id = ?")
+		args = append(args, q.TargetID)
+	}
+	if q.Action != "" {
+		conds = append(conds, "action = ?")
+		args = append(args, string(q.Action))
+	}
+	if !q.From.IsZero() {
+		conds = append(conds, "timestamp >= ?")
+		args = append(args, r.d.time(q.From))
+	}
+	if !q.Until.IsZero() {
+		conds = append(conds, "timestamp <= ?")
+		args = append(args, r.d.time(q.Until))
+	}
+
+	query := `SELECT ` + auditColumns + ` FROM audit_events WHERE ` + strings.Join(conds, " AND ") + ` ORDER BY seq`
+	if q.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, q.Limit)
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.d.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	events := []*domain.AuditEvent{}
+	for rows.Next() {
+		e, err := scanAuditEvent(rows)
+		if err != nil {
+			return nil, err
+		}
+		events = append(events, e)
+	}
+	return events, rows.Err()
+}
+
+// Redact applies domain.AuditEvent.Redact to the events in Go, as they
+// have to be rehashed, and writes them back in one transaction.
+func (r *AuditRepository) Redact(ctx context.Context, userID, pseudonym string) ([]*domain.AuditEvent, error) {
+	tenant := domain.TenantID(ctx)
+	redacted := []*domain.AuditEvent{}
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, r.d.rebind(`SELECT `+auditColumns+` FROM audit_events
+			WHERE tenant_id = ? AND (actor_id = ? OR target_id = ?) ORDER BY seq`), tenant, userID, userID)
+		if err != nil {
+			return err
+		}
+		for rows.Next() {
+			e, err := scanAuditEvent(rows)
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if e.Redact(userID, pseudonym) {
+				redacted = append(redacted, e)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for _, e := range redacted {
+			changes, err := auditChanges(e.Changes)
+			if err != nil {
+				return err
+			}
+			_, err = tx.ExecContext(ctx, r.d.rebind(`UPDATE audit_events
+				SET actor_id = ?, target_id = ?, changes = ?, reason = ?, ip = ?,
+					hash = ?, redacted = ?, original_hash = ?
+				WHERE tenant_id = ? AND seq = ?`),
+				nullString(e.ActorID), nullString(e.TargetID), changes, nullString(e.Reason), nullString(e.IP),
+				e.Hash, e.Redacted, nullString(e.OriginalHash), tenant, e.Seq)
+			if err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	return redacted, nil
+}
+
+// auditChanges is the changes column for c, the JSON that was hashed.
+func auditChanges(c map[string]domain.AuditChange) (sql.NullString, error) {
+	if c == nil {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(c)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func scanAuditEvent(row rowScanner) (*domain.AuditEvent, error) {
+	var (
+		e                                  domain.AuditEvent
+		action                             string
+		actor, target, changes, reason, ip sql.NullString
+		requestID, originalHash            sql.NullString
+		ts                                 nullTime
+	)
+	err := row.Scan(&e.TenantID, &e.Seq, &action, &actor, &target, &changes, &reason, &ip, &requestID, &ts, &e.PrevHash, &e.Hash, &e.Redacted, &originalHash)
+	if err != nil {
+		return nil, err
+	}
+
+	e.Action = domain.AuditAction(action)
+	e.ActorID = actor.String
+	e.TargetID = target.String
+	e.Reason = reason.String
+	e.IP = ip.String
+	e.RequestID = requestID.String
+	e.OriginalHash = originalHash.String
+	e.Timestamp = ts.Time
+	if changes.Valid {
+		if err := json.Unmarshal([]byte(changes.String), &e.Changes); err != nil {
+			return nil, err
+		}
+	}
+	return &e, nil
+}
@@ -0,0 +1,109 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+
+	_ "github.com/lib/pq"
+	sqladapter "github.com/yimsoijoi/7s-backend-challenge/internal/adapters/sql"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/porttest"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+func TestUserRepository_Conformance(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		porttest.UserRepository(t, func(t *testing.T) ports.UserRepository {
+			return sqladapter.NewUserRepository(openSQLite(t))
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("POSTGRES_TEST_DSN")
+		if dsn == "" {
+			t.Skip("POSTGRES_TEST_DSN not set")
+		}
+		porttest.UserRepository(t, func(t *testing.T) ports.UserRepository {
+			return sqladapter.NewUserRepository(openPostgres(t, dsn))
+		})
+	})
+}
+
+func TestOrganizationRepository_Conformance(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		porttest.OrganizationRepository(t, func(t *testing.T) ports.OrganizationRepository {
+			return sqladapter.NewOrganizationRepository(openSQLite(t))
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("POSTGRES_TEST_DSN")
+		if dsn == "" {
+			t.Skip("POSTGRES_TEST_DSN not set")
+		}
+		porttest.OrganizationRepository(t, func(t *testing.T) ports.OrganizationRepository {
+			return sqladapter.NewOrganizationRepository(openPostgres(t, dsn))
+		})
+	})
+}
+
+func TestAuditRepository_Conformance(t *testing.T) {
+	t.Run("sqlite", func(t *testing.T) {
+		porttest.AuditRepository(t, func(t *testing.T) ports.AuditRepository {
+			return sqladapter.NewAuditRepository(openSQLite(t))
+		})
+	})
+
+	t.Run("postgres", func(t *testing.T) {
+		dsn := os.Getenv("POSTGRES_TEST_DSN")
+		if dsn == "" {
+			t.Skip("POSTGRES_TEST_DSN not set")
+		}
+		porttest.AuditRepository(t, func(t *testing.T) ports.AuditRepository {
+			return sqladapter.NewAuditRepository(openPostgres(t, dsn))
+		})
+	})
+}
+
+// openPostgres returns a migrated database confined to a schema of its
+// own, which is dropped when the test ends.
+func openPostgres(t *testing.T, dsn string) *sql.DB {
+	ctx := context.Background()
+	schema := "test_" + primitive.NewObjectID().Hex()
+
+	admin, err := sql.Open("postgres", dsn)
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.Close() })
+	if _, err := admin.ExecContext(ctx, `CREATE SCHEMA `+schema); err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { admin.ExecContext(ctx, `DROP SCHEMA `+schema+` CASCADE`) })
+
+	db, err := sql.Open("postgres", withSearchPath(dsn, schema))
+	if err != nil {
+		t.Fatal(err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	if err := sqladapter.Migrate(ctx, db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+// withSearchPath adds search_path to a URL or key=value DSN.
+func withSearchPath(dsn, schema string) string {
+	if !strings.Contains(dsn, "://") {
+		return fmt.Sprintf("%s search_path=%s", dsn, schema)
+	}
+	if strings.Contains(dsn, "?") {
+		return dsn + "&search_path=" + schema
+	}
+	return dsn + "?search_path=" + schema
+}
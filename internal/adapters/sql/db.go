@@ -0,0 +1,153 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"database/sql/driver"
+	"errors"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/lib/pq"
+	"modernc.org/sqlite"
+	sqlite3 "modernc.org/sqlite/lib"
+)
+
+// dialect papers over the differences between PostgreSQL and SQLite.
+// Queries are written with ? placeholders.
+type dialect struct {
+	postgres bool
+}
+
+func dialectOf(db *sql.DB) dialect {
+	_, ok := db.Driver().(*pq.Driver)
+	return dialect{postgres: ok}
+}
+
+// rebind turns ? placeholders into PostgreSQL's $1, $2, ...
+func (d dialect) rebind(query string) string {
+	if !d.postgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+	return b.String()
+}
+
+// binary makes text comparisons on col byte-wise, as in MongoDB.
+func (d dialect) binary(col string) string {
+	if d.postgres {
+		return col + ` COLLATE "C"`
+	}
+	return col
+}
+
+// timestampFormat is fixed-width so SQLite's text timestamps sort and
+// compare chronologically.
+const timestampFormat = "2006-01-02T15:04:05.000000Z"
+
+// time converts t for storage. Both databases keep microseconds.
+func (d dialect) time(t time.Time) any {
+	t = t.UTC().Truncate(time.Microsecond)
+	if d.postgres {
+		return t
+	}
+	return t.Format(timestampFormat)
+}
+
+func (d dialect) nullTime(t *time.Time) any {
+	if t == nil {
+		return nil
+	}
+	return d.time(*t)
+}
+
+// nullTime scans timestamps stored by either dialect.
+type nullTime struct {
+	Time  time.Time
+	Valid bool
+}
+
+func (n *nullTime) Scan(v any) error {
+	switch v := v.(type) {
+	case nil:
+		n.Time, n.Valid = time.Time{}, false
+		return nil
+	case time.Time:
+		n.Time, n.Valid = v.UTC(), true
+		return nil
+	case []byte:
+		return n.Scan(string(v))
+	case string:
+		t, err := time.Parse(time.RFC3339Nano, v)
+		if err != nil {
+			return fmt.Errorf("scan timestamp %q: %w", v, err)
+		}
+		n.Time, n.Valid = t.UTC(), true
+		return nil
+	}
+	return fmt.Errorf("scan timestamp: unsupported type %T", v)
+}
+
+func (n nullTime) Value() (driver.Value, error) {
+	if !n.Valid {
+		return nil, nil
+	}
+	return n.Time, nil
+}
+
+func (n nullTime) ptr() *time.Time {
+	if !n.Valid {
+		return nil
+	}
+	t := n.Time
+	return &t
+}
+
+// isUniqueViolation reports whether err comes from a unique or primary
+// key constraint.
+func isUniqueViolation(err error) bool {
+	var pqErr *pq.Error
+	if errors.As(err, &pqErr) {
+		return pqErr.Code == "23505"
+	}
+	var liteErr *sqlite.Error
+	if errors.As(err, &liteErr) {
+		return liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_UNIQUE ||
+			liteErr.Code() == sqlite3.SQLITE_CONSTRAINT_PRIMARYKEY
+	}
+	return false
+}
+
+// inTx runs fn in a transaction and commits when it returns nil.
+func inTx(ctx context.Context, db *sql.DB, fn func(tx *sql.Tx) error) error {
+	tx, err := db.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		_ = tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
+
+func nullString(s string) sql.NullString {
+	return sql.NullString{String: s, Valid: s != ""}
+}
+
+// escapeLike quotes the LIKE wildcards in s for use with ESCAPE '\'.
+func escapeLike(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `%`, `\%`, `_`, `\_`).Replace(s)
+}
@@ -0,0 +1,353 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+)
+
+// migration is one schema step. {{ts}} and {{bool}} in its statements are
+// replaced with the dialect's timestamp and boolean types.
+type migration struct {
+	version    int
+	name       string
+	statements []string
+	// sqlite replaces statements on SQLite when set. SQLite cannot drop
+	// constraints, so changing one means rebuilding the table.
+	sqlite []string
+}
+
+// migrations are applied in order and never edited once released; schema
+// changes get a new migration.
+var migrations = []migration{
+	{
+		version: 1,
+		name:    "create users and outbox",
+		statements: []string{
+			`CREATE TABLE users (
+				id                       TEXT PRIMARY KEY,
+				name                     TEXT NOT NULL,
+				email                    TEXT NOT NULL,
+				password                 TEXT NOT NULL,
+				created_at               {{ts}} NOT NULL,
+				updated_at               {{ts}} NOT NULL,
+				deleted_at               {{ts}},
+				version                  BIGINT NOT NULL,
+				pending_email            TEXT,
+				pending_email_token_hash TEXT,
+				pending_email_expires_at {{ts}},
+				status                   TEXT NOT NULL DEFAULT '',
+				status_reason            TEXT,
+				status_changed_at        {{ts}},
+				display_name             TEXT,
+				locale                   TEXT,
+				timezone                 TEXT,
+				phone                    TEXT,
+				avatar_url               TEXT,
+				attributes               TEXT,
+				CONSTRAINT users_email_key UNIQUE (email)
+			)`,
+			`CREATE INDEX users_created_at_idx ON users (created_at, id)`,
+			`CREATE INDEX users_pending_email_token_hash_idx ON users (pending_email_token_hash)`,
+			`CREATE INDEX users_deleted_at_idx ON users (deleted_at)`,
+			`CREATE TABLE outbox (
+				id              TEXT PRIMARY KEY,
+				type            TEXT NOT NULL,
+				user_id         TEXT NOT NULL,
+				occurred_at     {{ts}} NOT NULL,
+				data            TEXT,
+				attempts        INTEGER NOT NULL DEFAULT 0,
+				last_error      TEXT,
+				next_attempt_at {{ts}} NOT NULL
+			)`,
+			`CREATE INDEX outbox_next_attempt_at_idx ON outbox (next_attempt_at, id)`,
+		},
+	},
+	{
+		version: 2,
+		name:    "create audit log and webhooks",
+		statements: []string{
+			`CREATE TABLE audit_events (
+				seq        BIGINT PRIMARY KEY,
+				action     TEXT NOT NULL,
+				actor_id   TEXT,
+				target_id  TEXT,
+				changes    TEXT,
+				reason     TEXT,
+				ip         TEXT,
+				request_id TEXT,
+				timestamp  {{ts}} NOT NULL,
+				prev_hash  TEXT NOT NULL,
+				hash       TEXT NOT NULL
+			)`,
+			`CREATE INDEX audit_events_actor_id_idx ON audit_events (actor_id, seq)`,
+			`CREATE INDEX audit_events_target_id_idx ON audit_events (target_id, seq)`,
+			`CREATE TABLE webhook_subscriptions (
+				id         TEXT PRIMARY KEY,
+				url        TEXT NOT NULL,
+				events     TEXT NOT NULL,
+				secret     TEXT NOT NULL,
+				active     {{bool}} NOT NULL,
+				created_at {{ts}} NOT NULL,
+				updated_at {{ts}} NOT NULL
+			)`,
+			`CREATE TABLE webhook_deliveries (
+				id              TEXT PRIMARY KEY,
+				subscription_id TEXT NOT NULL,
+				event_id        TEXT NOT NULL,
+				event_type      TEXT NOT NULL,
+				payload         TEXT NOT NULL,
+				status          TEXT NOT NULL,
+				tries           INTEGER NOT NULL,
+				attempts        TEXT NOT NULL,
+				next_attempt_at {{ts}} NOT NULL,
+				created_at      {{ts}} NOT NULL,
+				delivered_at    {{ts}},
+				CONSTRAINT webhook_deliveries_event_key UNIQUE (subscription_id, event_id)
+			)`,
+			`CREATE INDEX webhook_deliveries_due_idx ON webhook_deliveries (status, next_attempt_at)`,
+		},
+	},
+	{
+		version: 3,
+		name:    "add user invitations",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN invitation_token_hash TEXT`,
+			`ALTER TABLE users ADD COLUMN invitation_expires_at {{ts}}`,
+			`CREATE INDEX users_invitation_token_hash_idx ON users (invitation_token_hash)`,
+		},
+	},
+	{
+		version: 4,
+		name:    "mark redacted audit events",
+		statements: []string{
+			`ALTER TABLE audit_events ADD COLUMN redacted {{bool}} NOT NULL DEFAULT FALSE`,
+		},
+	},
+	{
+		version: 5,
+		name:    "scope users by tenant",
+		statements: []string{
+			`ALTER TABLE users ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE users DROP CONSTRAINT users_email_key`,
+			`ALTER TABLE users ADD CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email)`,
+			`DROP INDEX users_created_at_idx`,
+			`CREATE INDEX users_tenant_id_created_at_idx ON users (tenant_id, created_at, id)`,
+			`ALTER TABLE outbox ADD COLUMN tenant_id TEXT`,
+		},
+		sqlite: []string{
+			`CREATE TABLE users_scoped (
+				id                       TEXT PRIMARY KEY,
+				tenant_id                TEXT NOT NULL DEFAULT 'default',
+				name                     TEXT NOT NULL,
+				email                    TEXT NOT NULL,
+				password                 TEXT NOT NULL,
+				created_at               {{ts}} NOT NULL,
+				updated_at               {{ts}} NOT NULL,
+				deleted_at               {{ts}},
+				version                  BIGINT NOT NULL,
+				pending_email            TEXT,
+				pending_email_token_hash TEXT,
+				pending_email_expires_at {{ts}},
+				status                   TEXT NOT NULL DEFAULT '',
+				status_reason            TEXT,
+				status_changed_at        {{ts}},
+				display_name             TEXT,
+				locale                   TEXT,
+				timezone                 TEXT,
+				phone                    TEXT,
+				avatar_url               TEXT,
+				attributes               TEXT,
+				invitation_token_hash    TEXT,
+				invitation_expires_at    {{ts}},
+				CONSTRAINT users_tenant_id_email_key UNIQUE (tenant_id, email)
+			)`,
+			`INSERT INTO users_scoped (` + migratedUserColumns + `)
+				SELECT ` + migratedUserColumns + ` FROM users`,
+			`DROP TABLE users`,
+			`ALTER TABLE users_scoped RENAME TO users`,
+			`CREATE INDEX users_tenant_id_created_at_idx ON users (tenant_id, created_at, id)`,
+			`CREATE INDEX users_pending_email_token_hash_idx ON users (pending_email_token_hash)`,
+			`CREATE INDEX users_deleted_at_idx ON users (deleted_at)`,
+			`CREATE INDEX users_invitation_token_hash_idx ON users (invitation_token_hash)`,
+			`ALTER TABLE outbox ADD COLUMN tenant_id TEXT`,
+		},
+	},
+	{
+		version: 6,
+		name:    "create organizations and memberships",
+		statements: []string{
+			`CREATE TABLE organizations (
+				id         TEXT PRIMARY KEY,
+				tenant_id  TEXT NOT NULL,
+				name       TEXT NOT NULL,
+				created_at {{ts}} NOT NULL,
+				updated_at {{ts}} NOT NULL
+			)`,
+			`CREATE TABLE memberships (
+				organization_id TEXT NOT NULL REFERENCES organizations (id),
+				user_id         TEXT NOT NULL,
+				tenant_id       TEXT NOT NULL,
+				role            TEXT NOT NULL,
+				teams           TEXT,
+				state           TEXT NOT NULL,
+				invited_by      TEXT,
+				created_at      {{ts}} NOT NULL,
+				updated_at      {{ts}} NOT NULL,
+				PRIMARY KEY (organization_id, user_id)
+			)`,
+			`CREATE INDEX memberships_tenant_id_user_id_idx ON memberships (tenant_id, user_id)`,
+		},
+	},
+	{
+		version: 7,
+		name:    "scope audit events by tenant",
+		statements: []string{
+			`ALTER TABLE audit_events ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE audit_events DROP CONSTRAINT audit_events_pkey`,
+			`ALTER TABLE audit_events ADD CONSTRAINT audit_events_pkey PRIMARY KEY (tenant_id, seq)`,
+			`DROP INDEX audit_events_actor_id_idx`,
+			`DROP INDEX audit_events_target_id_idx`,
+			`CREATE INDEX audit_events_tenant_id_actor_id_idx ON audit_events (tenant_id, actor_id, seq)`,
+			`CREATE INDEX audit_events_tenant_id_target_id_idx ON audit_events (tenant_id, target_id, seq)`,
+		},
+		sqlite: []string{
+			`CREATE TABLE audit_events_scoped (
+				tenant_id  TEXT NOT NULL DEFAULT 'default',
+				seq        BIGINT NOT NULL,
+				action     TEXT NOT NULL,
+				actor_id   TEXT,
+				target_id  TEXT,
+				changes    TEXT,
+				reason     TEXT,
+				ip         TEXT,
+				request_id TEXT,
+				timestamp  {{ts}} NOT NULL,
+				prev_hash  TEXT NOT NULL,
+				hash       TEXT NOT NULL,
+				redacted   {{bool}} NOT NULL DEFAULT FALSE,
+				PRIMARY KEY (tenant_id, seq)
+			)`,
+			`INSERT INTO audit_events_scoped (` + migratedAuditColumns + `)
+				SELECT ` + migratedAuditColumns + ` FROM audit_events`,
+			`DROP TABLE audit_events`,
+			`ALTER TABLE audit_events_scoped RENAME TO audit_events`,
+			`CREATE INDEX audit_events_tenant_id_actor_id_idx ON audit_events (tenant_id, actor_id, seq)`,
+			`CREATE INDEX audit_events_tenant_id_target_id_idx ON audit_events (tenant_id, target_id, seq)`,
+		},
+	},
+	{
+		version: 8,
+		name:    "scope webhooks by tenant",
+		statements: []string{
+			`ALTER TABLE webhook_subscriptions ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default'`,
+			`ALTER TABLE webhook_deliveries ADD COLUMN tenant_id TEXT NOT NULL DEFAULT 'default'`,
+		},
+	},
+	{
+		version: 9,
+		name:    "rehash redacted audit events",
+		statements: []string{
+			`ALTER TABLE audit_events ADD COLUMN original_hash TEXT`,
+		},
+	},
+}
+
+// migratedUserColumns are the user columns before migration 5.
+const migratedUserColumns = `id, name, email, password, created_at, updated_at, deleted_at, version,
+	pending_email, pending_email_token_hash, pending_email_expires_at,
+	status, status_reason, status_changed_at,
+	display_name, locale, timezone, phone, avatar_url, attributes,
+	invitation_token_hash, invitation_expires_at`
+
+// migratedAuditColumns are the audit event columns before migration 7.
+const migratedAuditColumns = `seq, action, actor_id, target_id, changes, reason, ip, request_id,
+	timestamp, prev_hash, hash, redacted`
+
+// Migrate brings the schema up to date. Each migration runs in its own
+// transaction together with its row in schema_migrations, so a migration
+// another instance applied first is skipped.
+func Migrate(ctx context.Context, db *sql.DB) error {
+	d := dialectOf(db)
+
+	_, err := db.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    INTEGER PRIMARY KEY,
+		name       TEXT NOT NULL,
+		applied_at `+d.types().ts+` NOT NULL
+	)`)
+	if err != nil {
+		return fmt.Errorf("create schema_migrations: %w", err)
+	}
+
+	applied := map[int]bool{}
+	rows, err := db.QueryContext(ctx, `SELECT version FROM schema_migrations`)
+	if err != nil {
+		return err
+	}
+	for rows.Next() {
+		var v int
+		if err := rows.Scan(&v); err != nil {
+			rows.Close()
+			return err
+		}
+		applied[v] = true
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	for _, m := range migrations {
+		if applied[m.version] {
+			continue
+		}
+
+		err := inTx(ctx, db, func(tx *sql.Tx) error {
+			_, err := tx.ExecContext(ctx, d.rebind(
+				`INSERT INTO schema_migrations (version, name, applied_at) VALUES (?, ?, ?)`),
+				m.version, m.name, d.time(time.Now()),
+			)
+			if err != nil {
+				return err
+			}
+			statements := m.statements
+			if !d.postgres && m.sqlite != nil {
+				statements = m.sqlite
+			}
+			for _, stmt := range statements {
+				if _, err := tx.ExecContext(ctx, d.types().expand(stmt)); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+		if isUniqueViolation(err) {
+			continue
+		}
+		if err != nil {
+			return fmt.Errorf("migration %d (%s): %w", m.version, m.name, err)
+		}
+		log.Printf("Applied SQL migration %d: %s", m.version, m.name)
+	}
+	return nil
+}
+
+type columnTypes struct {
+	ts   string
+	bool string
+}
+
+func (d dialect) types() columnTypes {
+	if d.postgres {
+		return columnTypes{ts: "TIMESTAMPTZ", bool: "BOOLEAN"}
+	}
+	// SQLite keeps timestamps as fixed-width text, see timestampFormat.
+	return columnTypes{ts: "TEXT", bool: "INTEGER"}
+}
+
+func (t columnTypes) expand(stmt string) string {
+	return strings.NewReplacer("{{ts}}", t.ts, "{{bool}}", t.bool).Replace(stmt)
+}
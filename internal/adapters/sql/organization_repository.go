@@ -0,0 +1,233 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type OrganizationRepository struct {
+	db *sql.DB
+	d  dialect
+}
+
+func NewOrganizationRepository(db *sql.DB) ports.OrganizationRepository {
+	return &OrganizationRepository{db: db, d: dialectOf(db)}
+}
+
+const (
+	organizationColumns = `id, tenant_id, name, created_at, updated_at`
+	membershipColumns   = `organization_id, user_id, role, teams, state, invited_by, created_at, updated_at`
+)
+
+func (r *OrganizationRepository) Create(ctx context.Context, org *domain.Organization, owner *domain.Membership) error {
+	id := primitive.NewObjectID().Hex()
+	tenant := domain.TenantID(ctx)
+
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		_, err := tx.ExecContext(ctx, r.d.rebind(`INSERT INTO organizations (`+organizationColumns+`)
+			VALUES (?, ?, ?, ?, ?)`),
+			id, tenant, org.Name, r.d.time(org.CreatedAt), r.d.time(org.UpdatedAt))
+		if err != nil {
+			return err
+		}
+
+		m := *owner
+		m.OrganizationID = id
+		args, err := r.membershipArgs(&m)
+		if err != nil {
+			return err
+		}
+		_, err = tx.ExecContext(ctx, r.d.rebind(`INSERT INTO memberships (tenant_id, `+membershipColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`), append([]any{tenant}, args...)...)
+		return err
+	})
+	if err != nil {
+		return err
+	}
+
+	org.ID = id
+	org.TenantID = tenant
+	owner.OrganizationID = id
+	return nil
+}
+
+func (r *OrganizationRepository) FindByID(ctx context.Context, id string) (*domain.Organization, error) {
+	var (
+		org                  domain.Organization
+		createdAt, updatedAt nullTime
+	)
+	err := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT `+organizationColumns+` FROM organizations WHERE id = ? AND tenant_id = ?`),
+		id, domain.TenantID(ctx),
+	).Scan(&org.ID, &org.TenantID, &org.Name, &createdAt, &updatedAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+	org.CreatedAt = createdAt.Time
+	org.UpdatedAt = updatedAt.Time
+	return &org, nil
+}
+
+func (r *OrganizationRepository) AddMember(ctx context.Context, m *domain.Membership) error {
+	args, err := r.membershipArgs(m)
+	if err != nil {
+		return err
+	}
+	tenant := domain.TenantID(ctx)
+
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		var id string
+		err := tx.QueryRowContext(ctx, r.d.rebind(`SELECT id FROM organizations WHERE id = ? AND tenant_id = ?`),
+			m.OrganizationID, tenant).Scan(&id)
+		if errors.Is(err, sql.ErrNoRows) {
+			return domain.ErrNotFound
+		}
+		if err != nil {
+			return err
+		}
+
+		_, err = tx.ExecContext(ctx, r.d.rebind(`INSERT INTO memberships (tenant_id, `+membershipColumns+`)
+			VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?)`), append([]any{tenant}, args...)...)
+		if isUniqueViolation(err) {
+			return domain.ErrAlreadyExists
+		}
+		return err
+	})
+}
+
+func (r *OrganizationRepository) FindMember(ctx context.Context, orgID, userID string) (*domain.Membership, error) {
+	row := r.db.QueryRowContext(ctx, r.d.rebind(`SELECT `+membershipColumns+` FROM memberships
+		WHERE organization_id = ? AND user_id = ? AND tenant_id = ?`), orgID, userID, domain.TenantID(ctx))
+	m, err := scanMembership(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return m, err
+}
+
+func (r *OrganizationRepository) ListMembers(ctx context.Context, orgID string) ([]*domain.Membership, error) {
+	if _, err := r.FindByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+	return r.memberships(ctx, `organization_id = ? AND tenant_id = ? ORDER BY user_id`, orgID, domain.TenantID(ctx))
+}
+
+func (r *OrganizationRepository) MembershipsOf(ctx context.Context, userID string) ([]*domain.Membership, error) {
+	return r.memberships(ctx, `user_id = ? AND tenant_id = ? ORDER BY organization_id`, userID, domain.TenantID(ctx))
+}
+
+func (r *OrganizationRepository) memberships(ctx context.Context, where string, args ...any) ([]*domain.Membership, error) {
+	rows, err := r.db.QueryContext(ctx, r.d.rebind(`SELECT `+membershipColumns+` FROM memberships WHERE `+where), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	memberships := []*domain.Membership{}
+	for rows.Next() {
+		m, err := scanMembership(rows)
+		if err != nil {
+			return nil, err
+		}
+		memberships = append(memberships, m)
+	}
+	return memberships, rows.Err()
+}
+
+func (r *OrganizationRepository) UpdateMember(ctx context.Context, m *domain.Membership) error {
+	teams, err := teamsColumn(m.Teams)
+	if err != nil {
+		return err
+	}
+	res, err := r.db.ExecContext(ctx, r.d.rebind(`UPDATE memberships
+		SET role = ?, teams = ?, state = ?, invited_by = ?, updated_at = ?
+		WHERE organization_id = ? AND user_id = ? AND tenant_id = ?`),
+		string(m.Role), teams, string(m.State), nullString(m.InvitedBy), r.d.time(m.UpdatedAt),
+		m.OrganizationID, m.UserID, domain.TenantID(ctx))
+	return rowsOrNotFound(res, err)
+}
+
+func (r *OrganizationRepository) RemoveMember(ctx context.Context, orgID, userID string) error {
+	res, err := r.db.ExecContext(ctx, r.d.rebind(
+		`DELETE FROM memberships WHERE organization_id = ? AND user_id = ? AND tenant_id = ?`),
+		orgID, userID, domain.TenantID(ctx))
+	return rowsOrNotFound(res, err)
+}
+
+func (r *OrganizationRepository) RemoveUser(ctx context.Context, userID string) (int64, error) {
+	res, err := r.db.ExecContext(ctx, r.d.rebind(
+		`DELETE FROM memberships WHERE user_id = ? AND tenant_id = ?`), userID, domain.TenantID(ctx))
+	if err != nil {
+		return 0, err
+	}
+	return res.RowsAffected()
+}
+
+// membershipArgs are the values of membershipColumns.
+func (r *OrganizationRepository) membershipArgs(m *domain.Membership) ([]any, error) {
+	teams, err := teamsColumn(m.Teams)
+	if err != nil {
+		return nil, err
+	}
+	return []any{
+		m.OrganizationID, m.UserID, string(m.Role), teams, string(m.State), nullString(m.InvitedBy),
+		r.d.time(m.CreatedAt), r.d.time(m.UpdatedAt),
+	}, nil
+}
+
+// teamsColumn stores teams as a JSON array, NULL when there are none.
+func teamsColumn(teams []string) (sql.NullString, error) {
+	if len(teams) == 0 {
+		return sql.NullString{}, nil
+	}
+	b, err := json.Marshal(teams)
+	if err != nil {
+		return sql.NullString{}, err
+	}
+	return sql.NullString{String: string(b), Valid: true}, nil
+}
+
+func scanMembership(row rowScanner) (*domain.Membership, error) {
+	var (
+		m                    domain.Membership
+		role, state          string
+		teams, invitedBy     sql.NullString
+		createdAt, updatedAt nullTime
+	)
+	err := row.Scan(&m.OrganizationID, &m.UserID, &role, &teams, &state, &invitedBy, &createdAt, &updatedAt)
+	if err != nil {
+		return nil, err
+	}
+	if teams.Valid {
+		if err := json.Unmarshal([]byte(teams.String), &m.Teams); err != nil {
+			return nil, err
+		}
+	}
+	m.Role = domain.OrgRole(role)
+	m.State = domain.MembershipState(state)
+	m.InvitedBy = invitedBy.String
+	m.CreatedAt = createdAt.Time
+	m.UpdatedAt = updatedAt.Time
+	return &m, nil
+}
+
+// rowsOrNotFound turns a statement that changed no rows into
+// domain.ErrNotFound.
+func rowsOrNotFound(res sql.Result, err error) error {
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Join(domain.ErrNotFound, err)
+	}
+	return nil
+}
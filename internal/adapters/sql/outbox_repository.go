@@ -0,0 +1,91 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"errors"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OutboxRepository reads the outbox table the UserRepository writes to.
+// Delivered messages are deleted.
+type OutboxRepository struct {
+	db *sql.DB
+	d  dialect
+}
+
+func NewOutboxRepository(db *sql.DB) ports.OutboxRepository {
+	return &OutboxRepository{db: db, d: dialectOf(db)}
+}
+
+// insertOutbox stores e as due immediately, inside the transaction making
+// the change e describes.
+func insertOutbox(ctx context.Context, tx *sql.Tx, d dialect, e domain.Event) error {
+	var data sql.NullString
+	if e.Data != nil {
+		data = sql.NullString{String: string(e.Data), Valid: true}
+	}
+	_, err := tx.ExecContext(ctx, d.rebind(`INSERT INTO outbox
+		(id, type, tenant_id, user_id, occurred_at, data, attempts, next_attempt_at)
+		VALUES (?, ?, ?, ?, ?, ?, 0, ?)`),
+		primitive.NewObjectID().Hex(), string(e.Type), nullString(e.TenantID), e.UserID,
+		d.time(e.OccurredAt), data, d.time(e.OccurredAt))
+	return err
+}
+
+// Claim picks the oldest due message and pushes its next attempt past the
+// lease. The due condition is checked again by the update itself, so of
+// two relays racing for a message only one gets it.
+func (r *OutboxRepository) Claim(ctx context.Context, lease time.Duration) (*domain.OutboxMessage, error) {
+	now := time.Now()
+
+	row := r.db.QueryRowContext(ctx, r.d.rebind(`UPDATE outbox
+		SET next_attempt_at = ?, attempts = attempts + 1
+		WHERE id = (
+			SELECT id FROM outbox WHERE next_attempt_at <= ?
+			ORDER BY next_attempt_at, id LIMIT 1
+		) AND next_attempt_at <= ?
+		RETURNING id, type, tenant_id, user_id, occurred_at, data, attempts, last_error, next_attempt_at`),
+		r.d.time(now.Add(lease)), r.d.time(now), r.d.time(now))
+
+	var (
+		msg                domain.OutboxMessage
+		occurredAt, nextAt nullTime
+		tenantID, data     sql.NullString
+		lastError          sql.NullString
+		eventType          string
+	)
+	err := row.Scan(&msg.ID, &eventType, &tenantID, &msg.UserID, &occurredAt, &data, &msg.Attempts, &lastError, &nextAt)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	msg.Type = domain.EventType(eventType)
+	msg.TenantID = tenantID.String
+	msg.OccurredAt = occurredAt.Time
+	if data.Valid {
+		msg.Data = []byte(data.String)
+	}
+	msg.LastError = lastError.String
+	msg.NextAttemptAt = nextAt.Time
+	return &msg, nil
+}
+
+func (r *OutboxRepository) MarkDelivered(ctx context.Context, id string) error {
+	_, err := r.db.ExecContext(ctx, r.d.rebind(`DELETE FROM outbox WHERE id = ?`), id)
+	return err
+}
+
+func (r *OutboxRepository) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) error {
+	_, err := r.db.ExecContext(ctx, r.d.rebind(
+		`UPDATE outbox SET last_error = ?, next_attempt_at = ? WHERE id = ?`),
+		reason, r.d.time(retryAt), id)
+	return err
+}
@@ -0,0 +1,611 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository stores users in PostgreSQL or SQLite with the semantics
+// of the MongoDB repository. IDs are ObjectIDs so they sort in creation
+// order and look the same on every backend. Every change is recorded in
+// the outbox table in the same transaction. Every query but Purge's is
+// limited to the context's tenant.
+type UserRepository struct {
+	db *sql.DB
+	d  dialect
+}
+
+func NewUserRepository(db *sql.DB) ports.UserRepository {
+	return &UserRepository{db: db, d: dialectOf(db)}
+}
+
+const userColumns = `id, name, email, password, created_at, updated_at, deleted_at, version,
+	pending_email, pending_email_token_hash, pending_email_expires_at,
+	status, status_reason, status_changed_at,
+	display_name, locale, timezone, phone, avatar_url, attributes,
+	invitation_token_hash, invitation_expires_at, tenant_id`
+
+func (r *UserRepository) Create(ctx context.Context, u *domain.User) error {
+	created := *u
+	if _, err := primitive.ObjectIDFromHex(created.ID); err != nil {
+		created.ID = primitive.NewObjectID().Hex()
+	}
+	created.TenantID = domain.TenantID(ctx)
+	created.Version = 1
+
+	args, err := r.userArgs(&created)
+	if err != nil {
+		return err
+	}
+	event, err := domain.NewUserEvent(domain.EventUserCreated, created.TenantID, created.ID, &created)
+	if err != nil {
+		return err
+	}
+
+	err = inTx(ctx, r.db, func(tx *sql.Tx) error {
+		return r.insert(ctx, tx, args, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.ID = created.ID
+	u.TenantID = created.TenantID
+	u.Version = created.Version
+	return nil
+}
+
+func (r *UserRepository) CreateMany(ctx context.Context, users []*domain.User) error {
+	created := make([]domain.User, len(users))
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		for i, u := range users {
+			created[i] = *u
+			if _, err := primitive.ObjectIDFromHex(created[i].ID); err != nil {
+				created[i].ID = primitive.NewObjectID().Hex()
+			}
+			created[i].TenantID = domain.TenantID(ctx)
+			created[i].Version = 1
+
+			args, err := r.userArgs(&created[i])
+			if err != nil {
+				return err
+			}
+			event, err := domain.NewUserEvent(domain.EventUserCreated, created[i].TenantID, created[i].ID, &created[i])
+			if err != nil {
+				return err
+			}
+			if err := r.insert(ctx, tx, args, event); err != nil {
+				return err
+			}
+		}
+		return nil
+	})
+	if err != nil {
+		return err
+	}
+
+	for i, u := range users {
+		u.ID = created[i].ID
+		u.TenantID = created[i].TenantID
+		u.Version = created[i].Version
+	}
+	return nil
+}
+
+// insert stores a user, given as userArgs, and its event.
+func (r *UserRepository) insert(ctx context.Context, tx *sql.Tx, args []any, event domain.Event) error {
+	_, err := tx.ExecContext(ctx, r.d.rebind(`INSERT INTO users (`+userColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`), args...)
+	if isUniqueViolation(err) {
+		return domain.ErrEmailTaken
+	}
+	if err != nil {
+		return err
+	}
+	return insertOutbox(ctx, tx, r.d, event)
+}
+
+func (r *UserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, domain.ErrInvalidID
+	}
+	return r.findOne(ctx, `id = ?`, id)
+}
+
+func (r *UserRepository) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	if !primitive.IsValidObjectID(id) {
+		return nil, domain.ErrInvalidID
+	}
+	row := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT `+userColumns+` FROM users WHERE tenant_id = ? AND id = ?`),
+		domain.TenantID(ctx), id)
+	u, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return u, err
+}
+
+func (r *UserRepository) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
+	return r.findOne(ctx, `email = ?`, email)
+}
+
+func (r *UserRepository) FindByEmailChangeToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	return r.findOne(ctx, `pending_email_token_hash = ?`, tokenHash)
+}
+
+func (r *UserRepository) FindByInvitationToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	return r.findOne(ctx, `invitation_token_hash = ?`, tokenHash)
+}
+
+func (r *UserRepository) FindAll(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error) {
+	where, args := r.userFilter(ctx, q.Filter)
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, r.d.rebind(`SELECT COUNT(*) FROM users WHERE `+where), args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	dir, op := "DESC", "<"
+	if q.Order == domain.SortAsc {
+		dir, op = "ASC", ">"
+	}
+	sortCol := string(q.SortBy)
+	if q.SortBy != domain.SortByCreatedAt {
+		sortCol = r.d.binary(sortCol)
+	}
+
+	if q.Cursor != "" {
+		c, err := domain.DecodeCursor(q.Cursor, q)
+		if err != nil {
+			return nil, err
+		}
+		var value any = c.Value
+		if c.SortBy == domain.SortByCreatedAt {
+			value = r.d.time(c.CreatedAt())
+		}
+		where += fmt.Sprintf(` AND (%s %s ? OR (%s = ? AND id %s ?))`, sortCol, op, sortCol, op)
+		args = append(args, value, value, c.ID)
+	}
+
+	// One extra row tells us whether there is a next page.
+	query := fmt.Sprintf(`SELECT %s FROM users WHERE %s ORDER BY %s %s, id %s LIMIT ? OFFSET ?`,
+		userColumns, where, sortCol, dir, dir)
+	users, err := r.query(ctx, query, append(args, q.Limit+1, q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+
+	page := &domain.UserPage{Users: users, Total: total}
+	if len(users) > q.Limit {
+		page.Users = users[:q.Limit]
+		page.NextCursor = domain.NewCursor(q, page.Users[q.Limit-1]).Encode()
+	}
+	return page, nil
+}
+
+// Search matches the text anywhere in name or email, ignoring case, and
+// ranks name matches first. There is no full-text index behind it.
+func (r *UserRepository) Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error) {
+	pattern := "%" + escapeLike(strings.ToLower(q.Text)) + "%"
+	where := `tenant_id = ? AND deleted_at IS NULL AND (LOWER(name) LIKE ? ESCAPE '\' OR LOWER(email) LIKE ? ESCAPE '\')`
+	args := []any{domain.TenantID(ctx), pattern, pattern}
+	if len(q.UserIDs) > 0 {
+		cond, ids := idIn(q.UserIDs)
+		where += " AND " + cond
+		args = append(args, ids...)
+	}
+
+	var total int64
+	err := r.db.QueryRowContext(ctx, r.d.rebind(`SELECT COUNT(*) FROM users WHERE `+where), args...).Scan(&total)
+	if err != nil {
+		return nil, err
+	}
+
+	users, err := r.query(ctx, `SELECT `+userColumns+` FROM users WHERE `+where+`
+		ORDER BY CASE WHEN LOWER(name) LIKE ? ESCAPE '\' THEN 0 ELSE 1 END, id
+		LIMIT ? OFFSET ?`,
+		append(args, pattern, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	return &domain.UserPage{Users: users, Total: total}, nil
+}
+
+// eachPageSize is how many users Each reads at a time. Each pages by ID
+// rather than holding a cursor open, so fn can use the database even
+// when, as with SQLite, there is a single connection.
+const eachPageSize = 500
+
+func (r *UserRepository) Each(ctx context.Context, fn func(*domain.User) error) error {
+	tenant := domain.TenantID(ctx)
+	after := ""
+	for {
+		users, err := r.query(ctx, `SELECT `+userColumns+` FROM users
+			WHERE tenant_id = ? AND deleted_at IS NULL AND id > ? ORDER BY id LIMIT ?`, tenant, after, eachPageSize)
+		if err != nil {
+			return err
+		}
+		for _, u := range users {
+			if err := fn(u); err != nil {
+				return err
+			}
+		}
+		if len(users) < eachPageSize {
+			return nil
+		}
+		after = users[len(users)-1].ID
+	}
+}
+
+// Update only applies when the stored version still equals u.Version,
+// and bumps it on success. Password, creation time and deletion state
+// are left alone.
+func (r *UserRepository) Update(ctx context.Context, u *domain.User) error {
+	return r.update(ctx, u, false)
+}
+
+func (r *UserRepository) UpdateWithPassword(ctx context.Context, u *domain.User) error {
+	return r.update(ctx, u, true)
+}
+
+func (r *UserRepository) update(ctx context.Context, u *domain.User, withPassword bool) error {
+	if !primitive.IsValidObjectID(u.ID) {
+		return domain.ErrInvalidID
+	}
+
+	updated := *u
+	updated.Version++
+
+	args, err := r.userArgs(&updated)
+	if err != nil {
+		return err
+	}
+	tenant := domain.TenantID(ctx)
+	updated.TenantID = tenant
+	event, err := domain.NewUserEvent(domain.EventUserUpdated, tenant, u.ID, &updated)
+	if err != nil {
+		return err
+	}
+
+	// args follows userColumns; skip id, password, timestamps, deletion
+	// and tenant.
+	set := `name = ?, email = ?, updated_at = ?, version = ?,
+		pending_email = ?, pending_email_token_hash = ?, pending_email_expires_at = ?,
+		status = ?, status_reason = ?, status_changed_at = ?,
+		display_name = ?, locale = ?, timezone = ?, phone = ?, avatar_url = ?, attributes = ?,
+		invitation_token_hash = ?, invitation_expires_at = ?`
+	setArgs := append([]any{args[1], args[2], args[5], args[7]}, args[8:len(args)-1]...)
+	if withPassword {
+		set += `, password = ?`
+		setArgs = append(setArgs, args[3])
+	}
+
+	err = inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, r.d.rebind(`UPDATE users SET `+set+`
+			WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL AND version = ?`),
+			append(setArgs, u.ID, tenant, u.Version)...)
+		if isUniqueViolation(err) {
+			return domain.ErrEmailTaken
+		}
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return errors.Join(r.missingOrConflict(ctx, tx, tenant, u.ID), err)
+		}
+		return insertOutbox(ctx, tx, r.d, event)
+	})
+	if err != nil {
+		return err
+	}
+
+	u.Version++
+	return nil
+}
+
+// Delete is a soft delete: the user is hidden from every finder until
+// restored or purged. The email stays reserved in the meantime.
+func (r *UserRepository) Delete(ctx context.Context, id string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrInvalidID
+	}
+
+	tenant := domain.TenantID(ctx)
+	event, err := domain.NewUserEvent(domain.EventUserDeleted, tenant, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, r.d.rebind(
+			`UPDATE users SET deleted_at = ? WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`),
+			r.d.time(time.Now()), id, tenant)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return errors.Join(domain.ErrNotFound, err)
+		}
+		return insertOutbox(ctx, tx, r.d, event)
+	})
+}
+
+func (r *UserRepository) Restore(ctx context.Context, id string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrInvalidID
+	}
+
+	tenant := domain.TenantID(ctx)
+	event, err := domain.NewUserEvent(domain.EventUserRestored, tenant, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, r.d.rebind(
+			`UPDATE users SET deleted_at = NULL WHERE id = ? AND tenant_id = ? AND deleted_at IS NOT NULL`), id, tenant)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return errors.Join(domain.ErrNotFound, err)
+		}
+		return insertOutbox(ctx, tx, r.d, event)
+	})
+}
+
+// Purge permanently removes users of every tenant soft-deleted before the
+// given time.
+func (r *UserRepository) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+	rows, err := r.db.QueryContext(ctx, r.d.rebind(
+		`DELETE FROM users WHERE deleted_at < ? RETURNING id, tenant_id`), r.d.time(deletedBefore))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	purged := []domain.PurgedUser{}
+	for rows.Next() {
+		var u domain.PurgedUser
+		if err := rows.Scan(&u.ID, &u.TenantID); err != nil {
+			return nil, err
+		}
+		purged = append(purged, u)
+	}
+	return purged, rows.Err()
+}
+
+func (r *UserRepository) Erase(ctx context.Context, id string) error {
+	if !primitive.IsValidObjectID(id) {
+		return domain.ErrInvalidID
+	}
+
+	tenant := domain.TenantID(ctx)
+	event, err := domain.NewUserEvent(domain.EventUserErased, tenant, id, nil)
+	if err != nil {
+		return err
+	}
+
+	return inTx(ctx, r.db, func(tx *sql.Tx) error {
+		res, err := tx.ExecContext(ctx, r.d.rebind(`DELETE FROM users WHERE id = ? AND tenant_id = ?`), id, tenant)
+		if err != nil {
+			return err
+		}
+		if n, err := res.RowsAffected(); err != nil || n == 0 {
+			return errors.Join(domain.ErrNotFound, err)
+		}
+		_, err = tx.ExecContext(ctx, r.d.rebind(`UPDATE outbox SET data = NULL WHERE user_id = ?`), id)
+		if err != nil {
+			return err
+		}
+		return insertOutbox(ctx, tx, r.d, event)
+	})
+}
+
+func (r *UserRepository) Count(ctx context.Context) (int64, error) {
+	var n int64
+	err := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT COUNT(*) FROM users WHERE tenant_id = ? AND deleted_at IS NULL`), domain.TenantID(ctx)).Scan(&n)
+	return n, err
+}
+
+// missingOrConflict tells why an update by ID and version matched nothing.
+func (r *UserRepository) missingOrConflict(ctx context.Context, tx *sql.Tx, tenant, id string) error {
+	var n int64
+	err := tx.QueryRowContext(ctx, r.d.rebind(
+		`SELECT COUNT(*) FROM users WHERE id = ? AND tenant_id = ? AND deleted_at IS NULL`), id, tenant).Scan(&n)
+	if err != nil {
+		return err
+	}
+	if n == 0 {
+		return domain.ErrNotFound
+	}
+	return domain.ErrVersionConflict
+}
+
+func (r *UserRepository) findOne(ctx context.Context, cond string, arg any) (*domain.User, error) {
+	row := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT `+userColumns+` FROM users WHERE tenant_id = ? AND deleted_at IS NULL AND `+cond),
+		domain.TenantID(ctx), arg)
+	u, err := scanUser(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return u, err
+}
+
+// query returns the matching users without their passwords, as listings
+// never carry them.
+func (r *UserRepository) query(ctx context.Context, query string, args ...any) ([]*domain.User, error) {
+	rows, err := r.db.QueryContext(ctx, r.d.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	users := []*domain.User{}
+	for rows.Next() {
+		u, err := scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		u.Password = ""
+		users = append(users, u)
+	}
+	return users, rows.Err()
+}
+
+func (r *UserRepository) userFilter(ctx context.Context, f domain.UserFilter) (string, []any) {
+	conds := []string{"tenant_id = ?", "deleted_at IS NULL"}
+	args := []any{domain.TenantID(ctx)}
+
+	switch f.Status {
+	case "":
+	case domain.StatusActive:
+		// Users stored before statuses existed are active.
+		conds = append(conds, "status IN (?, '')")
+		args = append(args, string(f.Status))
+	default:
+		conds = append(conds, "status = ?")
+		args = append(args, string(f.Status))
+	}
+	if f.NamePrefix != "" {
+		conds = append(conds, `LOWER(name) LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(strings.ToLower(f.NamePrefix))+"%")
+	}
+	if f.EmailPrefix != "" {
+		conds = append(conds, `LOWER(email) LIKE ? ESCAPE '\'`)
+		args = append(args, escapeLike(strings.ToLower(f.EmailPrefix))+"%")
+	}
+	if !f.CreatedAfter.IsZero() {
+		conds = append(conds, "created_at >= ?")
+		args = append(args, r.d.time(f.CreatedAfter))
+	}
+	if !f.CreatedUntil.IsZero() {
+		conds = append(conds, "created_at < ?")
+		args = append(args, r.d.time(f.CreatedUntil))
+	}
+	if len(f.UserIDs) > 0 {
+		cond, ids := idIn(f.UserIDs)
+		conds = append(conds, cond)
+		args = append(args, ids...)
+	}
+
+	return strings.Join(conds, " AND "), args
+}
+
+// idIn matches the users with the given IDs.
+func idIn(ids []string) (string, []any) {
+	args := make([]any, len(ids))
+	for i, id := range ids {
+		args[i] = id
+	}
+	return "id IN (?" + strings.Repeat(", ?", len(ids)-1) + ")", args
+}
+
+// userArgs lists u's values in userColumns order.
+func (r *UserRepository) userArgs(u *domain.User) ([]any, error) {
+	var attributes sql.NullString
+	if len(u.Attributes) > 0 {
+		b, err := json.Marshal(u.Attributes)
+		if err != nil {
+			return nil, err
+		}
+		attributes = sql.NullString{String: string(b), Valid: true}
+	}
+
+	var pendingEmail, tokenHash sql.NullString
+	var expiresAt any
+	if p := u.PendingEmail; p != nil {
+		pendingEmail = sql.NullString{String: p.Email, Valid: true}
+		tokenHash = sql.NullString{String: p.TokenHash, Valid: true}
+		expiresAt = r.d.time(p.ExpiresAt)
+	}
+
+	var invitationTokenHash sql.NullString
+	var invitationExpiresAt any
+	if i := u.Invitation; i != nil {
+		invitationTokenHash = sql.NullString{String: i.TokenHash, Valid: true}
+		invitationExpiresAt = r.d.time(i.ExpiresAt)
+	}
+
+	return []any{
+		u.ID, u.Name, u.Email, u.Password,
+		r.d.time(u.CreatedAt), r.d.time(u.UpdatedAt), r.d.nullTime(u.DeletedAt), u.Version,
+		pendingEmail, tokenHash, expiresAt,
+		string(u.Status), nullString(u.StatusReason), r.d.nullTime(u.StatusChangedAt),
+		nullString(u.DisplayName), nullString(u.Locale), nullString(u.Timezone),
+		nullString(u.Phone), nullString(u.AvatarURL), attributes,
+		invitationTokenHash, invitationExpiresAt, u.TenantID,
+	}, nil
+}
+
+type rowScanner interface {
+	Scan(dest ...any) error
+}
+
+func scanUser(row rowScanner) (*domain.User, error) {
+	var (
+		u                                            domain.User
+		createdAt, updatedAt, deletedAt, changedAt   nullTime
+		expiresAt, invitationExpiresAt               nullTime
+		pendingEmail, tokenHash, reason, attributes  sql.NullString
+		invitationTokenHash                          sql.NullString
+		displayName, locale, timezone, phone, avatar sql.NullString
+		status                                       string
+	)
+	err := row.Scan(
+		&u.ID, &u.Name, &u.Email, &u.Password, &createdAt, &updatedAt, &deletedAt, &u.Version,
+		&pendingEmail, &tokenHash, &expiresAt,
+		&status, &reason, &changedAt,
+		&displayName, &locale, &timezone, &phone, &avatar, &attributes,
+		&invitationTokenHash, &invitationExpiresAt, &u.TenantID,
+	)
+	if err != nil {
+		return nil, err
+	}
+
+	u.CreatedAt = createdAt.Time
+	u.UpdatedAt = updatedAt.Time
+	u.DeletedAt = deletedAt.ptr()
+	if pendingEmail.Valid {
+		u.PendingEmail = &domain.EmailChange{
+			Email:     pendingEmail.String,
+			TokenHash: tokenHash.String,
+			ExpiresAt: expiresAt.Time,
+		}
+	}
+	if invitationTokenHash.Valid {
+		u.Invitation = &domain.Invitation{
+			TokenHash: invitationTokenHash.String,
+			ExpiresAt: invitationExpiresAt.Time,
+		}
+	}
+	u.Status = domain.UserStatus(status)
+	u.StatusReason = reason.String
+	u.StatusChangedAt = changedAt.ptr()
+	u.Profile = domain.Profile{
+		DisplayName: displayName.String,
+		Locale:      locale.String,
+		Timezone:    timezone.String,
+		Phone:       phone.String,
+		AvatarURL:   avatar.String,
+	}
+	if attributes.Valid {
+		if err := json.Unmarshal([]byte(attributes.String), &u.Attributes); err != nil {
+			return nil, err
+		}
+	}
+	return &u, nil
+}
@@ -0,0 +1,208 @@
+package sql_test
+
+import (
+	"context"
+	"database/sql"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sqladapter "github.com/yimsoijoi/7s-backend-challenge/internal/adapters/sql"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	_ "modernc.org/sqlite"
+)
+
+// openSQLite returns a migrated in-memory SQLite database.
+func openSQLite(t *testing.T) *sql.DB {
+	db, err := sql.Open("sqlite", "file::memory:")
+	if err != nil {
+		t.Fatal(err)
+	}
+	db.SetMaxOpenConns(1)
+	t.Cleanup(func() { db.Close() })
+
+	if err := sqladapter.Migrate(context.Background(), db); err != nil {
+		t.Fatal(err)
+	}
+	return db
+}
+
+func TestMigrate_Idempotent(t *testing.T) {
+	db := openSQLite(t)
+	assert.NoError(t, sqladapter.Migrate(context.Background(), db))
+
+	var n int
+	assert.NoError(t, db.QueryRow(`SELECT COUNT(*) FROM schema_migrations`).Scan(&n))
+	assert.Equal(t, 9, n)
+}
+
+func TestUserRepository_Create(t *testing.T) {
+	ctx := context.Background()
+	db := openSQLite(t)
+	repo := sqladapter.NewUserRepository(db)
+	outbox := sqladapter.NewOutboxRepository(db)
+
+	u := &domain.User{
+		Name:      "Alice",
+		Email:     "alice@test.com",
+		Password:  "hash",
+		CreatedAt: time.Now(),
+		Status:    domain.StatusActive,
+		Profile: domain.Profile{
+			Locale:     "en",
+			Attributes: map[string]interface{}{"team": "core", "tags": []interface{}{"a"}},
+		},
+	}
+	assert.NoError(t, repo.Create(ctx, u))
+	assert.Len(t, u.ID, 24)
+	assert.Equal(t, int64(1), u.Version)
+
+	stored, err := repo.FindByEmail(ctx, "alice@test.com")
+	assert.NoError(t, err)
+	assert.Equal(t, u.ID, stored.ID)
+	assert.Equal(t, "hash", stored.Password)
+	assert.Equal(t, "en", stored.Locale)
+	assert.Equal(t, map[string]interface{}{"team": "core", "tags": []interface{}{"a"}}, stored.Attributes)
+	assert.WithinDuration(t, u.CreatedAt, stored.CreatedAt, time.Microsecond)
+
+	err = repo.Create(ctx, &domain.User{Name: "Other", Email: "alice@test.com"})
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+	msg, err := outbox.Claim(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.EventUserCreated, msg.Type)
+	assert.Equal(t, u.ID, msg.UserID)
+	assert.Equal(t, 1, msg.Attempts)
+
+	// Claimed messages are leased; nothing else is due.
+	_, err = outbox.Claim(ctx, time.Minute)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	assert.NoError(t, outbox.MarkDelivered(ctx, msg.ID))
+}
+
+func TestUserRepository_FindAll(t *testing.T) {
+	ctx := context.Background()
+	repo := sqladapter.NewUserRepository(openSQLite(t))
+
+	base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+	for i, name := range []string{"Carol", "alice", "Bob", "Dave"} {
+		assert.NoError(t, repo.Create(ctx, &domain.User{
+			Name:      name,
+			Email:     fmt.Sprintf("%s@test.com", name),
+			Password:  "hash",
+			CreatedAt: base.Add(time.Duration(i) * time.Hour),
+		}))
+	}
+
+	t.Run("pages by cursor without passwords", func(t *testing.T) {
+		q := domain.UserQuery{SortBy: domain.SortByName, Order: domain.SortAsc, Limit: 2}
+
+		page, err := repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(4), page.Total)
+		assert.Equal(t, []string{"Bob", "Carol"}, names(page))
+		assert.Empty(t, page.Users[0].Password)
+
+		q.Cursor = page.NextCursor
+		page, err = repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Dave", "alice"}, names(page))
+		assert.Empty(t, page.NextCursor)
+	})
+
+	t.Run("newest first by created_at cursor", func(t *testing.T) {
+		q := domain.UserQuery{Limit: 3}
+		assert.NoError(t, q.Normalize())
+
+		page, err := repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Dave", "Bob", "alice"}, names(page))
+
+		q.Cursor = page.NextCursor
+		page, err = repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Carol"}, names(page))
+	})
+
+	t.Run("filters", func(t *testing.T) {
+		q := domain.UserQuery{Filter: domain.UserFilter{
+			NamePrefix:   "A",
+			CreatedUntil: base.Add(2 * time.Hour),
+		}}
+		assert.NoError(t, q.Normalize())
+
+		page, err := repo.FindAll(ctx, q)
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, names(page))
+		assert.Equal(t, int64(1), page.Total)
+	})
+
+	t.Run("search", func(t *testing.T) {
+		page, err := repo.Search(ctx, domain.UserSearch{Text: "AL", Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alice"}, names(page))
+	})
+}
+
+func TestUserRepository_Update(t *testing.T) {
+	ctx := context.Background()
+	repo := sqladapter.NewUserRepository(openSQLite(t))
+	u := &domain.User{Name: "Alice", Email: "alice@test.com", Password: "hash"}
+	assert.NoError(t, repo.Create(ctx, u))
+	assert.NoError(t, repo.Create(ctx, &domain.User{Name: "Bob", Email: "bob@test.com"}))
+
+	stale := *u
+
+	u.Name = "Alicia"
+	u.Password = "ignored"
+	u.PendingEmail = &domain.EmailChange{Email: "new@test.com", TokenHash: "t", ExpiresAt: time.Now()}
+	assert.NoError(t, repo.Update(ctx, u))
+	assert.Equal(t, int64(2), u.Version)
+
+	stored, err := repo.FindByEmailChangeToken(ctx, "t")
+	assert.NoError(t, err)
+	assert.Equal(t, "Alicia", stored.Name)
+	assert.Equal(t, "hash", stored.Password)
+	assert.Equal(t, "new@test.com", stored.PendingEmail.Email)
+
+	assert.ErrorIs(t, repo.Update(ctx, &stale), domain.ErrVersionConflict)
+
+	u.Email = "bob@test.com"
+	assert.ErrorIs(t, repo.Update(ctx, u), domain.ErrEmailTaken)
+}
+
+func TestUserRepository_DeleteRestorePurge(t *testing.T) {
+	ctx := context.Background()
+	repo := sqladapter.NewUserRepository(openSQLite(t))
+	u := &domain.User{Name: "Alice", Email: "alice@test.com"}
+	assert.NoError(t, repo.Create(ctx, u))
+
+	assert.ErrorIs(t, repo.Restore(ctx, u.ID), domain.ErrNotFound)
+
+	assert.NoError(t, repo.Delete(ctx, u.ID))
+	_, err := repo.FindByID(ctx, u.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	count, err := repo.Count(ctx)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(0), count)
+
+	assert.NoError(t, repo.Restore(ctx, u.ID))
+	_, err = repo.FindByID(ctx, u.ID)
+	assert.NoError(t, err)
+
+	assert.NoError(t, repo.Delete(ctx, u.ID))
+	purged, err := repo.Purge(ctx, time.Now().Add(time.Minute))
+	assert.NoError(t, err)
+	assert.Equal(t, []domain.PurgedUser{{ID: u.ID, TenantID: domain.DefaultTenant}}, purged)
+}
+
+func names(page *domain.UserPage) []string {
+	out := []string{}
+	for _, u := range page.Users {
+		out = append(out, u.Name)
+	}
+	return out
+}
@@ -0,0 +1,329 @@
+package sql
+
+import (
+	"context"
+	"database/sql"
+	"encoding/json"
+	"errors"
+	"strings"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+type WebhookRepository struct {
+	db *sql.DB
+	d  dialect
+}
+
+func NewWebhookRepository(db *sql.DB) ports.WebhookRepository {
+	return &WebhookRepository{db: db, d: dialectOf(db)}
+}
+
+const (
+	subscriptionColumns = `id, tenant_id, url, events, secret, active, created_at, updated_at`
+	deliveryColumns     = `id, tenant_id, subscription_id, event_id, event_type, payload, status, tries, attempts,
+		next_attempt_at, created_at, delivered_at`
+)
+
+// attemptRecord is how an attempt is kept in the attempts JSON column.
+type attemptRecord struct {
+	At         time.Time `json:"at"`
+	StatusCode int       `json:"status_code,omitempty"`
+	Error      string    `json:"error,omitempty"`
+	DurationMS int64     `json:"duration_ms"`
+}
+
+func (r *WebhookRepository) CreateSubscription(ctx context.Context, s *domain.WebhookSubscription) error {
+	id := primitive.NewObjectID().Hex()
+	tenant := domain.TenantID(ctx)
+
+	events := make([]string, 0, len(s.EventTypes))
+	for _, t := range s.EventTypes {
+		events = append(events, string(t))
+	}
+
+	_, err := r.db.ExecContext(ctx, r.d.rebind(`INSERT INTO webhook_subscriptions (`+subscriptionColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?)`),
+		id, tenant, s.URL, strings.Join(events, ","), s.Secret, s.Active, r.d.time(s.CreatedAt), r.d.time(s.UpdatedAt))
+	if err != nil {
+		return err
+	}
+
+	s.ID = id
+	s.TenantID = tenant
+	return nil
+}
+
+func (r *WebhookRepository) FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	row := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE id = ? AND tenant_id = ?`),
+		id, domain.TenantID(ctx))
+	s, err := scanSubscription(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return s, err
+}
+
+func (r *WebhookRepository) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	rows, err := r.db.QueryContext(ctx, r.d.rebind(
+		`SELECT `+subscriptionColumns+` FROM webhook_subscriptions WHERE tenant_id = ? ORDER BY id`),
+		domain.TenantID(ctx))
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	subs := []*domain.WebhookSubscription{}
+	for rows.Next() {
+		s, err := scanSubscription(rows)
+		if err != nil {
+			return nil, err
+		}
+		subs = append(subs, s)
+	}
+	return subs, rows.Err()
+}
+
+func (r *WebhookRepository) DeleteSubscription(ctx context.Context, id string) error {
+	res, err := r.db.ExecContext(ctx, r.d.rebind(
+		`DELETE FROM webhook_subscriptions WHERE id = ? AND tenant_id = ?`), id, domain.TenantID(ctx))
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Join(domain.ErrNotFound, err)
+	}
+	return nil
+}
+
+func (r *WebhookRepository) CreateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	id := primitive.NewObjectID().Hex()
+	args, err := r.deliveryArgs(id, d)
+	if err != nil {
+		return err
+	}
+
+	_, err = r.db.ExecContext(ctx, r.d.rebind(`INSERT INTO webhook_deliveries (`+deliveryColumns+`)
+		VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)`), args...)
+	if isUniqueViolation(err) {
+		return domain.ErrAlreadyExists
+	}
+	if err != nil {
+		return err
+	}
+
+	d.ID = id
+	return nil
+}
+
+func (r *WebhookRepository) FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	row := r.db.QueryRowContext(ctx, r.d.rebind(
+		`SELECT `+deliveryColumns+` FROM webhook_deliveries WHERE id = ? AND tenant_id = ?`),
+		id, domain.TenantID(ctx))
+	d, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return d, err
+}
+
+func (r *WebhookRepository) ListDeliveries(
+	ctx context.Context,
+	q domain.WebhookDeliveryQuery,
+) ([]*domain.WebhookDelivery, error) {
+	conds := []string{"tenant_id = ?"}
+	args := []any{domain.TenantID(ctx)}
+	if q.SubscriptionID != "" {
+		conds = append(conds, "subscription_id = ?")
+		args = append(args, q.SubscriptionID)
+	}
+	if q.Status != "" {
+		conds = append(conds, "status = ?")
+		args = append(args, string(q.Status))
+	}
+
+	rows, err := r.db.QueryContext(ctx, r.d.rebind(`SELECT `+deliveryColumns+` FROM webhook_deliveries
+		WHERE `+strings.Join(conds, " AND ")+` ORDER BY id DESC LIMIT ? OFFSET ?`),
+		append(args, q.Limit, q.Offset)...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	deliveries := []*domain.WebhookDelivery{}
+	for rows.Next() {
+		d, err := scanDelivery(rows)
+		if err != nil {
+			return nil, err
+		}
+		deliveries = append(deliveries, d)
+	}
+	return deliveries, rows.Err()
+}
+
+// ClaimDelivery pushes the next attempt past the lease. The due condition
+// is checked again by the update itself, so concurrent dispatchers never
+// share a delivery.
+func (r *WebhookRepository) ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	now := time.Now()
+
+	row := r.db.QueryRowContext(ctx, r.d.rebind(`UPDATE webhook_deliveries SET next_attempt_at = ?
+		WHERE id = (
+			SELECT id FROM webhook_deliveries WHERE status = ? AND next_attempt_at <= ?
+			ORDER BY next_attempt_at, id LIMIT 1
+		) AND status = ? AND next_attempt_at <= ?
+		RETURNING `+deliveryColumns),
+		r.d.time(now.Add(lease)),
+		string(domain.DeliveryPending), r.d.time(now),
+		string(domain.DeliveryPending), r.d.time(now))
+	d, err := scanDelivery(row)
+	if errors.Is(err, sql.ErrNoRows) {
+		return nil, domain.ErrNotFound
+	}
+	return d, err
+}
+
+// RedactDeliveries finds the deliveries by searching payloads for the
+// user's ID, as deliveries do not keep it in a column.
+func (r *WebhookRepository) RedactDeliveries(ctx context.Context, userID string) (int64, error) {
+	var n int64
+	err := inTx(ctx, r.db, func(tx *sql.Tx) error {
+		rows, err := tx.QueryContext(ctx, r.d.rebind(
+			`SELECT id, payload FROM webhook_deliveries WHERE tenant_id = ? AND payload LIKE ? ESCAPE '\'`),
+			domain.TenantID(ctx), "%"+escapeLike(domain.EventUserField(userID))+"%")
+		if err != nil {
+			return err
+		}
+		redacted := map[string]string{}
+		for rows.Next() {
+			var id, payload string
+			if err := rows.Scan(&id, &payload); err != nil {
+				rows.Close()
+				return err
+			}
+			stripped, err := domain.WithoutData([]byte(payload))
+			if err != nil {
+				rows.Close()
+				return err
+			}
+			if string(stripped) != payload {
+				redacted[id] = string(stripped)
+			}
+		}
+		rows.Close()
+		if err := rows.Err(); err != nil {
+			return err
+		}
+
+		for id, payload := range redacted {
+			_, err := tx.ExecContext(ctx, r.d.rebind(
+				`UPDATE webhook_deliveries SET payload = ? WHERE id = ?`), payload, id)
+			if err != nil {
+				return err
+			}
+		}
+		n = int64(len(redacted))
+		return nil
+	})
+	return n, err
+}
+
+func (r *WebhookRepository) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	args, err := r.deliveryArgs(d.ID, d)
+	if err != nil {
+		return err
+	}
+
+	res, err := r.db.ExecContext(ctx, r.d.rebind(`UPDATE webhook_deliveries SET
+			subscription_id = ?, event_id = ?, event_type = ?, payload = ?, status = ?, tries = ?,
+			attempts = ?, next_attempt_at = ?, created_at = ?, delivered_at = ?
+		WHERE id = ?`), append(args[2:], d.ID)...)
+	if err != nil {
+		return err
+	}
+	if n, err := res.RowsAffected(); err != nil || n == 0 {
+		return errors.Join(domain.ErrNotFound, err)
+	}
+	return nil
+}
+
+// deliveryArgs lists d's values in deliveryColumns order.
+func (r *WebhookRepository) deliveryArgs(id string, d *domain.WebhookDelivery) ([]any, error) {
+	attempts := make([]attemptRecord, 0, len(d.Attempts))
+	for _, a := range d.Attempts {
+		attempts = append(attempts, attemptRecord{
+			At:         a.At.UTC(),
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			DurationMS: a.Duration.Milliseconds(),
+		})
+	}
+	b, err := json.Marshal(attempts)
+	if err != nil {
+		return nil, err
+	}
+
+	return []any{
+		id, d.TenantID, d.SubscriptionID, d.EventID, string(d.EventType), string(d.Payload), string(d.Status), d.Tries,
+		string(b), r.d.time(d.NextAttemptAt), r.d.time(d.CreatedAt), r.d.nullTime(d.DeliveredAt),
+	}, nil
+}
+
+func scanSubscription(row rowScanner) (*domain.WebhookSubscription, error) {
+	var (
+		s                    domain.WebhookSubscription
+		events               string
+		createdAt, updatedAt nullTime
+	)
+	if err := row.Scan(&s.ID, &s.TenantID, &s.URL, &events, &s.Secret, &s.Active, &createdAt, &updatedAt); err != nil {
+		return nil, err
+	}
+
+	for _, t := range strings.Split(events, ",") {
+		if t != "" {
+			s.EventTypes = append(s.EventTypes, domain.EventType(t))
+		}
+	}
+	s.CreatedAt = createdAt.Time
+	s.UpdatedAt = updatedAt.Time
+	return &s, nil
+}
+
+func scanDelivery(row rowScanner) (*domain.WebhookDelivery, error) {
+	var (
+		d                                   domain.WebhookDelivery
+		eventType, payload, status, records string
+		nextAt, createdAt, deliveredAt      nullTime
+	)
+	err := row.Scan(&d.ID, &d.TenantID, &d.SubscriptionID, &d.EventID, &eventType, &payload, &status, &d.Tries,
+		&records, &nextAt, &createdAt, &deliveredAt)
+	if err != nil {
+		return nil, err
+	}
+
+	var attempts []attemptRecord
+	if err := json.Unmarshal([]byte(records), &attempts); err != nil {
+		return nil, err
+	}
+	d.Attempts = []domain.WebhookAttempt{}
+	for _, a := range attempts {
+		d.Attempts = append(d.Attempts, domain.WebhookAttempt{
+			At:         a.At,
+			StatusCode: a.StatusCode,
+			Error:      a.Error,
+			Duration:   time.Duration(a.DurationMS) * time.Millisecond,
+		})
+	}
+
+	d.EventType = domain.EventType(eventType)
+	d.Payload = []byte(payload)
+	d.Status = domain.WebhookDeliveryStatus(status)
+	d.NextAttemptAt = nextAt.Time
+	d.CreatedAt = createdAt.Time
+	d.DeliveredAt = deliveredAt.ptr()
+	return &d, nil
+}
@@ -0,0 +1,77 @@
+package sql_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	sqladapter "github.com/yimsoijoi/7s-backend-challenge/internal/adapters/sql"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestWebhookRepository_Deliveries(t *testing.T) {
+	ctx := context.Background()
+	repo := sqladapter.NewWebhookRepository(openSQLite(t))
+
+	sub := &domain.WebhookSubscription{
+		URL:        "https://example.com/hook",
+		EventTypes: []domain.EventType{domain.EventUserCreated, domain.EventUserDeleted},
+		Secret:     "s",
+		Active:     true,
+	}
+	assert.NoError(t, repo.CreateSubscription(ctx, sub))
+
+	found, err := repo.FindSubscription(ctx, sub.ID)
+	assert.NoError(t, err)
+	assert.True(t, found.Active)
+	assert.Equal(t, domain.DefaultTenant, found.TenantID)
+	assert.Equal(t, sub.EventTypes, found.EventTypes)
+
+	d := &domain.WebhookDelivery{
+		TenantID:       sub.TenantID,
+		SubscriptionID: sub.ID,
+		EventID:        "e1",
+		Payload:        []byte(`{}`),
+		Status:         domain.DeliveryPending,
+		NextAttemptAt:  time.Now(),
+	}
+	assert.NoError(t, repo.CreateDelivery(ctx, d))
+
+	dup := *d
+	assert.ErrorIs(t, repo.CreateDelivery(ctx, &dup), domain.ErrAlreadyExists)
+
+	claimed, err := repo.ClaimDelivery(ctx, time.Minute)
+	assert.NoError(t, err)
+	assert.Equal(t, d.ID, claimed.ID)
+
+	_, err = repo.ClaimDelivery(ctx, time.Minute)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	claimed.Tries++
+	claimed.Attempts = append(claimed.Attempts, domain.WebhookAttempt{
+		At:         time.Now(),
+		StatusCode: 500,
+		Duration:   1500 * time.Millisecond,
+	})
+	assert.NoError(t, repo.UpdateDelivery(ctx, claimed))
+
+	list, err := repo.ListDeliveries(ctx, domain.WebhookDeliveryQuery{SubscriptionID: sub.ID, Limit: 10})
+	assert.NoError(t, err)
+	if assert.Len(t, list, 1) && assert.Len(t, list[0].Attempts, 1) {
+		assert.Equal(t, 1, list[0].Tries)
+		assert.Equal(t, 500, list[0].Attempts[0].StatusCode)
+		assert.Equal(t, 1500*time.Millisecond, list[0].Attempts[0].Duration)
+	}
+
+	other := domain.WithTenant(ctx, "globex")
+	_, err = repo.FindSubscription(other, sub.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	list, err = repo.ListDeliveries(other, domain.WebhookDeliveryQuery{SubscriptionID: sub.ID, Limit: 10})
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+	assert.ErrorIs(t, repo.DeleteSubscription(other, sub.ID), domain.ErrNotFound)
+
+	assert.NoError(t, repo.DeleteSubscription(ctx, sub.ID))
+	assert.ErrorIs(t, repo.DeleteSubscription(ctx, sub.ID), domain.ErrNotFound)
+}
@@ -0,0 +1,170 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"maps"
+	"slices"
+	"strconv"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+const (
+	// auditAppendAttempts bounds retries when concurrent writers race for
+	// the same sequence number.
+	auditAppendAttempts = 10
+	auditVerifyBatch    = 500
+)
+
+type auditService struct {
+	repo ports.AuditRepository
+}
+
+func NewAuditService(r ports.AuditRepository) ports.AuditService {
+	return &auditService{repo: r}
+}
+
+func (s *auditService) Record(ctx context.Context, e domain.AuditEvent) error {
+	meta := domain.RequestMetaFrom(ctx)
+	if e.ActorID == "" {
+		e.ActorID = meta.ActorID
+	}
+	if e.IP == "" {
+		e.IP = meta.IP
+	}
+	if e.RequestID == "" {
+		e.RequestID = meta.RequestID
+	}
+	e.TenantID = domain.TenantID(ctx)
+	e.Timestamp = time.Now().UTC().Truncate(time.Millisecond)
+
+	for range auditAppendAttempts {
+		last, err := s.repo.Last(ctx)
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+			e.Seq, e.PrevHash = 1, ""
+		case err != nil:
+			return err
+		default:
+			e.Seq, e.PrevHash = last.Seq+1, last.ChainHash()
+		}
+		e.Hash = e.ComputeHash()
+
+		err = s.repo.Append(ctx, &e)
+		if !errors.Is(err, domain.ErrAlreadyExists) {
+			return err
+		}
+	}
+
+	return fmt.Errorf("append audit event: too much contention")
+}
+
+// recordAudit appends e to audit after the change it records has been
+// made, so a failure is logged instead of failing the request.
+func recordAudit(ctx context.Context, audit ports.AuditService, e domain.AuditEvent) {
+	if err := audit.Record(ctx, e); err != nil {
+		log.Printf("audit %s %s: %v", e.Action, e.TargetID, err)
+	}
+}
+
+func (s *auditService) Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error) {
+	if err := q.Normalize(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Find(ctx, q)
+}
+
+func (s *auditService) Redact(ctx context.Context, userID, pseudonym string) (int64, error) {
+	redacted, err := s.repo.Redact(ctx, userID, pseudonym)
+	if err != nil || len(redacted) == 0 {
+		return 0, err
+	}
+	if err := s.Record(ctx, domain.RedactionRecord(pseudonym, redacted)); err != nil {
+		return 0, err
+	}
+	return int64(len(redacted)), nil
+}
+
+func (s *auditService) Verify(ctx context.Context) (*domain.AuditVerification, error) {
+	result := &domain.AuditVerification{Valid: true}
+
+	// The hashes of the redacted events, and the ones the audit.redacted
+	// events vouch for, by sequence number.
+	redacted := map[int64]string{}
+	vouched := map[int64]string{}
+
+	var prev *domain.AuditEvent
+	for {
+		events, err := s.repo.Find(ctx, domain.AuditQuery{
+			AfterSeq: result.Checked,
+			Limit:    auditVerifyBatch,
+		})
+		if err != nil {
+			return nil, err
+		}
+
+		for _, e := range events {
+			if problem := verifyLink(prev, e); problem != "" {
+				result.Valid = false
+				result.BrokenAt = e.Seq
+				result.Problem = problem
+				return result, nil
+			}
+			result.Checked = e.Seq
+			if e.Redacted {
+				result.Redacted++
+				redacted[e.Seq] = e.Hash
+			}
+			if e.Action == domain.AuditEventsRedacted {
+				for key, change := range e.Changes {
+					seq, err := strconv.ParseInt(key, 10, 64)
+					hash, ok := change.After.(string)
+					if err != nil || !ok {
+						continue
+					}
+					vouched[seq] = hash
+				}
+			}
+			prev = e
+		}
+
+		if len(events) < auditVerifyBatch {
+			break
+		}
+	}
+
+	unvouched := slices.Sorted(maps.Keys(redacted))
+	unvouched = slices.DeleteFunc(unvouched, func(seq int64) bool { return vouched[seq] == redacted[seq] })
+	if len(unvouched) > 0 {
+		result.Valid = false
+		result.BrokenAt = unvouched[0]
+		result.Checked = unvouched[0] - 1
+		result.Problem = "redaction was not recorded"
+	}
+	return result, nil
+}
+
+// verifyLink checks e against the event before it. A redacted event links
+// to the next one with the hash it was recorded with.
+func verifyLink(prev, e *domain.AuditEvent) string {
+	wantSeq, wantPrev := int64(1), ""
+	if prev != nil {
+		wantSeq, wantPrev = prev.Seq+1, prev.ChainHash()
+	}
+
+	switch {
+	case e.Seq != wantSeq:
+		return fmt.Sprintf("expected event %d", wantSeq)
+	case e.PrevHash != wantPrev:
+		return "previous hash does not match"
+	case e.Hash != e.ComputeHash():
+		return "event was modified"
+	}
+	return ""
+}
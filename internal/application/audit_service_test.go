@@ -0,0 +1,212 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestAuditService_Record_ChainsEvents(t *testing.T) {
+	repo := &mocks.AuditRepositoryMock{}
+	svc := application.NewAuditService(repo)
+
+	ctx := domain.WithRequestMeta(context.Background(), domain.RequestMeta{
+		ActorID:   "admin",
+		IP:        "10.0.0.1",
+		RequestID: "req-1",
+	})
+
+	assert.NoError(t, svc.Record(ctx, domain.AuditEvent{Action: domain.AuditUserDeleted, TargetID: "u1"}))
+	assert.NoError(t, svc.Record(ctx, domain.AuditEvent{Action: domain.AuditUserRestored, TargetID: "u1"}))
+
+	assert.Len(t, repo.Events, 2)
+	first, second := repo.Events[0], repo.Events[1]
+	assert.Equal(t, int64(1), first.Seq)
+	assert.Empty(t, first.PrevHash)
+	assert.Equal(t, "admin", first.ActorID)
+	assert.Equal(t, "10.0.0.1", first.IP)
+	assert.Equal(t, "req-1", first.RequestID)
+	assert.Equal(t, int64(2), second.Seq)
+	assert.Equal(t, first.Hash, second.PrevHash)
+
+	result, err := svc.Verify(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(2), result.Checked)
+}
+
+func TestAuditService_Record_ChainsPerTenant(t *testing.T) {
+	repo := &mocks.AuditRepositoryMock{}
+	svc := application.NewAuditService(repo)
+	acme := domain.WithTenant(context.Background(), "acme")
+	globex := domain.WithTenant(context.Background(), "globex")
+
+	assert.NoError(t, svc.Record(acme, domain.AuditEvent{Action: domain.AuditUserDeleted, TargetID: "u1"}))
+	assert.NoError(t, svc.Record(globex, domain.AuditEvent{Action: domain.AuditUserDeleted, TargetID: "u2"}))
+	assert.NoError(t, svc.Record(acme, domain.AuditEvent{Action: domain.AuditUserRestored, TargetID: "u1"}))
+
+	assert.Equal(t, "acme", repo.Events[0].TenantID)
+	assert.Equal(t, int64(1), repo.Events[1].Seq, "globex numbers its chain from 1")
+	assert.Empty(t, repo.Events[1].PrevHash)
+	assert.Equal(t, int64(2), repo.Events[2].Seq)
+	assert.Equal(t, repo.Events[0].Hash, repo.Events[2].PrevHash)
+
+	events, err := svc.Find(globex, domain.AuditQuery{})
+	assert.NoError(t, err)
+	if assert.Len(t, events, 1) {
+		assert.Equal(t, "u2", events[0].TargetID)
+	}
+
+	result, err := svc.Verify(acme)
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(2), result.Checked)
+}
+
+func TestAuditService_Record_RetriesOnRace(t *testing.T) {
+	repo := &mocks.AuditRepositoryMock{}
+	raced := false
+	repo.AppendFn = func(ctx context.Context, e *domain.AuditEvent) error {
+		if !raced {
+			// Another writer takes this position first.
+			raced = true
+			other := domain.AuditEvent{Seq: e.Seq, TenantID: e.TenantID, Action: domain.AuditLoginFailed}
+			other.Hash = other.ComputeHash()
+			repo.Events = append(repo.Events, &other)
+		}
+		return nil
+	}
+	svc := application.NewAuditService(repo)
+
+	err := svc.Record(context.Background(), domain.AuditEvent{Action: domain.AuditUserDeleted})
+
+	assert.NoError(t, err)
+	assert.Len(t, repo.Events, 2)
+	assert.Equal(t, int64(2), repo.Events[1].Seq)
+	assert.Equal(t, repo.Events[0].Hash, repo.Events[1].PrevHash)
+}
+
+func TestAuditService_Verify_DetectsTampering(t *testing.T) {
+	repo := &mocks.AuditRepositoryMock{}
+	svc := application.NewAuditService(repo)
+
+	for _, target := range []string{"u1", "u2", "u3"} {
+		assert.NoError(t, svc.Record(context.Background(), domain.AuditEvent{
+			Action:   domain.AuditUserDeleted,
+			TargetID: target,
+		}))
+	}
+
+	repo.Events[1].TargetID = "someone-else"
+
+	result, err := svc.Verify(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(2), result.BrokenAt)
+	assert.Equal(t, int64(1), result.Checked)
+}
+
+func TestAuditService_Verify_DetectsRemovedEvent(t *testing.T) {
+	repo := &mocks.AuditRepositoryMock{}
+	svc := application.NewAuditService(repo)
+
+	for range 3 {
+		assert.NoError(t, svc.Record(context.Background(), domain.AuditEvent{Action: domain.AuditLoginFailed}))
+	}
+	repo.Events = append(repo.Events[:1], repo.Events[2:]...)
+
+	result, err := svc.Verify(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), result.BrokenAt)
+}
+
+// recordAndRedact records three events, two of them about u1, and erases
+// u1 from them.
+func recordAndRedact(t *testing.T) (*mocks.AuditRepositoryMock, ports.AuditService) {
+	repo := &mocks.AuditRepositoryMock{}
+	svc := application.NewAuditService(repo)
+
+	for _, target := range []string{"u1", "u2", "u1"} {
+		assert.NoError(t, svc.Record(context.Background(), domain.AuditEvent{
+			Action:   domain.AuditUserDeleted,
+			TargetID: target,
+			Reason:   "spam",
+		}))
+	}
+
+	n, err := svc.Redact(context.Background(), "u1", "erased-1")
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	return repo, svc
+}
+
+func TestAuditService_Redact_RecordsTheNewHashes(t *testing.T) {
+	repo, svc := recordAndRedact(t)
+
+	assert.Equal(t, "erased-1", repo.Events[0].TargetID)
+	assert.Empty(t, repo.Events[0].Reason)
+	if assert.Len(t, repo.Events, 4) {
+		record := repo.Events[3]
+		assert.Equal(t, domain.AuditEventsRedacted, record.Action)
+		assert.Equal(t, "erased-1", record.TargetID)
+		assert.Equal(t, map[string]domain.AuditChange{
+			"1": {Before: repo.Events[0].OriginalHash, After: repo.Events[0].Hash},
+			"3": {Before: repo.Events[2].OriginalHash, After: repo.Events[2].Hash},
+		}, record.Changes)
+	}
+
+	result, err := svc.Verify(context.Background())
+
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+	assert.Equal(t, int64(4), result.Checked)
+	assert.Equal(t, int64(2), result.Redacted)
+}
+
+func TestAuditService_Verify_DetectsTamperingWithRedactedEvents(t *testing.T) {
+	repo, svc := recordAndRedact(t)
+	repo.Events[2].Reason = "made up"
+
+	result, err := svc.Verify(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), result.BrokenAt)
+	assert.Equal(t, "event was modified", result.Problem)
+
+	// Rehashing the edit is caught by the audit.redacted record.
+	repo.Events[2].Hash = repo.Events[2].ComputeHash()
+
+	result, err = svc.Verify(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(3), result.BrokenAt)
+	assert.Equal(t, "redaction was not recorded", result.Problem)
+}
+
+func TestAuditService_Verify_DetectsUnrecordedRedaction(t *testing.T) {
+	repo := &mocks.AuditRepositoryMock{}
+	svc := application.NewAuditService(repo)
+	for _, target := range []string{"u1", "u2"} {
+		assert.NoError(t, svc.Record(context.Background(), domain.AuditEvent{Action: domain.AuditUserDeleted, TargetID: target}))
+	}
+
+	_, err := repo.Redact(context.Background(), "u2", "erased-1")
+	assert.NoError(t, err)
+
+	result, err := svc.Verify(context.Background())
+
+	assert.NoError(t, err)
+	assert.False(t, result.Valid)
+	assert.Equal(t, int64(2), result.BrokenAt)
+	assert.Equal(t, int64(1), result.Checked)
+}
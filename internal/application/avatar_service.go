@@ -0,0 +1,114 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"image"
+	_ "image/gif"
+	_ "image/jpeg"
+	"image/png"
+	"io"
+	"net/url"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"golang.org/x/image/draw"
+)
+
+type avatarService struct {
+	repo    ports.UserRepository
+	store   ports.BlobStore
+	audit   ports.AuditService
+	baseURL string
+}
+
+// NewAvatarService stores avatars in store and records the users' changed
+// avatar URLs in audit. baseURL prefixes the avatar URL saved on the user;
+// when empty the URL is relative to the API.
+func NewAvatarService(r ports.UserRepository, store ports.BlobStore, audit ports.AuditService, baseURL string) ports.AvatarService {
+	return &avatarService{repo: r, store: store, audit: audit, baseURL: baseURL}
+}
+
+func (s *avatarService) Upload(ctx context.Context, userID string, r io.Reader) (*domain.User, error) {
+	data, err := io.ReadAll(io.LimitReader(r, domain.MaxAvatarBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(data) > domain.MaxAvatarBytes {
+		return nil, fmt.Errorf("%w: larger than %d bytes", domain.ErrInvalidAvatar, domain.MaxAvatarBytes)
+	}
+
+	// Check dimensions before decoding so a tiny file cannot expand into a huge bitmap.
+	cfg, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: must be a PNG, JPEG or GIF image", domain.ErrInvalidAvatar)
+	}
+	if cfg.Width > domain.MaxAvatarPixels || cfg.Height > domain.MaxAvatarPixels {
+		return nil, fmt.Errorf("%w: larger than %dx%d pixels", domain.ErrInvalidAvatar, domain.MaxAvatarPixels, domain.MaxAvatarPixels)
+	}
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("%w: %v", domain.ErrInvalidAvatar, err)
+	}
+
+	user, err := s.repo.FindByID(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	original, err := s.store.Put(ctx, domain.AvatarKey(userID, domain.AvatarOriginal), "image/"+format, data)
+	if err != nil {
+		return nil, err
+	}
+
+	for size, px := range domain.AvatarThumbnails {
+		var buf bytes.Buffer
+		if err := png.Encode(&buf, thumbnail(img, px)); err != nil {
+			return nil, err
+		}
+		if _, err := s.store.Put(ctx, domain.AvatarKey(userID, size), "image/png", buf.Bytes()); err != nil {
+			return nil, err
+		}
+	}
+
+	// Avatars are fetched without credentials or tenant headers, so the
+	// URL names the tenant. The version query changes with the content so
+	// clients can cache aggressively.
+	before := *user
+	user.AvatarURL = fmt.Sprintf("%s/users/%s/avatar?tenant=%s&v=%.12s",
+		s.baseURL, userID, url.QueryEscape(domain.TenantID(ctx)), original.ETag)
+	user.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	if changes := domain.AuditDiff(&before, user); changes != nil {
+		recordAudit(ctx, s.audit, domain.AuditEvent{Action: domain.AuditUserUpdated, TargetID: userID, Changes: changes})
+	}
+	return user, nil
+}
+
+// Open serves only the avatars of users the repository finds, so deleted
+// users and those of other tenants have none.
+func (s *avatarService) Open(ctx context.Context, userID string, size domain.AvatarSize) (io.ReadCloser, *ports.BlobInfo, error) {
+	if _, err := s.repo.FindByID(ctx, userID); err != nil {
+		return nil, nil, err
+	}
+	return s.store.Get(ctx, domain.AvatarKey(userID, size))
+}
+
+// thumbnail center-crops img to a square and scales it to px*px.
+func thumbnail(img image.Image, px int) image.Image {
+	b := img.Bounds()
+	side := min(b.Dx(), b.Dy())
+	crop := image.Rect(0, 0, side, side).Add(image.Pt(
+		b.Min.X+(b.Dx()-side)/2,
+		b.Min.Y+(b.Dy()-side)/2,
+	))
+
+	dst := image.NewRGBA(image.Rect(0, 0, px, px))
+	draw.CatmullRom.Scale(dst, dst.Bounds(), img, crop, draw.Src, nil)
+	return dst
+}
@@ -0,0 +1,101 @@
+package application_test
+
+import (
+	"bytes"
+	"context"
+	"image"
+	"image/color"
+	"image/png"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestAvatarService_Upload(t *testing.T) {
+	src := image.NewRGBA(image.Rect(0, 0, 400, 300))
+	src.Set(10, 10, color.White)
+	var buf bytes.Buffer
+	assert.NoError(t, png.Encode(&buf, src))
+
+	var saved *domain.User
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id}, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			saved = user
+			return nil
+		},
+	}
+	store := &mocks.BlobStoreMock{}
+	var recorded []domain.AuditEvent
+	audit := &mocks.AuditServiceMock{RecordFn: func(ctx context.Context, e domain.AuditEvent) error {
+		recorded = append(recorded, e)
+		return nil
+	}}
+
+	svc := application.NewAvatarService(repo, store, audit, "https://api.example.com")
+
+	user, err := svc.Upload(context.Background(), "u1", &buf)
+
+	assert.NoError(t, err)
+	assert.Equal(t, user, saved)
+	if assert.Len(t, recorded, 1) {
+		assert.Equal(t, domain.AuditUserUpdated, recorded[0].Action)
+		assert.Equal(t, "u1", recorded[0].TargetID)
+		assert.Equal(t, user.AvatarURL, recorded[0].Changes["avatar_url"].After)
+	}
+	assert.True(t, strings.HasPrefix(user.AvatarURL, "https://api.example.com/users/u1/avatar?tenant=default&v="))
+	assert.Equal(t, "image/png", store.Infos[domain.AvatarKey("u1", domain.AvatarOriginal)].ContentType)
+
+	thumb, err := png.DecodeConfig(bytes.NewReader(store.Blobs[domain.AvatarKey("u1", domain.AvatarSmall)]))
+	assert.NoError(t, err)
+	assert.Equal(t, 64, thumb.Width)
+	assert.Equal(t, 64, thumb.Height)
+}
+
+func TestAvatarService_Upload_RejectsNonImage(t *testing.T) {
+	svc := application.NewAvatarService(&mocks.UserRepositoryMock{}, &mocks.BlobStoreMock{}, &mocks.AuditServiceMock{}, "")
+
+	_, err := svc.Upload(context.Background(), "u1", strings.NewReader("<svg></svg>"))
+
+	assert.ErrorIs(t, err, domain.ErrInvalidAvatar)
+}
+
+func TestAvatarService_Upload_RejectsTooLarge(t *testing.T) {
+	svc := application.NewAvatarService(&mocks.UserRepositoryMock{}, &mocks.BlobStoreMock{}, &mocks.AuditServiceMock{}, "")
+
+	big := bytes.NewReader(make([]byte, domain.MaxAvatarBytes+1))
+	_, err := svc.Upload(context.Background(), "u1", big)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidAvatar)
+}
+
+func TestAvatarService_Open_DeletedUser(t *testing.T) {
+	deleted := map[string]bool{"u2": true}
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			if deleted[id] {
+				return nil, domain.ErrNotFound
+			}
+			return &domain.User{ID: id}, nil
+		},
+	}
+	store := &mocks.BlobStoreMock{}
+	for _, id := range []string{"u1", "u2"} {
+		_, _ = store.Put(context.Background(), domain.AvatarKey(id, domain.AvatarOriginal), "image/png", []byte("png"))
+	}
+	svc := application.NewAvatarService(repo, store, &mocks.AuditServiceMock{}, "")
+
+	rc, _, err := svc.Open(context.Background(), "u1", domain.AvatarOriginal)
+	if assert.NoError(t, err) {
+		rc.Close()
+	}
+
+	_, _, err = svc.Open(context.Background(), "u2", domain.AvatarOriginal)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,12 @@
+package application
+
+import "time"
+
+// backoff doubles base with every attempt after the first, up to max.
+func backoff(attempts int, base, max time.Duration) time.Duration {
+	d := base
+	for i := 1; i < attempts && d < max; i++ {
+		d *= 2
+	}
+	return min(d, max)
+}
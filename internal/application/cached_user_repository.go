@@ -0,0 +1,223 @@
+package application
+
+import (
+	"bytes"
+	"context"
+	"encoding/gob"
+	"errors"
+	"log"
+	"sync/atomic"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"golang.org/x/sync/singleflight"
+)
+
+func init() {
+	// Free-form attributes hold nested maps and slices.
+	gob.Register(map[string]interface{}{})
+	gob.Register([]interface{}{})
+}
+
+// CachedUserRepository serves FindByID, which every authenticated request
+// makes, from a cache and passes everything else through to the wrapped
+// repository. Users that do not exist are cached too, for negativeTTL.
+// Concurrent misses for the same user share one load.
+//
+// The password hash is never cached, so FindByID returns users without
+// it. Login reads it through FindByEmail, which is not cached.
+//
+// Writes through this repository drop the user's entry. Writes made
+// elsewhere, such as on another instance with a per-process cache, are
+// seen once the entry expires, or at once when their events are
+// published to the repository.
+type CachedUserRepository struct {
+	ports.UserRepository
+	cache       ports.Cache
+	ttl         time.Duration
+	negativeTTL time.Duration
+	loads       singleflight.Group
+	// writes counts invalidations, so a load overlapping one does not
+	// cache what it read before the write.
+	writes atomic.Uint64
+}
+
+func NewCachedUserRepository(
+	r ports.UserRepository,
+	c ports.Cache,
+	ttl, negativeTTL time.Duration,
+) *CachedUserRepository {
+	return &CachedUserRepository{
+		UserRepository: r,
+		cache:          c,
+		ttl:            ttl,
+		negativeTTL:    negativeTTL,
+	}
+}
+
+func (r *CachedUserRepository) FindByID(ctx context.Context, id string) (*domain.User, error) {
+	key := userCacheKey(domain.TenantID(ctx), id)
+
+	b, err := r.cache.Get(ctx, key)
+	if err != nil && !errors.Is(err, domain.ErrNotFound) {
+		log.Printf("user cache get failed: %v", err)
+	}
+	if err == nil {
+		if len(b) == 0 {
+			return nil, domain.ErrNotFound
+		}
+		if u, err := decodeUser(b); err == nil {
+			return u, nil
+		}
+	}
+
+	// The load outlives a caller that gives up, so the others sharing it
+	// are not failed by that caller's cancellation.
+	loaded := r.loads.DoChan(key, func() (interface{}, error) {
+		return r.load(context.WithoutCancel(ctx), id)
+	})
+	select {
+	case res := <-loaded:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		// Decode per caller so nobody shares a user with another.
+		return decodeUser(res.Val.([]byte))
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// load reads the user from the repository and caches the result.
+func (r *CachedUserRepository) load(ctx context.Context, id string) ([]byte, error) {
+	writes := r.writes.Load()
+
+	u, err := r.UserRepository.FindByID(ctx, id)
+	if errors.Is(err, domain.ErrNotFound) {
+		r.set(ctx, id, []byte{}, r.negativeTTL, writes)
+		return nil, err
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	u.Password = ""
+	b, err := encodeUser(u)
+	if err != nil {
+		return nil, err
+	}
+	r.set(ctx, id, b, r.ttl, writes)
+	return b, nil
+}
+
+func (r *CachedUserRepository) Create(ctx context.Context, u *domain.User) error {
+	err := r.UserRepository.Create(ctx, u)
+	if err == nil {
+		// The ID may have been cached as missing.
+		r.invalidate(ctx, u.ID)
+	}
+	return err
+}
+
+func (r *CachedUserRepository) CreateMany(ctx context.Context, users []*domain.User) error {
+	err := r.UserRepository.CreateMany(ctx, users)
+	if err == nil {
+		for _, u := range users {
+			r.invalidate(ctx, u.ID)
+		}
+	}
+	return err
+}
+
+// Update drops the cached user even when it fails: a version conflict
+// may come from a stale cached copy.
+func (r *CachedUserRepository) Update(ctx context.Context, u *domain.User) error {
+	err := r.UserRepository.Update(ctx, u)
+	r.invalidate(ctx, u.ID)
+	return err
+}
+
+func (r *CachedUserRepository) UpdateWithPassword(ctx context.Context, u *domain.User) error {
+	err := r.UserRepository.UpdateWithPassword(ctx, u)
+	r.invalidate(ctx, u.ID)
+	return err
+}
+
+func (r *CachedUserRepository) Delete(ctx context.Context, id string) error {
+	err := r.UserRepository.Delete(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+func (r *CachedUserRepository) Restore(ctx context.Context, id string) error {
+	err := r.UserRepository.Restore(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+func (r *CachedUserRepository) Erase(ctx context.Context, id string) error {
+	err := r.UserRepository.Erase(ctx, id)
+	r.invalidate(ctx, id)
+	return err
+}
+
+// Publish drops the cached copy of the event's user, so the repository
+// can follow changes made by other instances. Events naming no tenant are
+// taken to be about the default one.
+func (r *CachedUserRepository) Publish(ctx context.Context, e domain.Event) error {
+	r.invalidate(domain.WithTenant(ctx, e.TenantID), e.UserID)
+	return nil
+}
+
+// set caches b unless a write was made since the load began, writes
+// being the count of invalidations at that time.
+func (r *CachedUserRepository) set(ctx context.Context, id string, b []byte, ttl time.Duration, writes uint64) {
+	if ttl <= 0 || r.writes.Load() != writes {
+		return
+	}
+	key := userCacheKey(domain.TenantID(ctx), id)
+	if err := r.cache.Set(ctx, key, b, ttl); err != nil {
+		log.Printf("user cache set failed: %v", err)
+		return
+	}
+	// A write that came in meanwhile may already have deleted the key.
+	if r.writes.Load() != writes {
+		if err := r.cache.Delete(ctx, key); err != nil {
+			log.Printf("user cache delete failed: %v", err)
+		}
+	}
+}
+
+// invalidate drops the cached user and detaches any load in flight, so
+// later reads do not get what it read before the write.
+func (r *CachedUserRepository) invalidate(ctx context.Context, id string) {
+	key := userCacheKey(domain.TenantID(ctx), id)
+	r.writes.Add(1)
+	r.loads.Forget(key)
+	if err := r.cache.Delete(context.WithoutCancel(ctx), key); err != nil {
+		log.Printf("user cache delete failed: %v", err)
+	}
+}
+
+// userCacheKey keeps the tenants apart: a user cached for one tenant must
+// not be served, or reported missing, to another.
+func userCacheKey(tenantID, id string) string {
+	return "user:" + tenantID + ":" + id
+}
+
+func encodeUser(u *domain.User) ([]byte, error) {
+	var buf bytes.Buffer
+	if err := gob.NewEncoder(&buf).Encode(u); err != nil {
+		return nil, err
+	}
+	return buf.Bytes(), nil
+}
+
+func decodeUser(b []byte) (*domain.User, error) {
+	var u domain.User
+	if err := gob.NewDecoder(bytes.NewReader(b)).Decode(&u); err != nil {
+		return nil, err
+	}
+	return &u, nil
+}
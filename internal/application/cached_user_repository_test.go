@@ -0,0 +1,267 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestCachedUserRepository_FindByID(t *testing.T) {
+	ctx := context.Background()
+
+	t.Run("serves repeated reads from the cache", func(t *testing.T) {
+		var loads atomic.Int32
+		repo := &mocks.UserRepositoryMock{
+			FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+				loads.Add(1)
+				return &domain.User{
+					ID:       id,
+					Name:     "Alice",
+					Password: "hash",
+					Profile:  domain.Profile{Attributes: map[string]interface{}{"tags": []interface{}{"a"}}},
+				}, nil
+			},
+		}
+		cache := &mocks.CacheMock{}
+		cached := application.NewCachedUserRepository(repo, cache, time.Minute, time.Second)
+
+		for i := 0; i < 3; i++ {
+			u, err := cached.FindByID(ctx, "u1")
+			assert.NoError(t, err)
+			assert.Equal(t, "Alice", u.Name)
+			assert.Empty(t, u.Password)
+			assert.Equal(t, []interface{}{"a"}, u.Attributes["tags"])
+		}
+		assert.Equal(t, int32(1), loads.Load())
+		assert.Equal(t, time.Minute, cache.TTLs["user:default:u1"])
+
+		// Callers get copies of their own.
+		u, _ := cached.FindByID(ctx, "u1")
+		u.Name = "Changed"
+		u, _ = cached.FindByID(ctx, "u1")
+		assert.Equal(t, "Alice", u.Name)
+	})
+
+	t.Run("caches missing users", func(t *testing.T) {
+		var loads atomic.Int32
+		repo := &mocks.UserRepositoryMock{
+			FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+				loads.Add(1)
+				return nil, domain.ErrNotFound
+			},
+		}
+		cache := &mocks.CacheMock{}
+		cached := application.NewCachedUserRepository(repo, cache, time.Minute, time.Second)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.FindByID(ctx, "missing")
+			assert.ErrorIs(t, err, domain.ErrNotFound)
+		}
+		assert.Equal(t, int32(1), loads.Load())
+		assert.Equal(t, time.Second, cache.TTLs["user:default:missing"])
+	})
+
+	t.Run("does not cache failures", func(t *testing.T) {
+		var loads atomic.Int32
+		repo := &mocks.UserRepositoryMock{
+			FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+				loads.Add(1)
+				return nil, errors.New("database down")
+			},
+		}
+		cached := application.NewCachedUserRepository(repo, &mocks.CacheMock{}, time.Minute, time.Second)
+
+		for i := 0; i < 2; i++ {
+			_, err := cached.FindByID(ctx, "u1")
+			assert.EqualError(t, err, "database down")
+		}
+		assert.Equal(t, int32(2), loads.Load())
+	})
+
+	t.Run("falls back to the repository when the cache fails", func(t *testing.T) {
+		repo := &mocks.UserRepositoryMock{
+			FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+				return &domain.User{ID: id, Name: "Alice"}, nil
+			},
+		}
+		cache := &mocks.CacheMock{Err: errors.New("connection refused")}
+		cached := application.NewCachedUserRepository(repo, cache, time.Minute, time.Second)
+
+		u, err := cached.FindByID(ctx, "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, "Alice", u.Name)
+	})
+
+	t.Run("collapses concurrent misses", func(t *testing.T) {
+		var loads atomic.Int32
+		release := make(chan struct{})
+		repo := &mocks.UserRepositoryMock{
+			FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+				loads.Add(1)
+				<-release
+				return &domain.User{ID: id, Name: "Alice"}, nil
+			},
+		}
+		cached := application.NewCachedUserRepository(repo, &mocks.CacheMock{}, time.Minute, time.Second)
+
+		var wg sync.WaitGroup
+		users := make([]*domain.User, 10)
+		for i := range users {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				users[i], _ = cached.FindByID(ctx, "u1")
+			}()
+		}
+		time.Sleep(50 * time.Millisecond)
+		close(release)
+		wg.Wait()
+
+		assert.Equal(t, int32(1), loads.Load())
+		for _, u := range users {
+			if assert.NotNil(t, u) {
+				assert.Equal(t, "Alice", u.Name)
+			}
+		}
+		assert.NotSame(t, users[0], users[1])
+	})
+
+	t.Run("a caller giving up does not fail the others", func(t *testing.T) {
+		release := make(chan struct{})
+		repo := &mocks.UserRepositoryMock{
+			FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+				<-release
+				return &domain.User{ID: id}, ctx.Err()
+			},
+		}
+		cached := application.NewCachedUserRepository(repo, &mocks.CacheMock{}, time.Minute, time.Second)
+
+		cancelled, cancel := context.WithCancel(ctx)
+		first := make(chan error)
+		go func() {
+			_, err := cached.FindByID(cancelled, "u1")
+			first <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		second := make(chan error)
+		go func() {
+			_, err := cached.FindByID(ctx, "u1")
+			second <- err
+		}()
+		time.Sleep(20 * time.Millisecond)
+
+		cancel()
+		assert.ErrorIs(t, <-first, context.Canceled)
+		close(release)
+		assert.NoError(t, <-second)
+	})
+}
+
+func TestCachedUserRepository_NeverCachesPasswords(t *testing.T) {
+	ctx := context.Background()
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Email: "alice@test.com", Password: "$2a$10$secret-hash"}, nil
+		},
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: "u1", Email: email, Password: "$2a$10$secret-hash"}, nil
+		},
+	}
+	cache := &mocks.CacheMock{}
+	cached := application.NewCachedUserRepository(repo, cache, time.Minute, time.Second)
+
+	_, err := cached.FindByID(ctx, "u1")
+	assert.NoError(t, err)
+	assert.NotContains(t, string(cache.Values["user:default:u1"]), "secret-hash")
+
+	// Login still gets the hash, from the repository.
+	u, err := cached.FindByEmail(ctx, "alice@test.com")
+	assert.NoError(t, err)
+	assert.Equal(t, "$2a$10$secret-hash", u.Password)
+}
+
+func TestCachedUserRepository_Invalidation(t *testing.T) {
+	ctx := context.Background()
+
+	stored := &domain.User{ID: "u1", Name: "Alice", Version: 1}
+	deleted := false
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			if deleted {
+				return nil, domain.ErrNotFound
+			}
+			u := *stored
+			return &u, nil
+		},
+		UpdateFn: func(ctx context.Context, u *domain.User) error {
+			if u.Version != stored.Version {
+				return domain.ErrVersionConflict
+			}
+			u.Version++
+			*stored = *u
+			return nil
+		},
+		DeleteFn: func(ctx context.Context, id string) error {
+			deleted = true
+			return nil
+		},
+		RestoreFn: func(ctx context.Context, id string) error {
+			deleted = false
+			return nil
+		},
+	}
+	cache := &mocks.CacheMock{}
+	cached := application.NewCachedUserRepository(repo, cache, time.Minute, time.Minute)
+
+	u, err := cached.FindByID(ctx, "u1")
+	assert.NoError(t, err)
+
+	u.Name = "Alice Liddell"
+	assert.NoError(t, cached.Update(ctx, u))
+	u, err = cached.FindByID(ctx, "u1")
+	assert.NoError(t, err)
+	assert.Equal(t, "Alice Liddell", u.Name)
+	assert.Equal(t, int64(2), u.Version)
+
+	// A failed update still drops the entry, in case it was stale.
+	stored.Version = 5
+	assert.ErrorIs(t, cached.Update(ctx, u), domain.ErrVersionConflict)
+	u, _ = cached.FindByID(ctx, "u1")
+	assert.Equal(t, int64(5), u.Version)
+
+	assert.NoError(t, cached.Delete(ctx, "u1"))
+	_, err = cached.FindByID(ctx, "u1")
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	assert.NoError(t, cached.Restore(ctx, "u1"))
+	_, err = cached.FindByID(ctx, "u1")
+	assert.NoError(t, err)
+}
+
+func TestCachedUserRepository_Publish(t *testing.T) {
+	ctx := context.Background()
+
+	var loads atomic.Int32
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			loads.Add(1)
+			return &domain.User{ID: id}, nil
+		},
+	}
+	cached := application.NewCachedUserRepository(repo, &mocks.CacheMock{}, time.Minute, time.Minute)
+
+	_, _ = cached.FindByID(ctx, "u1")
+	assert.NoError(t, cached.Publish(ctx, domain.Event{Type: domain.EventUserUpdated, UserID: "u1"}))
+	_, _ = cached.FindByID(ctx, "u1")
+
+	assert.Equal(t, int32(2), loads.Load())
+}
@@ -0,0 +1,106 @@
+package application
+
+import (
+	"context"
+	"sync"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// subscriberBuffer is how many events a subscriber may lag behind before
+// it is disconnected.
+const subscriberBuffer = 256
+
+// EventBroker fans published events out to in-process subscribers and
+// keeps the most recent ones so reconnecting clients can resume. A
+// subscriber only receives its own tenant's events.
+type EventBroker struct {
+	mu      sync.Mutex
+	history []domain.Event
+	size    int
+	subs    map[chan domain.Event]string // subscriber's tenant
+}
+
+func NewEventBroker(history int) *EventBroker {
+	return &EventBroker{
+		size: history,
+		subs: map[chan domain.Event]string{},
+	}
+}
+
+// Publish never blocks: subscribers that cannot keep up are dropped.
+// Events already in the history are ignored, since the outbox may deliver
+// an event twice.
+func (b *EventBroker) Publish(_ context.Context, e domain.Event) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if b.indexOf(e.ID) >= 0 {
+		return nil
+	}
+
+	b.history = append(b.history, e)
+	if len(b.history) > b.size {
+		b.history = b.history[len(b.history)-b.size:]
+	}
+
+	tenant := e.Tenant()
+	for ch, subTenant := range b.subs {
+		if subTenant != tenant {
+			continue
+		}
+		select {
+		case ch <- e:
+		default:
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}
+	return nil
+}
+
+func (b *EventBroker) Subscribe(ctx context.Context, lastEventID string) (<-chan domain.Event, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	tenant := domain.TenantID(ctx)
+	var backlog []domain.Event
+	if lastEventID != "" {
+		i := b.indexOf(lastEventID)
+		if i < 0 {
+			return nil, domain.ErrEventsLost
+		}
+		for _, e := range b.history[i+1:] {
+			if e.Tenant() == tenant {
+				backlog = append(backlog, e)
+			}
+		}
+	}
+
+	ch := make(chan domain.Event, max(subscriberBuffer, len(backlog)))
+	for _, e := range backlog {
+		ch <- e
+	}
+	b.subs[ch] = tenant
+
+	go func() {
+		<-ctx.Done()
+		b.mu.Lock()
+		defer b.mu.Unlock()
+		if _, ok := b.subs[ch]; ok {
+			delete(b.subs, ch)
+			close(ch)
+		}
+	}()
+
+	return ch, nil
+}
+
+func (b *EventBroker) indexOf(id string) int {
+	for i := len(b.history) - 1; i >= 0; i-- {
+		if b.history[i].ID == id {
+			return i
+		}
+	}
+	return -1
+}
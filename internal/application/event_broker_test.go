@@ -0,0 +1,76 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+func TestEventBroker_LiveAndResume(t *testing.T) {
+	broker := application.NewEventBroker(2)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	live, err := broker.Subscribe(ctx, "")
+	assert.NoError(t, err)
+
+	for _, id := range []string{"1", "2", "3"} {
+		assert.NoError(t, broker.Publish(ctx, domain.Event{ID: id}))
+	}
+	// Duplicates from the outbox are dropped.
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "3"}))
+
+	assert.Equal(t, "1", (<-live).ID)
+	assert.Equal(t, "2", (<-live).ID)
+	assert.Equal(t, "3", (<-live).ID)
+	assert.Empty(t, live)
+
+	resumed, err := broker.Subscribe(ctx, "2")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", (<-resumed).ID)
+
+	// "1" fell out of the history of two.
+	_, err = broker.Subscribe(ctx, "1")
+	assert.ErrorIs(t, err, domain.ErrEventsLost)
+}
+
+func TestEventBroker_ClosesOnCancel(t *testing.T) {
+	broker := application.NewEventBroker(10)
+	ctx, cancel := context.WithCancel(context.Background())
+
+	events, err := broker.Subscribe(ctx, "")
+	assert.NoError(t, err)
+
+	cancel()
+	_, open := <-events
+	assert.False(t, open)
+}
+
+func TestEventBroker_ScopedByTenant(t *testing.T) {
+	broker := application.NewEventBroker(10)
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "1", TenantID: "acme"}))
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "2", TenantID: "globex"}))
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "3", TenantID: "acme"}))
+
+	acme, err := broker.Subscribe(domain.WithTenant(ctx, "acme"), "1")
+	assert.NoError(t, err)
+	assert.Equal(t, "3", (<-acme).ID)
+
+	legacy, err := broker.Subscribe(ctx, "")
+	assert.NoError(t, err)
+
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "4", TenantID: "globex"}))
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "5"}))
+	assert.NoError(t, broker.Publish(ctx, domain.Event{ID: "6", TenantID: "acme"}))
+
+	assert.Equal(t, "6", (<-acme).ID)
+	assert.Equal(t, "5", (<-legacy).ID, "events without a tenant belong to the default one")
+	assert.Empty(t, acme)
+	assert.Empty(t, legacy)
+}
@@ -0,0 +1,352 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"slices"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+type organizationService struct {
+	orgs   ports.OrganizationRepository
+	users  ports.UserRepository
+	mailer ports.Mailer
+	audit  ports.AuditService
+}
+
+// NewOrganizationService looks up invitees through users and mails them
+// through mailer.
+func NewOrganizationService(
+	orgs ports.OrganizationRepository,
+	users ports.UserRepository,
+	mailer ports.Mailer,
+	audit ports.AuditService,
+) ports.OrganizationService {
+	return &organizationService{orgs: orgs, users: users, mailer: mailer, audit: audit}
+}
+
+func (s *organizationService) Create(ctx context.Context, actorID, name string) (*domain.Organization, error) {
+	name, err := domain.NormalizeOrganizationName(name)
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	org := &domain.Organization{Name: name, CreatedAt: now, UpdatedAt: now}
+	owner := &domain.Membership{
+		UserID:    actorID,
+		Role:      domain.OrgRoleOwner,
+		State:     domain.MembershipActive,
+		CreatedAt: now,
+		UpdatedAt: now,
+	}
+	if err := s.orgs.Create(ctx, org, owner); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditOrganizationCreated,
+		TargetID: org.ID,
+		Changes:  map[string]domain.AuditChange{"name": {After: org.Name}},
+	})
+	return org, nil
+}
+
+// Get is open to invited members too, so they can see what they were
+// invited to.
+func (s *organizationService) Get(ctx context.Context, actorID, orgID string) (*domain.Organization, error) {
+	if _, err := s.orgs.FindMember(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+	return s.orgs.FindByID(ctx, orgID)
+}
+
+func (s *organizationService) List(ctx context.Context, actorID string) ([]*domain.Organization, error) {
+	memberships, err := s.orgs.MembershipsOf(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+
+	orgs := make([]*domain.Organization, 0, len(memberships))
+	for _, m := range memberships {
+		org, err := s.orgs.FindByID(ctx, m.OrganizationID)
+		if err != nil {
+			return nil, err
+		}
+		orgs = append(orgs, org)
+	}
+	return orgs, nil
+}
+
+func (s *organizationService) Members(ctx context.Context, actorID, orgID, team string) ([]*domain.Membership, error) {
+	if _, err := s.activeMember(ctx, orgID, actorID); err != nil {
+		return nil, err
+	}
+
+	members, err := s.orgs.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	if team != "" {
+		members = slices.DeleteFunc(members, func(m *domain.Membership) bool { return !m.InTeam(team) })
+	}
+	return members, nil
+}
+
+func (s *organizationService) Invite(
+	ctx context.Context,
+	actorID, orgID, email string,
+	role domain.OrgRole,
+	teams []string,
+) error {
+	role, err := domain.ParseOrgRole(string(role))
+	if err != nil {
+		return err
+	}
+	if teams, err = domain.NormalizeTeams(teams); err != nil {
+		return err
+	}
+
+	actor, err := s.activeMember(ctx, orgID, actorID)
+	if err != nil {
+		return err
+	}
+	if !actor.Role.CanManage(role) {
+		return fmt.Errorf("%w: a %s cannot invite a %s", domain.ErrForbidden, actor.Role, role)
+	}
+	org, err := s.orgs.FindByID(ctx, orgID)
+	if err != nil {
+		return err
+	}
+	user, err := s.users.FindByEmail(ctx, email)
+	if errors.Is(err, domain.ErrNotFound) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	now := time.Now()
+	m := &domain.Membership{
+		OrganizationID: orgID,
+		UserID:         user.ID,
+		Role:           role,
+		Teams:          teams,
+		State:          domain.MembershipInvited,
+		InvitedBy:      actorID,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+	err = s.orgs.AddMember(ctx, m)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditMemberInvited,
+		TargetID: user.ID,
+		Changes:  domain.MembershipAuditDiff(nil, m),
+	})
+
+	// The invitation stands without the mail; the invitee also finds it
+	// in their list of organizations.
+	err = s.mailer.Send(ctx, user.Email, "You have been invited to "+org.Name,
+		"You have been invited to join "+org.Name+" as "+string(role)+
+			".\nAccept the invitation from your list of organizations.")
+	if err != nil {
+		log.Printf("send invitation to %s for organization %s: %v", user.ID, orgID, err)
+	}
+	return nil
+}
+
+func (s *organizationService) Accept(ctx context.Context, actorID, orgID string) (*domain.Membership, error) {
+	m, err := s.orgs.FindMember(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	if m.State != domain.MembershipInvited {
+		return nil, fmt.Errorf("%w: no pending invitation", domain.ErrNotFound)
+	}
+
+	before := *m
+	m.State = domain.MembershipActive
+	m.UpdatedAt = time.Now()
+	if err := s.orgs.UpdateMember(ctx, m); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditMemberJoined,
+		TargetID: actorID,
+		Changes:  domain.MembershipAuditDiff(&before, m),
+	})
+	return m, nil
+}
+
+func (s *organizationService) ChangeMember(
+	ctx context.Context,
+	actorID, orgID, userID string,
+	patch domain.MembershipPatch,
+) (*domain.Membership, error) {
+	actor, err := s.activeMember(ctx, orgID, actorID)
+	if err != nil {
+		return nil, err
+	}
+	m, err := s.orgs.FindMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if !actor.Role.CanManage(m.Role) {
+		return nil, fmt.Errorf("%w: a %s cannot change a %s", domain.ErrForbidden, actor.Role, m.Role)
+	}
+
+	before := *m
+	if patch.Role != nil {
+		role, err := domain.ParseOrgRole(string(*patch.Role))
+		if err != nil {
+			return nil, err
+		}
+		if !actor.Role.CanManage(role) {
+			return nil, fmt.Errorf("%w: a %s cannot grant %s", domain.ErrForbidden, actor.Role, role)
+		}
+		if role != domain.OrgRoleOwner {
+			if err := s.checkNotLastOwner(ctx, m); err != nil {
+				return nil, err
+			}
+		}
+		m.Role = role
+	}
+	if patch.Teams != nil {
+		if m.Teams, err = domain.NormalizeTeams(*patch.Teams); err != nil {
+			return nil, err
+		}
+	}
+
+	changes := domain.MembershipAuditDiff(&before, m)
+	if len(changes) == 1 {
+		// Only the organization, which is always listed.
+		return m, nil
+	}
+	m.UpdatedAt = time.Now()
+	if err := s.orgs.UpdateMember(ctx, m); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditMemberChanged,
+		TargetID: userID,
+		Changes:  changes,
+	})
+	return m, nil
+}
+
+// Remove lets invited users decline by removing themselves.
+func (s *organizationService) Remove(ctx context.Context, actorID, orgID, userID string) error {
+	var actor *domain.Membership
+	if actorID != userID {
+		var err error
+		if actor, err = s.activeMember(ctx, orgID, actorID); err != nil {
+			return err
+		}
+	}
+	m, err := s.orgs.FindMember(ctx, orgID, userID)
+	if err != nil {
+		return err
+	}
+	if actor != nil && !actor.Role.CanManage(m.Role) {
+		return fmt.Errorf("%w: a %s cannot remove a %s", domain.ErrForbidden, actor.Role, m.Role)
+	}
+	if err := s.checkNotLastOwner(ctx, m); err != nil {
+		return err
+	}
+
+	if err := s.orgs.RemoveMember(ctx, orgID, userID); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditMemberRemoved,
+		TargetID: userID,
+		Changes:  domain.MembershipAuditDiff(m, nil),
+	})
+	return nil
+}
+
+func (s *organizationService) VisibleUsers(ctx context.Context, actorID, orgID string) ([]string, error) {
+	if orgID != "" {
+		if _, err := s.activeMember(ctx, orgID, actorID); err != nil {
+			return nil, err
+		}
+		return s.memberIDs(ctx, nil, orgID)
+	}
+
+	memberships, err := s.orgs.MembershipsOf(ctx, actorID)
+	if err != nil {
+		return nil, err
+	}
+	ids := []string{actorID}
+	for _, m := range memberships {
+		if m.State != domain.MembershipActive {
+			continue
+		}
+		if ids, err = s.memberIDs(ctx, ids, m.OrganizationID); err != nil {
+			return nil, err
+		}
+	}
+	slices.Sort(ids)
+	return slices.Compact(ids), nil
+}
+
+// memberIDs appends the user IDs of orgID's active members to ids. Users
+// who were only invited have not agreed to be seen.
+func (s *organizationService) memberIDs(ctx context.Context, ids []string, orgID string) ([]string, error) {
+	members, err := s.orgs.ListMembers(ctx, orgID)
+	if err != nil {
+		return nil, err
+	}
+	for _, m := range members {
+		if m.State == domain.MembershipActive {
+			ids = append(ids, m.UserID)
+		}
+	}
+	return ids, nil
+}
+
+// activeMember returns userID's membership of orgID. Invited members are
+// forbidden what needs an active membership.
+func (s *organizationService) activeMember(ctx context.Context, orgID, userID string) (*domain.Membership, error) {
+	m, err := s.orgs.FindMember(ctx, orgID, userID)
+	if err != nil {
+		return nil, err
+	}
+	if m.State != domain.MembershipActive {
+		return nil, fmt.Errorf("%w: the invitation has not been accepted", domain.ErrForbidden)
+	}
+	return m, nil
+}
+
+// checkNotLastOwner fails when m is the only active owner of its
+// organization.
+func (s *organizationService) checkNotLastOwner(ctx context.Context, m *domain.Membership) error {
+	if m.Role != domain.OrgRoleOwner || m.State != domain.MembershipActive {
+		return nil
+	}
+	members, err := s.orgs.ListMembers(ctx, m.OrganizationID)
+	if err != nil {
+		return err
+	}
+	for _, other := range members {
+		if other.UserID != m.UserID && other.Role == domain.OrgRoleOwner && other.State == domain.MembershipActive {
+			return nil
+		}
+	}
+	return domain.ErrLastOwner
+}
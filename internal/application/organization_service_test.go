@@ -0,0 +1,262 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+// newOrganizationService knows the users alice, bob and carol, whose
+// emails are their IDs at test.com.
+func newOrganizationService(orgs *mocks.OrganizationRepositoryMock, mailer *mocks.MailerMock, audit *mocks.AuditServiceMock) ports.OrganizationService {
+	users := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			for _, id := range []string{"alice", "bob", "carol"} {
+				if email == id+"@test.com" {
+					return &domain.User{ID: id, Email: email}, nil
+				}
+			}
+			return nil, domain.ErrNotFound
+		},
+	}
+	return application.NewOrganizationService(orgs, users, mailer, audit)
+}
+
+// setupOrganization has alice own an organization that bob is an active
+// admin of and carol is invited to as a member of the backend team.
+func setupOrganization(t *testing.T, svc ports.OrganizationService) *domain.Organization {
+	ctx := context.Background()
+	org, err := svc.Create(ctx, "alice", "Acme")
+	assert.NoError(t, err)
+	assert.NoError(t, svc.Invite(ctx, "alice", org.ID, "bob@test.com", domain.OrgRoleAdmin, nil))
+	_, err = svc.Accept(ctx, "bob", org.ID)
+	assert.NoError(t, err)
+	assert.NoError(t, svc.Invite(ctx, "bob", org.ID, "carol@test.com", domain.OrgRoleMember, []string{" backend "}))
+	return org
+}
+
+func TestOrganizationService_CreateAndInvite(t *testing.T) {
+	orgs := &mocks.OrganizationRepositoryMock{}
+	var sent []string
+	mailer := &mocks.MailerMock{SendFn: func(ctx context.Context, to, subject, body string) error {
+		sent = append(sent, to+": "+subject)
+		return nil
+	}}
+	var actions []domain.AuditAction
+	audit := &mocks.AuditServiceMock{RecordFn: func(ctx context.Context, e domain.AuditEvent) error {
+		actions = append(actions, e.Action)
+		return nil
+	}}
+	svc := newOrganizationService(orgs, mailer, audit)
+
+	org := setupOrganization(t, svc)
+
+	assert.Equal(t, "Acme", org.Name)
+	assert.Equal(t, []string{"bob@test.com: You have been invited to Acme", "carol@test.com: You have been invited to Acme"}, sent)
+	assert.Equal(t, []domain.AuditAction{
+		domain.AuditOrganizationCreated,
+		domain.AuditMemberInvited,
+		domain.AuditMemberJoined,
+		domain.AuditMemberInvited,
+	}, actions)
+
+	carol, err := orgs.FindMember(context.Background(), org.ID, "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MembershipInvited, carol.State)
+	assert.Equal(t, []string{"backend"}, carol.Teams)
+	assert.Equal(t, "bob", carol.InvitedBy)
+
+	list, err := svc.List(context.Background(), "carol")
+	assert.NoError(t, err)
+	assert.Len(t, list, 1)
+}
+
+func TestOrganizationService_Create_InvalidName(t *testing.T) {
+	svc := newOrganizationService(&mocks.OrganizationRepositoryMock{}, &mocks.MailerMock{}, &mocks.AuditServiceMock{})
+
+	_, err := svc.Create(context.Background(), "alice", "  ")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidOrganization)
+}
+
+func TestOrganizationService_Invite_Rules(t *testing.T) {
+	ctx := context.Background()
+	orgs := &mocks.OrganizationRepositoryMock{}
+	svc := newOrganizationService(orgs, &mocks.MailerMock{}, &mocks.AuditServiceMock{})
+	org := setupOrganization(t, svc)
+	other, err := svc.Create(ctx, "carol", "Globex")
+	assert.NoError(t, err)
+
+	tests := []struct {
+		name    string
+		actorID string
+		orgID   string
+		email   string
+		role    domain.OrgRole
+		wantErr error
+	}{
+		{"admins cannot invite owners", "bob", org.ID, "dave@test.com", domain.OrgRoleOwner, domain.ErrForbidden},
+		{"invited members cannot invite", "carol", org.ID, "dave@test.com", domain.OrgRoleMember, domain.ErrForbidden},
+		{"non-members do not see the organization", "alice", other.ID, "bob@test.com", domain.OrgRoleMember, domain.ErrNotFound},
+		{"unknown roles", "alice", org.ID, "bob@test.com", "boss", domain.ErrInvalidOrganization},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := svc.Invite(ctx, tt.actorID, tt.orgID, tt.email, tt.role, nil)
+			assert.ErrorIs(t, err, tt.wantErr)
+		})
+	}
+}
+
+func TestOrganizationService_Invite_DoesNotRevealRegisteredEmails(t *testing.T) {
+	ctx := context.Background()
+	orgs := &mocks.OrganizationRepositoryMock{}
+	var sent []string
+	mailer := &mocks.MailerMock{SendFn: func(ctx context.Context, to, subject, body string) error {
+		sent = append(sent, to)
+		return nil
+	}}
+	svc := newOrganizationService(orgs, mailer, &mocks.AuditServiceMock{})
+	org := setupOrganization(t, svc)
+	sent = nil
+
+	assert.NoError(t, svc.Invite(ctx, "alice", org.ID, "dave@test.com", domain.OrgRoleMember, nil))
+	assert.NoError(t, svc.Invite(ctx, "alice", org.ID, "carol@test.com", domain.OrgRoleAdmin, nil))
+
+	assert.Empty(t, sent)
+	members, err := orgs.ListMembers(ctx, org.ID)
+	assert.NoError(t, err)
+	assert.Len(t, members, 3)
+	carol, err := orgs.FindMember(ctx, org.ID, "carol")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrgRoleMember, carol.Role)
+}
+
+func TestOrganizationService_Invite_MailFailureKeepsInvitation(t *testing.T) {
+	ctx := context.Background()
+	orgs := &mocks.OrganizationRepositoryMock{}
+	mailer := &mocks.MailerMock{SendFn: func(ctx context.Context, to, subject, body string) error {
+		return errors.New("smtp down")
+	}}
+	svc := newOrganizationService(orgs, mailer, &mocks.AuditServiceMock{})
+	org, err := svc.Create(ctx, "alice", "Acme")
+	assert.NoError(t, err)
+
+	assert.NoError(t, svc.Invite(ctx, "alice", org.ID, "bob@test.com", domain.OrgRoleMember, nil))
+
+	m, err := orgs.FindMember(ctx, org.ID, "bob")
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MembershipInvited, m.State)
+}
+
+func TestOrganizationService_Accept(t *testing.T) {
+	ctx := context.Background()
+	svc := newOrganizationService(&mocks.OrganizationRepositoryMock{}, &mocks.MailerMock{}, &mocks.AuditServiceMock{})
+	org := setupOrganization(t, svc)
+
+	_, err := svc.Members(ctx, "carol", org.ID, "")
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+
+	m, err := svc.Accept(ctx, "carol", org.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.MembershipActive, m.State)
+
+	_, err = svc.Accept(ctx, "carol", org.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+
+	members, err := svc.Members(ctx, "carol", org.ID, "backend")
+	assert.NoError(t, err)
+	if assert.Len(t, members, 1) {
+		assert.Equal(t, "carol", members[0].UserID)
+	}
+}
+
+func TestOrganizationService_ChangeMember(t *testing.T) {
+	ctx := context.Background()
+	var changes map[string]domain.AuditChange
+	audit := &mocks.AuditServiceMock{RecordFn: func(ctx context.Context, e domain.AuditEvent) error {
+		if e.Action == domain.AuditMemberChanged {
+			changes = e.Changes
+		}
+		return nil
+	}}
+	svc := newOrganizationService(&mocks.OrganizationRepositoryMock{}, &mocks.MailerMock{}, audit)
+	org := setupOrganization(t, svc)
+	admin, owner := domain.OrgRoleAdmin, domain.OrgRoleOwner
+
+	m, err := svc.ChangeMember(ctx, "bob", org.ID, "carol", domain.MembershipPatch{Role: &admin, Teams: &[]string{}})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrgRoleAdmin, m.Role)
+	assert.Empty(t, m.Teams)
+	assert.Equal(t, domain.AuditChange{Before: "member", After: "admin"}, changes["role"])
+	assert.Contains(t, changes, "organization_id")
+
+	_, err = svc.ChangeMember(ctx, "bob", org.ID, "carol", domain.MembershipPatch{Role: &owner})
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	_, err = svc.ChangeMember(ctx, "bob", org.ID, "alice", domain.MembershipPatch{Role: &admin})
+	assert.ErrorIs(t, err, domain.ErrForbidden)
+	_, err = svc.ChangeMember(ctx, "alice", org.ID, "alice", domain.MembershipPatch{Role: &admin})
+	assert.ErrorIs(t, err, domain.ErrLastOwner)
+
+	_, err = svc.ChangeMember(ctx, "alice", org.ID, "bob", domain.MembershipPatch{Role: &owner})
+	assert.NoError(t, err)
+	m, err = svc.ChangeMember(ctx, "alice", org.ID, "alice", domain.MembershipPatch{Role: &admin})
+	assert.NoError(t, err)
+	assert.Equal(t, domain.OrgRoleAdmin, m.Role)
+}
+
+func TestOrganizationService_Remove(t *testing.T) {
+	ctx := context.Background()
+	svc := newOrganizationService(&mocks.OrganizationRepositoryMock{}, &mocks.MailerMock{}, &mocks.AuditServiceMock{})
+	org := setupOrganization(t, svc)
+
+	assert.ErrorIs(t, svc.Remove(ctx, "bob", org.ID, "alice"), domain.ErrForbidden)
+	assert.ErrorIs(t, svc.Remove(ctx, "carol", org.ID, "bob"), domain.ErrForbidden)
+	assert.ErrorIs(t, svc.Remove(ctx, "alice", org.ID, "alice"), domain.ErrLastOwner)
+	assert.ErrorIs(t, svc.Remove(ctx, "alice", org.ID, "dave"), domain.ErrNotFound)
+
+	// Invited members decline by removing themselves.
+	assert.NoError(t, svc.Remove(ctx, "carol", org.ID, "carol"))
+	assert.NoError(t, svc.Remove(ctx, "alice", org.ID, "bob"))
+
+	members, err := svc.Members(ctx, "alice", org.ID, "")
+	assert.NoError(t, err)
+	assert.Len(t, members, 1)
+}
+
+func TestOrganizationService_VisibleUsers(t *testing.T) {
+	ctx := context.Background()
+	svc := newOrganizationService(&mocks.OrganizationRepositoryMock{}, &mocks.MailerMock{}, &mocks.AuditServiceMock{})
+	org := setupOrganization(t, svc)
+	other, err := svc.Create(ctx, "dave", "Globex")
+	assert.NoError(t, err)
+
+	// Invitations neither widen what carol sees nor make her seen until
+	// accepted.
+	ids, err := svc.VisibleUsers(ctx, "bob", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, ids)
+
+	ids, err = svc.VisibleUsers(ctx, "carol", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"carol"}, ids)
+
+	ids, err = svc.VisibleUsers(ctx, "alice", org.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob"}, ids)
+
+	_, err = svc.Accept(ctx, "carol", org.ID)
+	assert.NoError(t, err)
+	ids, err = svc.VisibleUsers(ctx, "bob", "")
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"alice", "bob", "carol"}, ids)
+
+	_, err = svc.VisibleUsers(ctx, "alice", other.ID)
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
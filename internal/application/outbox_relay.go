@@ -0,0 +1,83 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+const (
+	// OutboxLease is how long a claimed message is hidden from other relays
+	// while it is being published.
+	OutboxLease = 30 * time.Second
+
+	outboxMinBackoff = time.Second
+	outboxMaxBackoff = 10 * time.Minute
+)
+
+// OutboxRelay publishes outbox messages. A message is only marked
+// delivered after the publisher accepted it, so delivery is at-least-once.
+type OutboxRelay struct {
+	repo     ports.OutboxRepository
+	pub      ports.EventPublisher
+	interval time.Duration
+}
+
+func NewOutboxRelay(r ports.OutboxRepository, pub ports.EventPublisher, interval time.Duration) *OutboxRelay {
+	return &OutboxRelay{
+		repo:     r,
+		pub:      pub,
+		interval: interval,
+	}
+}
+
+// RelayOnce publishes every message that is due and returns how many were
+// delivered. Failed messages are rescheduled with exponential backoff.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		msg, err := r.repo.Claim(ctx, OutboxLease)
+		if errors.Is(err, domain.ErrNotFound) {
+			return delivered, nil
+		}
+		if err != nil {
+			return delivered, err
+		}
+
+		if err := r.pub.Publish(ctx, msg.Event); err != nil {
+			retryAt := time.Now().Add(backoff(msg.Attempts, outboxMinBackoff, outboxMaxBackoff))
+			log.Printf("publish %s %s failed (attempt %d): %v", msg.Type, msg.ID, msg.Attempts, err)
+			if err := r.repo.MarkFailed(ctx, msg.ID, err.Error(), retryAt); err != nil {
+				return delivered, err
+			}
+			continue
+		}
+
+		if err := r.repo.MarkDelivered(ctx, msg.ID); err != nil {
+			return delivered, err
+		}
+		delivered++
+	}
+}
+
+// Run relays on every interval until ctx is cancelled.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	ticker := time.NewTicker(r.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := r.RelayOnce(ctx); err != nil {
+				log.Printf("relay outbox failed: %v", err)
+			}
+		case <-ctx.Done():
+			log.Println("Stopping outbox relay")
+			return
+		}
+	}
+}
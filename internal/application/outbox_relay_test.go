@@ -0,0 +1,66 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestOutboxRelay_RelayOnce_Delivers(t *testing.T) {
+	repo := &mocks.OutboxRepositoryMock{Messages: []*domain.OutboxMessage{
+		{Event: domain.Event{ID: "1", Type: domain.EventUserCreated}},
+		{Event: domain.Event{ID: "2", Type: domain.EventUserUpdated}},
+	}}
+
+	var published []string
+	pub := &mocks.EventPublisherMock{
+		PublishFn: func(ctx context.Context, e domain.Event) error {
+			published = append(published, e.ID)
+			return nil
+		},
+	}
+
+	relay := application.NewOutboxRelay(repo, pub, time.Second)
+
+	n, err := relay.RelayOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, n)
+	assert.Equal(t, []string{"1", "2"}, published)
+	assert.True(t, repo.Delivered["1"])
+	assert.True(t, repo.Delivered["2"])
+}
+
+func TestOutboxRelay_RelayOnce_ReschedulesFailures(t *testing.T) {
+	repo := &mocks.OutboxRepositoryMock{Messages: []*domain.OutboxMessage{
+		{Event: domain.Event{ID: "1", Type: domain.EventUserDeleted}},
+	}}
+
+	pub := &mocks.EventPublisherMock{
+		PublishFn: func(ctx context.Context, e domain.Event) error {
+			return errors.New("broker down")
+		},
+	}
+
+	relay := application.NewOutboxRelay(repo, pub, time.Second)
+
+	n, err := relay.RelayOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.False(t, repo.Delivered["1"])
+	assert.Equal(t, "broker down", repo.Messages[0].LastError)
+	assert.WithinDuration(t, time.Now().Add(time.Second), repo.Messages[0].NextAttemptAt, 100*time.Millisecond)
+
+	// Not due yet, so a second pass leaves it alone.
+	n, err = relay.RelayOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	assert.Equal(t, 1, repo.Messages[0].Attempts)
+}
@@ -0,0 +1,154 @@
+package application
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"io"
+	"slices"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+type privacyService struct {
+	users    ports.UserRepository
+	audit    ports.AuditRepository
+	auditLog ports.AuditService
+	webhooks ports.WebhookRepository
+	orgs     ports.OrganizationRepository
+	blobs    ports.BlobStore
+}
+
+// NewPrivacyService reads the audit log through audit, and redacts it and
+// records erasures through auditLog.
+func NewPrivacyService(
+	users ports.UserRepository,
+	audit ports.AuditRepository,
+	auditLog ports.AuditService,
+	webhooks ports.WebhookRepository,
+	orgs ports.OrganizationRepository,
+	blobs ports.BlobStore,
+) ports.PrivacyService {
+	return &privacyService{users: users, audit: audit, auditLog: auditLog, webhooks: webhooks, orgs: orgs, blobs: blobs}
+}
+
+// Export covers deleted users too: their data is held until they are
+// purged.
+func (s *privacyService) Export(ctx context.Context, userID string) (*domain.SubjectArchive, error) {
+	user, err := s.users.FindByIDIncludingDeleted(ctx, userID)
+	if err != nil {
+		return nil, err
+	}
+
+	archive := &domain.SubjectArchive{
+		GeneratedAt: time.Now().UTC(),
+		User:        user,
+	}
+
+	if user.AvatarURL != "" {
+		rc, info, err := s.blobs.Get(ctx, domain.AvatarKey(userID, domain.AvatarOriginal))
+		switch {
+		case errors.Is(err, domain.ErrNotFound):
+		case err != nil:
+			return nil, err
+		default:
+			data, err := io.ReadAll(rc)
+			rc.Close()
+			if err != nil {
+				return nil, err
+			}
+			archive.Avatar = &domain.AvatarFile{ContentType: info.ContentType, Data: data}
+		}
+	}
+
+	if archive.AuditEvents, err = s.auditEvents(ctx, userID); err != nil {
+		return nil, err
+	}
+	if archive.Memberships, err = s.orgs.MembershipsOf(ctx, userID); err != nil {
+		return nil, err
+	}
+	return archive, nil
+}
+
+// auditEvents returns the events by or about the user, in chain order.
+func (s *privacyService) auditEvents(ctx context.Context, userID string) ([]*domain.AuditEvent, error) {
+	bySeq := map[int64]*domain.AuditEvent{}
+	for _, q := range []domain.AuditQuery{{ActorID: userID}, {TargetID: userID}} {
+		q.Limit = domain.MaxPageSize
+		for {
+			events, err := s.audit.Find(ctx, q)
+			if err != nil {
+				return nil, err
+			}
+			for _, e := range events {
+				bySeq[e.Seq] = e
+				q.AfterSeq = e.Seq
+			}
+			if len(events) < q.Limit {
+				break
+			}
+		}
+	}
+
+	events := make([]*domain.AuditEvent, 0, len(bySeq))
+	for _, e := range bySeq {
+		events = append(events, e)
+	}
+	slices.SortFunc(events, func(a, b *domain.AuditEvent) int { return cmp.Compare(a.Seq, b.Seq) })
+	return events, nil
+}
+
+// Erase removes the user before anything else, so a failure part way
+// can be retried: the later steps do not need the user to exist.
+func (s *privacyService) Erase(ctx context.Context, userID, reason string) (*domain.ErasureReport, error) {
+	token, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+	report := &domain.ErasureReport{Pseudonym: "erased-" + token[:24]}
+
+	err = s.users.Erase(ctx, userID)
+	switch {
+	case err == nil:
+		report.UserDeleted = true
+	case !errors.Is(err, domain.ErrNotFound):
+		return nil, err
+	}
+
+	for _, key := range domain.AvatarKeys(userID) {
+		if err := s.blobs.Delete(ctx, key); err != nil {
+			return nil, err
+		}
+	}
+
+	if report.WebhookDeliveries, err = s.webhooks.RedactDeliveries(ctx, userID); err != nil {
+		return nil, err
+	}
+	if report.Memberships, err = s.orgs.RemoveUser(ctx, userID); err != nil {
+		return nil, err
+	}
+
+	// Users erasing themselves are named by their pseudonym in the events
+	// recording it too.
+	if meta := domain.RequestMetaFrom(ctx); meta.ActorID == userID {
+		ctx = domain.WithRequestMeta(ctx, domain.RequestMeta{ActorID: report.Pseudonym, RequestID: meta.RequestID})
+	}
+	if report.AuditEvents, err = s.auditLog.Redact(ctx, userID, report.Pseudonym); err != nil {
+		return nil, err
+	}
+	if !report.UserDeleted && report.AuditEvents == 0 && report.WebhookDeliveries == 0 && report.Memberships == 0 {
+		return nil, domain.ErrNotFound
+	}
+
+	err = s.auditLog.Record(ctx, domain.AuditEvent{
+		Action:   domain.AuditUserErased,
+		TargetID: report.Pseudonym,
+		Reason:   reason,
+	})
+	if err != nil {
+		return nil, err
+	}
+	return report, nil
+}
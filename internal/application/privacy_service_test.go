@@ -0,0 +1,150 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+// recordAudit fills the audit log with events by and about u1 among others.
+func recordAudit(t *testing.T, repo *mocks.AuditRepositoryMock) {
+	svc := application.NewAuditService(repo)
+	for _, e := range []struct{ actor, target string }{
+		{"u1", "u1"},
+		{"admin", "u2"},
+		{"admin", "u1"},
+		{"u1", "u3"},
+	} {
+		ctx := domain.WithRequestMeta(context.Background(), domain.RequestMeta{ActorID: e.actor, IP: "10.0.0.1"})
+		assert.NoError(t, svc.Record(ctx, domain.AuditEvent{Action: domain.AuditUserUpdated, TargetID: e.target, Reason: "because"}))
+	}
+}
+
+func TestPrivacyService_Export(t *testing.T) {
+	users := &mocks.UserRepositoryMock{
+		FindByIDIncludingDeletedFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Email: "u1@test.com", Profile: domain.Profile{AvatarURL: "/users/u1/avatar"}}, nil
+		},
+	}
+	audit := &mocks.AuditRepositoryMock{}
+	recordAudit(t, audit)
+	blobs := &mocks.BlobStoreMock{}
+	_, _ = blobs.Put(context.Background(), domain.AvatarKey("u1", domain.AvatarOriginal), "image/png", []byte("png"))
+	orgs := &mocks.OrganizationRepositoryMock{}
+	_ = orgs.Create(context.Background(), &domain.Organization{Name: "Acme"}, &domain.Membership{UserID: "u1", Role: domain.OrgRoleOwner})
+	svc := application.NewPrivacyService(users, audit, application.NewAuditService(audit), &mocks.WebhookRepositoryMock{}, orgs, blobs)
+
+	archive, err := svc.Export(context.Background(), "u1")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "u1@test.com", archive.User.Email)
+	if assert.NotNil(t, archive.Avatar) {
+		assert.Equal(t, "image/png", archive.Avatar.ContentType)
+		assert.Equal(t, []byte("png"), archive.Avatar.Data)
+	}
+	var seqs []int64
+	for _, e := range archive.AuditEvents {
+		seqs = append(seqs, e.Seq)
+	}
+	assert.Equal(t, []int64{1, 3, 4}, seqs)
+	if assert.Len(t, archive.Memberships, 1) {
+		assert.Equal(t, domain.OrgRoleOwner, archive.Memberships[0].Role)
+	}
+}
+
+func TestPrivacyService_Export_NotFound(t *testing.T) {
+	users := &mocks.UserRepositoryMock{
+		FindByIDIncludingDeletedFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return nil, domain.ErrNotFound
+		},
+	}
+	audit := &mocks.AuditRepositoryMock{}
+	svc := application.NewPrivacyService(users, audit, application.NewAuditService(audit), &mocks.WebhookRepositoryMock{}, &mocks.OrganizationRepositoryMock{}, &mocks.BlobStoreMock{})
+
+	_, err := svc.Export(context.Background(), "u1")
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
+
+func TestPrivacyService_Erase(t *testing.T) {
+	var erased []string
+	users := &mocks.UserRepositoryMock{
+		EraseFn: func(ctx context.Context, id string) error {
+			erased = append(erased, id)
+			return nil
+		},
+	}
+	audit := &mocks.AuditRepositoryMock{}
+	recordAudit(t, audit)
+	blobs := &mocks.BlobStoreMock{}
+	for _, size := range []domain.AvatarSize{domain.AvatarOriginal, domain.AvatarLarge, domain.AvatarSmall} {
+		_, _ = blobs.Put(context.Background(), domain.AvatarKey("u1", size), "image/png", []byte("png"))
+	}
+	orgs := &mocks.OrganizationRepositoryMock{}
+	_ = orgs.Create(context.Background(), &domain.Organization{Name: "Acme"}, &domain.Membership{UserID: "u1", Role: domain.OrgRoleOwner})
+	webhooks := &mocks.WebhookRepositoryMock{
+		Deliveries: []*domain.WebhookDelivery{
+			{ID: "d1", TenantID: domain.DefaultTenant, Payload: []byte(`{"id":"e1","type":"user.updated","user_id":"u1","data":{"email":"u1@test.com"}}`)},
+			{ID: "d2", TenantID: domain.DefaultTenant, Payload: []byte(`{"id":"e2","type":"user.updated","user_id":"u2","data":{"email":"u2@test.com"}}`)},
+		},
+	}
+	svc := application.NewPrivacyService(users, audit, application.NewAuditService(audit), webhooks, orgs, blobs)
+
+	ctx := domain.WithRequestMeta(context.Background(), domain.RequestMeta{ActorID: "admin"})
+	report, err := svc.Erase(ctx, "u1", "GDPR request")
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"u1"}, erased)
+	assert.True(t, report.UserDeleted)
+	assert.Equal(t, int64(3), report.AuditEvents)
+	assert.Equal(t, int64(1), report.WebhookDeliveries)
+	assert.Equal(t, int64(1), report.Memberships)
+	assert.Empty(t, orgs.Memberships)
+	assert.Empty(t, blobs.Blobs)
+	assert.NotContains(t, string(webhooks.Deliveries[0].Payload), "u1@test.com")
+	assert.Contains(t, string(webhooks.Deliveries[1].Payload), "u2@test.com")
+
+	for _, e := range audit.Events {
+		assert.NotEqual(t, "u1", e.ActorID)
+		assert.NotEqual(t, "u1", e.TargetID)
+	}
+	first := audit.Events[0]
+	assert.Equal(t, report.Pseudonym, first.ActorID)
+	assert.Equal(t, report.Pseudonym, first.TargetID)
+	assert.Empty(t, first.IP)
+	assert.Empty(t, first.Reason)
+	assert.True(t, first.Redacted)
+
+	record := audit.Events[len(audit.Events)-2]
+	assert.Equal(t, domain.AuditEventsRedacted, record.Action)
+	assert.Len(t, record.Changes, 3)
+
+	marker := audit.Events[len(audit.Events)-1]
+	assert.Equal(t, domain.AuditUserErased, marker.Action)
+	assert.Equal(t, "admin", marker.ActorID)
+	assert.Equal(t, report.Pseudonym, marker.TargetID)
+	assert.Equal(t, "GDPR request", marker.Reason)
+
+	result, err := application.NewAuditService(audit).Verify(context.Background())
+	assert.NoError(t, err)
+	assert.True(t, result.Valid)
+}
+
+func TestPrivacyService_Erase_NothingHeld(t *testing.T) {
+	users := &mocks.UserRepositoryMock{
+		EraseFn: func(ctx context.Context, id string) error {
+			return domain.ErrNotFound
+		},
+	}
+	audit := &mocks.AuditRepositoryMock{}
+	svc := application.NewPrivacyService(users, audit, application.NewAuditService(audit), &mocks.WebhookRepositoryMock{}, &mocks.OrganizationRepositoryMock{}, &mocks.BlobStoreMock{})
+
+	_, err := svc.Erase(context.Background(), "u1", "")
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+	assert.Empty(t, audit.Events)
+}
@@ -0,0 +1,97 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// Purger permanently removes soft-deleted users once they have been
+// deleted for longer than the retention period, together with their
+// memberships and avatar, and records a user.purged audit event for each.
+type Purger struct {
+	repo      ports.UserRepository
+	orgs      ports.OrganizationRepository
+	blobs     ports.BlobStore
+	audit     ports.AuditService
+	retention time.Duration
+	interval  time.Duration
+}
+
+func NewPurger(
+	r ports.UserRepository,
+	orgs ports.OrganizationRepository,
+	blobs ports.BlobStore,
+	audit ports.AuditService,
+	retention, interval time.Duration,
+) *Purger {
+	return &Purger{
+		repo:      r,
+		orgs:      orgs,
+		blobs:     blobs,
+		audit:     audit,
+		retention: retention,
+		interval:  interval,
+	}
+}
+
+// PurgeOnce returns how many users it removed. Cleaning up after one user
+// does not stop it cleaning up after the others; what fails to go is
+// left for an erasure of the user to remove.
+func (p *Purger) PurgeOnce(ctx context.Context) (int64, error) {
+	purged, err := p.repo.Purge(ctx, time.Now().Add(-p.retention))
+
+	var errs []error
+	for _, u := range purged {
+		tenantCtx := domain.WithTenant(ctx, u.TenantID)
+		recordAudit(tenantCtx, p.audit, domain.AuditEvent{
+			Action:   domain.AuditUserPurged,
+			TargetID: u.ID,
+			Reason:   "deleted for longer than the retention period",
+		})
+		if err := p.cleanUp(tenantCtx, u.ID); err != nil {
+			errs = append(errs, fmt.Errorf("clean up after user %s: %w", u.ID, err))
+		}
+	}
+	return int64(len(purged)), errors.Join(append([]error{err}, errs...)...)
+}
+
+func (p *Purger) cleanUp(ctx context.Context, userID string) error {
+	if _, err := p.orgs.RemoveUser(ctx, userID); err != nil {
+		return err
+	}
+	for _, key := range domain.AvatarKeys(userID) {
+		if err := p.blobs.Delete(ctx, key); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Run purges on every interval until ctx is cancelled.
+func (p *Purger) Run(ctx context.Context) {
+	ticker := time.NewTicker(p.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			n, err := p.PurgeOnce(ctx)
+			if err != nil {
+				log.Printf("purge deleted users failed: %v", err)
+				continue
+			}
+			if n > 0 {
+				log.Printf("Purged %d deleted users", n)
+			}
+		case <-ctx.Done():
+			log.Println("Stopping purger")
+			return
+		}
+	}
+}
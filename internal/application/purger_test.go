@@ -0,0 +1,80 @@
+package application_test
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+func TestPurger_PurgeOnce_UsesRetention(t *testing.T) {
+	retention := 30 * 24 * time.Hour
+
+	repo := &mocks.UserRepositoryMock{
+		PurgeFn: func(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+			assert.WithinDuration(t, time.Now().Add(-retention), deletedBefore, time.Second)
+			return []domain.PurgedUser{{ID: "u1"}, {ID: "u2"}, {ID: "u3"}}, nil
+		},
+	}
+
+	purger := application.NewPurger(repo, &mocks.OrganizationRepositoryMock{}, &mocks.BlobStoreMock{}, &mocks.AuditServiceMock{}, retention, time.Hour)
+
+	n, err := purger.PurgeOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(3), n)
+}
+
+func TestPurger_PurgeOnce_RemovesMembershipsAndAvatars(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		PurgeFn: func(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+			return []domain.PurgedUser{{ID: "u1", TenantID: "acme"}}, nil
+		},
+	}
+	orgs := &mocks.OrganizationRepositoryMock{}
+	acme := domain.WithTenant(context.Background(), "acme")
+	_ = orgs.Create(acme, &domain.Organization{Name: "Acme"}, &domain.Membership{UserID: "u1", Role: domain.OrgRoleOwner})
+	_ = orgs.Create(acme, &domain.Organization{Name: "Acme Labs"}, &domain.Membership{UserID: "u2", Role: domain.OrgRoleOwner})
+	blobs := &mocks.BlobStoreMock{}
+	for _, key := range append(domain.AvatarKeys("u1"), domain.AvatarKeys("u2")...) {
+		_, _ = blobs.Put(context.Background(), key, "image/png", []byte("png"))
+	}
+
+	n, err := application.NewPurger(repo, orgs, blobs, &mocks.AuditServiceMock{}, time.Hour, time.Hour).PurgeOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, int64(1), n)
+	if assert.Len(t, orgs.Memberships, 1) {
+		assert.Equal(t, "u2", orgs.Memberships[0].UserID)
+	}
+	assert.Len(t, blobs.Blobs, len(domain.AvatarKeys("u2")))
+	for _, key := range domain.AvatarKeys("u1") {
+		assert.NotContains(t, blobs.Blobs, key)
+	}
+}
+
+func TestPurger_PurgeOnce_RecordsEachUser(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		PurgeFn: func(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+			return []domain.PurgedUser{{ID: "u1", TenantID: "acme"}, {ID: "u2", TenantID: "globex"}}, nil
+		},
+	}
+	audit := &mocks.AuditRepositoryMock{}
+	purger := application.NewPurger(repo, &mocks.OrganizationRepositoryMock{}, &mocks.BlobStoreMock{},
+		application.NewAuditService(audit), time.Hour, time.Hour)
+
+	_, err := purger.PurgeOnce(context.Background())
+
+	assert.NoError(t, err)
+	if assert.Len(t, audit.Events, 2) {
+		for i, want := range []domain.PurgedUser{{ID: "u1", TenantID: "acme"}, {ID: "u2", TenantID: "globex"}} {
+			assert.Equal(t, domain.AuditUserPurged, audit.Events[i].Action)
+			assert.Equal(t, want.ID, audit.Events[i].TargetID)
+			assert.Equal(t, want.TenantID, audit.Events[i].TenantID, "recorded in the user's tenant")
+		}
+	}
+}
@@ -2,7 +2,12 @@ package application
 
 import (
 	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/hex"
 	"errors"
+	"fmt"
+	"strings"
 	"time"
 
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
@@ -11,67 +16,423 @@ import (
 	"golang.org/x/crypto/bcrypt"
 )
 
+// EmailChangeTTL is how long an email change confirmation token stays valid.
+const EmailChangeTTL = 24 * time.Hour
+
 type userService struct {
-	repo ports.UserRepository
-	jwt  infrastructure.JWTManager
+	repo    ports.UserRepository
+	jwt     infrastructure.JWTManager
+	mailer  ports.Mailer
+	attrs   ports.AttributeValidator
+	audit   ports.AuditService
+	tenants ports.TenantRegistry
 }
 
-func NewUserService(r ports.UserRepository, jwt infrastructure.JWTManager) ports.UserService {
-	return &userService{repo: r, jwt: jwt}
+// NewUserService serves the tenant of each call's context, see
+// domain.TenantID, with that tenant's password policy and token TTL.
+func NewUserService(
+	r ports.UserRepository,
+	jwt infrastructure.JWTManager,
+	mailer ports.Mailer,
+	attrs ports.AttributeValidator,
+	audit ports.AuditService,
+	tenants ports.TenantRegistry,
+) ports.UserService {
+	return &userService{repo: r, jwt: jwt, mailer: mailer, attrs: attrs, audit: audit, tenants: tenants}
 }
 
 func (s *userService) Register(ctx context.Context, name, email, password string) error {
+	if err := s.checkPassword(ctx, password); err != nil {
+		return err
+	}
 	if _, err := s.repo.FindByEmail(ctx, email); err == nil {
-		return errors.New("email already exists")
+		return domain.ErrEmailTaken
 	}
 
 	hash, _ := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
 
+	now := time.Now()
 	user := &domain.User{
 		Name:      name,
 		Email:     email,
 		Password:  string(hash),
-		CreatedAt: time.Now(),
+		CreatedAt: now,
+		UpdatedAt: now,
+		Status:    domain.StatusActive,
+	}
+
+	if err := s.repo.Create(ctx, user); err != nil {
+		return err
 	}
 
-	return s.repo.Create(ctx, user)
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditUserRegistered,
+		TargetID: user.ID,
+		Changes:  domain.AuditDiff(nil, user),
+	})
+	return nil
 }
 
 func (s *userService) GetByID(ctx context.Context, id string) (*domain.User, error) {
 	return s.repo.FindByID(ctx, id)
 }
 
-func (s *userService) List(ctx context.Context) ([]*domain.User, error) {
-	return s.repo.FindAll(ctx)
+func (s *userService) List(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error) {
+	if err := q.Normalize(); err != nil {
+		return nil, err
+	}
+	if q.Cursor != "" {
+		if _, err := domain.DecodeCursor(q.Cursor, q); err != nil {
+			return nil, err
+		}
+	}
+
+	return s.repo.FindAll(ctx, q)
+}
+
+func (s *userService) Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error) {
+	if err := q.Normalize(); err != nil {
+		return nil, err
+	}
+
+	return s.repo.Search(ctx, q)
 }
 
 func (s *userService) Login(ctx context.Context, email, password string) (string, error) {
 	user, err := s.repo.FindByEmail(ctx, email)
 	if err != nil {
+		recordAudit(ctx, s.audit, domain.AuditEvent{Action: domain.AuditLoginFailed, Reason: "unknown email"})
 		return "", errors.New("invalid credentials")
 	}
 
 	if bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)) != nil {
+		recordAudit(ctx, s.audit, domain.AuditEvent{
+			Action:   domain.AuditLoginFailed,
+			TargetID: user.ID,
+			Reason:   "wrong password",
+		})
 		return "", errors.New("invalid credentials")
 	}
 
-	return s.jwt.Generate(user.ID)
+	// Only reveal the status to someone who knows the password.
+	if user.CurrentStatus() != domain.StatusActive {
+		recordAudit(ctx, s.audit, domain.AuditEvent{
+			Action:   domain.AuditLoginFailed,
+			TargetID: user.ID,
+			Reason:   "account " + string(user.CurrentStatus()),
+		})
+		return "", fmt.Errorf("%w: %s", domain.ErrAccountInactive, user.CurrentStatus())
+	}
+
+	tenant, err := s.tenants.Tenant(domain.TenantID(ctx))
+	if err != nil {
+		return "", err
+	}
+	token, err := s.jwt.Generate(user.ID, tenant.ID, tenant.TokenTTL)
+	if err != nil {
+		return "", err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditLoginSucceeded,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+	})
+	return token, nil
+}
+
+// Authenticate resolves a token to its user, rejecting tokens of users that
+// were deleted or are no longer active since the token was issued. The
+// user is looked up in the token's tenant, which must be ctx's when ctx
+// names one: a token does not work across tenants.
+func (s *userService) Authenticate(ctx context.Context, token string) (*domain.User, error) {
+	claims, err := s.jwt.Validate(token)
+	if err != nil {
+		return nil, domain.ErrUnauthenticated
+	}
+	if tenant, ok := domain.TenantFrom(ctx); ok && tenant != claims.TenantID {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	user, err := s.repo.FindByID(domain.WithTenant(ctx, claims.TenantID), claims.UserID)
+	if err != nil {
+		return nil, domain.ErrUnauthenticated
+	}
+
+	if user.CurrentStatus() != domain.StatusActive {
+		return nil, fmt.Errorf("%w: %s", domain.ErrAccountInactive, user.CurrentStatus())
+	}
+
+	return user, nil
 }
 
 func (s *userService) Update(
 	ctx context.Context,
 	id, name, email string,
-) error {
+	expectedVersion int64,
+) (*domain.User, error) {
+	return s.Patch(ctx, id, domain.UserPatch{Name: &name, Email: &email}, expectedVersion)
+}
+
+func (s *userService) Patch(
+	ctx context.Context,
+	id string,
+	patch domain.UserPatch,
+	expectedVersion int64,
+) (*domain.User, error) {
+	if err := patch.Validate(); err != nil {
+		return nil, err
+	}
+
 	user, err := s.repo.FindByID(ctx, id)
 	if err != nil {
+		return nil, err
+	}
+
+	if expectedVersion != 0 && user.Version != expectedVersion {
+		// The user may come from a cache that missed a write made
+		// elsewhere. Rather than fail until the entry expires, drop it
+		// and check against the stored user.
+		if c, ok := s.repo.(interface{ invalidate(context.Context, string) }); ok {
+			c.invalidate(ctx, id)
+			if user, err = s.repo.FindByID(ctx, id); err != nil {
+				return nil, err
+			}
+		}
+		if user.Version != expectedVersion {
+			return nil, domain.ErrVersionConflict
+		}
+	}
+
+	// A new email only takes effect once confirmed from that mailbox.
+	newEmail := ""
+	if patch.Email != nil && *patch.Email != user.Email {
+		newEmail = *patch.Email
+	}
+	patch.Email = nil
+
+	if patch.IsEmpty() && newEmail == "" {
+		return user, nil
+	}
+
+	before := *user
+	patch.Apply(user)
+
+	if patch.Attributes != nil {
+		if err := s.attrs.Validate(user.Attributes); err != nil {
+			return nil, fmt.Errorf("%w: attributes: %v", domain.ErrInvalidPatch, err)
+		}
+	}
+
+	var token string
+	if newEmail != "" {
+		if _, err := s.repo.FindByEmail(ctx, newEmail); err == nil {
+			return nil, domain.ErrEmailTaken
+		}
+
+		token, err = newToken()
+		if err != nil {
+			return nil, err
+		}
+		user.PendingEmail = &domain.EmailChange{
+			Email:     newEmail,
+			TokenHash: hashToken(token),
+			ExpiresAt: time.Now().Add(EmailChangeTTL),
+		}
+	}
+
+	user.UpdatedAt = time.Now()
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditUserUpdated,
+		TargetID: user.ID,
+		Changes:  domain.AuditDiff(&before, user),
+	})
+
+	if newEmail != "" {
+		if err := s.sendEmailChange(ctx, user, token); err != nil {
+			return nil, err
+		}
+	}
+
+	return user, nil
+}
+
+func (s *userService) sendEmailChange(ctx context.Context, user *domain.User, token string) error {
+	pending := user.PendingEmail.Email
+
+	err := s.mailer.Send(ctx, pending, "Confirm your new email address",
+		"Use this token to confirm your new email address:\n\n"+token+
+			"\n\nIt expires at "+user.PendingEmail.ExpiresAt.UTC().Format(time.RFC1123)+".")
+	if err != nil {
+		return fmt.Errorf("send confirmation: %w", err)
+	}
+
+	err = s.mailer.Send(ctx, user.Email, "Your email address is being changed",
+		"A request was made to change your account email to "+pending+
+			".\nIf this wasn't you, change your password and contact support.")
+	if err != nil {
+		return fmt.Errorf("send notice: %w", err)
+	}
+
+	return nil
+}
+
+func (s *userService) ConfirmEmailChange(ctx context.Context, token string) error {
+	user, err := s.repo.FindByEmailChangeToken(ctx, hashToken(token))
+	if err != nil || user.PendingEmail == nil {
+		return domain.ErrInvalidToken
+	}
+	if time.Now().After(user.PendingEmail.ExpiresAt) {
+		return domain.ErrInvalidToken
+	}
+
+	if other, err := s.repo.FindByEmail(ctx, user.PendingEmail.Email); err == nil && other.ID != user.ID {
+		return domain.ErrEmailTaken
+	}
+
+	before := *user
+	user.Email = user.PendingEmail.Email
+	user.PendingEmail = nil
+	user.UpdatedAt = time.Now()
+
+	if err := s.repo.Update(ctx, user); err != nil {
 		return err
 	}
 
-	user.Name = name
-	user.Email = email
-	return s.repo.Update(ctx, user)
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditUserEmailConfirmed,
+		TargetID: user.ID,
+		Changes:  domain.AuditDiff(&before, user),
+	})
+	return nil
+}
+
+func (s *userService) AcceptInvitation(ctx context.Context, token, password string) error {
+	if password == "" {
+		return fmt.Errorf("%w: password cannot be empty", domain.ErrInvalidPatch)
+	}
+
+	user, err := s.repo.FindByInvitationToken(ctx, hashToken(token))
+	if err != nil || user.Invitation == nil {
+		return domain.ErrInvalidToken
+	}
+	if time.Now().After(user.Invitation.ExpiresAt) {
+		return domain.ErrInvalidToken
+	}
+	if err := s.checkPassword(ctx, password); err != nil {
+		return err
+	}
+
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
+	if err != nil {
+		return err
+	}
+
+	before := *user
+	now := time.Now()
+	user.Password = string(hash)
+	user.Invitation = nil
+	// An admin may have changed the status since; only lift the pending one.
+	if user.CurrentStatus() == domain.StatusPending {
+		user.Status = domain.StatusActive
+		user.StatusReason = ""
+		user.StatusChangedAt = &now
+	}
+	user.UpdatedAt = now
+
+	if err := s.repo.UpdateWithPassword(ctx, user); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditInvitationAccepted,
+		ActorID:  user.ID,
+		TargetID: user.ID,
+		Changes:  domain.AuditDiff(&before, user),
+	})
+	return nil
+}
+
+// checkPassword applies the password policy of ctx's tenant.
+func (s *userService) checkPassword(ctx context.Context, password string) error {
+	tenant, err := s.tenants.Tenant(domain.TenantID(ctx))
+	if err != nil {
+		return err
+	}
+	return tenant.PasswordPolicy.Check(password)
+}
+
+func newToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
+		return "", err
+	}
+	return hex.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
+}
+
+func (s *userService) ChangeStatus(
+	ctx context.Context,
+	id string,
+	to domain.UserStatus,
+	reason string,
+) (*domain.User, error) {
+	if (to == domain.StatusSuspended || to == domain.StatusDisabled) && strings.TrimSpace(reason) == "" {
+		return nil, fmt.Errorf("%w: a reason is required", domain.ErrInvalidStatus)
+	}
+
+	user, err := s.repo.FindByID(ctx, id)
+	if err != nil {
+		return nil, err
+	}
+
+	from := user.CurrentStatus()
+	if !from.CanTransitionTo(to) {
+		return nil, fmt.Errorf("%w: %s to %s", domain.ErrInvalidStatus, from, to)
+	}
+
+	before := *user
+	now := time.Now()
+	user.Status = to
+	user.StatusReason = strings.TrimSpace(reason)
+	user.StatusChangedAt = &now
+	user.UpdatedAt = now
+
+	if err := s.repo.Update(ctx, user); err != nil {
+		return nil, err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditUserStatusChanged,
+		TargetID: user.ID,
+		Changes:  domain.AuditDiff(&before, user),
+		Reason:   user.StatusReason,
+	})
+	return user, nil
 }
 
 func (s *userService) Delete(ctx context.Context, id string) error {
-	return s.repo.Delete(ctx, id)
+	if err := s.repo.Delete(ctx, id); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{Action: domain.AuditUserDeleted, TargetID: id})
+	return nil
+}
+
+func (s *userService) Restore(ctx context.Context, id string) error {
+	if err := s.repo.Restore(ctx, id); err != nil {
+		return err
+	}
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{Action: domain.AuditUserRestored, TargetID: id})
+	return nil
 }
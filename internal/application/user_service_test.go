@@ -3,11 +3,14 @@ package application_test
 import (
 	"context"
 	"errors"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
 	"golang.org/x/crypto/bcrypt"
 
 	jwtmocks "github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure/mocks"
@@ -30,13 +33,35 @@ func TestUserService_Register_Success(t *testing.T) {
 
 	jwt := &jwtmocks.JWTManagerMock{}
 
-	svc := application.NewUserService(repo, jwt)
+	svc := application.NewUserService(repo, jwt, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
 
 	err := svc.Register(context.Background(), "John", "john@test.com", "secret")
 
 	assert.NoError(t, err)
 }
 
+func TestUserService_Register_WeakPassword(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		CreateFn: func(ctx context.Context, user *domain.User) error {
+			t.Error("a weak password must not be stored")
+			return nil
+		},
+	}
+	tenants := &mocks.TenantRegistryMock{
+		TenantFn: func(id string) (*domain.Tenant, error) {
+			assert.Equal(t, "acme", id)
+			return &domain.Tenant{ID: id, PasswordPolicy: domain.PasswordPolicy{MinLength: 10, RequireDigit: true}}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, tenants)
+
+	err := svc.Register(domain.WithTenant(context.Background(), "acme"), "John", "john@test.com", "secret")
+
+	assert.ErrorIs(t, err, domain.ErrWeakPassword)
+	assert.ErrorContains(t, err, "at least 10 characters, a digit")
+}
+
 func TestUserService_Register_EmailExists(t *testing.T) {
 	repo := &mocks.UserRepositoryMock{
 		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
@@ -44,7 +69,7 @@ func TestUserService_Register_EmailExists(t *testing.T) {
 		},
 	}
 
-	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{})
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
 
 	err := svc.Register(context.Background(), "John", "john@test.com", "secret")
 
@@ -65,13 +90,15 @@ func TestUserService_Login_Success(t *testing.T) {
 	}
 
 	jwt := &jwtmocks.JWTManagerMock{
-		GenerateFn: func(userID string) (string, error) {
+		GenerateFn: func(userID, tenantID string, ttl time.Duration) (string, error) {
 			assert.Equal(t, "user-id", userID)
+			assert.Equal(t, domain.DefaultTenant, tenantID)
+			assert.Zero(t, ttl)
 			return "jwt-token", nil
 		},
 	}
 
-	svc := application.NewUserService(repo, jwt)
+	svc := application.NewUserService(repo, jwt, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
 
 	token, err := svc.Login(context.Background(), "john@test.com", "secret")
 
@@ -79,6 +106,36 @@ func TestUserService_Login_Success(t *testing.T) {
 	assert.Equal(t, "jwt-token", token)
 }
 
+func TestUserService_Login_TenantTokenTTL(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+
+	repo := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			assert.Equal(t, "acme", domain.TenantID(ctx))
+			return &domain.User{ID: "user-id", TenantID: "acme", Password: string(hash)}, nil
+		},
+	}
+	jwt := &jwtmocks.JWTManagerMock{
+		GenerateFn: func(userID, tenantID string, ttl time.Duration) (string, error) {
+			assert.Equal(t, "acme", tenantID)
+			assert.Equal(t, 15*time.Minute, ttl)
+			return "jwt-token", nil
+		},
+	}
+	tenants := &mocks.TenantRegistryMock{
+		TenantFn: func(id string) (*domain.Tenant, error) {
+			return &domain.Tenant{ID: id, TokenTTL: 15 * time.Minute}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, jwt, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, tenants)
+
+	token, err := svc.Login(domain.WithTenant(context.Background(), "acme"), "john@test.com", "secret")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "jwt-token", token)
+}
+
 func TestUserService_Login_InvalidPassword(t *testing.T) {
 	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
 
@@ -88,7 +145,7 @@ func TestUserService_Login_InvalidPassword(t *testing.T) {
 		},
 	}
 
-	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{})
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
 
 	_, err := svc.Login(context.Background(), "john@test.com", "wrong")
 
@@ -98,18 +155,561 @@ func TestUserService_Login_InvalidPassword(t *testing.T) {
 func TestUserService_Update(t *testing.T) {
 	repo := &mocks.UserRepositoryMock{
 		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
-			return &domain.User{ID: id}, nil
+			return &domain.User{ID: id, Email: "old@test.com"}, nil
+		},
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return nil, errors.New("not found")
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			assert.Equal(t, "New", user.Name)
+			assert.Equal(t, "old@test.com", user.Email)
+			assert.Equal(t, "new@test.com", user.PendingEmail.Email)
+			return nil
+		},
+	}
+
+	sent := map[string]string{}
+	mailer := &mocks.MailerMock{
+		SendFn: func(ctx context.Context, to, subject, body string) error {
+			sent[to] = body
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, mailer, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Update(context.Background(), "id", "New", "new@test.com", 0)
+
+	assert.NoError(t, err)
+	assert.Contains(t, sent, "new@test.com")
+	assert.Contains(t, sent, "old@test.com")
+}
+
+func TestUserService_Update_EmailTaken(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Email: "old@test.com"}, nil
+		},
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: "other"}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Update(context.Background(), "id", "New", "new@test.com", 0)
+
+	assert.ErrorIs(t, err, domain.ErrEmailTaken)
+}
+
+func TestUserService_ConfirmEmailChange(t *testing.T) {
+	var token string
+	stored := &domain.User{ID: "id", Email: "old@test.com"}
+
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return stored, nil
+		},
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return nil, errors.New("not found")
+		},
+		FindByTokenFn: func(ctx context.Context, tokenHash string) (*domain.User, error) {
+			if stored.PendingEmail == nil || stored.PendingEmail.TokenHash != tokenHash {
+				return nil, errors.New("not found")
+			}
+			return stored, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			stored = user
+			return nil
+		},
+	}
+	mailer := &mocks.MailerMock{
+		SendFn: func(ctx context.Context, to, subject, body string) error {
+			if to == "new@test.com" {
+				token = strings.Split(body, "\n\n")[1]
+			}
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, mailer, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	email := "new@test.com"
+	_, err := svc.Patch(context.Background(), "id", domain.UserPatch{Email: &email}, 0)
+	assert.NoError(t, err)
+
+	assert.ErrorIs(t, svc.ConfirmEmailChange(context.Background(), "wrong"), domain.ErrInvalidToken)
+
+	err = svc.ConfirmEmailChange(context.Background(), token)
+	assert.NoError(t, err)
+	assert.Equal(t, "new@test.com", stored.Email)
+	assert.Nil(t, stored.PendingEmail)
+}
+
+func TestUserService_Update_StaleVersion(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Version: 3}, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			t.Fatal("update must not be attempted")
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Update(context.Background(), "id", "New", "new@test.com", 2)
+
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
+}
+
+func TestUserService_List_AppliesDefaults(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindAllFn: func(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error) {
+			assert.Equal(t, domain.SortByCreatedAt, q.SortBy)
+			assert.Equal(t, domain.SortDesc, q.Order)
+			assert.Equal(t, domain.DefaultPageSize, q.Limit)
+			return &domain.UserPage{Total: 0}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.List(context.Background(), domain.UserQuery{})
+
+	assert.NoError(t, err)
+}
+
+func TestUserService_List_InvalidQuery(t *testing.T) {
+	svc := application.NewUserService(&mocks.UserRepositoryMock{}, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.List(context.Background(), domain.UserQuery{SortBy: "password"})
+	assert.ErrorIs(t, err, domain.ErrInvalidQuery)
+
+	_, err = svc.List(context.Background(), domain.UserQuery{Cursor: "abc", Offset: 10})
+	assert.ErrorIs(t, err, domain.ErrInvalidQuery)
+}
+
+func TestUserService_Search(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		SearchFn: func(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error) {
+			assert.Equal(t, "john", q.Text)
+			assert.Equal(t, domain.DefaultPageSize, q.Limit)
+			return &domain.UserPage{}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Search(context.Background(), domain.UserSearch{Text: "  john "})
+	assert.NoError(t, err)
+
+	_, err = svc.Search(context.Background(), domain.UserSearch{Text: "   "})
+	assert.ErrorIs(t, err, domain.ErrInvalidQuery)
+}
+
+func TestUserService_Restore(t *testing.T) {
+	restored := ""
+	repo := &mocks.UserRepositoryMock{
+		RestoreFn: func(ctx context.Context, id string) error {
+			restored = id
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	err := svc.Restore(context.Background(), "id")
+
+	assert.NoError(t, err)
+	assert.Equal(t, "id", restored)
+}
+
+func TestUserService_Patch_OnlyProvidedFields(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Name: "Old", Email: "old@test.com", Version: 1}, nil
 		},
 		UpdateFn: func(ctx context.Context, user *domain.User) error {
 			assert.Equal(t, "New", user.Name)
-			assert.Equal(t, "new@test.com", user.Email)
+			assert.Equal(t, "old@test.com", user.Email)
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	name := "New"
+	user, err := svc.Patch(context.Background(), "id", domain.UserPatch{Name: &name}, 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "New", user.Name)
+}
+
+func TestUserService_Patch_RejectsBlankField(t *testing.T) {
+	svc := application.NewUserService(&mocks.UserRepositoryMock{}, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	blank := " "
+	_, err := svc.Patch(context.Background(), "id", domain.UserPatch{Email: &blank}, 0)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+}
+
+func TestUserService_Patch_ValidatesMergedAttributes(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{
+				ID:      id,
+				Profile: domain.Profile{Attributes: map[string]interface{}{"team": "core"}},
+			}, nil
+		},
+	}
+	attrs := &mocks.AttributeValidatorMock{
+		ValidateFn: func(attrs map[string]interface{}) error {
+			assert.Equal(t, map[string]interface{}{"team": "core", "level": float64(2)}, attrs)
+			return errors.New("level: not allowed")
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, attrs, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Patch(context.Background(), "id", domain.UserPatch{
+		Attributes: map[string]interface{}{"level": float64(2)},
+	}, 0)
+
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+}
+
+func TestUserService_Patch_MergesAttributes(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{
+				ID: id,
+				Profile: domain.Profile{Attributes: map[string]interface{}{
+					"team":  "core",
+					"prefs": map[string]interface{}{"theme": "dark"},
+					"tags":  map[string]interface{}{"a": true},
+				}},
+			}, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error { return nil },
+	}
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	user, err := svc.Patch(context.Background(), "id", domain.UserPatch{
+		Attributes: map[string]interface{}{
+			"team":  nil,
+			"prefs": map[string]interface{}{"theme": nil},
+			"tags":  map[string]interface{}{},
+			"flags": map[string]interface{}{},
+		},
+	}, 0)
+
+	// Only null removes a key; objects left or given empty stay.
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"prefs": map[string]interface{}{},
+		"tags":  map[string]interface{}{"a": true},
+		"flags": map[string]interface{}{},
+	}, user.Attributes)
+}
+
+func TestUserService_Patch_Profile(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id}, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			assert.Equal(t, "Asia/Bangkok", user.Timezone)
+			assert.Equal(t, "+66812345678", user.Phone)
+			assert.False(t, user.UpdatedAt.IsZero())
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	tz, phone, bad := "Asia/Bangkok", "+66812345678", "0812345678"
+
+	_, err := svc.Patch(context.Background(), "id", domain.UserPatch{Timezone: &tz, Phone: &phone}, 0)
+	assert.NoError(t, err)
+
+	_, err = svc.Patch(context.Background(), "id", domain.UserPatch{Phone: &bad}, 0)
+	assert.ErrorIs(t, err, domain.ErrInvalidPatch)
+}
+
+func TestUserService_Login_Suspended(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+
+	repo := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: "user-id", Password: string(hash), Status: domain.StatusSuspended}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Login(context.Background(), "john@test.com", "secret")
+
+	assert.ErrorIs(t, err, domain.ErrAccountInactive)
+}
+
+func TestUserService_ChangeStatus(t *testing.T) {
+	var saved *domain.User
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id}, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			saved = user
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.ChangeStatus(context.Background(), "id", domain.StatusSuspended, "")
+	assert.ErrorIs(t, err, domain.ErrInvalidStatus)
+
+	user, err := svc.ChangeStatus(context.Background(), "id", domain.StatusSuspended, "spam")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.StatusSuspended, saved.Status)
+	assert.Equal(t, "spam", user.StatusReason)
+	assert.NotNil(t, user.StatusChangedAt)
+}
+
+func TestUserService_ChangeStatus_InvalidTransition(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Status: domain.StatusDisabled}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	_, err := svc.ChangeStatus(context.Background(), "id", domain.StatusSuspended, "spam")
+
+	assert.ErrorIs(t, err, domain.ErrInvalidStatus)
+}
+
+func TestUserService_Authenticate(t *testing.T) {
+	status := domain.StatusActive
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, TenantID: domain.TenantID(ctx), Status: status}, nil
+		},
+	}
+	jwt := &jwtmocks.JWTManagerMock{
+		ValidateFn: func(token string) (*infrastructure.Claims, error) {
+			if token != "jwt-token" {
+				return nil, errors.New("invalid token")
+			}
+			return &infrastructure.Claims{UserID: "user-id", TenantID: "acme"}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, jwt, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	user, err := svc.Authenticate(context.Background(), "jwt-token")
+	assert.NoError(t, err)
+	assert.Equal(t, "user-id", user.ID)
+	assert.Equal(t, "acme", user.TenantID)
+
+	_, err = svc.Authenticate(context.Background(), "forged")
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated)
+
+	_, err = svc.Authenticate(domain.WithTenant(context.Background(), "other"), "jwt-token")
+	assert.ErrorIs(t, err, domain.ErrUnauthenticated, "a token only works in its own tenant")
+
+	status = domain.StatusSuspended
+	_, err = svc.Authenticate(context.Background(), "jwt-token")
+	assert.ErrorIs(t, err, domain.ErrAccountInactive)
+}
+
+func TestUserService_Login_RecordsFailure(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.DefaultCost)
+
+	repo := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return &domain.User{ID: "user-id", Password: string(hash)}, nil
+		},
+	}
+
+	var recorded []domain.AuditEvent
+	audit := &mocks.AuditServiceMock{
+		RecordFn: func(ctx context.Context, e domain.AuditEvent) error {
+			recorded = append(recorded, e)
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, audit, &mocks.TenantRegistryMock{})
+
+	_, err := svc.Login(context.Background(), "john@test.com", "wrong")
+
+	assert.Error(t, err)
+	assert.Len(t, recorded, 1)
+	assert.Equal(t, domain.AuditLoginFailed, recorded[0].Action)
+	assert.Equal(t, "user-id", recorded[0].TargetID)
+}
+
+func TestUserService_Patch_RecordsDiff(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			return &domain.User{ID: id, Name: "Old", Email: "john@test.com", Version: 3}, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			return nil
+		},
+	}
+
+	var recorded domain.AuditEvent
+	audit := &mocks.AuditServiceMock{
+		RecordFn: func(ctx context.Context, e domain.AuditEvent) error {
+			recorded = e
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, audit, &mocks.TenantRegistryMock{})
+
+	name := "New"
+	_, err := svc.Patch(context.Background(), "id", domain.UserPatch{Name: &name}, 0)
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.AuditUserUpdated, recorded.Action)
+	assert.Equal(t, map[string]domain.AuditChange{
+		"name": {Before: "Old", After: "New"},
+	}, recorded.Changes)
+}
+
+func TestUserService_Register_RecordsRedactedPassword(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			return nil, errors.New("not found")
+		},
+		CreateFn: func(ctx context.Context, user *domain.User) error {
+			user.ID = "user-id"
+			return nil
+		},
+	}
+
+	var recorded domain.AuditEvent
+	audit := &mocks.AuditServiceMock{
+		RecordFn: func(ctx context.Context, e domain.AuditEvent) error {
+			recorded = e
+			return nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, audit, &mocks.TenantRegistryMock{})
+
+	err := svc.Register(context.Background(), "John", "john@test.com", "secret")
+
+	assert.NoError(t, err)
+	assert.Equal(t, domain.AuditUserRegistered, recorded.Action)
+	assert.Equal(t, "user-id", recorded.TargetID)
+	assert.Equal(t, domain.AuditChange{After: domain.Redacted}, recorded.Changes["password"])
+	assert.Equal(t, domain.AuditChange{After: "John"}, recorded.Changes["name"])
+}
+
+func TestUserService_AcceptInvitation(t *testing.T) {
+	var stored *domain.User
+	var token string
+
+	repo := &mocks.UserRepositoryMock{
+		CreateManyFn: func(ctx context.Context, users []*domain.User) error {
+			stored = users[0]
+			stored.ID = "id"
+			return nil
+		},
+		FindByInvitationTokenFn: func(ctx context.Context, tokenHash string) (*domain.User, error) {
+			if stored.Invitation == nil || stored.Invitation.TokenHash != tokenHash {
+				return nil, domain.ErrNotFound
+			}
+			u := *stored
+			return &u, nil
+		},
+		UpdateWithPasswordFn: func(ctx context.Context, user *domain.User) error {
+			stored = user
+			return nil
+		},
+	}
+	mailer := &mocks.MailerMock{
+		SendFn: func(ctx context.Context, to, subject, body string) error {
+			token = strings.Split(body, "\n\n")[1]
 			return nil
 		},
 	}
 
-	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{})
+	transfer := application.NewUserTransferService(repo, mailer, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+	_, err := transfer.Import(context.Background(), strings.NewReader("name,email\nJohn,john@test.com\n"), domain.FormatCSV, domain.ImportOptions{Invite: true})
+	assert.NoError(t, err)
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, mailer, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	assert.ErrorIs(t, svc.AcceptInvitation(context.Background(), "wrong", "secret"), domain.ErrInvalidToken)
+	assert.ErrorIs(t, svc.AcceptInvitation(context.Background(), token, ""), domain.ErrInvalidPatch)
+
+	assert.NoError(t, svc.AcceptInvitation(context.Background(), token, "secret"))
+	assert.Equal(t, domain.StatusActive, stored.Status)
+	assert.Nil(t, stored.Invitation)
+	assert.NoError(t, bcrypt.CompareHashAndPassword([]byte(stored.Password), []byte("secret")))
+
+	assert.ErrorIs(t, svc.AcceptInvitation(context.Background(), token, "again"), domain.ErrInvalidToken, "the token is single use")
+}
+
+func TestUserService_AcceptInvitation_Expired(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByInvitationTokenFn: func(ctx context.Context, tokenHash string) (*domain.User, error) {
+			return &domain.User{
+				ID:         "id",
+				Status:     domain.StatusPending,
+				Invitation: &domain.Invitation{TokenHash: tokenHash, ExpiresAt: time.Now().Add(-time.Minute)},
+			}, nil
+		},
+	}
+
+	svc := application.NewUserService(repo, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
+
+	assert.ErrorIs(t, svc.AcceptInvitation(context.Background(), "token", "secret"), domain.ErrInvalidToken)
+}
+
+func TestUserService_Patch_StaleCache(t *testing.T) {
+	ctx := context.Background()
+	stored := &domain.User{ID: "u1", Name: "Alice", Version: 1}
+	repo := &mocks.UserRepositoryMock{
+		FindByIDFn: func(ctx context.Context, id string) (*domain.User, error) {
+			u := *stored
+			return &u, nil
+		},
+		UpdateFn: func(ctx context.Context, user *domain.User) error {
+			if user.Version != stored.Version {
+				return domain.ErrVersionConflict
+			}
+			user.Version++
+			stored = user
+			return nil
+		},
+	}
+	cached := application.NewCachedUserRepository(repo, &mocks.CacheMock{}, time.Minute, time.Second)
+	svc := application.NewUserService(cached, &jwtmocks.JWTManagerMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{}, &mocks.TenantRegistryMock{})
 
-	err := svc.Update(context.Background(), "id", "New", "new@test.com")
+	_, err := cached.FindByID(ctx, "u1")
+	assert.NoError(t, err)
+	// Written elsewhere, past the cache.
+	stored = &domain.User{ID: "u1", Name: "Bob", Version: 2}
 
+	// The client saw the stored version; the cached one is older.
+	name := "Carol"
+	user, err := svc.Patch(ctx, "u1", domain.UserPatch{Name: &name}, 2)
 	assert.NoError(t, err)
+	assert.Equal(t, "Carol", user.Name)
+	assert.Equal(t, int64(3), stored.Version)
+
+	_, err = svc.Patch(ctx, "u1", domain.UserPatch{Name: &name}, 2)
+	assert.ErrorIs(t, err, domain.ErrVersionConflict)
 }
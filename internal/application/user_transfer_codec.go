@@ -0,0 +1,206 @@
+package application
+
+import (
+	"bufio"
+	"bytes"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// maxImportLine bounds one NDJSON line.
+const maxImportLine = 1 << 20
+
+// rowError is a row that could not be read. Reading goes on with the
+// next row.
+type rowError struct {
+	err error
+}
+
+func (e *rowError) Error() string { return e.err.Error() }
+
+// rowReader reads import rows. Next returns io.EOF after the last row and
+// a *rowError for a row it could not read.
+type rowReader interface {
+	Next() (domain.ImportRow, error)
+}
+
+func newRowReader(r io.Reader, format domain.TransferFormat) (rowReader, error) {
+	if format == domain.FormatCSV {
+		return newCSVRowReader(r)
+	}
+	s := bufio.NewScanner(r)
+	s.Buffer(make([]byte, 64*1024), maxImportLine)
+	return &ndjsonRowReader{s: s}, nil
+}
+
+// csvRowReader maps columns by the header row. name and email are
+// required; other columns, such as those an export adds, are ignored.
+type csvRowReader struct {
+	r    *csv.Reader
+	cols map[string]int
+}
+
+func newCSVRowReader(r io.Reader) (*csvRowReader, error) {
+	cr := csv.NewReader(r)
+	cr.FieldsPerRecord = -1
+	cr.TrimLeadingSpace = true
+
+	header, err := cr.Read()
+	if errors.Is(err, io.EOF) {
+		return nil, fmt.Errorf("%w: empty file", domain.ErrInvalidImport)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("%w: header: %v", domain.ErrInvalidImport, err)
+	}
+
+	cols := map[string]int{}
+	for i, name := range header {
+		if i == 0 {
+			name = strings.TrimPrefix(name, "\ufeff")
+		}
+		cols[strings.ToLower(strings.TrimSpace(name))] = i
+	}
+	for _, required := range []string{"name", "email"} {
+		if _, ok := cols[required]; !ok {
+			return nil, fmt.Errorf("%w: header has no %s column", domain.ErrInvalidImport, required)
+		}
+	}
+	return &csvRowReader{r: cr, cols: cols}, nil
+}
+
+func (c *csvRowReader) Next() (domain.ImportRow, error) {
+	record, err := c.r.Read()
+	if err != nil {
+		var parseErr *csv.ParseError
+		if errors.As(err, &parseErr) {
+			return domain.ImportRow{Line: parseErr.StartLine}, &rowError{err: parseErr.Err}
+		}
+		return domain.ImportRow{}, err
+	}
+	line, _ := c.r.FieldPos(0)
+
+	get := func(col string) string {
+		if i, ok := c.cols[col]; ok && i < len(record) {
+			return strings.TrimSpace(record[i])
+		}
+		return ""
+	}
+	row := domain.ImportRow{
+		Line:         line,
+		Name:         get("name"),
+		Email:        get("email"),
+		PasswordHash: get("password_hash"),
+		DisplayName:  get("display_name"),
+		Locale:       get("locale"),
+		Timezone:     get("timezone"),
+		Phone:        get("phone"),
+	}
+	if attrs := get("attributes"); attrs != "" {
+		if err := json.Unmarshal([]byte(attrs), &row.Attributes); err != nil {
+			return row, &rowError{err: errors.New("attributes must be a JSON object")}
+		}
+	}
+	return row, nil
+}
+
+// ndjsonRowReader reads one JSON object per line, skipping blank lines.
+// Unknown fields are ignored.
+type ndjsonRowReader struct {
+	s    *bufio.Scanner
+	line int
+}
+
+func (n *ndjsonRowReader) Next() (domain.ImportRow, error) {
+	for n.s.Scan() {
+		n.line++
+		b := bytes.TrimSpace(n.s.Bytes())
+		if len(b) == 0 {
+			continue
+		}
+
+		row := domain.ImportRow{Line: n.line}
+		if err := json.Unmarshal(b, &row); err != nil {
+			return row, &rowError{err: errors.New("not a JSON object of user fields")}
+		}
+		row.Line = n.line
+		return row, nil
+	}
+	if errors.Is(n.s.Err(), bufio.ErrTooLong) {
+		return domain.ImportRow{}, fmt.Errorf("%w: line %d is longer than %d bytes", domain.ErrInvalidImport, n.line+1, maxImportLine)
+	}
+	if err := n.s.Err(); err != nil {
+		return domain.ImportRow{}, err
+	}
+	return domain.ImportRow{}, io.EOF
+}
+
+// rowWriter writes exported users. Output may be buffered until Flush.
+type rowWriter interface {
+	Write(u *domain.User) error
+	Flush() error
+}
+
+func newRowWriter(w io.Writer, format domain.TransferFormat) (rowWriter, error) {
+	if format == domain.FormatCSV {
+		cw := csv.NewWriter(w)
+		if err := cw.Write(exportColumns); err != nil {
+			return nil, err
+		}
+		return &csvRowWriter{w: cw}, nil
+	}
+	bw := bufio.NewWriter(w)
+	return &ndjsonRowWriter{w: bw, enc: json.NewEncoder(bw)}, nil
+}
+
+// exportColumns are the import columns, but the password hash, and the
+// user's ID, status and timestamps.
+var exportColumns = []string{
+	"id", "name", "email", "status", "created_at", "updated_at",
+	"display_name", "locale", "timezone", "phone", "avatar_url", "attributes",
+}
+
+type csvRowWriter struct {
+	w *csv.Writer
+}
+
+func (c *csvRowWriter) Write(u *domain.User) error {
+	var attrs string
+	if len(u.Attributes) > 0 {
+		b, err := json.Marshal(u.Attributes)
+		if err != nil {
+			return err
+		}
+		attrs = string(b)
+	}
+	return c.w.Write([]string{
+		u.ID, u.Name, u.Email, string(u.CurrentStatus()),
+		u.CreatedAt.UTC().Format(time.RFC3339), u.UpdatedAt.UTC().Format(time.RFC3339),
+		u.DisplayName, u.Locale, u.Timezone, u.Phone, u.AvatarURL, attrs,
+	})
+}
+
+func (c *csvRowWriter) Flush() error {
+	c.w.Flush()
+	return c.w.Error()
+}
+
+// ndjsonRowWriter writes users as the API shows them, one per line.
+type ndjsonRowWriter struct {
+	w   *bufio.Writer
+	enc *json.Encoder
+}
+
+func (n *ndjsonRowWriter) Write(u *domain.User) error {
+	return n.enc.Encode(u)
+}
+
+func (n *ndjsonRowWriter) Flush() error {
+	return n.w.Flush()
+}
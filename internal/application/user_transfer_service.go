@@ -0,0 +1,273 @@
+package application
+
+import (
+	"cmp"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"slices"
+	"strings"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// InvitationTTL is how long an invitation to choose a password stays valid.
+const InvitationTTL = 7 * 24 * time.Hour
+
+// importBatchSize is how many users an import creates at once.
+const importBatchSize = 500
+
+type userTransferService struct {
+	repo   ports.UserRepository
+	mailer ports.Mailer
+	attrs  ports.AttributeValidator
+	audit  ports.AuditService
+}
+
+func NewUserTransferService(
+	r ports.UserRepository,
+	mailer ports.Mailer,
+	attrs ports.AttributeValidator,
+	audit ports.AuditService,
+) ports.UserTransferService {
+	return &userTransferService{repo: r, mailer: mailer, attrs: attrs, audit: audit}
+}
+
+// importedUser is a valid row waiting to be created.
+type importedUser struct {
+	line  int
+	user  *domain.User
+	token string
+}
+
+// Import creates users in batches. A batch is created at once unless one
+// of its emails is taken, in which case its users are created one by one
+// to tell which rows failed.
+func (s *userTransferService) Import(
+	ctx context.Context,
+	r io.Reader,
+	format domain.TransferFormat,
+	opts domain.ImportOptions,
+) (*domain.ImportReport, error) {
+	report := &domain.ImportReport{DryRun: opts.DryRun, Errors: []domain.ImportError{}}
+	done := func(err error) (*domain.ImportReport, error) {
+		slices.SortStableFunc(report.Errors, func(a, b domain.ImportError) int { return cmp.Compare(a.Line, b.Line) })
+		return report, err
+	}
+
+	rows, err := newRowReader(r, format)
+	if err != nil {
+		return done(err)
+	}
+
+	seen := map[string]int{}
+	var batch []importedUser
+	for {
+		row, err := rows.Next()
+		if errors.Is(err, io.EOF) {
+			break
+		}
+		var rowErr *rowError
+		if err != nil && !errors.As(err, &rowErr) {
+			return done(err)
+		}
+		report.Total++
+		if err != nil {
+			fail(report, row.Line, row.Email, err)
+			continue
+		}
+
+		if first, ok := seen[row.Email]; ok {
+			fail(report, row.Line, row.Email, fmt.Errorf("%w: also on line %d", domain.ErrEmailTaken, first))
+			continue
+		}
+		u, token, err := s.prepare(row, opts)
+		if err != nil {
+			fail(report, row.Line, row.Email, err)
+			continue
+		}
+		seen[row.Email] = row.Line
+
+		batch = append(batch, importedUser{line: row.Line, user: u, token: token})
+		if len(batch) == importBatchSize {
+			if err := s.flush(ctx, batch, opts, report); err != nil {
+				return done(err)
+			}
+			batch = batch[:0]
+		}
+	}
+
+	return done(s.flush(ctx, batch, opts, report))
+}
+
+// prepare turns a row into the user to create and, for an invitation,
+// the token to send.
+func (s *userTransferService) prepare(row domain.ImportRow, opts domain.ImportOptions) (*domain.User, string, error) {
+	if err := row.Validate(); err != nil {
+		return nil, "", err
+	}
+	if row.Attributes != nil {
+		if err := s.attrs.Validate(row.Attributes); err != nil {
+			return nil, "", fmt.Errorf("%w: attributes: %v", domain.ErrInvalidImport, err)
+		}
+	}
+
+	now := time.Now()
+	u := &domain.User{
+		Name:      row.Name,
+		Email:     row.Email,
+		CreatedAt: now,
+		UpdatedAt: now,
+		Status:    domain.StatusActive,
+		Profile: domain.Profile{
+			DisplayName: row.DisplayName,
+			Locale:      row.Locale,
+			Timezone:    row.Timezone,
+			Phone:       row.Phone,
+			Attributes:  row.Attributes,
+		},
+	}
+
+	var token string
+	switch {
+	case row.PasswordHash != "":
+		if _, err := bcrypt.Cost([]byte(row.PasswordHash)); err != nil {
+			return nil, "", fmt.Errorf("%w: password_hash is not a bcrypt hash", domain.ErrInvalidImport)
+		}
+		u.Password = row.PasswordHash
+	case opts.Invite:
+		var err error
+		token, err = newToken()
+		if err != nil {
+			return nil, "", err
+		}
+		u.Status = domain.StatusPending
+		u.StatusChangedAt = &now
+		u.Invitation = &domain.Invitation{
+			TokenHash: hashToken(token),
+			ExpiresAt: now.Add(InvitationTTL),
+		}
+	default:
+		return nil, "", fmt.Errorf("%w: password_hash is required unless inviting", domain.ErrInvalidImport)
+	}
+	return u, token, nil
+}
+
+func (s *userTransferService) flush(
+	ctx context.Context,
+	batch []importedUser,
+	opts domain.ImportOptions,
+	report *domain.ImportReport,
+) error {
+	if len(batch) == 0 {
+		return nil
+	}
+
+	if opts.DryRun {
+		// Emails of deleted users are only caught by a real import.
+		for _, b := range batch {
+			_, err := s.repo.FindByEmail(ctx, b.user.Email)
+			switch {
+			case err == nil:
+				fail(report, b.line, b.user.Email, domain.ErrEmailTaken)
+			case errors.Is(err, domain.ErrNotFound):
+				report.Created++
+			default:
+				return err
+			}
+		}
+		return nil
+	}
+
+	users := make([]*domain.User, len(batch))
+	for i, b := range batch {
+		users[i] = b.user
+	}
+	err := s.repo.CreateMany(ctx, users)
+	if errors.Is(err, domain.ErrAlreadyExists) {
+		for _, b := range batch {
+			err := s.repo.Create(ctx, b.user)
+			if errors.Is(err, domain.ErrAlreadyExists) {
+				fail(report, b.line, b.user.Email, err)
+				continue
+			}
+			if err != nil {
+				return err
+			}
+			s.created(ctx, b, report)
+		}
+		return nil
+	}
+	if err != nil {
+		return err
+	}
+
+	for _, b := range batch {
+		s.created(ctx, b, report)
+	}
+	return nil
+}
+
+// created records an imported user and sends its invitation. An
+// invitation that cannot be sent is reported; the user is kept.
+func (s *userTransferService) created(ctx context.Context, b importedUser, report *domain.ImportReport) {
+	report.Created++
+
+	recordAudit(ctx, s.audit, domain.AuditEvent{
+		Action:   domain.AuditUserImported,
+		TargetID: b.user.ID,
+		Changes:  domain.AuditDiff(nil, b.user),
+	})
+
+	if b.token == "" {
+		return
+	}
+	err := s.mailer.Send(ctx, b.user.Email, "You have been invited",
+		"An account was created for you. Use this token to choose your password:\n\n"+b.token+
+			"\n\nIt expires at "+b.user.Invitation.ExpiresAt.UTC().Format(time.RFC1123)+".")
+	if err != nil {
+		report.Errors = append(report.Errors, domain.ImportError{
+			Line:  b.line,
+			Email: b.user.Email,
+			Error: "created, but the invitation could not be sent: " + err.Error(),
+		})
+		return
+	}
+	report.Invited++
+}
+
+// Export streams users from the repository, so the whole collection is
+// never held in memory.
+func (s *userTransferService) Export(ctx context.Context, w io.Writer, format domain.TransferFormat) (int64, error) {
+	out, err := newRowWriter(w, format)
+	if err != nil {
+		return 0, err
+	}
+
+	var n int64
+	err = s.repo.Each(ctx, func(u *domain.User) error {
+		if err := out.Write(u); err != nil {
+			return err
+		}
+		n++
+		return nil
+	})
+	if err != nil {
+		return n, err
+	}
+	return n, out.Flush()
+}
+
+// fail reports a row that was not imported.
+func fail(report *domain.ImportReport, line int, email string, err error) {
+	report.Failed++
+	report.Errors = append(report.Errors, domain.ImportError{
+		Line:  line,
+		Email: email,
+		Error: strings.TrimPrefix(err.Error(), domain.ErrInvalidImport.Error()+": "),
+	})
+}
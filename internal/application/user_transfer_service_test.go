@@ -0,0 +1,224 @@
+package application_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+	"golang.org/x/crypto/bcrypt"
+)
+
+func TestUserTransferService_Import_CSV(t *testing.T) {
+	hash, _ := bcrypt.GenerateFromPassword([]byte("secret"), bcrypt.MinCost)
+
+	var created []*domain.User
+	repo := &mocks.UserRepositoryMock{
+		CreateManyFn: func(ctx context.Context, users []*domain.User) error {
+			for i, u := range users {
+				u.ID = fmt.Sprintf("id%d", i)
+			}
+			created = append(created, users...)
+			return nil
+		},
+	}
+	svc := application.NewUserTransferService(repo, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+
+	in := "\ufeffName,Email,Password_Hash,Locale,Unknown\n" +
+		"John,john@test.com," + string(hash) + ",en-US,x\n" +
+		"Jane,not-an-email," + string(hash) + ",,\n" +
+		"Johnny,john@test.com," + string(hash) + ",,\n" +
+		"Ann,ann@test.com,,,\n" +
+		"Bob,bob@test.com,plain,,\n"
+
+	report, err := svc.Import(context.Background(), strings.NewReader(in), domain.FormatCSV, domain.ImportOptions{})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 5, report.Total)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 4, report.Failed)
+	if assert.Len(t, created, 1) {
+		assert.Equal(t, "john@test.com", created[0].Email)
+		assert.Equal(t, string(hash), created[0].Password)
+		assert.Equal(t, "en-US", created[0].Locale)
+		assert.Equal(t, domain.StatusActive, created[0].Status)
+	}
+	var lines []int
+	for _, e := range report.Errors {
+		lines = append(lines, e.Line)
+	}
+	assert.Equal(t, []int{3, 4, 5, 6}, lines)
+	if len(report.Errors) == 4 {
+		assert.Contains(t, report.Errors[1].Error, "also on line 2")
+		assert.Equal(t, "password_hash is required unless inviting", report.Errors[2].Error)
+		assert.Equal(t, "password_hash is not a bcrypt hash", report.Errors[3].Error)
+	}
+}
+
+func TestUserTransferService_Import_Invite(t *testing.T) {
+	var created []*domain.User
+	repo := &mocks.UserRepositoryMock{
+		CreateManyFn: func(ctx context.Context, users []*domain.User) error {
+			created = append(created, users...)
+			return nil
+		},
+	}
+	tokens := map[string]string{}
+	mailer := &mocks.MailerMock{
+		SendFn: func(ctx context.Context, to, subject, body string) error {
+			if to == "bob@test.com" {
+				return errors.New("mailbox full")
+			}
+			tokens[to] = strings.Split(body, "\n\n")[1]
+			return nil
+		},
+	}
+	svc := application.NewUserTransferService(repo, mailer, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+
+	in := `{"name":"Ann","email":"ann@test.com","attributes":{"team":"a"}}
+
+{"name":"Bob","email":"bob@test.com"}
+not json
+`
+	report, err := svc.Import(context.Background(), strings.NewReader(in), domain.FormatNDJSON, domain.ImportOptions{Invite: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, 3, report.Total)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 1, report.Invited)
+	assert.Equal(t, 1, report.Failed)
+	if assert.Len(t, report.Errors, 2) {
+		assert.Equal(t, 3, report.Errors[0].Line)
+		assert.Contains(t, report.Errors[0].Error, "invitation could not be sent")
+		assert.Equal(t, 4, report.Errors[1].Line)
+	}
+	if assert.Len(t, created, 2) {
+		ann := created[0]
+		assert.Equal(t, domain.StatusPending, ann.Status)
+		assert.Empty(t, ann.Password)
+		assert.Equal(t, "a", ann.Attributes["team"])
+		if assert.NotNil(t, ann.Invitation) {
+			assert.NotEmpty(t, tokens["ann@test.com"])
+			assert.NotEqual(t, tokens["ann@test.com"], ann.Invitation.TokenHash, "only the hash is stored")
+			assert.WithinDuration(t, time.Now().Add(application.InvitationTTL), ann.Invitation.ExpiresAt, time.Minute)
+		}
+	}
+}
+
+func TestUserTransferService_Import_TakenEmails(t *testing.T) {
+	var created []string
+	repo := &mocks.UserRepositoryMock{
+		CreateManyFn: func(ctx context.Context, users []*domain.User) error {
+			return domain.ErrEmailTaken
+		},
+		CreateFn: func(ctx context.Context, u *domain.User) error {
+			if u.Email == "bob@test.com" {
+				return domain.ErrEmailTaken
+			}
+			created = append(created, u.Email)
+			return nil
+		},
+	}
+	svc := application.NewUserTransferService(repo, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+
+	in := "name,email\nAnn,ann@test.com\nBob,bob@test.com\nCarol,carol@test.com\n"
+	report, err := svc.Import(context.Background(), strings.NewReader(in), domain.FormatCSV, domain.ImportOptions{Invite: true})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"ann@test.com", "carol@test.com"}, created)
+	assert.Equal(t, 2, report.Created)
+	assert.Equal(t, 1, report.Failed)
+	if assert.Len(t, report.Errors, 1) {
+		assert.Equal(t, domain.ImportError{Line: 3, Email: "bob@test.com", Error: domain.ErrEmailTaken.Error()}, report.Errors[0])
+	}
+}
+
+func TestUserTransferService_Import_DryRun(t *testing.T) {
+	repo := &mocks.UserRepositoryMock{
+		FindByEmailFn: func(ctx context.Context, email string) (*domain.User, error) {
+			if email == "bob@test.com" {
+				return &domain.User{Email: email}, nil
+			}
+			return nil, domain.ErrNotFound
+		},
+	}
+	svc := application.NewUserTransferService(repo, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+
+	in := "name,email\nAnn,ann@test.com\nBob,bob@test.com\n"
+	report, err := svc.Import(context.Background(), strings.NewReader(in), domain.FormatCSV, domain.ImportOptions{Invite: true, DryRun: true})
+
+	assert.NoError(t, err)
+	assert.True(t, report.DryRun)
+	assert.Equal(t, 1, report.Created)
+	assert.Equal(t, 0, report.Invited)
+	assert.Equal(t, 1, report.Failed)
+}
+
+func TestUserTransferService_Import_InvalidFile(t *testing.T) {
+	svc := application.NewUserTransferService(&mocks.UserRepositoryMock{}, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+
+	_, err := svc.Import(context.Background(), strings.NewReader("name,mail\n"), domain.FormatCSV, domain.ImportOptions{})
+	assert.ErrorIs(t, err, domain.ErrInvalidImport)
+
+	_, err = svc.Import(context.Background(), strings.NewReader(""), domain.FormatCSV, domain.ImportOptions{})
+	assert.ErrorIs(t, err, domain.ErrInvalidImport)
+}
+
+func TestUserTransferService_Export(t *testing.T) {
+	createdAt := time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC)
+	repo := &mocks.UserRepositoryMock{
+		EachFn: func(ctx context.Context, fn func(*domain.User) error) error {
+			for _, u := range []*domain.User{
+				{ID: "1", Name: "Ann", Email: "ann@test.com", Status: domain.StatusActive, CreatedAt: createdAt, UpdatedAt: createdAt},
+				{ID: "2", Name: "Bob, Jr.", Email: "bob@test.com", Status: domain.StatusPending, CreatedAt: createdAt, UpdatedAt: createdAt,
+					Profile: domain.Profile{Attributes: map[string]interface{}{"team": "b"}}},
+			} {
+				if err := fn(u); err != nil {
+					return err
+				}
+			}
+			return nil
+		},
+	}
+	svc := application.NewUserTransferService(repo, &mocks.MailerMock{}, &mocks.AttributeValidatorMock{}, &mocks.AuditServiceMock{})
+
+	var csv strings.Builder
+	n, err := svc.Export(context.Background(), &csv, domain.FormatCSV)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	assert.Equal(t,
+		"id,name,email,status,created_at,updated_at,display_name,locale,timezone,phone,avatar_url,attributes\n"+
+			"1,Ann,ann@test.com,active,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z,,,,,,\n"+
+			`2,"Bob, Jr.",bob@test.com,pending,2024-01-02T03:04:05Z,2024-01-02T03:04:05Z,,,,,,"{""team"":""b""}"`+"\n",
+		csv.String())
+
+	var ndjson strings.Builder
+	n, err = svc.Export(context.Background(), &ndjson, domain.FormatNDJSON)
+	assert.NoError(t, err)
+	assert.Equal(t, int64(2), n)
+	lines := strings.Split(strings.TrimSpace(ndjson.String()), "\n")
+	if assert.Len(t, lines, 2) {
+		assert.Contains(t, lines[1], `"email":"bob@test.com"`)
+		assert.NotContains(t, lines[1], "password")
+	}
+
+	// An export can be imported back with invitations.
+	var reimported []*domain.User
+	repo.CreateManyFn = func(ctx context.Context, users []*domain.User) error {
+		reimported = append(reimported, users...)
+		return nil
+	}
+	report, err := svc.Import(context.Background(), strings.NewReader(csv.String()), domain.FormatCSV, domain.ImportOptions{Invite: true})
+	assert.NoError(t, err)
+	assert.Equal(t, 2, report.Created)
+	if assert.Len(t, reimported, 2) {
+		assert.Equal(t, "Bob, Jr.", reimported[1].Name)
+		assert.Equal(t, "b", reimported[1].Attributes["team"])
+	}
+}
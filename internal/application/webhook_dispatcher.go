@@ -0,0 +1,125 @@
+package application
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+const (
+	// WebhookLease is how long a claimed delivery is hidden from other
+	// dispatchers while it is being sent.
+	WebhookLease = time.Minute
+
+	webhookMinBackoff = 30 * time.Second
+	webhookMaxBackoff = 6 * time.Hour
+)
+
+// WebhookDispatcher sends queued webhook deliveries. Failed deliveries are
+// retried with exponential backoff and end up dead after
+// domain.MaxWebhookAttempts tries.
+type WebhookDispatcher struct {
+	repo     ports.WebhookRepository
+	sender   ports.WebhookSender
+	interval time.Duration
+}
+
+func NewWebhookDispatcher(r ports.WebhookRepository, sender ports.WebhookSender, interval time.Duration) *WebhookDispatcher {
+	return &WebhookDispatcher{
+		repo:     r,
+		sender:   sender,
+		interval: interval,
+	}
+}
+
+// DispatchOnce sends every delivery that is due and returns how many succeeded.
+func (w *WebhookDispatcher) DispatchOnce(ctx context.Context) (int, error) {
+	delivered := 0
+	for {
+		d, err := w.repo.ClaimDelivery(ctx, WebhookLease)
+		if errors.Is(err, domain.ErrNotFound) {
+			return delivered, nil
+		}
+		if err != nil {
+			return delivered, err
+		}
+
+		if w.attempt(ctx, d) {
+			delivered++
+		}
+		if err := w.repo.UpdateDelivery(ctx, d); err != nil {
+			return delivered, err
+		}
+	}
+}
+
+// attempt tries d once and records the outcome on it.
+func (w *WebhookDispatcher) attempt(ctx context.Context, d *domain.WebhookDelivery) bool {
+	start := time.Now()
+	attempt := domain.WebhookAttempt{At: start}
+	d.Tries++
+
+	sub, err := w.repo.FindSubscription(domain.WithTenant(ctx, d.TenantID), d.SubscriptionID)
+	switch {
+	case err != nil:
+		attempt.Error = "subscription not found"
+		d.Attempts = append(d.Attempts, attempt)
+		d.Status = domain.DeliveryDead
+		return false
+	case !sub.Active:
+		attempt.Error = "subscription inactive"
+		d.Attempts = append(d.Attempts, attempt)
+		d.Status = domain.DeliveryDead
+		return false
+	}
+
+	attempt.StatusCode, err = w.sender.Send(ctx, sub, d)
+	attempt.Duration = time.Since(start)
+	if err == nil && (attempt.StatusCode < 200 || attempt.StatusCode > 299) {
+		err = fmt.Errorf("unexpected status %d", attempt.StatusCode)
+	}
+	if err != nil {
+		attempt.Error = err.Error()
+	}
+	d.Attempts = append(d.Attempts, attempt)
+
+	if err == nil {
+		now := time.Now()
+		d.Status = domain.DeliveryDelivered
+		d.DeliveredAt = &now
+		return true
+	}
+
+	if d.Tries >= domain.MaxWebhookAttempts {
+		d.Status = domain.DeliveryDead
+		log.Printf("webhook delivery %s to %s is dead: %v", d.ID, sub.URL, err)
+		return false
+	}
+
+	d.Status = domain.DeliveryPending
+	d.NextAttemptAt = time.Now().Add(backoff(d.Tries, webhookMinBackoff, webhookMaxBackoff))
+	return false
+}
+
+// Run dispatches on every interval until ctx is cancelled.
+func (w *WebhookDispatcher) Run(ctx context.Context) {
+	ticker := time.NewTicker(w.interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+			if _, err := w.DispatchOnce(ctx); err != nil {
+				log.Printf("dispatch webhooks failed: %v", err)
+			}
+		case <-ctx.Done():
+			log.Println("Stopping webhook dispatcher")
+			return
+		}
+	}
+}
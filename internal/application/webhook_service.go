@@ -0,0 +1,142 @@
+package application
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+type webhookService struct {
+	repo ports.WebhookRepository
+}
+
+func NewWebhookService(r ports.WebhookRepository) ports.WebhookService {
+	return &webhookService{repo: r}
+}
+
+func (s *webhookService) Subscribe(
+	ctx context.Context,
+	url string,
+	events []domain.EventType,
+) (*domain.WebhookSubscription, error) {
+	secret, err := newToken()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	sub := &domain.WebhookSubscription{
+		URL:        url,
+		EventTypes: events,
+		Secret:     secret,
+		Active:     true,
+		CreatedAt:  now,
+		UpdatedAt:  now,
+	}
+	if err := sub.Validate(); err != nil {
+		return nil, err
+	}
+
+	if err := s.repo.CreateSubscription(ctx, sub); err != nil {
+		return nil, err
+	}
+	return sub, nil
+}
+
+func (s *webhookService) GetSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	return s.repo.FindSubscription(ctx, id)
+}
+
+func (s *webhookService) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	return s.repo.ListSubscriptions(ctx)
+}
+
+func (s *webhookService) Unsubscribe(ctx context.Context, id string) error {
+	return s.repo.DeleteSubscription(ctx, id)
+}
+
+func (s *webhookService) Deliveries(
+	ctx context.Context,
+	q domain.WebhookDeliveryQuery,
+) ([]*domain.WebhookDelivery, error) {
+	if err := q.Normalize(); err != nil {
+		return nil, err
+	}
+	return s.repo.ListDeliveries(ctx, q)
+}
+
+func (s *webhookService) Redeliver(
+	ctx context.Context,
+	subscriptionID, deliveryID string,
+) (*domain.WebhookDelivery, error) {
+	d, err := s.repo.FindDelivery(ctx, deliveryID)
+	if err != nil {
+		return nil, err
+	}
+	if d.SubscriptionID != subscriptionID {
+		return nil, domain.ErrNotFound
+	}
+
+	d.Status = domain.DeliveryPending
+	d.Tries = 0
+	d.NextAttemptAt = time.Now()
+	d.DeliveredAt = nil
+	if err := s.repo.UpdateDelivery(ctx, d); err != nil {
+		return nil, err
+	}
+	return d, nil
+}
+
+type webhookQueue struct {
+	repo ports.WebhookRepository
+}
+
+// NewWebhookQueue returns a publisher that queues one delivery per
+// subscription wanting the event. The WebhookDispatcher sends them.
+func NewWebhookQueue(r ports.WebhookRepository) ports.EventPublisher {
+	return &webhookQueue{repo: r}
+}
+
+// Publish queues e for every subscription of its tenant that wants it.
+// The outbox may publish an event twice; the second time finds the
+// deliveries queued.
+func (s *webhookQueue) Publish(ctx context.Context, e domain.Event) error {
+	ctx = domain.WithTenant(ctx, e.TenantID)
+	tenant := domain.TenantID(ctx)
+	subs, err := s.repo.ListSubscriptions(ctx)
+	if err != nil {
+		return err
+	}
+
+	var payload []byte
+	for _, sub := range subs {
+		if sub.TenantID != tenant || !sub.Wants(e.Type) {
+			continue
+		}
+		if payload == nil {
+			if payload, err = json.Marshal(e); err != nil {
+				return err
+			}
+		}
+
+		now := time.Now()
+		err := s.repo.CreateDelivery(ctx, &domain.WebhookDelivery{
+			TenantID:       tenant,
+			SubscriptionID: sub.ID,
+			EventID:        e.ID,
+			EventType:      e.Type,
+			Payload:        payload,
+			Status:         domain.DeliveryPending,
+			NextAttemptAt:  now,
+			CreatedAt:      now,
+		})
+		if err != nil && !errors.Is(err, domain.ErrAlreadyExists) {
+			return err
+		}
+	}
+	return nil
+}
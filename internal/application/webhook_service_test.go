@@ -0,0 +1,192 @@
+package application_test
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/application"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports/mocks"
+)
+
+// receiver is an httptest webhook endpoint answering with the given
+// status codes in turn and checking every signature.
+func receiver(t *testing.T, secret *string, statuses ...int) (*httptest.Server, *int) {
+	calls := 0
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, _ := io.ReadAll(r.Body)
+		ok := infrastructure.VerifyWebhook(
+			*secret,
+			r.Header.Get(infrastructure.WebhookTimestampHeader),
+			r.Header.Get(infrastructure.WebhookSignatureHeader),
+			body,
+		)
+		assert.True(t, ok, "signature must verify")
+
+		status := statuses[min(calls, len(statuses)-1)]
+		calls++
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(srv.Close)
+	return srv, &calls
+}
+
+func subscribeAndQueue(t *testing.T, repo *mocks.WebhookRepositoryMock, url string) *domain.WebhookSubscription {
+	svc := application.NewWebhookService(repo)
+	sub, err := svc.Subscribe(context.Background(), url, []domain.EventType{domain.EventUserCreated})
+	assert.NoError(t, err)
+
+	queue := application.NewWebhookQueue(repo)
+	assert.NoError(t, queue.Publish(context.Background(), domain.Event{ID: "evt-1", Type: domain.EventUserCreated}))
+	return sub
+}
+
+func TestWebhookService_Subscribe_Validates(t *testing.T) {
+	svc := application.NewWebhookService(&mocks.WebhookRepositoryMock{})
+
+	_, err := svc.Subscribe(context.Background(), "ftp://example.com", nil)
+	assert.ErrorIs(t, err, domain.ErrInvalidWebhook)
+
+	_, err = svc.Subscribe(context.Background(), "https://example.com/hook", []domain.EventType{"user.exploded"})
+	assert.ErrorIs(t, err, domain.ErrInvalidWebhook)
+
+	sub, err := svc.Subscribe(context.Background(), "https://example.com/hook", nil)
+	assert.NoError(t, err)
+	assert.NotEmpty(t, sub.Secret)
+	assert.True(t, sub.Active)
+}
+
+func TestWebhookQueue_Publish_MatchesAndDeduplicates(t *testing.T) {
+	repo := &mocks.WebhookRepositoryMock{}
+	svc := application.NewWebhookService(repo)
+	_, _ = svc.Subscribe(context.Background(), "https://a.example.com", []domain.EventType{domain.EventUserCreated})
+	_, _ = svc.Subscribe(context.Background(), "https://b.example.com", []domain.EventType{domain.EventUserDeleted})
+	_, _ = svc.Subscribe(context.Background(), "https://c.example.com", nil)
+
+	queue := application.NewWebhookQueue(repo)
+	event := domain.Event{ID: "evt-1", Type: domain.EventUserCreated}
+
+	assert.NoError(t, queue.Publish(context.Background(), event))
+	assert.NoError(t, queue.Publish(context.Background(), event))
+
+	assert.Len(t, repo.Deliveries, 2)
+	assert.Equal(t, "sub-1", repo.Deliveries[0].SubscriptionID)
+	assert.Equal(t, "sub-3", repo.Deliveries[1].SubscriptionID)
+}
+
+func TestWebhookQueue_Publish_OnlyToTheEventsTenant(t *testing.T) {
+	repo := &mocks.WebhookRepositoryMock{}
+	svc := application.NewWebhookService(repo)
+	acme := domain.WithTenant(context.Background(), "acme")
+	_, _ = svc.Subscribe(acme, "https://acme.example.com", nil)
+	_, _ = svc.Subscribe(domain.WithTenant(context.Background(), "globex"), "https://globex.example.com", nil)
+
+	queue := application.NewWebhookQueue(repo)
+	assert.NoError(t, queue.Publish(context.Background(), domain.Event{ID: "evt-1", TenantID: "acme", Type: domain.EventUserCreated}))
+
+	if assert.Len(t, repo.Deliveries, 1) {
+		assert.Equal(t, "sub-1", repo.Deliveries[0].SubscriptionID)
+		assert.Equal(t, "acme", repo.Deliveries[0].TenantID)
+	}
+
+	subs, err := svc.ListSubscriptions(acme)
+	assert.NoError(t, err)
+	assert.Len(t, subs, 1)
+	assert.ErrorIs(t, svc.Unsubscribe(acme, "sub-2"), domain.ErrNotFound)
+}
+
+func TestWebhookDispatcher_DeliversSigned(t *testing.T) {
+	var secret string
+	srv, calls := receiver(t, &secret, http.StatusNoContent)
+
+	repo := &mocks.WebhookRepositoryMock{}
+	sub := subscribeAndQueue(t, repo, srv.URL)
+	secret = sub.Secret
+
+	dispatcher := application.NewWebhookDispatcher(repo, infrastructure.NewWebhookSender(time.Second), time.Second)
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 1, *calls)
+	d := repo.Deliveries[0]
+	assert.Equal(t, domain.DeliveryDelivered, d.Status)
+	assert.NotNil(t, d.DeliveredAt)
+	assert.Len(t, d.Attempts, 1)
+	assert.Equal(t, http.StatusNoContent, d.Attempts[0].StatusCode)
+}
+
+func TestWebhookDispatcher_RetriesWithBackoff(t *testing.T) {
+	var secret string
+	srv, _ := receiver(t, &secret, http.StatusInternalServerError)
+
+	repo := &mocks.WebhookRepositoryMock{}
+	sub := subscribeAndQueue(t, repo, srv.URL)
+	secret = sub.Secret
+
+	dispatcher := application.NewWebhookDispatcher(repo, infrastructure.NewWebhookSender(time.Second), time.Second)
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, n)
+	d := repo.Deliveries[0]
+	assert.Equal(t, domain.DeliveryPending, d.Status)
+	assert.Equal(t, 1, d.Tries)
+	assert.Equal(t, http.StatusInternalServerError, d.Attempts[0].StatusCode)
+	assert.WithinDuration(t, time.Now().Add(30*time.Second), d.NextAttemptAt, time.Second)
+}
+
+func TestWebhookDispatcher_DeadLetterAndRedeliver(t *testing.T) {
+	var secret string
+	srv, calls := receiver(t, &secret, http.StatusBadGateway, http.StatusOK)
+
+	repo := &mocks.WebhookRepositoryMock{}
+	sub := subscribeAndQueue(t, repo, srv.URL)
+	secret = sub.Secret
+
+	// Pretend every earlier try already failed.
+	d := repo.Deliveries[0]
+	d.Tries = domain.MaxWebhookAttempts - 1
+
+	dispatcher := application.NewWebhookDispatcher(repo, infrastructure.NewWebhookSender(time.Second), time.Second)
+
+	_, err := dispatcher.DispatchOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DeliveryDead, d.Status)
+
+	dead, err := application.NewWebhookService(repo).Deliveries(context.Background(), domain.WebhookDeliveryQuery{
+		SubscriptionID: sub.ID,
+		Status:         domain.DeliveryDead,
+	})
+	assert.NoError(t, err)
+	assert.Len(t, dead, 1)
+
+	_, err = application.NewWebhookService(repo).Redeliver(context.Background(), sub.ID, d.ID)
+	assert.NoError(t, err)
+	assert.Equal(t, domain.DeliveryPending, d.Status)
+	assert.Equal(t, 0, d.Tries)
+
+	n, err := dispatcher.DispatchOnce(context.Background())
+	assert.NoError(t, err)
+	assert.Equal(t, 1, n)
+	assert.Equal(t, 2, *calls)
+	assert.Equal(t, domain.DeliveryDelivered, d.Status)
+	assert.Len(t, d.Attempts, 2)
+}
+
+func TestWebhookService_Redeliver_WrongSubscription(t *testing.T) {
+	repo := &mocks.WebhookRepositoryMock{}
+	subscribeAndQueue(t, repo, "https://example.com/hook")
+
+	_, err := application.NewWebhookService(repo).Redeliver(context.Background(), "sub-99", repo.Deliveries[0].ID)
+
+	assert.ErrorIs(t, err, domain.ErrNotFound)
+}
@@ -0,0 +1,267 @@
+package domain
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strconv"
+	"time"
+)
+
+type AuditAction string
+
+const (
+	AuditUserRegistered     AuditAction = "user.registered"
+	AuditUserUpdated        AuditAction = "user.updated"
+	AuditUserEmailConfirmed AuditAction = "user.email_confirmed"
+	AuditUserStatusChanged  AuditAction = "user.status_changed"
+	AuditUserDeleted        AuditAction = "user.deleted"
+	AuditUserRestored       AuditAction = "user.restored"
+	AuditUserImported       AuditAction = "user.imported"
+	AuditInvitationAccepted AuditAction = "user.invitation_accepted"
+	AuditUserErased         AuditAction = "user.erased"
+	AuditUserPurged         AuditAction = "user.purged"
+	AuditEventsRedacted     AuditAction = "audit.redacted"
+	AuditLoginSucceeded     AuditAction = "auth.login_succeeded"
+	AuditLoginFailed        AuditAction = "auth.login_failed"
+
+	AuditOrganizationCreated AuditAction = "organization.created"
+	AuditMemberInvited       AuditAction = "organization.member_invited"
+	AuditMemberJoined        AuditAction = "organization.member_joined"
+	AuditMemberChanged       AuditAction = "organization.member_changed"
+	AuditMemberRemoved       AuditAction = "organization.member_removed"
+)
+
+// Redacted replaces secret values in audit diffs.
+const Redacted = "[REDACTED]"
+
+// AuditChange is the value of one field before and after a mutation.
+type AuditChange struct {
+	Before interface{} `json:"before"`
+	After  interface{} `json:"after"`
+}
+
+// AuditEvent records who did what to whom. Each tenant's events form a
+// hash chain of their own, numbered from 1: each Hash covers the event
+// and the previous event's hash, so editing or removing an event breaks
+// every hash after it. The one sanctioned edit is Redact, which rehashes
+// the event and keeps the hash it was chained with as OriginalHash; an
+// audit.redacted event later in the chain vouches for the new hash.
+type AuditEvent struct {
+	Seq       int64                  `json:"seq"`
+	TenantID  string                 `json:"tenant_id"`
+	Action    AuditAction            `json:"action"`
+	ActorID   string                 `json:"actor_id,omitempty"`
+	TargetID  string                 `json:"target_id,omitempty"`
+	Changes   map[string]AuditChange `json:"changes,omitempty"`
+	Reason    string                 `json:"reason,omitempty"`
+	IP        string                 `json:"ip,omitempty"`
+	RequestID string                 `json:"request_id,omitempty"`
+	Timestamp time.Time              `json:"timestamp"`
+	PrevHash  string                 `json:"prev_hash"`
+	Hash      string                 `json:"hash"`
+	// Redacted is set once personal data was removed from the event.
+	Redacted bool `json:"redacted,omitempty"`
+	// OriginalHash is the hash of the event as recorded, which the next
+	// event's PrevHash refers to. It is set by Redact.
+	OriginalHash string `json:"original_hash,omitempty"`
+}
+
+// Redact removes what the event holds about userID, the subject of an
+// erasure: the user's ID is replaced with pseudonym and, when the user
+// is the target, the diff and reason go; when the user is the actor,
+// their IP goes. The event is then rehashed. It reports whether the event
+// was about the user.
+func (e *AuditEvent) Redact(userID, pseudonym string) bool {
+	about := false
+	if e.TargetID == userID {
+		e.TargetID = pseudonym
+		e.Changes = nil
+		e.Reason = ""
+		about = true
+	}
+	if e.ActorID == userID {
+		e.ActorID = pseudonym
+		e.IP = ""
+		about = true
+	}
+	if !about {
+		return false
+	}
+	if e.OriginalHash == "" {
+		e.OriginalHash = e.Hash
+	}
+	e.Redacted = true
+	e.Hash = e.ComputeHash()
+	return true
+}
+
+// ChainHash is the hash the next event's PrevHash refers to.
+func (e *AuditEvent) ChainHash() string {
+	if e.OriginalHash != "" {
+		return e.OriginalHash
+	}
+	return e.Hash
+}
+
+// RedactionRecord is the audit.redacted event vouching for the hashes of
+// the redacted events. Its diff maps each event's sequence number to the
+// hash it was chained with and the hash it has now.
+func RedactionRecord(pseudonym string, redacted []*AuditEvent) AuditEvent {
+	changes := make(map[string]AuditChange, len(redacted))
+	for _, e := range redacted {
+		changes[strconv.FormatInt(e.Seq, 10)] = AuditChange{Before: e.ChainHash(), After: e.Hash}
+	}
+	return AuditEvent{Action: AuditEventsRedacted, TargetID: pseudonym, Changes: changes}
+}
+
+// ComputeHash returns the hash of e as it is now. Timestamps are hashed at
+// millisecond precision, the precision they are stored with. The tenant
+// is left out, as chains recorded before tenants existed were hashed
+// without one; an event moved to another tenant's chain breaks both
+// chains' numbering instead.
+func (e *AuditEvent) ComputeHash() string {
+	b, _ := json.Marshal(struct {
+		Seq       int64                  `json:"seq"`
+		Action    AuditAction            `json:"action"`
+		ActorID   string                 `json:"actor_id"`
+		TargetID  string                 `json:"target_id"`
+		Changes   map[string]AuditChange `json:"changes"`
+		Reason    string                 `json:"reason"`
+		IP        string                 `json:"ip"`
+		RequestID string                 `json:"request_id"`
+		Timestamp int64                  `json:"timestamp"`
+		PrevHash  string                 `json:"prev_hash"`
+	}{
+		e.Seq, e.Action, e.ActorID, e.TargetID, e.Changes, e.Reason,
+		e.IP, e.RequestID, e.Timestamp.UnixMilli(), e.PrevHash,
+	})
+	sum := sha256.Sum256(b)
+	return hex.EncodeToString(sum[:])
+}
+
+// AuditQuery selects audit events in chain order. Zero values mean "no constraint".
+type AuditQuery struct {
+	ActorID  string
+	TargetID string
+	Action   AuditAction
+	From     time.Time
+	Until    time.Time
+	AfterSeq int64
+	Limit    int
+}
+
+// Normalize fills defaults and validates the query.
+func (q *AuditQuery) Normalize() error {
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	if q.AfterSeq < 0 {
+		return fmt.Errorf("%w: after_seq must not be negative", ErrInvalidQuery)
+	}
+	if !q.From.IsZero() && !q.Until.IsZero() && q.Until.Before(q.From) {
+		return fmt.Errorf("%w: until is before from", ErrInvalidQuery)
+	}
+	return nil
+}
+
+// AuditVerification is the result of walking the hash chain.
+// BrokenAt is the sequence number of the first event that fails to verify.
+// Redacted counts the redacted events, whose current hashes were checked
+// against the audit.redacted events recording the redactions.
+type AuditVerification struct {
+	Valid    bool   `json:"valid"`
+	Checked  int64  `json:"checked"`
+	Redacted int64  `json:"redacted,omitempty"`
+	BrokenAt int64  `json:"broken_at,omitempty"`
+	Problem  string `json:"problem,omitempty"`
+}
+
+// AuditDiff lists the fields that differ between two versions of a user.
+// Either side may be nil. Secrets are never recorded, only that they changed.
+func AuditDiff(before, after *User) map[string]AuditChange {
+	changes := diffViews(auditView(before), auditView(after))
+
+	var oldPassword, newPassword string
+	if before != nil {
+		oldPassword = before.Password
+	}
+	if after != nil {
+		newPassword = after.Password
+	}
+	if oldPassword != newPassword {
+		change := AuditChange{}
+		if oldPassword != "" {
+			change.Before = Redacted
+		}
+		if newPassword != "" {
+			change.After = Redacted
+		}
+		changes["password"] = change
+	}
+
+	if len(changes) == 0 {
+		return nil
+	}
+	return changes
+}
+
+// MembershipAuditDiff is AuditDiff for memberships. The organization is
+// always named, changed or not, so every event says where it happened.
+func MembershipAuditDiff(before, after *Membership) map[string]AuditChange {
+	from, to := jsonView(before), jsonView(after)
+	for _, view := range []map[string]interface{}{from, to} {
+		delete(view, "created_at")
+		delete(view, "updated_at")
+	}
+
+	changes := diffViews(from, to)
+	changes["organization_id"] = AuditChange{Before: from["organization_id"], After: to["organization_id"]}
+	return changes
+}
+
+func diffViews(from, to map[string]interface{}) map[string]AuditChange {
+	changes := map[string]AuditChange{}
+	for key, v := range to {
+		if old, ok := from[key]; !ok || !reflect.DeepEqual(old, v) {
+			changes[key] = AuditChange{Before: from[key], After: v}
+		}
+	}
+	for key, old := range from {
+		if _, ok := to[key]; !ok {
+			changes[key] = AuditChange{Before: old}
+		}
+	}
+	return changes
+}
+
+// auditView is the JSON form of u without bookkeeping fields. Secrets are
+// already left out of the JSON form.
+func auditView(u *User) map[string]interface{} {
+	if u == nil {
+		return map[string]interface{}{}
+	}
+	view := jsonView(u)
+	delete(view, "version")
+	delete(view, "updated_at")
+	return view
+}
+
+// jsonView is the JSON form of v, empty for a nil pointer.
+func jsonView(v interface{}) map[string]interface{} {
+	view := map[string]interface{}{}
+	if rv := reflect.ValueOf(v); rv.Kind() == reflect.Pointer && rv.IsNil() {
+		return view
+	}
+	b, err := json.Marshal(v)
+	if err != nil {
+		return view
+	}
+	_ = json.Unmarshal(b, &view)
+	return view
+}
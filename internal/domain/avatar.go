@@ -0,0 +1,48 @@
+package domain
+
+import "fmt"
+
+const (
+	// MaxAvatarBytes caps the size of an uploaded avatar file.
+	MaxAvatarBytes = 5 << 20
+	// MaxAvatarPixels caps each side of an uploaded avatar image.
+	MaxAvatarPixels = 4096
+)
+
+type AvatarSize string
+
+const (
+	AvatarOriginal AvatarSize = "original"
+	AvatarLarge    AvatarSize = "256"
+	AvatarSmall    AvatarSize = "64"
+)
+
+// AvatarThumbnails are generated on upload, keyed by their square side in pixels.
+var AvatarThumbnails = map[AvatarSize]int{
+	AvatarLarge: 256,
+	AvatarSmall: 64,
+}
+
+func ParseAvatarSize(s string) (AvatarSize, error) {
+	if s == "" || AvatarSize(s) == AvatarOriginal {
+		return AvatarOriginal, nil
+	}
+	if _, ok := AvatarThumbnails[AvatarSize(s)]; ok {
+		return AvatarSize(s), nil
+	}
+	return "", fmt.Errorf("%w: unknown size %q", ErrInvalidAvatar, s)
+}
+
+// AvatarKey is where an avatar rendition is kept in blob storage.
+func AvatarKey(userID string, size AvatarSize) string {
+	return "avatars/" + userID + "/" + string(size)
+}
+
+// AvatarKeys lists the keys of every rendition of the user's avatar.
+func AvatarKeys(userID string) []string {
+	keys := []string{AvatarKey(userID, AvatarOriginal)}
+	for size := range AvatarThumbnails {
+		keys = append(keys, AvatarKey(userID, size))
+	}
+	return keys
+}
@@ -0,0 +1,30 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+var (
+	ErrInvalidQuery    = errors.New("invalid query")
+	ErrVersionConflict = errors.New("version conflict")
+	ErrInvalidPatch    = errors.New("invalid patch")
+	ErrAlreadyExists   = errors.New("already exists")
+	ErrEmailTaken      = fmt.Errorf("email %w", ErrAlreadyExists)
+	ErrInvalidToken    = errors.New("invalid or expired token")
+	ErrNotFound        = errors.New("not found")
+	ErrInvalidID       = errors.New("invalid id")
+	ErrInvalidAvatar   = errors.New("invalid avatar")
+	ErrInvalidStatus   = errors.New("invalid status transition")
+	ErrAccountInactive = errors.New("account is not active")
+	ErrUnauthenticated = errors.New("unauthenticated")
+	ErrInvalidWebhook  = errors.New("invalid webhook")
+	ErrInvalidImport   = errors.New("invalid import")
+	ErrEventsLost      = errors.New("events since the given id are no longer available")
+	ErrUnknownTenant   = errors.New("unknown tenant")
+	ErrWeakPassword    = errors.New("password does not meet the policy")
+	ErrForbidden       = errors.New("forbidden")
+
+	ErrInvalidOrganization = errors.New("invalid organization")
+	ErrLastOwner           = errors.New("the organization's last owner cannot leave or be demoted")
+)
@@ -0,0 +1,76 @@
+package domain
+
+import (
+	"encoding/json"
+	"time"
+)
+
+type EventType string
+
+const (
+	EventUserCreated  EventType = "user.created"
+	EventUserUpdated  EventType = "user.updated"
+	EventUserDeleted  EventType = "user.deleted"
+	EventUserRestored EventType = "user.restored"
+	// EventUserErased tells consumers to forget the user. It carries no data.
+	EventUserErased EventType = "user.erased"
+)
+
+// Event is a domain event. It is written to the outbox together with the
+// change it describes and published once that change is committed.
+type Event struct {
+	ID         string          `json:"id"`
+	Type       EventType       `json:"type"`
+	TenantID   string          `json:"tenant_id,omitempty"`
+	UserID     string          `json:"user_id"`
+	OccurredAt time.Time       `json:"occurred_at"`
+	Data       json.RawMessage `json:"data,omitempty"`
+}
+
+// Tenant is the tenant e belongs to. Events recorded before tenants
+// existed belong to the default one.
+func (e Event) Tenant() string {
+	if e.TenantID == "" {
+		return DefaultTenant
+	}
+	return e.TenantID
+}
+
+// NewUserEvent builds an event carrying a snapshot of u. Secrets are left
+// out by the user's JSON form. A nil u gives an event without data.
+func NewUserEvent(t EventType, tenantID, userID string, u *User) (Event, error) {
+	e := Event{Type: t, TenantID: tenantID, UserID: userID, OccurredAt: time.Now().UTC()}
+	if u != nil {
+		b, err := json.Marshal(u)
+		if err != nil {
+			return e, err
+		}
+		e.Data = b
+	}
+	return e, nil
+}
+
+// EventUserField is how an event's JSON names its user, for finding the
+// stored payloads about a user.
+func EventUserField(userID string) string {
+	return `"user_id":"` + userID + `"`
+}
+
+// WithoutData returns the event JSON in payload with its user snapshot
+// removed, for when the user is erased.
+func WithoutData(payload []byte) ([]byte, error) {
+	var e Event
+	if err := json.Unmarshal(payload, &e); err != nil {
+		return nil, err
+	}
+	e.Data = nil
+	return json.Marshal(e)
+}
+
+// OutboxMessage is an event waiting in the outbox to be published.
+type OutboxMessage struct {
+	Event
+	Attempts      int
+	LastError     string
+	NextAttemptAt time.Time
+}
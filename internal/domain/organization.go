@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+	"time"
+	"unicode/utf8"
+)
+
+const (
+	maxOrganizationName = 100
+	maxTeamName         = 64
+	maxTeams            = 20
+)
+
+// Organization groups users of a tenant. Its members hold a role in it
+// and may be sorted into teams.
+type Organization struct {
+	ID        string    `json:"id"`
+	TenantID  string    `json:"tenant_id"`
+	Name      string    `json:"name"`
+	CreatedAt time.Time `json:"created_at"`
+	UpdatedAt time.Time `json:"updated_at"`
+}
+
+// OrgRole is what a member may do in their organization.
+type OrgRole string
+
+const (
+	// OrgRoleOwner manages the organization and all its members.
+	OrgRoleOwner OrgRole = "owner"
+	// OrgRoleAdmin manages the members who are not owners.
+	OrgRoleAdmin OrgRole = "admin"
+	// OrgRoleMember only belongs.
+	OrgRoleMember OrgRole = "member"
+)
+
+// ParseOrgRole accepts the known roles.
+func ParseOrgRole(s string) (OrgRole, error) {
+	switch r := OrgRole(s); r {
+	case OrgRoleOwner, OrgRoleAdmin, OrgRoleMember:
+		return r, nil
+	}
+	return "", fmt.Errorf("%w: unknown role %q", ErrInvalidOrganization, s)
+}
+
+// CanManage reports whether a member with role r may invite, change or
+// remove members holding other, and grant other.
+func (r OrgRole) CanManage(other OrgRole) bool {
+	switch r {
+	case OrgRoleOwner:
+		return true
+	case OrgRoleAdmin:
+		return other != OrgRoleOwner
+	}
+	return false
+}
+
+type MembershipState string
+
+const (
+	// MembershipInvited is a membership the user has yet to accept.
+	MembershipInvited MembershipState = "invited"
+	MembershipActive  MembershipState = "active"
+)
+
+// Membership is a user's place in an organization. A user holds at most
+// one per organization.
+type Membership struct {
+	OrganizationID string          `json:"organization_id"`
+	UserID         string          `json:"user_id"`
+	Role           OrgRole         `json:"role"`
+	Teams          []string        `json:"teams,omitempty"`
+	State          MembershipState `json:"state"`
+	InvitedBy      string          `json:"invited_by,omitempty"`
+	CreatedAt      time.Time       `json:"created_at"`
+	UpdatedAt      time.Time       `json:"updated_at"`
+}
+
+// InTeam reports whether the member belongs to team.
+func (m *Membership) InTeam(team string) bool {
+	return slices.Contains(m.Teams, team)
+}
+
+// MembershipPatch changes the fields that are set.
+type MembershipPatch struct {
+	Role  *OrgRole
+	Teams *[]string
+}
+
+// NormalizeOrganizationName trims name and checks its length.
+func NormalizeOrganizationName(name string) (string, error) {
+	name = strings.TrimSpace(name)
+	if name == "" {
+		return "", fmt.Errorf("%w: name cannot be empty", ErrInvalidOrganization)
+	}
+	if utf8.RuneCountInString(name) > maxOrganizationName {
+		return "", fmt.Errorf("%w: name is longer than %d characters", ErrInvalidOrganization, maxOrganizationName)
+	}
+	return name, nil
+}
+
+// NormalizeTeams trims the team names and returns them sorted, without
+// duplicates.
+func NormalizeTeams(teams []string) ([]string, error) {
+	var normalized []string
+	for _, t := range teams {
+		t = strings.TrimSpace(t)
+		if t == "" {
+			return nil, fmt.Errorf("%w: team name cannot be empty", ErrInvalidOrganization)
+		}
+		if utf8.RuneCountInString(t) > maxTeamName {
+			return nil, fmt.Errorf("%w: team name is longer than %d characters", ErrInvalidOrganization, maxTeamName)
+		}
+		normalized = append(normalized, t)
+	}
+	slices.Sort(normalized)
+	normalized = slices.Compact(normalized)
+	if len(normalized) > maxTeams {
+		return nil, fmt.Errorf("%w: more than %d teams", ErrInvalidOrganization, maxTeams)
+	}
+	return normalized, nil
+}
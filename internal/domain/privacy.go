@@ -0,0 +1,29 @@
+package domain
+
+import "time"
+
+// SubjectArchive is everything held about a user, as answered to a
+// subject access request. The avatar is only there when one was uploaded.
+type SubjectArchive struct {
+	GeneratedAt time.Time     `json:"generated_at"`
+	User        *User         `json:"user"`
+	Avatar      *AvatarFile   `json:"avatar,omitempty"`
+	AuditEvents []*AuditEvent `json:"audit_events"`
+	Memberships []*Membership `json:"memberships"`
+}
+
+// AvatarFile is an uploaded avatar; Data is base64 in JSON.
+type AvatarFile struct {
+	ContentType string `json:"content_type"`
+	Data        []byte `json:"data"`
+}
+
+// ErasureReport sums up an erasure. Pseudonym replaces the user's ID in
+// the audit log and names the user.erased event recorded for it.
+type ErasureReport struct {
+	Pseudonym         string `json:"pseudonym"`
+	UserDeleted       bool   `json:"user_deleted"`
+	AuditEvents       int64  `json:"audit_events_redacted"`
+	WebhookDeliveries int64  `json:"webhook_deliveries_redacted"`
+	Memberships       int64  `json:"memberships_removed"`
+}
@@ -0,0 +1,22 @@
+package domain
+
+import "context"
+
+// RequestMeta describes the request a use case runs on behalf of.
+// Inbound adapters attach it to the context; it is empty for background work.
+type RequestMeta struct {
+	ActorID   string
+	IP        string
+	RequestID string
+}
+
+type requestMetaKey struct{}
+
+func WithRequestMeta(ctx context.Context, m RequestMeta) context.Context {
+	return context.WithValue(ctx, requestMetaKey{}, m)
+}
+
+func RequestMetaFrom(ctx context.Context) RequestMeta {
+	m, _ := ctx.Value(requestMetaKey{}).(RequestMeta)
+	return m
+}
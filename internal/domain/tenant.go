@@ -0,0 +1,93 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"time"
+	"unicode"
+)
+
+// DefaultTenant owns the users stored before tenants existed, and serves
+// requests that name no tenant.
+const DefaultTenant = "default"
+
+// Tenant is a customer hosted on the deployment. Its users are invisible
+// to every other tenant.
+type Tenant struct {
+	ID string `json:"id"`
+	// Hosts are the request hosts that select the tenant.
+	Hosts          []string       `json:"hosts,omitempty"`
+	PasswordPolicy PasswordPolicy `json:"password_policy"`
+	// TokenTTL is how long the tenant's login tokens stay valid.
+	TokenTTL time.Duration `json:"-"`
+}
+
+// PasswordPolicy is what a tenant requires of new passwords. The zero
+// policy accepts any password.
+type PasswordPolicy struct {
+	MinLength     int  `json:"min_length,omitempty"`
+	RequireUpper  bool `json:"require_upper,omitempty"`
+	RequireLower  bool `json:"require_lower,omitempty"`
+	RequireDigit  bool `json:"require_digit,omitempty"`
+	RequireSymbol bool `json:"require_symbol,omitempty"`
+}
+
+// Check reports every rule password breaks, wrapped in ErrWeakPassword.
+func (p PasswordPolicy) Check(password string) error {
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case !unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+
+	var broken []string
+	if n := len([]rune(password)); n < p.MinLength {
+		broken = append(broken, fmt.Sprintf("at least %d characters", p.MinLength))
+	}
+	if p.RequireUpper && !upper {
+		broken = append(broken, "an uppercase letter")
+	}
+	if p.RequireLower && !lower {
+		broken = append(broken, "a lowercase letter")
+	}
+	if p.RequireDigit && !digit {
+		broken = append(broken, "a digit")
+	}
+	if p.RequireSymbol && !symbol {
+		broken = append(broken, "a symbol")
+	}
+	if len(broken) > 0 {
+		return fmt.Errorf("%w: needs %s", ErrWeakPassword, strings.Join(broken, ", "))
+	}
+	return nil
+}
+
+type tenantKey struct{}
+
+// WithTenant scopes ctx, and the repositories used with it, to a tenant.
+func WithTenant(ctx context.Context, tenantID string) context.Context {
+	return context.WithValue(ctx, tenantKey{}, tenantID)
+}
+
+// TenantFrom returns the tenant ctx was scoped to, if any.
+func TenantFrom(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(tenantKey{}).(string)
+	return id, ok
+}
+
+// TenantID returns the tenant ctx was scoped to, DefaultTenant when none.
+func TenantID(ctx context.Context) string {
+	if id, ok := TenantFrom(ctx); ok && id != "" {
+		return id
+	}
+	return DefaultTenant
+}
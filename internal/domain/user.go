@@ -3,9 +3,54 @@ package domain
 import "time"
 
 type User struct {
-	ID        string    `json:"id" bson:"_id,omitempty"`
-	Name      string    `json:"name" bson:"name"`
-	Email     string    `json:"email" bson:"email"`
-	Password  string    `json:"-" bson:"password"`
-	CreatedAt time.Time `json:"created_at" bson:"created_at"`
+	ID string `json:"id"`
+	// TenantID is set by the repository from the context the user is
+	// created with.
+	TenantID  string     `json:"tenant_id"`
+	Name      string     `json:"name"`
+	Email     string     `json:"email"`
+	Password  string     `json:"-"`
+	CreatedAt time.Time  `json:"created_at"`
+	UpdatedAt time.Time  `json:"updated_at"`
+	DeletedAt *time.Time `json:"deleted_at,omitempty"`
+	// Version is bumped on every update and guards against lost updates.
+	Version int64 `json:"version"`
+	// PendingEmail is set while an email change awaits confirmation.
+	PendingEmail *EmailChange `json:"pending_email,omitempty"`
+	// Invitation is set while an invited user has yet to choose a password.
+	Invitation *Invitation `json:"invitation,omitempty"`
+
+	Status          UserStatus `json:"status"`
+	StatusReason    string     `json:"status_reason,omitempty"`
+	StatusChangedAt *time.Time `json:"status_changed_at,omitempty"`
+
+	Profile
+}
+
+// PurgedUser names a user removed for good by a purge.
+type PurgedUser struct {
+	ID       string
+	TenantID string
+}
+
+type EmailChange struct {
+	Email     string    `json:"email"`
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+type Invitation struct {
+	TokenHash string    `json:"-"`
+	ExpiresAt time.Time `json:"expires_at"`
+}
+
+// Profile holds the optional, user-editable details of a User.
+type Profile struct {
+	DisplayName string `json:"display_name,omitempty"`
+	Locale      string `json:"locale,omitempty"`
+	Timezone    string `json:"timezone,omitempty"`
+	Phone       string `json:"phone,omitempty"`
+	AvatarURL   string `json:"avatar_url,omitempty"`
+	// Attributes are free-form, validated against the configured JSON Schema.
+	Attributes map[string]interface{} `json:"attributes,omitempty"`
 }
@@ -0,0 +1,172 @@
+package domain
+
+import (
+	"fmt"
+	"net/mail"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"time"
+	"unicode/utf8"
+
+	"golang.org/x/text/language"
+)
+
+const MaxDisplayNameLength = 100
+
+var e164 = regexp.MustCompile(`^\+[1-9][0-9]{1,14}$`)
+
+// UserPatch lists the fields to change. Nil fields are left untouched;
+// an empty string clears an optional profile field.
+type UserPatch struct {
+	Name  *string
+	Email *string
+
+	DisplayName *string
+	Locale      *string
+	Timezone    *string
+	Phone       *string
+	AvatarURL   *string
+	// Attributes is merged into the existing attributes following
+	// JSON Merge Patch rules: a nil value removes the key.
+	Attributes map[string]interface{}
+}
+
+func (p UserPatch) IsEmpty() bool {
+	return p.Name == nil && p.Email == nil &&
+		p.DisplayName == nil && p.Locale == nil && p.Timezone == nil &&
+		p.Phone == nil && p.AvatarURL == nil && p.Attributes == nil
+}
+
+func (p UserPatch) Validate() error {
+	if p.Name != nil && strings.TrimSpace(*p.Name) == "" {
+		return fmt.Errorf("%w: name cannot be empty", ErrInvalidPatch)
+	}
+	if p.Email != nil {
+		if strings.TrimSpace(*p.Email) == "" {
+			return fmt.Errorf("%w: email cannot be empty", ErrInvalidPatch)
+		}
+		if a, err := mail.ParseAddress(*p.Email); err != nil || a.Address != *p.Email {
+			return fmt.Errorf("%w: invalid email", ErrInvalidPatch)
+		}
+	}
+
+	if p.DisplayName != nil && utf8.RuneCountInString(*p.DisplayName) > MaxDisplayNameLength {
+		return fmt.Errorf("%w: display_name is longer than %d characters", ErrInvalidPatch, MaxDisplayNameLength)
+	}
+	if p.Locale != nil && *p.Locale != "" {
+		if _, err := language.Parse(*p.Locale); err != nil {
+			return fmt.Errorf("%w: invalid locale", ErrInvalidPatch)
+		}
+	}
+	if p.Timezone != nil && *p.Timezone != "" {
+		if _, err := time.LoadLocation(*p.Timezone); err != nil {
+			return fmt.Errorf("%w: invalid timezone", ErrInvalidPatch)
+		}
+	}
+	if p.Phone != nil && *p.Phone != "" && !e164.MatchString(*p.Phone) {
+		return fmt.Errorf("%w: phone must be in E.164 format", ErrInvalidPatch)
+	}
+	if p.AvatarURL != nil && *p.AvatarURL != "" {
+		u, err := url.Parse(*p.AvatarURL)
+		if err != nil || (u.Scheme != "https" && u.Scheme != "http") || u.Host == "" {
+			return fmt.Errorf("%w: avatar_url must be an http(s) URL", ErrInvalidPatch)
+		}
+	}
+
+	return nil
+}
+
+func (p UserPatch) Apply(u *User) {
+	if p.Name != nil {
+		u.Name = *p.Name
+	}
+	if p.Email != nil {
+		u.Email = *p.Email
+	}
+	if p.DisplayName != nil {
+		u.DisplayName = *p.DisplayName
+	}
+	if p.Locale != nil {
+		u.Locale = *p.Locale
+		if tag, err := language.Parse(*p.Locale); err == nil {
+			u.Locale = tag.String()
+		}
+	}
+	if p.Timezone != nil {
+		u.Timezone = *p.Timezone
+	}
+	if p.Phone != nil {
+		u.Phone = *p.Phone
+	}
+	if p.AvatarURL != nil {
+		u.AvatarURL = *p.AvatarURL
+	}
+	if p.Attributes != nil {
+		u.Attributes = MergeAttributes(u.Attributes, p.Attributes)
+	}
+}
+
+// MergeAttributes applies patch onto attrs with JSON Merge Patch semantics
+// and returns the result, nil when no attribute is left. attrs is not
+// modified.
+func MergeAttributes(attrs, patch map[string]interface{}) map[string]interface{} {
+	out := mergePatch(attrs, patch)
+	if len(out) == 0 {
+		return nil
+	}
+	return out
+}
+
+// mergePatch is RFC 7386's MergePatch for objects: only a null removes a
+// member, so an object emptied by the merge is kept.
+func mergePatch(target, patch map[string]interface{}) map[string]interface{} {
+	out := make(map[string]interface{}, len(target)+len(patch))
+	for k, v := range target {
+		out[k] = v
+	}
+
+	for k, v := range patch {
+		if v == nil {
+			delete(out, k)
+			continue
+		}
+		if sub, ok := v.(map[string]interface{}); ok {
+			cur, _ := out[k].(map[string]interface{})
+			out[k] = mergePatch(cur, sub)
+			continue
+		}
+		out[k] = v
+	}
+	return out
+}
+
+// DiffAttributes returns the merge patch that turns from into to.
+func DiffAttributes(from, to map[string]interface{}) map[string]interface{} {
+	patch := map[string]interface{}{}
+
+	for k := range from {
+		if _, ok := to[k]; !ok {
+			patch[k] = nil
+		}
+	}
+	for k, v := range to {
+		old, existed := from[k]
+		if existed && reflect.DeepEqual(old, v) {
+			continue
+		}
+		oldMap, oldIsMap := old.(map[string]interface{})
+		newMap, newIsMap := v.(map[string]interface{})
+		if oldIsMap && newIsMap {
+			patch[k] = DiffAttributes(oldMap, newMap)
+			continue
+		}
+		patch[k] = v
+	}
+
+	if len(patch) == 0 {
+		return nil
+	}
+	return patch
+}
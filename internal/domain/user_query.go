@@ -0,0 +1,182 @@
+package domain
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+)
+
+const (
+	DefaultPageSize = 20
+	MaxPageSize     = 100
+)
+
+type UserSortField string
+
+const (
+	SortByCreatedAt UserSortField = "created_at"
+	SortByName      UserSortField = "name"
+	SortByEmail     UserSortField = "email"
+)
+
+type SortOrder string
+
+const (
+	SortDesc SortOrder = "desc"
+	SortAsc  SortOrder = "asc"
+)
+
+// UserFilter narrows a user listing. Zero values mean "no constraint".
+type UserFilter struct {
+	Status       UserStatus
+	NamePrefix   string
+	EmailPrefix  string
+	CreatedAfter time.Time
+	CreatedUntil time.Time
+	// UserIDs keeps only the listed users.
+	UserIDs []string
+}
+
+// UserQuery describes one page of a user listing.
+// Cursor (keyset) and Offset pagination are mutually exclusive.
+type UserQuery struct {
+	Filter UserFilter
+	SortBy UserSortField
+	Order  SortOrder
+	Limit  int
+	Offset int
+	Cursor string
+}
+
+type UserPage struct {
+	Users      []*User `json:"users"`
+	NextCursor string  `json:"next_cursor,omitempty"`
+	Total      int64   `json:"total"`
+}
+
+// Normalize fills defaults and validates the query.
+func (q *UserQuery) Normalize() error {
+	if q.SortBy == "" {
+		q.SortBy = SortByCreatedAt
+	}
+	switch q.SortBy {
+	case SortByCreatedAt, SortByName, SortByEmail:
+	default:
+		return fmt.Errorf("%w: unknown sort field %q", ErrInvalidQuery, q.SortBy)
+	}
+
+	if q.Order == "" {
+		q.Order = SortDesc
+	}
+	if q.Order != SortAsc && q.Order != SortDesc {
+		return fmt.Errorf("%w: unknown sort order %q", ErrInvalidQuery, q.Order)
+	}
+
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: negative offset", ErrInvalidQuery)
+	}
+	if q.Cursor != "" && q.Offset > 0 {
+		return fmt.Errorf("%w: cursor and offset cannot be combined", ErrInvalidQuery)
+	}
+
+	f := q.Filter
+	if f.Status != "" && !f.Status.Valid() {
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, f.Status)
+	}
+	if !f.CreatedAfter.IsZero() && !f.CreatedUntil.IsZero() && f.CreatedUntil.Before(f.CreatedAfter) {
+		return fmt.Errorf("%w: created range is inverted", ErrInvalidQuery)
+	}
+
+	return nil
+}
+
+// UserSearch is a free-text lookup by name or email, ordered by relevance.
+type UserSearch struct {
+	Text   string
+	Limit  int
+	Offset int
+	// UserIDs keeps only the listed users.
+	UserIDs []string
+}
+
+func (q *UserSearch) Normalize() error {
+	q.Text = strings.TrimSpace(q.Text)
+	if q.Text == "" {
+		return fmt.Errorf("%w: empty search", ErrInvalidQuery)
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: negative offset", ErrInvalidQuery)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	return nil
+}
+
+// Cursor is the keyset position after the last user of a page.
+// It is bound to the sort it was issued for.
+type Cursor struct {
+	SortBy UserSortField `json:"s"`
+	Order  SortOrder     `json:"o"`
+	Value  string        `json:"v"`
+	ID     string        `json:"id"`
+}
+
+func NewCursor(q UserQuery, last *User) Cursor {
+	c := Cursor{SortBy: q.SortBy, Order: q.Order, ID: last.ID}
+	switch q.SortBy {
+	case SortByName:
+		c.Value = last.Name
+	case SortByEmail:
+		c.Value = last.Email
+	default:
+		c.Value = last.CreatedAt.UTC().Format(time.RFC3339Nano)
+	}
+	return c
+}
+
+func (c Cursor) Encode() string {
+	b, _ := json.Marshal(c)
+	return base64.RawURLEncoding.EncodeToString(b)
+}
+
+// DecodeCursor parses a token issued by Encode and checks it matches the query sort.
+func DecodeCursor(token string, q UserQuery) (Cursor, error) {
+	var c Cursor
+
+	b, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if err := json.Unmarshal(b, &c); err != nil || c.ID == "" {
+		return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+	}
+	if c.SortBy != q.SortBy || c.Order != q.Order {
+		return c, fmt.Errorf("%w: cursor does not match sort", ErrInvalidQuery)
+	}
+	if c.SortBy == SortByCreatedAt {
+		if _, err := time.Parse(time.RFC3339Nano, c.Value); err != nil {
+			return c, fmt.Errorf("%w: malformed cursor", ErrInvalidQuery)
+		}
+	}
+
+	return c, nil
+}
+
+// CreatedAt returns the cursor value as a time when sorting by creation date.
+func (c Cursor) CreatedAt() time.Time {
+	t, _ := time.Parse(time.RFC3339Nano, c.Value)
+	return t
+}
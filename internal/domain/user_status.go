@@ -0,0 +1,40 @@
+package domain
+
+type UserStatus string
+
+const (
+	StatusPending   UserStatus = "pending"
+	StatusActive    UserStatus = "active"
+	StatusSuspended UserStatus = "suspended"
+	StatusDisabled  UserStatus = "disabled"
+)
+
+// statusTransitions lists the statuses each status may move to.
+var statusTransitions = map[UserStatus][]UserStatus{
+	StatusPending:   {StatusActive, StatusDisabled},
+	StatusActive:    {StatusSuspended, StatusDisabled},
+	StatusSuspended: {StatusActive, StatusDisabled},
+	StatusDisabled:  {StatusActive},
+}
+
+func (s UserStatus) Valid() bool {
+	_, ok := statusTransitions[s]
+	return ok
+}
+
+func (s UserStatus) CanTransitionTo(to UserStatus) bool {
+	for _, allowed := range statusTransitions[s] {
+		if allowed == to {
+			return true
+		}
+	}
+	return false
+}
+
+// CurrentStatus treats users stored before statuses existed as active.
+func (u *User) CurrentStatus() UserStatus {
+	if u.Status == "" {
+		return StatusActive
+	}
+	return u.Status
+}
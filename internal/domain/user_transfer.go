@@ -0,0 +1,79 @@
+package domain
+
+import (
+	"fmt"
+	"strings"
+)
+
+// TransferFormat is a file format users are imported from and exported to.
+type TransferFormat string
+
+const (
+	FormatCSV    TransferFormat = "csv"
+	FormatNDJSON TransferFormat = "ndjson"
+)
+
+func ParseTransferFormat(s string) (TransferFormat, error) {
+	switch f := TransferFormat(strings.ToLower(s)); f {
+	case FormatCSV, FormatNDJSON:
+		return f, nil
+	}
+	return "", fmt.Errorf("%w: format must be csv or ndjson", ErrInvalidImport)
+}
+
+// ImportOptions control an import. Rows without a password hash are
+// rejected unless Invite is set, in which case the user is created
+// pending and sent an invitation to choose a password. A DryRun validates
+// every row and reports what would be created without writing anything.
+type ImportOptions struct {
+	Invite bool
+	DryRun bool
+}
+
+// ImportRow is one user read from an import file. Line is where the row
+// starts in the file, for error reports.
+type ImportRow struct {
+	Line         int                    `json:"-"`
+	Name         string                 `json:"name"`
+	Email        string                 `json:"email"`
+	PasswordHash string                 `json:"password_hash"`
+	DisplayName  string                 `json:"display_name"`
+	Locale       string                 `json:"locale"`
+	Timezone     string                 `json:"timezone"`
+	Phone        string                 `json:"phone"`
+	Attributes   map[string]interface{} `json:"attributes"`
+}
+
+// Validate checks the row's fields with the rules a patch is held to.
+// The password hash and attributes are checked by the importer.
+func (r ImportRow) Validate() error {
+	p := UserPatch{
+		Name:        &r.Name,
+		Email:       &r.Email,
+		DisplayName: &r.DisplayName,
+		Locale:      &r.Locale,
+		Timezone:    &r.Timezone,
+		Phone:       &r.Phone,
+	}
+	if err := p.Validate(); err != nil {
+		return fmt.Errorf("%w: %s", ErrInvalidImport, strings.TrimPrefix(err.Error(), ErrInvalidPatch.Error()+": "))
+	}
+	return nil
+}
+
+// ImportReport sums up an import. Errors lists the rows that were not
+// imported, and invitations that could not be sent, by line.
+type ImportReport struct {
+	DryRun  bool          `json:"dry_run,omitempty"`
+	Total   int           `json:"total"`
+	Created int           `json:"created"`
+	Invited int           `json:"invited"`
+	Failed  int           `json:"failed"`
+	Errors  []ImportError `json:"errors"`
+}
+
+type ImportError struct {
+	Line  int    `json:"line"`
+	Email string `json:"email,omitempty"`
+	Error string `json:"error"`
+}
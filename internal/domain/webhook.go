@@ -0,0 +1,109 @@
+package domain
+
+import (
+	"fmt"
+	"net/url"
+	"slices"
+	"time"
+)
+
+// MaxWebhookAttempts is how often a delivery is tried before it is
+// moved to the dead letters.
+const MaxWebhookAttempts = 8
+
+var EventTypes = []EventType{EventUserCreated, EventUserUpdated, EventUserDeleted, EventUserRestored, EventUserErased}
+
+// WebhookSubscription asks for events of its tenant's users to be POSTed
+// to URL. An empty EventTypes means every event. Secret signs the payloads
+// and is only shown when the subscription is created.
+type WebhookSubscription struct {
+	ID string `json:"id"`
+	// TenantID is set by the repository from the context the subscription
+	// is created with.
+	TenantID   string      `json:"tenant_id"`
+	URL        string      `json:"url"`
+	EventTypes []EventType `json:"events,omitempty"`
+	Secret     string      `json:"-"`
+	Active     bool        `json:"active"`
+	CreatedAt  time.Time   `json:"created_at"`
+	UpdatedAt  time.Time   `json:"updated_at"`
+}
+
+func (s *WebhookSubscription) Validate() error {
+	u, err := url.Parse(s.URL)
+	if err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		return fmt.Errorf("%w: url must be an absolute http(s) URL", ErrInvalidWebhook)
+	}
+	for _, t := range s.EventTypes {
+		if !slices.Contains(EventTypes, t) {
+			return fmt.Errorf("%w: unknown event %q", ErrInvalidWebhook, t)
+		}
+	}
+	return nil
+}
+
+// Wants reports whether events of type t go to this subscription.
+func (s *WebhookSubscription) Wants(t EventType) bool {
+	return s.Active && (len(s.EventTypes) == 0 || slices.Contains(s.EventTypes, t))
+}
+
+type WebhookDeliveryStatus string
+
+const (
+	DeliveryPending   WebhookDeliveryStatus = "pending"
+	DeliveryDelivered WebhookDeliveryStatus = "delivered"
+	DeliveryDead      WebhookDeliveryStatus = "dead"
+)
+
+// WebhookDelivery is one event on its way to one subscription.
+// Attempts is the delivery log; Tries counts the attempts since the
+// delivery was queued or last redelivered.
+type WebhookDelivery struct {
+	ID string `json:"id"`
+	// TenantID is the subscription's tenant.
+	TenantID       string                `json:"-"`
+	SubscriptionID string                `json:"subscription_id"`
+	EventID        string                `json:"event_id"`
+	EventType      EventType             `json:"event_type"`
+	Payload        []byte                `json:"-"`
+	Status         WebhookDeliveryStatus `json:"status"`
+	Tries          int                   `json:"tries"`
+	Attempts       []WebhookAttempt      `json:"attempts"`
+	NextAttemptAt  time.Time             `json:"next_attempt_at"`
+	CreatedAt      time.Time             `json:"created_at"`
+	DeliveredAt    *time.Time            `json:"delivered_at,omitempty"`
+}
+
+// WebhookAttempt records one try. StatusCode is zero when no response came back.
+type WebhookAttempt struct {
+	At         time.Time     `json:"at"`
+	StatusCode int           `json:"status_code,omitempty"`
+	Error      string        `json:"error,omitempty"`
+	Duration   time.Duration `json:"duration_ns"`
+}
+
+// WebhookDeliveryQuery lists deliveries, newest first.
+type WebhookDeliveryQuery struct {
+	SubscriptionID string
+	Status         WebhookDeliveryStatus
+	Limit          int
+	Offset         int
+}
+
+func (q *WebhookDeliveryQuery) Normalize() error {
+	switch q.Status {
+	case "", DeliveryPending, DeliveryDelivered, DeliveryDead:
+	default:
+		return fmt.Errorf("%w: unknown status %q", ErrInvalidQuery, q.Status)
+	}
+	if q.Limit <= 0 {
+		q.Limit = DefaultPageSize
+	}
+	if q.Limit > MaxPageSize {
+		q.Limit = MaxPageSize
+	}
+	if q.Offset < 0 {
+		return fmt.Errorf("%w: offset must not be negative", ErrInvalidQuery)
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/santhosh-tekuri/jsonschema/v5"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// anyObject accepts any attributes when no schema is configured.
+const anyObject = `{"type": "object"}`
+
+type attributeSchema struct {
+	schema *jsonschema.Schema
+}
+
+// NewAttributeSchema compiles the JSON Schema at path.
+// An empty path allows any attributes object.
+func NewAttributeSchema(path string) (ports.AttributeValidator, error) {
+	c := jsonschema.NewCompiler()
+
+	url := path
+	if path == "" {
+		url = "attributes.json"
+		if err := c.AddResource(url, strings.NewReader(anyObject)); err != nil {
+			return nil, err
+		}
+	}
+
+	schema, err := c.Compile(url)
+	if err != nil {
+		return nil, fmt.Errorf("compile attribute schema: %w", err)
+	}
+
+	return &attributeSchema{schema: schema}, nil
+}
+
+func (s *attributeSchema) Validate(attrs map[string]interface{}) error {
+	if attrs == nil {
+		attrs = map[string]interface{}{}
+	}
+
+	// Round-trip through JSON so values decoded from storage have the
+	// plain JSON types the validator expects.
+	b, err := json.Marshal(attrs)
+	if err != nil {
+		return err
+	}
+	var v interface{}
+	if err := json.Unmarshal(b, &v); err != nil {
+		return err
+	}
+
+	return s.schema.Validate(v)
+}
@@ -0,0 +1,49 @@
+package infrastructure_test
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+)
+
+func TestAttributeSchema_NoSchemaAllowsAnyObject(t *testing.T) {
+	v, err := infrastructure.NewAttributeSchema("")
+	if err != nil {
+		t.Fatalf("NewAttributeSchema() error = %v", err)
+	}
+
+	if err := v.Validate(map[string]interface{}{"team": "core", "level": 3}); err != nil {
+		t.Errorf("expected attributes to be valid, got %v", err)
+	}
+}
+
+func TestAttributeSchema_ValidatesAgainstFile(t *testing.T) {
+	// Arrange
+	path := filepath.Join(t.TempDir(), "attributes.json")
+	schema := `{
+		"type": "object",
+		"properties": {"level": {"type": "integer", "minimum": 1}},
+		"additionalProperties": false
+	}`
+	if err := os.WriteFile(path, []byte(schema), 0o600); err != nil {
+		t.Fatal(err)
+	}
+
+	v, err := infrastructure.NewAttributeSchema(path)
+	if err != nil {
+		t.Fatalf("NewAttributeSchema() error = %v", err)
+	}
+
+	// Act & Assert
+	if err := v.Validate(map[string]interface{}{"level": int64(2)}); err != nil {
+		t.Errorf("expected valid attributes, got %v", err)
+	}
+	if err := v.Validate(map[string]interface{}{"level": 0}); err == nil {
+		t.Error("expected error for level below minimum")
+	}
+	if err := v.Validate(map[string]interface{}{"team": "core"}); err == nil {
+		t.Error("expected error for unknown attribute")
+	}
+}
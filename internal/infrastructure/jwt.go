@@ -5,11 +5,22 @@ import (
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 )
 
+// Claims are what a token says about its holder.
+type Claims struct {
+	UserID   string
+	TenantID string
+}
+
 type JWTManager interface {
-	Generate(userID string) (string, error)
-	Validate(token string) (string, error)
+	// Generate issues a token for a user of the tenant that expires after
+	// ttl, or after the manager's TTL when ttl is zero.
+	Generate(userID, tenantID string, ttl time.Duration) (string, error)
+	// Validate returns the claims of a valid token. Tokens issued before
+	// tenants existed belong to domain.DefaultTenant.
+	Validate(token string) (*Claims, error)
 }
 
 type jwtManager struct {
@@ -21,19 +32,23 @@ func NewJWTManager(secret string, ttl time.Duration) JWTManager {
 	return &jwtManager{secret: []byte(secret), ttl: ttl}
 }
 
-func (j *jwtManager) Generate(userID string) (string, error) {
+func (j *jwtManager) Generate(userID, tenantID string, ttl time.Duration) (string, error) {
+	if ttl == 0 {
+		ttl = j.ttl
+	}
 	claims := jwt.MapClaims{
-		"sub": userID,
-		"exp": time.Now().Add(j.ttl).Unix(),
+		"sub":    userID,
+		"tenant": tenantID,
+		"exp":    time.Now().Add(ttl).Unix(),
 	}
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, claims)
 	return token.SignedString(j.secret)
 }
 
-func (j *jwtManager) Validate(tokenStr string) (string, error) {
+func (j *jwtManager) Validate(tokenStr string) (*Claims, error) {
 	if tokenStr == "" {
-		return "", errors.New("empty token")
+		return nil, errors.New("empty token")
 	}
 	token, err := jwt.Parse(tokenStr, func(t *jwt.Token) (interface{}, error) {
 		if _, ok := t.Method.(*jwt.SigningMethodHMAC); !ok {
@@ -43,9 +58,17 @@ func (j *jwtManager) Validate(tokenStr string) (string, error) {
 	})
 
 	if err != nil || !token.Valid {
-		return "", errors.New("invalid token")
+		return nil, errors.New("invalid token")
 	}
 
 	claims := token.Claims.(jwt.MapClaims)
-	return claims["sub"].(string), nil
+	userID, _ := claims["sub"].(string)
+	if userID == "" {
+		return nil, errors.New("invalid token")
+	}
+	tenantID, _ := claims["tenant"].(string)
+	if tenantID == "" {
+		tenantID = domain.DefaultTenant
+	}
+	return &Claims{UserID: userID, TenantID: tenantID}, nil
 }
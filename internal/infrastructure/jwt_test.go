@@ -5,6 +5,8 @@ import (
 	"testing"
 	"time"
 
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
 )
 
@@ -17,19 +19,55 @@ func TestJWTManager_GenerateAndValidate_Success(t *testing.T) {
 	jwtManager := infrastructure.NewJWTManager(secret, ttl)
 
 	// Act
-	token, err := jwtManager.Generate(userID)
+	token, err := jwtManager.Generate(userID, "acme", 0)
 	if err != nil {
 		t.Fatalf("Generate() error = %v", err)
 	}
 
-	gotUserID, err := jwtManager.Validate(token)
+	claims, err := jwtManager.Validate(token)
 	if err != nil {
 		t.Fatalf("Validate() error = %v", err)
 	}
 
 	// Assert
-	if gotUserID != userID {
-		t.Errorf("expected userID %q, got %q", userID, gotUserID)
+	if claims.UserID != userID {
+		t.Errorf("expected userID %q, got %q", userID, claims.UserID)
+	}
+	if claims.TenantID != "acme" {
+		t.Errorf("expected tenant %q, got %q", "acme", claims.TenantID)
+	}
+}
+
+func TestJWTManager_Validate_TokenWithoutTenant(t *testing.T) {
+	// Tokens issued before tenants existed only carry sub and exp.
+	token, err := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
+		"sub": "user-123",
+		"exp": time.Now().Add(time.Minute).Unix(),
+	}).SignedString([]byte("secret"))
+	if err != nil {
+		t.Fatalf("SignedString() error = %v", err)
+	}
+
+	claims, err := infrastructure.NewJWTManager("secret", time.Minute).Validate(token)
+	if err != nil {
+		t.Fatalf("Validate() error = %v", err)
+	}
+
+	if claims.TenantID != domain.DefaultTenant {
+		t.Errorf("expected tenant %q, got %q", domain.DefaultTenant, claims.TenantID)
+	}
+}
+
+func TestJWTManager_Generate_TTLOverride(t *testing.T) {
+	jwtManager := infrastructure.NewJWTManager("secret", time.Hour)
+
+	token, err := jwtManager.Generate("user-123", "acme", -time.Minute)
+	if err != nil {
+		t.Fatalf("Generate() error = %v", err)
+	}
+
+	if _, err := jwtManager.Validate(token); err == nil {
+		t.Fatal("expected error for token expired by its own TTL")
 	}
 }
 
@@ -58,7 +96,7 @@ func TestJWTManager_Validate_WrongSecret(t *testing.T) {
 	managerA := infrastructure.NewJWTManager("secret-A", time.Minute)
 	managerB := infrastructure.NewJWTManager("secret-B", time.Minute)
 
-	token, err := managerA.Generate("user-123")
+	token, err := managerA.Generate("user-123", domain.DefaultTenant, 0)
 	if err != nil {
 		t.Fatalf("Generate() error = %v", err)
 	}
@@ -76,7 +114,7 @@ func TestJWTManager_Validate_ExpiredToken(t *testing.T) {
 	// Arrange
 	jwtManager := infrastructure.NewJWTManager("secret", -1*time.Minute)
 
-	token, err := jwtManager.Generate("user-123")
+	token, err := jwtManager.Generate("user-123", domain.DefaultTenant, 0)
 	if err != nil {
 		t.Fatalf("Generate() error = %v", err)
 	}
@@ -93,7 +131,7 @@ func TestJWTManager_Validate_ExpiredToken(t *testing.T) {
 func TestJWTManager_Generate_TokenFormat(t *testing.T) {
 	jwtManager := infrastructure.NewJWTManager("secret", time.Minute)
 
-	token, err := jwtManager.Generate("user-123")
+	token, err := jwtManager.Generate("user-123", domain.DefaultTenant, 0)
 	if err != nil {
 		t.Fatalf("Generate() error = %v", err)
 	}
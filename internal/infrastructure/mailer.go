@@ -0,0 +1,54 @@
+package infrastructure
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/smtp"
+	"strings"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+type logMailer struct{}
+
+// NewLogMailer writes emails to the log instead of sending them.
+// Meant for local development.
+func NewLogMailer() ports.Mailer {
+	return logMailer{}
+}
+
+func (logMailer) Send(_ context.Context, to, subject, body string) error {
+	log.Printf("mail to=%s subject=%q\n%s", to, subject, body)
+	return nil
+}
+
+type SMTPConfig struct {
+	Addr     string
+	Username string
+	Password string
+	From     string
+}
+
+type smtpMailer struct {
+	cfg SMTPConfig
+}
+
+func NewSMTPMailer(cfg SMTPConfig) ports.Mailer {
+	return &smtpMailer{cfg: cfg}
+}
+
+func (m *smtpMailer) Send(_ context.Context, to, subject, body string) error {
+	var auth smtp.Auth
+	if m.cfg.Username != "" {
+		host, _, _ := strings.Cut(m.cfg.Addr, ":")
+		auth = smtp.PlainAuth("", m.cfg.Username, m.cfg.Password, host)
+	}
+
+	msg := fmt.Sprintf(
+		"From: %s\r\nTo: %s\r\nSubject: %s\r\nContent-Type: text/plain; charset=UTF-8\r\n\r\n%s",
+		m.cfg.From, to, subject, body,
+	)
+
+	return smtp.SendMail(m.cfg.Addr, auth, m.cfg.From, []string{to}, []byte(msg))
+}
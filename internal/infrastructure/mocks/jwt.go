@@ -1,14 +1,20 @@
 package mocks
 
+import (
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+)
+
 type JWTManagerMock struct {
-	GenerateFn func(userID string) (string, error)
-	ValidateFn func(token string) (string, error)
+	GenerateFn func(userID, tenantID string, ttl time.Duration) (string, error)
+	ValidateFn func(token string) (*infrastructure.Claims, error)
 }
 
-func (m *JWTManagerMock) Generate(userID string) (string, error) {
-	return m.GenerateFn(userID)
+func (m *JWTManagerMock) Generate(userID, tenantID string, ttl time.Duration) (string, error) {
+	return m.GenerateFn(userID, tenantID, ttl)
 }
 
-func (m *JWTManagerMock) Validate(token string) (string, error) {
+func (m *JWTManagerMock) Validate(token string) (*infrastructure.Claims, error) {
 	return m.ValidateFn(token)
 }
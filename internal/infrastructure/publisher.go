@@ -0,0 +1,109 @@
+package infrastructure
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+type logPublisher struct{}
+
+// NewLogPublisher writes events to the log. Meant for local development.
+func NewLogPublisher() ports.EventPublisher {
+	return logPublisher{}
+}
+
+func (logPublisher) Publish(_ context.Context, e domain.Event) error {
+	log.Printf("event id=%s type=%s user=%s", e.ID, e.Type, e.UserID)
+	return nil
+}
+
+type webhookPublisher struct {
+	url    string
+	client *http.Client
+}
+
+// NewWebhookPublisher POSTs each event as JSON to url. Any response other
+// than 2xx is a failure and the event is retried.
+func NewWebhookPublisher(url string) ports.EventPublisher {
+	return &webhookPublisher{url: url, client: &http.Client{Timeout: 10 * time.Second}}
+}
+
+func (p *webhookPublisher) Publish(ctx context.Context, e domain.Event) error {
+	body, err := json.Marshal(e)
+	if err != nil {
+		return err
+	}
+
+	req, err := http.NewRequestWithContext(ctx, http.MethodPost, p.url, bytes.NewReader(body))
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-Event-ID", e.ID)
+	req.Header.Set("X-Event-Type", string(e.Type))
+
+	resp, err := p.client.Do(req)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		return fmt.Errorf("webhook responded %s", resp.Status)
+	}
+	return nil
+}
+
+// MemoryPublisher keeps published events in memory, for tests and
+// single-process setups.
+type MemoryPublisher struct {
+	mu     sync.Mutex
+	events []domain.Event
+}
+
+func NewMemoryPublisher() *MemoryPublisher {
+	return &MemoryPublisher{}
+}
+
+func (p *MemoryPublisher) Publish(_ context.Context, e domain.Event) error {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	p.events = append(p.events, e)
+	return nil
+}
+
+// Events returns a copy of everything published so far.
+func (p *MemoryPublisher) Events() []domain.Event {
+	p.mu.Lock()
+	defer p.mu.Unlock()
+	return append([]domain.Event(nil), p.events...)
+}
+
+type fanoutPublisher struct {
+	pubs []ports.EventPublisher
+}
+
+// NewFanoutPublisher publishes every event to all pubs in order, stopping
+// at the first failure. The event is then retried for all of them, so
+// each must tolerate duplicates.
+func NewFanoutPublisher(pubs ...ports.EventPublisher) ports.EventPublisher {
+	return &fanoutPublisher{pubs: pubs}
+}
+
+func (p *fanoutPublisher) Publish(ctx context.Context, e domain.Event) error {
+	for _, pub := range p.pubs {
+		if err := pub.Publish(ctx, e); err != nil {
+			return err
+		}
+	}
+	return nil
+}
@@ -0,0 +1,47 @@
+package infrastructure_test
+
+import (
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+)
+
+func TestWebhookPublisher_Publish(t *testing.T) {
+	var got domain.Event
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.Header.Get("X-Event-Type") != string(domain.EventUserCreated) {
+			t.Errorf("unexpected X-Event-Type %q", r.Header.Get("X-Event-Type"))
+		}
+		_ = json.NewDecoder(r.Body).Decode(&got)
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer srv.Close()
+
+	pub := infrastructure.NewWebhookPublisher(srv.URL)
+
+	err := pub.Publish(context.Background(), domain.Event{ID: "1", Type: domain.EventUserCreated, UserID: "u1"})
+	if err != nil {
+		t.Fatalf("Publish() error = %v", err)
+	}
+	if got.ID != "1" || got.UserID != "u1" {
+		t.Errorf("unexpected event received: %+v", got)
+	}
+}
+
+func TestWebhookPublisher_Publish_ErrorStatus(t *testing.T) {
+	srv := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusServiceUnavailable)
+	}))
+	defer srv.Close()
+
+	pub := infrastructure.NewWebhookPublisher(srv.URL)
+
+	if err := pub.Publish(context.Background(), domain.Event{ID: "1"}); err == nil {
+		t.Fatal("expected error for 503 response, got nil")
+	}
+}
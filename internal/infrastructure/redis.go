@@ -0,0 +1,32 @@
+package infrastructure
+
+import (
+	"context"
+	"log"
+	"time"
+
+	"github.com/redis/go-redis/v9"
+)
+
+type RedisConfig struct {
+	// URL is a redis:// or rediss:// URL.
+	URL     string
+	Timeout time.Duration
+}
+
+func NewRedisClient(cfg RedisConfig) *redis.Client {
+	opts, err := redis.ParseURL(cfg.URL)
+	if err != nil {
+		log.Fatalf("redis config failed: %v", err)
+	}
+	client := redis.NewClient(opts)
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+	if err := client.Ping(ctx).Err(); err != nil {
+		log.Fatalf("redis ping failed: %v", err)
+	}
+
+	log.Println("Redis connected")
+	return client
+}
@@ -0,0 +1,55 @@
+package infrastructure
+
+import (
+	"context"
+	"database/sql"
+	"log"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	// Database drivers for NewSQLDatabase.
+	_ "github.com/lib/pq"
+	_ "modernc.org/sqlite"
+)
+
+type SQLConfig struct {
+	// Driver is "postgres" or "sqlite".
+	Driver  string
+	DSN     string
+	Timeout time.Duration
+}
+
+func NewSQLDatabase(cfg SQLConfig) *sql.DB {
+	if cfg.Driver == "sqlite" {
+		// SQLite creates the database file but not its directory.
+		path, _, _ := strings.Cut(strings.TrimPrefix(cfg.DSN, "file:"), "?")
+		if path != "" && !strings.HasPrefix(path, ":memory:") {
+			if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+				log.Fatalf("sqlite create directory failed: %v", err)
+			}
+		}
+	}
+
+	db, err := sql.Open(cfg.Driver, cfg.DSN)
+	if err != nil {
+		log.Fatalf("%s open failed: %v", cfg.Driver, err)
+	}
+	if cfg.Driver == "sqlite" {
+		// SQLite allows one writer at a time; queueing in the pool avoids
+		// busy errors.
+		db.SetMaxOpenConns(1)
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout)
+	defer cancel()
+
+	if err := db.PingContext(ctx); err != nil {
+		log.Fatalf("%s ping failed: %v", cfg.Driver, err)
+	}
+
+	log.Printf("%s connected", cfg.Driver)
+
+	return db
+}
@@ -0,0 +1,102 @@
+package infrastructure
+
+import (
+	"encoding/json"
+	"fmt"
+	"net"
+	"os"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// tenantIDPattern keeps tenant IDs safe to embed in cache keys and URLs.
+var tenantIDPattern = regexp.MustCompile(`^[a-z0-9][a-z0-9_-]{0,62}$`)
+
+type tenantConfig struct {
+	domain.Tenant
+	TokenTTLMinutes int `json:"token_ttl_minutes"`
+}
+
+type tenantRegistry struct {
+	byID   map[string]*domain.Tenant
+	byHost map[string]*domain.Tenant
+}
+
+// NewTenantRegistry loads the tenants from the JSON array at path. Tenants
+// without a token_ttl_minutes get tokenTTL. An empty path, or a file not
+// listing domain.DefaultTenant, adds it with no password policy.
+func NewTenantRegistry(path string, tokenTTL time.Duration) (ports.TenantRegistry, error) {
+	var configs []tenantConfig
+	if path != "" {
+		b, err := os.ReadFile(path)
+		if err != nil {
+			return nil, err
+		}
+		if err := json.Unmarshal(b, &configs); err != nil {
+			return nil, fmt.Errorf("parse tenants: %w", err)
+		}
+	}
+
+	r := &tenantRegistry{
+		byID:   map[string]*domain.Tenant{},
+		byHost: map[string]*domain.Tenant{},
+	}
+	for _, c := range configs {
+		t := c.Tenant
+		if !tenantIDPattern.MatchString(t.ID) {
+			return nil, fmt.Errorf("tenant %q: id must be lowercase letters, digits, - or _", t.ID)
+		}
+		if _, ok := r.byID[t.ID]; ok {
+			return nil, fmt.Errorf("tenant %q: listed twice", t.ID)
+		}
+		if c.TokenTTLMinutes < 0 || t.PasswordPolicy.MinLength < 0 {
+			return nil, fmt.Errorf("tenant %q: negative token_ttl_minutes or min_length", t.ID)
+		}
+		t.TokenTTL = time.Duration(c.TokenTTLMinutes) * time.Minute
+		if t.TokenTTL == 0 {
+			t.TokenTTL = tokenTTL
+		}
+		for _, host := range t.Hosts {
+			host = normalizeHost(host)
+			if other, ok := r.byHost[host]; ok {
+				return nil, fmt.Errorf("tenant %q: host %s belongs to %q", t.ID, host, other.ID)
+			}
+			r.byHost[host] = &t
+		}
+		r.byID[t.ID] = &t
+	}
+	if _, ok := r.byID[domain.DefaultTenant]; !ok {
+		r.byID[domain.DefaultTenant] = &domain.Tenant{ID: domain.DefaultTenant, TokenTTL: tokenTTL}
+	}
+	return r, nil
+}
+
+func (r *tenantRegistry) Tenant(id string) (*domain.Tenant, error) {
+	t, ok := r.byID[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %q", domain.ErrUnknownTenant, id)
+	}
+	c := *t
+	return &c, nil
+}
+
+// TenantForHost ignores the port and case of host.
+func (r *tenantRegistry) TenantForHost(host string) (*domain.Tenant, error) {
+	t, ok := r.byHost[normalizeHost(host)]
+	if !ok {
+		return nil, fmt.Errorf("%w: no tenant for host %s", domain.ErrUnknownTenant, host)
+	}
+	c := *t
+	return &c, nil
+}
+
+func normalizeHost(host string) string {
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	return strings.ToLower(strings.TrimSuffix(host, "."))
+}
@@ -0,0 +1,91 @@
+package infrastructure_test
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+)
+
+func writeTenants(t *testing.T, config string) string {
+	path := filepath.Join(t.TempDir(), "tenants.json")
+	if err := os.WriteFile(path, []byte(config), 0o600); err != nil {
+		t.Fatal(err)
+	}
+	return path
+}
+
+func TestTenantRegistry_NoConfigHasDefaultTenant(t *testing.T) {
+	r, err := infrastructure.NewTenantRegistry("", 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewTenantRegistry() error = %v", err)
+	}
+
+	tenant, err := r.Tenant(domain.DefaultTenant)
+	if err != nil {
+		t.Fatalf("Tenant() error = %v", err)
+	}
+	if tenant.TokenTTL != 15*time.Minute {
+		t.Errorf("expected the default token TTL, got %s", tenant.TokenTTL)
+	}
+	if _, err := r.Tenant("acme"); !errors.Is(err, domain.ErrUnknownTenant) {
+		t.Errorf("expected ErrUnknownTenant, got %v", err)
+	}
+}
+
+func TestTenantRegistry_LoadsFile(t *testing.T) {
+	path := writeTenants(t, `[
+		{"id": "acme", "hosts": ["acme.example.com"], "token_ttl_minutes": 60,
+		 "password_policy": {"min_length": 12, "require_digit": true}},
+		{"id": "globex", "hosts": ["Globex.example.com"]}
+	]`)
+
+	r, err := infrastructure.NewTenantRegistry(path, 15*time.Minute)
+	if err != nil {
+		t.Fatalf("NewTenantRegistry() error = %v", err)
+	}
+
+	acme, err := r.TenantForHost("ACME.example.com:8080")
+	if err != nil {
+		t.Fatalf("TenantForHost() error = %v", err)
+	}
+	if acme.ID != "acme" || acme.TokenTTL != time.Hour || acme.PasswordPolicy.MinLength != 12 {
+		t.Errorf("unexpected tenant %+v", acme)
+	}
+
+	globex, err := r.TenantForHost("globex.example.com")
+	if err != nil {
+		t.Fatalf("TenantForHost() error = %v", err)
+	}
+	if globex.TokenTTL != 15*time.Minute {
+		t.Errorf("expected the default token TTL, got %s", globex.TokenTTL)
+	}
+
+	if _, err := r.Tenant(domain.DefaultTenant); err != nil {
+		t.Errorf("expected the default tenant to be added, got %v", err)
+	}
+	if _, err := r.TenantForHost("other.example.com"); !errors.Is(err, domain.ErrUnknownTenant) {
+		t.Errorf("expected ErrUnknownTenant, got %v", err)
+	}
+}
+
+func TestTenantRegistry_RejectsInvalidConfig(t *testing.T) {
+	tests := map[string]string{
+		"bad id":       `[{"id": "Acme Corp"}]`,
+		"duplicate id": `[{"id": "acme"}, {"id": "acme"}]`,
+		"shared host":  `[{"id": "a", "hosts": ["x.com"]}, {"id": "b", "hosts": ["X.com"]}]`,
+		"negative ttl": `[{"id": "acme", "token_ttl_minutes": -1}]`,
+		"not an array": `{"id": "acme"}`,
+	}
+	for name, config := range tests {
+		t.Run(name, func(t *testing.T) {
+			if _, err := infrastructure.NewTenantRegistry(writeTenants(t, config), time.Minute); err == nil {
+				t.Error("expected an error")
+			}
+		})
+	}
+}
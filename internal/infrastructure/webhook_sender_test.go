@@ -0,0 +1,25 @@
+package infrastructure_test
+
+import (
+	"testing"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/infrastructure"
+)
+
+func TestVerifyWebhook(t *testing.T) {
+	body := []byte(`{"id":"1"}`)
+	sig := infrastructure.SignWebhook("secret", "1700000000", body)
+
+	if !infrastructure.VerifyWebhook("secret", "1700000000", sig, body) {
+		t.Fatal("expected signature to verify")
+	}
+	if infrastructure.VerifyWebhook("secret", "1700000000", sig, []byte(`{"id":"2"}`)) {
+		t.Error("expected tampered body to fail")
+	}
+	if infrastructure.VerifyWebhook("secret", "1700000001", sig, body) {
+		t.Error("expected changed timestamp to fail")
+	}
+	if infrastructure.VerifyWebhook("other", "1700000000", sig, body) {
+		t.Error("expected wrong secret to fail")
+	}
+}
@@ -0,0 +1,22 @@
+package ports
+
+import (
+	"context"
+	"io"
+	"time"
+)
+
+type BlobInfo struct {
+	ContentType string
+	Size        int64
+	ETag        string
+	UpdatedAt   time.Time
+}
+
+// BlobStore keeps binary objects by key. Get returns domain.ErrNotFound
+// for a missing key.
+type BlobStore interface {
+	Put(ctx context.Context, key, contentType string, data []byte) (*BlobInfo, error)
+	Get(ctx context.Context, key string) (io.ReadCloser, *BlobInfo, error)
+	Delete(ctx context.Context, key string) error
+}
@@ -0,0 +1,107 @@
+package mocks
+
+import (
+	"context"
+	"errors"
+	"slices"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+type AuditServiceMock struct {
+	RecordFn func(ctx context.Context, e domain.AuditEvent) error
+	FindFn   func(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error)
+	RedactFn func(ctx context.Context, userID, pseudonym string) (int64, error)
+	VerifyFn func(ctx context.Context) (*domain.AuditVerification, error)
+}
+
+func (m *AuditServiceMock) Record(ctx context.Context, e domain.AuditEvent) error {
+	if m.RecordFn != nil {
+		return m.RecordFn(ctx, e)
+	}
+	return nil
+}
+
+func (m *AuditServiceMock) Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error) {
+	if m.FindFn != nil {
+		return m.FindFn(ctx, q)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *AuditServiceMock) Redact(ctx context.Context, userID, pseudonym string) (int64, error) {
+	if m.RedactFn != nil {
+		return m.RedactFn(ctx, userID, pseudonym)
+	}
+	return 0, errors.New("not implemented")
+}
+
+func (m *AuditServiceMock) Verify(ctx context.Context) (*domain.AuditVerification, error) {
+	if m.VerifyFn != nil {
+		return m.VerifyFn(ctx)
+	}
+	return nil, errors.New("not implemented")
+}
+
+// AuditRepositoryMock keeps events in memory, in the order appended. Like
+// the repositories it works on the chain of the context's tenant.
+type AuditRepositoryMock struct {
+	Events   []*domain.AuditEvent
+	AppendFn func(ctx context.Context, e *domain.AuditEvent) error
+}
+
+func (m *AuditRepositoryMock) Append(ctx context.Context, e *domain.AuditEvent) error {
+	if m.AppendFn != nil {
+		if err := m.AppendFn(ctx, e); err != nil {
+			return err
+		}
+	}
+	for _, stored := range m.Events {
+		if stored.TenantID == e.TenantID && stored.Seq == e.Seq {
+			return domain.ErrAlreadyExists
+		}
+	}
+	stored := *e
+	m.Events = append(m.Events, &stored)
+	return nil
+}
+
+func (m *AuditRepositoryMock) Last(ctx context.Context) (*domain.AuditEvent, error) {
+	tenant := domain.TenantID(ctx)
+	for _, e := range slices.Backward(m.Events) {
+		if e.TenantID == tenant {
+			return e, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *AuditRepositoryMock) Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error) {
+	tenant := domain.TenantID(ctx)
+	var out []*domain.AuditEvent
+	for _, e := range m.Events {
+		if e.TenantID != tenant ||
+			e.Seq <= q.AfterSeq ||
+			(q.ActorID != "" && e.ActorID != q.ActorID) ||
+			(q.TargetID != "" && e.TargetID != q.TargetID) ||
+			(q.Action != "" && e.Action != q.Action) {
+			continue
+		}
+		out = append(out, e)
+		if q.Limit > 0 && len(out) == q.Limit {
+			break
+		}
+	}
+	return out, nil
+}
+
+func (m *AuditRepositoryMock) Redact(ctx context.Context, userID, pseudonym string) ([]*domain.AuditEvent, error) {
+	tenant := domain.TenantID(ctx)
+	redacted := []*domain.AuditEvent{}
+	for _, e := range m.Events {
+		if e.TenantID == tenant && e.Redact(userID, pseudonym) {
+			redacted = append(redacted, e)
+		}
+	}
+	return redacted, nil
+}
@@ -0,0 +1,59 @@
+package mocks
+
+import (
+	"bytes"
+	"context"
+	"io"
+	"sync"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// BlobStoreMock keeps blobs in memory.
+type BlobStoreMock struct {
+	mu    sync.Mutex
+	Blobs map[string][]byte
+	Infos map[string]*ports.BlobInfo
+}
+
+func (m *BlobStoreMock) Put(ctx context.Context, key, contentType string, data []byte) (*ports.BlobInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Blobs == nil {
+		m.Blobs = map[string][]byte{}
+		m.Infos = map[string]*ports.BlobInfo{}
+	}
+
+	info := &ports.BlobInfo{
+		ContentType: contentType,
+		Size:        int64(len(data)),
+		ETag:        key,
+		UpdatedAt:   time.Now(),
+	}
+	m.Blobs[key] = data
+	m.Infos[key] = info
+	return info, nil
+}
+
+func (m *BlobStoreMock) Get(ctx context.Context, key string) (io.ReadCloser, *ports.BlobInfo, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, ok := m.Blobs[key]
+	if !ok {
+		return nil, nil, domain.ErrNotFound
+	}
+	return io.NopCloser(bytes.NewReader(data)), m.Infos[key], nil
+}
+
+func (m *BlobStoreMock) Delete(ctx context.Context, key string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	delete(m.Blobs, key)
+	delete(m.Infos, key)
+	return nil
+}
@@ -0,0 +1,62 @@
+package mocks
+
+import (
+	"context"
+	"sync"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// CacheMock keeps values in memory and ignores TTLs. With Err set every
+// call fails with it.
+type CacheMock struct {
+	mu     sync.Mutex
+	Values map[string][]byte
+	TTLs   map[string]time.Duration
+	Err    error
+}
+
+func (m *CacheMock) Get(ctx context.Context, key string) ([]byte, error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return nil, m.Err
+	}
+	v, ok := m.Values[key]
+	if !ok {
+		return nil, domain.ErrNotFound
+	}
+	return v, nil
+}
+
+func (m *CacheMock) Set(ctx context.Context, key string, value []byte, ttl time.Duration) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	if m.Values == nil {
+		m.Values = map[string][]byte{}
+		m.TTLs = map[string]time.Duration{}
+	}
+	m.Values[key] = value
+	m.TTLs[key] = ttl
+	return nil
+}
+
+func (m *CacheMock) Delete(ctx context.Context, keys ...string) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	if m.Err != nil {
+		return m.Err
+	}
+	for _, k := range keys {
+		delete(m.Values, k)
+		delete(m.TTLs, k)
+	}
+	return nil
+}
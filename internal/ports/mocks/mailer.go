@@ -0,0 +1,14 @@
+package mocks
+
+import "context"
+
+type MailerMock struct {
+	SendFn func(ctx context.Context, to, subject, body string) error
+}
+
+func (m *MailerMock) Send(ctx context.Context, to, subject, body string) error {
+	if m.SendFn != nil {
+		return m.SendFn(ctx, to, subject, body)
+	}
+	return nil
+}
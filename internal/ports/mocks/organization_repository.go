@@ -0,0 +1,118 @@
+package mocks
+
+import (
+	"cmp"
+	"context"
+	"slices"
+	"strconv"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// OrganizationRepositoryMock keeps organizations and memberships in
+// memory, ignoring tenants. It hands out copies.
+type OrganizationRepositoryMock struct {
+	Organizations []*domain.Organization
+	Memberships   []*domain.Membership
+}
+
+func (m *OrganizationRepositoryMock) Create(ctx context.Context, org *domain.Organization, owner *domain.Membership) error {
+	org.ID = "org-" + strconv.Itoa(len(m.Organizations)+1)
+	org.TenantID = domain.TenantID(ctx)
+	owner.OrganizationID = org.ID
+	stored := *org
+	m.Organizations = append(m.Organizations, &stored)
+	return m.AddMember(ctx, owner)
+}
+
+func (m *OrganizationRepositoryMock) FindByID(ctx context.Context, id string) (*domain.Organization, error) {
+	for _, org := range m.Organizations {
+		if org.ID == id {
+			found := *org
+			return &found, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *OrganizationRepositoryMock) AddMember(ctx context.Context, ms *domain.Membership) error {
+	if _, err := m.FindByID(ctx, ms.OrganizationID); err != nil {
+		return err
+	}
+	if m.index(ms.OrganizationID, ms.UserID) >= 0 {
+		return domain.ErrAlreadyExists
+	}
+	m.Memberships = append(m.Memberships, copyMembership(ms))
+	return nil
+}
+
+func (m *OrganizationRepositoryMock) FindMember(ctx context.Context, orgID, userID string) (*domain.Membership, error) {
+	i := m.index(orgID, userID)
+	if i < 0 {
+		return nil, domain.ErrNotFound
+	}
+	return copyMembership(m.Memberships[i]), nil
+}
+
+func (m *OrganizationRepositoryMock) ListMembers(ctx context.Context, orgID string) ([]*domain.Membership, error) {
+	if _, err := m.FindByID(ctx, orgID); err != nil {
+		return nil, err
+	}
+	members := []*domain.Membership{}
+	for _, ms := range m.Memberships {
+		if ms.OrganizationID == orgID {
+			members = append(members, copyMembership(ms))
+		}
+	}
+	slices.SortFunc(members, func(a, b *domain.Membership) int { return cmp.Compare(a.UserID, b.UserID) })
+	return members, nil
+}
+
+func (m *OrganizationRepositoryMock) MembershipsOf(ctx context.Context, userID string) ([]*domain.Membership, error) {
+	memberships := []*domain.Membership{}
+	for _, ms := range m.Memberships {
+		if ms.UserID == userID {
+			memberships = append(memberships, copyMembership(ms))
+		}
+	}
+	slices.SortFunc(memberships, func(a, b *domain.Membership) int {
+		return cmp.Compare(a.OrganizationID, b.OrganizationID)
+	})
+	return memberships, nil
+}
+
+func (m *OrganizationRepositoryMock) UpdateMember(ctx context.Context, ms *domain.Membership) error {
+	i := m.index(ms.OrganizationID, ms.UserID)
+	if i < 0 {
+		return domain.ErrNotFound
+	}
+	m.Memberships[i] = copyMembership(ms)
+	return nil
+}
+
+func (m *OrganizationRepositoryMock) RemoveMember(ctx context.Context, orgID, userID string) error {
+	i := m.index(orgID, userID)
+	if i < 0 {
+		return domain.ErrNotFound
+	}
+	m.Memberships = slices.Delete(m.Memberships, i, i+1)
+	return nil
+}
+
+func (m *OrganizationRepositoryMock) RemoveUser(ctx context.Context, userID string) (int64, error) {
+	n := len(m.Memberships)
+	m.Memberships = slices.DeleteFunc(m.Memberships, func(ms *domain.Membership) bool { return ms.UserID == userID })
+	return int64(n - len(m.Memberships)), nil
+}
+
+func (m *OrganizationRepositoryMock) index(orgID, userID string) int {
+	return slices.IndexFunc(m.Memberships, func(ms *domain.Membership) bool {
+		return ms.OrganizationID == orgID && ms.UserID == userID
+	})
+}
+
+func copyMembership(ms *domain.Membership) *domain.Membership {
+	c := *ms
+	c.Teams = slices.Clone(ms.Teams)
+	return &c
+}
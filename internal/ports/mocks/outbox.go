@@ -0,0 +1,57 @@
+package mocks
+
+import (
+	"context"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// OutboxRepositoryMock keeps messages in memory. Claim ignores leases and
+// returns the first message that is neither delivered nor scheduled later.
+type OutboxRepositoryMock struct {
+	Messages  []*domain.OutboxMessage
+	Delivered map[string]bool
+}
+
+func (m *OutboxRepositoryMock) Claim(ctx context.Context, lease time.Duration) (*domain.OutboxMessage, error) {
+	now := time.Now()
+	for _, msg := range m.Messages {
+		if m.Delivered[msg.ID] || msg.NextAttemptAt.After(now) {
+			continue
+		}
+		msg.Attempts++
+		msg.NextAttemptAt = now.Add(lease)
+		return msg, nil
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *OutboxRepositoryMock) MarkDelivered(ctx context.Context, id string) error {
+	if m.Delivered == nil {
+		m.Delivered = map[string]bool{}
+	}
+	m.Delivered[id] = true
+	return nil
+}
+
+func (m *OutboxRepositoryMock) MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) error {
+	for _, msg := range m.Messages {
+		if msg.ID == id {
+			msg.LastError = reason
+			msg.NextAttemptAt = retryAt
+		}
+	}
+	return nil
+}
+
+type EventPublisherMock struct {
+	PublishFn func(ctx context.Context, e domain.Event) error
+}
+
+func (m *EventPublisherMock) Publish(ctx context.Context, e domain.Event) error {
+	if m.PublishFn != nil {
+		return m.PublishFn(ctx, e)
+	}
+	return nil
+}
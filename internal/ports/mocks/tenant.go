@@ -0,0 +1,26 @@
+package mocks
+
+import (
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// TenantRegistryMock knows every tenant, with no password policy and the
+// JWT manager's token TTL, unless its functions say otherwise.
+type TenantRegistryMock struct {
+	TenantFn        func(id string) (*domain.Tenant, error)
+	TenantForHostFn func(host string) (*domain.Tenant, error)
+}
+
+func (m *TenantRegistryMock) Tenant(id string) (*domain.Tenant, error) {
+	if m.TenantFn != nil {
+		return m.TenantFn(id)
+	}
+	return &domain.Tenant{ID: id}, nil
+}
+
+func (m *TenantRegistryMock) TenantForHost(host string) (*domain.Tenant, error) {
+	if m.TenantForHostFn != nil {
+		return m.TenantForHostFn(host)
+	}
+	return nil, domain.ErrUnknownTenant
+}
@@ -3,18 +3,29 @@ package mocks
 import (
 	"context"
 	"errors"
+	"time"
 
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 )
 
 type UserRepositoryMock struct {
-	FindByEmailFn func(ctx context.Context, email string) (*domain.User, error)
-	FindByIDFn    func(ctx context.Context, id string) (*domain.User, error)
-	FindAllFn     func(ctx context.Context) ([]*domain.User, error)
-	CreateFn      func(ctx context.Context, user *domain.User) error
-	UpdateFn      func(ctx context.Context, user *domain.User) error
-	DeleteFn      func(ctx context.Context, id string) error
-	CountFn       func(ctx context.Context) (int64, error)
+	FindByEmailFn              func(ctx context.Context, email string) (*domain.User, error)
+	FindByTokenFn              func(ctx context.Context, tokenHash string) (*domain.User, error)
+	FindByInvitationTokenFn    func(ctx context.Context, tokenHash string) (*domain.User, error)
+	FindByIDFn                 func(ctx context.Context, id string) (*domain.User, error)
+	FindByIDIncludingDeletedFn func(ctx context.Context, id string) (*domain.User, error)
+	FindAllFn                  func(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error)
+	SearchFn                   func(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error)
+	EachFn                     func(ctx context.Context, fn func(*domain.User) error) error
+	CreateFn                   func(ctx context.Context, user *domain.User) error
+	CreateManyFn               func(ctx context.Context, users []*domain.User) error
+	UpdateFn                   func(ctx context.Context, user *domain.User) error
+	UpdateWithPasswordFn       func(ctx context.Context, user *domain.User) error
+	DeleteFn                   func(ctx context.Context, id string) error
+	RestoreFn                  func(ctx context.Context, id string) error
+	PurgeFn                    func(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error)
+	EraseFn                    func(ctx context.Context, id string) error
+	CountFn                    func(ctx context.Context) (int64, error)
 }
 
 func (m *UserRepositoryMock) FindByEmail(ctx context.Context, email string) (*domain.User, error) {
@@ -31,13 +42,48 @@ func (m *UserRepositoryMock) FindByID(ctx context.Context, id string) (*domain.U
 	return nil, errors.New("not implemented")
 }
 
-func (m *UserRepositoryMock) FindAll(ctx context.Context) ([]*domain.User, error) {
+func (m *UserRepositoryMock) FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error) {
+	if m.FindByIDIncludingDeletedFn != nil {
+		return m.FindByIDIncludingDeletedFn(ctx, id)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *UserRepositoryMock) FindByEmailChangeToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	if m.FindByTokenFn != nil {
+		return m.FindByTokenFn(ctx, tokenHash)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *UserRepositoryMock) FindByInvitationToken(ctx context.Context, tokenHash string) (*domain.User, error) {
+	if m.FindByInvitationTokenFn != nil {
+		return m.FindByInvitationTokenFn(ctx, tokenHash)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *UserRepositoryMock) FindAll(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error) {
 	if m.FindAllFn != nil {
-		return m.FindAllFn(ctx)
+		return m.FindAllFn(ctx, q)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *UserRepositoryMock) Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error) {
+	if m.SearchFn != nil {
+		return m.SearchFn(ctx, q)
 	}
 	return nil, errors.New("not implemented")
 }
 
+func (m *UserRepositoryMock) Each(ctx context.Context, fn func(*domain.User) error) error {
+	if m.EachFn != nil {
+		return m.EachFn(ctx, fn)
+	}
+	return errors.New("not implemented")
+}
+
 func (m *UserRepositoryMock) Create(ctx context.Context, user *domain.User) error {
 	if m.CreateFn != nil {
 		return m.CreateFn(ctx, user)
@@ -45,6 +91,13 @@ func (m *UserRepositoryMock) Create(ctx context.Context, user *domain.User) erro
 	return errors.New("not implemented")
 }
 
+func (m *UserRepositoryMock) CreateMany(ctx context.Context, users []*domain.User) error {
+	if m.CreateManyFn != nil {
+		return m.CreateManyFn(ctx, users)
+	}
+	return errors.New("not implemented")
+}
+
 func (m *UserRepositoryMock) Update(ctx context.Context, user *domain.User) error {
 	if m.UpdateFn != nil {
 		return m.UpdateFn(ctx, user)
@@ -52,6 +105,13 @@ func (m *UserRepositoryMock) Update(ctx context.Context, user *domain.User) erro
 	return errors.New("not implemented")
 }
 
+func (m *UserRepositoryMock) UpdateWithPassword(ctx context.Context, user *domain.User) error {
+	if m.UpdateWithPasswordFn != nil {
+		return m.UpdateWithPasswordFn(ctx, user)
+	}
+	return errors.New("not implemented")
+}
+
 func (m *UserRepositoryMock) Delete(ctx context.Context, id string) error {
 	if m.DeleteFn != nil {
 		return m.DeleteFn(ctx, id)
@@ -59,6 +119,27 @@ func (m *UserRepositoryMock) Delete(ctx context.Context, id string) error {
 	return errors.New("not implemented")
 }
 
+func (m *UserRepositoryMock) Restore(ctx context.Context, id string) error {
+	if m.RestoreFn != nil {
+		return m.RestoreFn(ctx, id)
+	}
+	return errors.New("not implemented")
+}
+
+func (m *UserRepositoryMock) Purge(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error) {
+	if m.PurgeFn != nil {
+		return m.PurgeFn(ctx, deletedBefore)
+	}
+	return nil, errors.New("not implemented")
+}
+
+func (m *UserRepositoryMock) Erase(ctx context.Context, id string) error {
+	if m.EraseFn != nil {
+		return m.EraseFn(ctx, id)
+	}
+	return errors.New("not implemented")
+}
+
 func (m *UserRepositoryMock) Count(ctx context.Context) (int64, error) {
 	if m.CountFn != nil {
 		return m.CountFn(ctx)
@@ -0,0 +1,12 @@
+package mocks
+
+type AttributeValidatorMock struct {
+	ValidateFn func(attrs map[string]interface{}) error
+}
+
+func (m *AttributeValidatorMock) Validate(attrs map[string]interface{}) error {
+	if m.ValidateFn != nil {
+		return m.ValidateFn(attrs)
+	}
+	return nil
+}
@@ -0,0 +1,117 @@
+package mocks
+
+import (
+	"context"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// WebhookRepositoryMock keeps subscriptions and deliveries in memory,
+// scoped to the context's tenant like the repositories. Claims ignore
+// leases.
+type WebhookRepositoryMock struct {
+	Subscriptions []*domain.WebhookSubscription
+	Deliveries    []*domain.WebhookDelivery
+}
+
+func (m *WebhookRepositoryMock) CreateSubscription(ctx context.Context, s *domain.WebhookSubscription) error {
+	s.ID = "sub-" + strconv.Itoa(len(m.Subscriptions)+1)
+	s.TenantID = domain.TenantID(ctx)
+	m.Subscriptions = append(m.Subscriptions, s)
+	return nil
+}
+
+func (m *WebhookRepositoryMock) FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error) {
+	for _, s := range m.Subscriptions {
+		if s.ID == id && s.TenantID == domain.TenantID(ctx) {
+			return s, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *WebhookRepositoryMock) ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error) {
+	var out []*domain.WebhookSubscription
+	for _, s := range m.Subscriptions {
+		if s.TenantID == domain.TenantID(ctx) {
+			out = append(out, s)
+		}
+	}
+	return out, nil
+}
+
+func (m *WebhookRepositoryMock) DeleteSubscription(ctx context.Context, id string) error {
+	for i, s := range m.Subscriptions {
+		if s.ID == id && s.TenantID == domain.TenantID(ctx) {
+			m.Subscriptions = append(m.Subscriptions[:i], m.Subscriptions[i+1:]...)
+			return nil
+		}
+	}
+	return domain.ErrNotFound
+}
+
+func (m *WebhookRepositoryMock) CreateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	for _, existing := range m.Deliveries {
+		if existing.SubscriptionID == d.SubscriptionID && existing.EventID == d.EventID {
+			return domain.ErrAlreadyExists
+		}
+	}
+	d.ID = "delivery-" + strconv.Itoa(len(m.Deliveries)+1)
+	m.Deliveries = append(m.Deliveries, d)
+	return nil
+}
+
+func (m *WebhookRepositoryMock) FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error) {
+	for _, d := range m.Deliveries {
+		if d.ID == id && d.TenantID == domain.TenantID(ctx) {
+			return d, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *WebhookRepositoryMock) ListDeliveries(ctx context.Context, q domain.WebhookDeliveryQuery) ([]*domain.WebhookDelivery, error) {
+	var out []*domain.WebhookDelivery
+	for _, d := range m.Deliveries {
+		if d.TenantID == domain.TenantID(ctx) &&
+			(q.SubscriptionID == "" || d.SubscriptionID == q.SubscriptionID) &&
+			(q.Status == "" || d.Status == q.Status) {
+			out = append(out, d)
+		}
+	}
+	return out, nil
+}
+
+func (m *WebhookRepositoryMock) ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error) {
+	now := time.Now()
+	for _, d := range m.Deliveries {
+		if d.Status == domain.DeliveryPending && !d.NextAttemptAt.After(now) {
+			d.NextAttemptAt = now.Add(lease)
+			return d, nil
+		}
+	}
+	return nil, domain.ErrNotFound
+}
+
+func (m *WebhookRepositoryMock) UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error {
+	return nil
+}
+
+func (m *WebhookRepositoryMock) RedactDeliveries(ctx context.Context, userID string) (int64, error) {
+	var n int64
+	for _, d := range m.Deliveries {
+		if d.TenantID != domain.TenantID(ctx) || !strings.Contains(string(d.Payload), domain.EventUserField(userID)) {
+			continue
+		}
+		payload, err := domain.WithoutData(d.Payload)
+		if err != nil {
+			return n, err
+		}
+		d.Payload = payload
+		n++
+	}
+	return n, nil
+}
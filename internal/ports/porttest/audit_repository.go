@@ -0,0 +1,117 @@
+package porttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+)
+
+// AuditRepository runs the ports.AuditRepository contract against the
+// repositories newRepo returns. newRepo is called once per subtest and
+// must return an empty repository.
+func AuditRepository(t *testing.T, newRepo func(t *testing.T) ports.AuditRepository) {
+	t.Run("appended events are read back in order", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		_, err := repo.Last(ctx)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		first := appendAuditEvent(t, ctx, repo, nil, "u1")
+		second := appendAuditEvent(t, ctx, repo, first, "u2")
+
+		last, err := repo.Last(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, second, last)
+
+		events, err := repo.Find(ctx, domain.AuditQuery{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []*domain.AuditEvent{first, second}, events)
+
+		events, err = repo.Find(ctx, domain.AuditQuery{TargetID: "u2", Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []*domain.AuditEvent{second}, events)
+
+		taken := *second
+		assert.ErrorIs(t, repo.Append(ctx, &taken), domain.ErrAlreadyExists)
+	})
+
+	t.Run("chains are scoped by tenant", func(t *testing.T) {
+		repo := newRepo(t)
+		acme := domain.WithTenant(context.Background(), "acme")
+		globex := domain.WithTenant(context.Background(), "globex")
+
+		// Both tenants number their chains from 1.
+		ours := appendAuditEvent(t, acme, repo, nil, "u1")
+		theirs := appendAuditEvent(t, globex, repo, nil, "u1")
+
+		last, err := repo.Last(acme)
+		assert.NoError(t, err)
+		assert.Equal(t, ours, last)
+
+		events, err := repo.Find(globex, domain.AuditQuery{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []*domain.AuditEvent{theirs}, events)
+
+		_, err = repo.Last(context.Background())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		redacted, err := repo.Redact(acme, "u1", "erased-1")
+		assert.NoError(t, err)
+		assert.Len(t, redacted, 1)
+
+		events, err = repo.Find(globex, domain.AuditQuery{Limit: 10})
+		assert.NoError(t, err)
+		assert.Equal(t, []*domain.AuditEvent{theirs}, events)
+	})
+
+	t.Run("redacted events are rehashed", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		first := appendAuditEvent(t, ctx, repo, nil, "u1")
+		appendAuditEvent(t, ctx, repo, first, "u2")
+
+		redacted, err := repo.Redact(ctx, "u1", "erased-1")
+		assert.NoError(t, err)
+		if !assert.Len(t, redacted, 1) {
+			return
+		}
+		e := redacted[0]
+		assert.Equal(t, "erased-1", e.TargetID)
+		assert.Nil(t, e.Changes)
+		assert.True(t, e.Redacted)
+		assert.Equal(t, first.Hash, e.OriginalHash)
+		assert.Equal(t, e.ComputeHash(), e.Hash)
+
+		events, err := repo.Find(ctx, domain.AuditQuery{Limit: 10})
+		assert.NoError(t, err)
+		if assert.Len(t, events, 2) {
+			assert.Equal(t, e, events[0])
+		}
+	})
+}
+
+// appendAuditEvent appends an event targeting targetID to the chain of
+// ctx's tenant, after prev.
+func appendAuditEvent(t *testing.T, ctx context.Context, repo ports.AuditRepository, prev *domain.AuditEvent, targetID string) *domain.AuditEvent {
+	e := &domain.AuditEvent{
+		Seq:       1,
+		TenantID:  domain.TenantID(ctx),
+		Action:    domain.AuditUserUpdated,
+		ActorID:   "admin",
+		TargetID:  targetID,
+		Changes:   map[string]domain.AuditChange{"name": {Before: "Old", After: "New"}},
+		Timestamp: time.Now().UTC().Truncate(time.Millisecond),
+	}
+	if prev != nil {
+		e.Seq, e.PrevHash = prev.Seq+1, prev.Hash
+	}
+	e.Hash = e.ComputeHash()
+	assert.NoError(t, repo.Append(ctx, e))
+	return e
+}
@@ -0,0 +1,170 @@
+package porttest
+
+import (
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// OrganizationRepository runs the ports.OrganizationRepository contract
+// against the repositories newRepo returns. newRepo is called once per
+// subtest and must return an empty repository.
+func OrganizationRepository(t *testing.T, newRepo func(t *testing.T) ports.OrganizationRepository) {
+	t.Run("create stores the organization with its owner", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		org, owner := newOrganization(t, ctx, repo, "Acme", "u1")
+		assert.NotEmpty(t, org.ID)
+		assert.Equal(t, domain.DefaultTenant, org.TenantID)
+		assert.Equal(t, org.ID, owner.OrganizationID)
+
+		found, err := repo.FindByID(ctx, org.ID)
+		assert.NoError(t, err)
+		assert.Equal(t, org, found)
+
+		m, err := repo.FindMember(ctx, org.ID, "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, owner, m)
+	})
+
+	t.Run("missing organizations and members are not found", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+		org, _ := newOrganization(t, ctx, repo, "Acme", "u1")
+		missing := primitive.NewObjectID().Hex()
+
+		_, err := repo.FindByID(ctx, missing)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByID(ctx, "not-an-id")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindMember(ctx, org.ID, "u2")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.ListMembers(ctx, missing)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		assert.ErrorIs(t, repo.AddMember(ctx, newMembership(missing, "u2", domain.OrgRoleMember)), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.UpdateMember(ctx, newMembership(org.ID, "u2", domain.OrgRoleMember)), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.RemoveMember(ctx, org.ID, "u2"), domain.ErrNotFound)
+	})
+
+	t.Run("members", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+		org, owner := newOrganization(t, ctx, repo, "Acme", "u1")
+
+		invited := newMembership(org.ID, "u3", domain.OrgRoleMember)
+		invited.State = domain.MembershipInvited
+		invited.Teams = []string{"backend", "oncall"}
+		invited.InvitedBy = "u1"
+		assert.NoError(t, repo.AddMember(ctx, invited))
+		assert.NoError(t, repo.AddMember(ctx, newMembership(org.ID, "u2", domain.OrgRoleAdmin)))
+		assert.ErrorIs(t, repo.AddMember(ctx, newMembership(org.ID, "u3", domain.OrgRoleAdmin)), domain.ErrAlreadyExists)
+
+		members, err := repo.ListMembers(ctx, org.ID)
+		assert.NoError(t, err)
+		if assert.Len(t, members, 3) {
+			assert.Equal(t, owner, members[0])
+			assert.Equal(t, "u2", members[1].UserID)
+			assert.Equal(t, invited, members[2])
+		}
+
+		invited.State = domain.MembershipActive
+		invited.Role = domain.OrgRoleAdmin
+		invited.Teams = nil
+		invited.UpdatedAt = invited.UpdatedAt.Add(time.Minute)
+		assert.NoError(t, repo.UpdateMember(ctx, invited))
+		m, err := repo.FindMember(ctx, org.ID, "u3")
+		assert.NoError(t, err)
+		assert.Equal(t, invited, m)
+
+		assert.NoError(t, repo.RemoveMember(ctx, org.ID, "u2"))
+		members, err = repo.ListMembers(ctx, org.ID)
+		assert.NoError(t, err)
+		assert.Len(t, members, 2)
+	})
+
+	t.Run("memberships of a user", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+		first, _ := newOrganization(t, ctx, repo, "Acme", "u1")
+		second, _ := newOrganization(t, ctx, repo, "Globex", "u2")
+		newOrganization(t, ctx, repo, "Initech", "u3")
+		assert.NoError(t, repo.AddMember(ctx, newMembership(second.ID, "u1", domain.OrgRoleMember)))
+
+		memberships, err := repo.MembershipsOf(ctx, "u1")
+		assert.NoError(t, err)
+		var orgs []string
+		for _, m := range memberships {
+			orgs = append(orgs, m.OrganizationID)
+		}
+		assert.Equal(t, []string{first.ID, second.ID}, orgs)
+
+		n, err := repo.RemoveUser(ctx, "u1")
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+		memberships, err = repo.MembershipsOf(ctx, "u1")
+		assert.NoError(t, err)
+		assert.Empty(t, memberships)
+		_, err = repo.FindMember(ctx, second.ID, "u2")
+		assert.NoError(t, err)
+	})
+
+	t.Run("organizations are scoped by tenant", func(t *testing.T) {
+		acme := domain.WithTenant(context.Background(), "acme")
+		globex := domain.WithTenant(context.Background(), "globex")
+		repo := newRepo(t)
+
+		org, _ := newOrganization(t, acme, repo, "Acme", "u1")
+		assert.Equal(t, "acme", org.TenantID)
+
+		_, err := repo.FindByID(globex, org.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindMember(globex, org.ID, "u1")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.ListMembers(globex, org.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		assert.ErrorIs(t, repo.AddMember(globex, newMembership(org.ID, "u2", domain.OrgRoleMember)), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.UpdateMember(globex, newMembership(org.ID, "u1", domain.OrgRoleMember)), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.RemoveMember(globex, org.ID, "u1"), domain.ErrNotFound)
+
+		memberships, err := repo.MembershipsOf(globex, "u1")
+		assert.NoError(t, err)
+		assert.Empty(t, memberships)
+		n, err := repo.RemoveUser(globex, "u1")
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+		memberships, err = repo.MembershipsOf(acme, "u1")
+		assert.NoError(t, err)
+		assert.Len(t, memberships, 1)
+	})
+}
+
+func newOrganization(
+	t *testing.T,
+	ctx context.Context,
+	repo ports.OrganizationRepository,
+	name, ownerID string,
+) (*domain.Organization, *domain.Membership) {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	org := &domain.Organization{Name: name, CreatedAt: now, UpdatedAt: now}
+	owner := newMembership("", ownerID, domain.OrgRoleOwner)
+	assert.NoError(t, repo.Create(ctx, org, owner))
+	return org, owner
+}
+
+func newMembership(orgID, userID string, role domain.OrgRole) *domain.Membership {
+	now := time.Now().UTC().Truncate(time.Millisecond)
+	return &domain.Membership{
+		OrganizationID: orgID,
+		UserID:         userID,
+		Role:           role,
+		State:          domain.MembershipActive,
+		CreatedAt:      now,
+		UpdatedAt:      now,
+	}
+}
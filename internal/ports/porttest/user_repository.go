@@ -0,0 +1,562 @@
+// Package porttest holds conformance suites that every implementation of
+// a port has to pass, whatever its storage.
+package porttest
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"slices"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+	"github.com/yimsoijoi/7s-backend-challenge/internal/ports"
+	"go.mongodb.org/mongo-driver/bson/primitive"
+)
+
+// UserRepository checks the contract documented on ports.UserRepository.
+// newRepo is called once per subtest and must return an empty repository.
+func UserRepository(t *testing.T, newRepo func(t *testing.T) ports.UserRepository) {
+	t.Run("create assigns an ID and the first version", func(t *testing.T) {
+		repo := newRepo(t)
+
+		u := newUser("alice", time.Now())
+		assert.NoError(t, repo.Create(context.Background(), u))
+		assert.NotEmpty(t, u.ID)
+		assert.Equal(t, int64(1), u.Version)
+
+		found, err := repo.FindByID(context.Background(), u.ID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, u.Email, found.Email)
+			assert.Equal(t, int64(1), found.Version)
+		}
+
+		found, err = repo.FindByEmail(context.Background(), u.Email)
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, "hash", found.Password)
+		}
+	})
+
+	t.Run("emails are unique", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		alice := newUser("alice", time.Now())
+		bob := newUser("bob", time.Now())
+		assert.NoError(t, repo.Create(ctx, alice))
+		assert.NoError(t, repo.Create(ctx, bob))
+
+		err := repo.Create(ctx, newUser("alice", time.Now()))
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+		assert.ErrorIs(t, err, domain.ErrAlreadyExists)
+
+		bob.Email = alice.Email
+		assert.ErrorIs(t, repo.Update(ctx, bob), domain.ErrEmailTaken)
+
+		// The email stays reserved while its user is soft-deleted.
+		assert.NoError(t, repo.Delete(ctx, alice.ID))
+		err = repo.Create(ctx, newUser("alice", time.Now()))
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+	})
+
+	t.Run("finders report missing users as not found", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		_, err := repo.FindByID(ctx, primitive.NewObjectID().Hex())
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByEmail(ctx, "nobody@test.com")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByEmailChangeToken(ctx, "no-such-token")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+	})
+
+	t.Run("malformed IDs are rejected", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		_, err := repo.FindByID(ctx, "not-an-id")
+		assert.ErrorIs(t, err, domain.ErrInvalidID)
+		assert.ErrorIs(t, repo.Update(ctx, &domain.User{ID: "not-an-id", Version: 1}), domain.ErrInvalidID)
+		assert.ErrorIs(t, repo.Delete(ctx, "not-an-id"), domain.ErrInvalidID)
+		assert.ErrorIs(t, repo.Restore(ctx, "not-an-id"), domain.ErrInvalidID)
+	})
+
+	t.Run("deleted users are hidden until restored", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		u := newUser("alice", time.Now())
+		u.PendingEmail = &domain.EmailChange{
+			Email:     "new@test.com",
+			TokenHash: "token-hash",
+			ExpiresAt: time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond),
+		}
+		assert.NoError(t, repo.Create(ctx, u))
+		assert.NoError(t, repo.Delete(ctx, u.ID))
+
+		_, err := repo.FindByID(ctx, u.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByEmail(ctx, u.Email)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByEmailChangeToken(ctx, "token-hash")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		n, err := repo.Count(ctx)
+		assert.NoError(t, err)
+		assert.Zero(t, n)
+
+		// Unless asked for by ID, deleted or not.
+		found, err := repo.FindByIDIncludingDeleted(ctx, u.ID)
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, u.Email, found.Email)
+			assert.NotNil(t, found.DeletedAt)
+		}
+		_, err = repo.FindByIDIncludingDeleted(domain.WithTenant(ctx, "globex"), u.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByIDIncludingDeleted(ctx, "not-an-id")
+		assert.ErrorIs(t, err, domain.ErrInvalidID)
+
+		assert.ErrorIs(t, repo.Delete(ctx, u.ID), domain.ErrNotFound)
+		assert.NoError(t, repo.Restore(ctx, u.ID))
+		assert.ErrorIs(t, repo.Restore(ctx, u.ID), domain.ErrNotFound)
+
+		found, err = repo.FindByEmailChangeToken(ctx, "token-hash")
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, u.ID, found.ID)
+		}
+	})
+
+	t.Run("delete and restore of a missing user", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		assert.ErrorIs(t, repo.Delete(ctx, primitive.NewObjectID().Hex()), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.Restore(ctx, primitive.NewObjectID().Hex()), domain.ErrNotFound)
+	})
+
+	t.Run("update", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		u := newUser("alice", time.Now())
+		assert.NoError(t, repo.Create(ctx, u))
+
+		u.Name = "Alice Liddell"
+		u.Password = "changed"
+		assert.NoError(t, repo.Update(ctx, u))
+		assert.Equal(t, int64(2), u.Version)
+
+		found, err := repo.FindByEmail(ctx, u.Email)
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, "Alice Liddell", found.Name)
+			assert.Equal(t, "hash", found.Password, "Update must not change the password")
+			assert.Equal(t, int64(2), found.Version)
+		}
+
+		stale := *u
+		stale.Version = 1
+		assert.ErrorIs(t, repo.Update(ctx, &stale), domain.ErrVersionConflict)
+		assert.Equal(t, int64(1), stale.Version)
+	})
+
+	t.Run("update of a missing ID", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		missing := newUser("ghost", time.Now())
+		missing.ID = primitive.NewObjectID().Hex()
+		missing.Version = 1
+		assert.ErrorIs(t, repo.Update(ctx, missing), domain.ErrNotFound)
+
+		deleted := newUser("alice", time.Now())
+		assert.NoError(t, repo.Create(ctx, deleted))
+		assert.NoError(t, repo.Delete(ctx, deleted.ID))
+		assert.ErrorIs(t, repo.Update(ctx, deleted), domain.ErrNotFound)
+	})
+
+	t.Run("create many is all or nothing", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		alice := newUser("alice", time.Now())
+		bob := newUser("bob", time.Now())
+		assert.NoError(t, repo.CreateMany(ctx, []*domain.User{alice, bob}))
+		for _, u := range []*domain.User{alice, bob} {
+			assert.NotEmpty(t, u.ID)
+			assert.Equal(t, int64(1), u.Version)
+			found, err := repo.FindByEmail(ctx, u.Email)
+			if assert.NoError(t, err) {
+				assert.Equal(t, u.ID, found.ID)
+			}
+		}
+
+		carol := newUser("carol", time.Now())
+		err := repo.CreateMany(ctx, []*domain.User{carol, newUser("bob", time.Now())})
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+		assert.Empty(t, carol.ID)
+		_, err = repo.FindByEmail(ctx, carol.Email)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		err = repo.CreateMany(ctx, []*domain.User{newUser("dave", time.Now()), newUser("dave", time.Now())})
+		assert.ErrorIs(t, err, domain.ErrEmailTaken)
+		n, err := repo.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+
+	t.Run("invitations and passwords", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		expiresAt := time.Now().Add(time.Hour).UTC().Truncate(time.Millisecond)
+		u := newUser("alice", time.Now())
+		u.Password = ""
+		u.Status = domain.StatusPending
+		u.Invitation = &domain.Invitation{TokenHash: "token-hash", ExpiresAt: expiresAt}
+		assert.NoError(t, repo.Create(ctx, u))
+
+		found, err := repo.FindByInvitationToken(ctx, "token-hash")
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) && assert.NotNil(t, found.Invitation) {
+			assert.Equal(t, u.ID, found.ID)
+			assert.True(t, expiresAt.Equal(found.Invitation.ExpiresAt))
+		}
+		_, err = repo.FindByInvitationToken(ctx, "other-hash")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		u.Invitation = nil
+		u.Status = domain.StatusActive
+		u.Password = "chosen"
+		assert.NoError(t, repo.UpdateWithPassword(ctx, u))
+		assert.Equal(t, int64(2), u.Version)
+
+		found, err = repo.FindByEmail(ctx, u.Email)
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, "chosen", found.Password)
+			assert.Nil(t, found.Invitation)
+		}
+		_, err = repo.FindByInvitationToken(ctx, "token-hash")
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+
+		stale := *u
+		stale.Version = 1
+		assert.ErrorIs(t, repo.UpdateWithPassword(ctx, &stale), domain.ErrVersionConflict)
+	})
+
+	t.Run("each visits live users in ID order", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		var created []*domain.User
+		for _, name := range []string{"carol", "alice", "bob", "dave"} {
+			u := newUser(name, time.Now())
+			assert.NoError(t, repo.Create(ctx, u))
+			created = append(created, u)
+		}
+		assert.NoError(t, repo.Delete(ctx, created[3].ID))
+
+		var seen []*domain.User
+		assert.NoError(t, repo.Each(ctx, func(u *domain.User) error {
+			seen = append(seen, u)
+			return nil
+		}))
+		want := ids(created[:3])
+		slices.Sort(want)
+		assert.Equal(t, want, ids(seen))
+		for _, u := range seen {
+			assert.Empty(t, u.Password)
+		}
+
+		stop := errors.New("stop")
+		calls := 0
+		err := repo.Each(ctx, func(*domain.User) error {
+			calls++
+			return stop
+		})
+		assert.ErrorIs(t, err, stop)
+		assert.Equal(t, 1, calls)
+	})
+
+	t.Run("list ordering", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		base := time.Date(2024, 1, 1, 0, 0, 0, 0, time.UTC)
+		for i, name := range []string{"carol", "Alice", "bob", "Dave", "bob"} {
+			u := newUser(name, base.Add(time.Duration(i)*time.Hour))
+			u.Email = fmt.Sprintf("user%d@test.com", i)
+			assert.NoError(t, repo.Create(ctx, u))
+		}
+
+		newest, err := listAll(ctx, repo, normalized(t, domain.UserQuery{Limit: 2}))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"bob", "Dave", "bob", "Alice", "carol"}, names(newest))
+
+		oldest, err := listAll(ctx, repo, normalized(t, domain.UserQuery{Order: domain.SortAsc, Limit: 2}))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"carol", "Alice", "bob", "Dave", "bob"}, names(oldest))
+
+		// Names compare byte-wise and ties are broken by ID.
+		byName, err := listAll(ctx, repo, normalized(t, domain.UserQuery{SortBy: domain.SortByName, Order: domain.SortAsc, Limit: 2}))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"Alice", "Dave", "bob", "bob", "carol"}, names(byName))
+		if len(byName) == 5 {
+			assert.Less(t, byName[2].ID, byName[3].ID)
+		}
+
+		byNameDesc, err := listAll(ctx, repo, normalized(t, domain.UserQuery{SortBy: domain.SortByName, Limit: 2}))
+		assert.NoError(t, err)
+		assert.Equal(t, reversed(ids(byName)), ids(byNameDesc))
+
+		page, err := repo.FindAll(ctx, normalized(t, domain.UserQuery{SortBy: domain.SortByEmail, Order: domain.SortAsc, Limit: 2, Offset: 1}))
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(5), page.Total)
+			assert.Equal(t, []string{"Alice", "bob"}, names(page.Users))
+		}
+	})
+
+	t.Run("listing and search restricted to user IDs", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		var created []*domain.User
+		for i, name := range []string{"alice", "bob", "carol"} {
+			u := newUser(name, time.Now())
+			u.Email = fmt.Sprintf("user%d@test.com", i)
+			assert.NoError(t, repo.Create(ctx, u))
+			created = append(created, u)
+		}
+
+		q := normalized(t, domain.UserQuery{
+			Order:  domain.SortAsc,
+			Filter: domain.UserFilter{UserIDs: []string{created[0].ID, created[2].ID, "not-an-id"}},
+		})
+		page, err := repo.FindAll(ctx, q)
+		if assert.NoError(t, err) {
+			assert.Equal(t, int64(2), page.Total)
+			assert.Equal(t, []string{"alice", "carol"}, names(page.Users))
+		}
+
+		search, err := repo.Search(ctx, domain.UserSearch{Text: "bob", Limit: 10, UserIDs: q.Filter.UserIDs})
+		if assert.NoError(t, err) {
+			assert.Zero(t, search.Total)
+		}
+		search, err = repo.Search(ctx, domain.UserSearch{Text: "carol", Limit: 10, UserIDs: q.Filter.UserIDs})
+		if assert.NoError(t, err) {
+			assert.Equal(t, []string{"carol"}, names(search.Users))
+		}
+	})
+
+	t.Run("listings never return passwords", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		for _, name := range []string{"alice", "bob"} {
+			assert.NoError(t, repo.Create(ctx, newUser(name, time.Now())))
+		}
+
+		page, err := repo.FindAll(ctx, normalized(t, domain.UserQuery{Limit: 10}))
+		if assert.NoError(t, err) {
+			assert.Len(t, page.Users, 2)
+			for _, u := range page.Users {
+				assert.Empty(t, u.Password)
+			}
+		}
+
+		page, err = repo.Search(ctx, domain.UserSearch{Text: "alice", Limit: 10})
+		if assert.NoError(t, err) {
+			assert.Len(t, page.Users, 1)
+			for _, u := range page.Users {
+				assert.Empty(t, u.Password)
+			}
+		}
+	})
+
+	t.Run("purge removes users deleted before the cutoff", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		alice := newUser("alice", time.Now())
+		bob := newUser("bob", time.Now())
+		assert.NoError(t, repo.Create(ctx, alice))
+		assert.NoError(t, repo.Create(ctx, bob))
+		assert.NoError(t, repo.Delete(ctx, alice.ID))
+
+		purged, err := repo.Purge(ctx, time.Now().Add(-time.Hour))
+		assert.NoError(t, err)
+		assert.Empty(t, purged)
+
+		purged, err = repo.Purge(ctx, time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.Equal(t, []domain.PurgedUser{{ID: alice.ID, TenantID: domain.DefaultTenant}}, purged)
+		assert.ErrorIs(t, repo.Restore(ctx, alice.ID), domain.ErrNotFound)
+
+		// The purged user's email is free again.
+		assert.NoError(t, repo.Create(ctx, newUser("alice", time.Now())))
+		n, err := repo.Count(ctx)
+		assert.NoError(t, err)
+		assert.Equal(t, int64(2), n)
+	})
+
+	t.Run("erase removes live and deleted users", func(t *testing.T) {
+		ctx := context.Background()
+		repo := newRepo(t)
+
+		alice := newUser("alice", time.Now())
+		bob := newUser("bob", time.Now())
+		assert.NoError(t, repo.Create(ctx, alice))
+		assert.NoError(t, repo.Create(ctx, bob))
+		assert.NoError(t, repo.Delete(ctx, bob.ID))
+
+		assert.NoError(t, repo.Erase(ctx, alice.ID))
+		assert.NoError(t, repo.Erase(ctx, bob.ID))
+		_, err := repo.FindByID(ctx, alice.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		assert.ErrorIs(t, repo.Restore(ctx, bob.ID), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.Erase(ctx, alice.ID), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.Erase(ctx, "not-an-id"), domain.ErrInvalidID)
+
+		// The erased users' emails are free again.
+		assert.NoError(t, repo.Create(ctx, newUser("alice", time.Now())))
+		assert.NoError(t, repo.Create(ctx, newUser("bob", time.Now())))
+	})
+
+	t.Run("users are scoped by tenant", func(t *testing.T) {
+		repo := newRepo(t)
+		acme := domain.WithTenant(context.Background(), "acme")
+		globex := domain.WithTenant(context.Background(), "globex")
+
+		alice := newUser("alice", time.Now())
+		assert.NoError(t, repo.Create(acme, alice))
+		assert.Equal(t, "acme", alice.TenantID)
+
+		// The same email can be used once per tenant.
+		other := newUser("alice", time.Now())
+		assert.NoError(t, repo.Create(globex, other))
+		assert.ErrorIs(t, repo.Create(acme, newUser("alice", time.Now())), domain.ErrEmailTaken)
+		bob := newUser("bob", time.Now())
+		assert.NoError(t, repo.CreateMany(globex, []*domain.User{bob}))
+		assert.Equal(t, "globex", bob.TenantID)
+
+		found, err := repo.FindByEmail(acme, alice.Email)
+		assert.NoError(t, err)
+		if assert.NotNil(t, found) {
+			assert.Equal(t, alice.ID, found.ID)
+			assert.Equal(t, "acme", found.TenantID)
+		}
+
+		// Another tenant's users look missing.
+		_, err = repo.FindByID(globex, alice.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		_, err = repo.FindByID(context.Background(), alice.ID)
+		assert.ErrorIs(t, err, domain.ErrNotFound)
+		stolen := *alice
+		stolen.Name = "mallory"
+		assert.ErrorIs(t, repo.Update(globex, &stolen), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.Delete(globex, alice.ID), domain.ErrNotFound)
+		assert.ErrorIs(t, repo.Erase(globex, alice.ID), domain.ErrNotFound)
+		assert.NoError(t, repo.Delete(acme, alice.ID))
+		assert.ErrorIs(t, repo.Restore(globex, alice.ID), domain.ErrNotFound)
+		assert.NoError(t, repo.Restore(acme, alice.ID))
+
+		all, err := listAll(globex, repo, normalized(t, domain.UserQuery{SortBy: domain.SortByName, Order: domain.SortAsc}))
+		assert.NoError(t, err)
+		assert.Equal(t, []string{"alice", "bob"}, names(all))
+		assert.Equal(t, []string{other.ID, bob.ID}, ids(all))
+
+		search := domain.UserSearch{Text: "alice"}
+		assert.NoError(t, search.Normalize())
+		page, err := repo.Search(acme, search)
+		assert.NoError(t, err)
+		if assert.NotNil(t, page) {
+			assert.Equal(t, int64(1), page.Total)
+			assert.Equal(t, []string{alice.ID}, ids(page.Users))
+		}
+
+		var visited []string
+		assert.NoError(t, repo.Each(acme, func(u *domain.User) error {
+			visited = append(visited, u.ID)
+			return nil
+		}))
+		assert.Equal(t, []string{alice.ID}, visited)
+
+		for ctx, want := range map[context.Context]int64{acme: 1, globex: 2, context.Background(): 0} {
+			n, err := repo.Count(ctx)
+			assert.NoError(t, err)
+			assert.Equal(t, want, n)
+		}
+
+		// Purge spans every tenant.
+		assert.NoError(t, repo.Delete(acme, alice.ID))
+		assert.NoError(t, repo.Delete(globex, bob.ID))
+		purged, err := repo.Purge(context.Background(), time.Now().Add(time.Minute))
+		assert.NoError(t, err)
+		assert.ElementsMatch(t, []domain.PurgedUser{
+			{ID: alice.ID, TenantID: "acme"},
+			{ID: bob.ID, TenantID: "globex"},
+		}, purged)
+	})
+}
+
+func newUser(name string, createdAt time.Time) *domain.User {
+	createdAt = createdAt.UTC().Truncate(time.Millisecond)
+	return &domain.User{
+		Name:      name,
+		Email:     fmt.Sprintf("%s@test.com", name),
+		Password:  "hash",
+		CreatedAt: createdAt,
+		UpdatedAt: createdAt,
+		Status:    domain.StatusActive,
+	}
+}
+
+func normalized(t *testing.T, q domain.UserQuery) domain.UserQuery {
+	assert.NoError(t, q.Normalize())
+	return q
+}
+
+// listAll follows next cursors from the first page to the last.
+func listAll(ctx context.Context, repo ports.UserRepository, q domain.UserQuery) ([]*domain.User, error) {
+	var users []*domain.User
+	for {
+		page, err := repo.FindAll(ctx, q)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, page.Users...)
+		if page.NextCursor == "" || len(users) > 100 {
+			return users, nil
+		}
+		q.Cursor = page.NextCursor
+	}
+}
+
+func names(users []*domain.User) []string {
+	names := make([]string, len(users))
+	for i, u := range users {
+		names[i] = u.Name
+	}
+	return names
+}
+
+func ids(users []*domain.User) []string {
+	ids := make([]string, len(users))
+	for i, u := range users {
+		ids[i] = u.ID
+	}
+	return ids
+}
+
+func reversed(s []string) []string {
+	s = slices.Clone(s)
+	slices.Reverse(s)
+	return s
+}
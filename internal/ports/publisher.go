@@ -0,0 +1,23 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// EventPublisher delivers domain events to the outside world. Delivery is
+// at-least-once, so the same event may be published more than once.
+type EventPublisher interface {
+	Publish(ctx context.Context, e domain.Event) error
+}
+
+// EventStream hands out live events to subscribers.
+type EventStream interface {
+	// Subscribe returns the buffered events after lastEventID followed by
+	// live ones, only those of the ctx tenant. With an empty lastEventID only live events are sent. If
+	// lastEventID is no longer buffered it fails with domain.ErrEventsLost
+	// and the caller has to resynchronise. The channel is closed when ctx
+	// ends or the subscriber falls too far behind.
+	Subscribe(ctx context.Context, lastEventID string) (<-chan domain.Event, error)
+}
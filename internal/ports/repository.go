@@ -2,16 +2,125 @@ package ports
 
 import (
 	"context"
+	"time"
 
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 )
 
+// UserRepository stores users. Soft-deleted users are invisible to every
+// method but Restore, Purge and Erase, and finders, Update, Delete and Restore
+// report a missing user with domain.ErrNotFound. IDs are ObjectID hex
+// strings; others are rejected with domain.ErrInvalidID. FindByEmail, which
+// login uses, carries the password; FindByID may leave it out and listings
+// never carry it.
+//
+// Every method but Purge only sees the users of domain.TenantID(ctx), and
+// Create and CreateMany set the users' TenantID to it. Users of other
+// tenants are reported missing. Emails stay unique within a tenant among
+// all its stored users, deleted ones included.
+// internal/ports/porttest checks implementations against this contract.
 type UserRepository interface {
 	Create(ctx context.Context, user *domain.User) error
+	// CreateMany creates all of users or, failing, none of them. Any email
+	// already taken fails it with domain.ErrEmailTaken.
+	CreateMany(ctx context.Context, users []*domain.User) error
 	FindByID(ctx context.Context, id string) (*domain.User, error)
+	// FindByIDIncludingDeleted is FindByID that also finds users deleted
+	// but not yet purged.
+	FindByIDIncludingDeleted(ctx context.Context, id string) (*domain.User, error)
 	FindByEmail(ctx context.Context, email string) (*domain.User, error)
-	FindAll(ctx context.Context) ([]*domain.User, error)
+	FindByEmailChangeToken(ctx context.Context, tokenHash string) (*domain.User, error)
+	FindByInvitationToken(ctx context.Context, tokenHash string) (*domain.User, error)
+	FindAll(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error)
+	Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error)
+	// Each streams every user that is not deleted, in ID order and
+	// without passwords, to fn and stops at the first error fn returns.
+	Each(ctx context.Context, fn func(*domain.User) error) error
+	// Update stores the user but never its password.
 	Update(ctx context.Context, user *domain.User) error
+	// UpdateWithPassword is Update that stores the password as well.
+	UpdateWithPassword(ctx context.Context, user *domain.User) error
 	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+	// Purge spans all tenants and returns the users it removed.
+	Purge(ctx context.Context, deletedBefore time.Time) ([]domain.PurgedUser, error)
+	// Erase permanently removes the user, deleted or not, strips the
+	// user's data from its outbox events and records a
+	// domain.EventUserErased event.
+	Erase(ctx context.Context, id string) error
 	Count(ctx context.Context) (int64, error)
 }
+
+// OrganizationRepository stores organizations and their memberships.
+// Like UserRepository, every method only sees the organizations of
+// domain.TenantID(ctx) and reports missing organizations and memberships
+// with domain.ErrNotFound. Create sets the organization's ID and TenantID.
+// internal/ports/porttest checks implementations against this contract.
+type OrganizationRepository interface {
+	// Create stores the organization together with its first membership.
+	Create(ctx context.Context, org *domain.Organization, owner *domain.Membership) error
+	FindByID(ctx context.Context, id string) (*domain.Organization, error)
+	// AddMember fails with domain.ErrAlreadyExists when the user already
+	// holds a membership in the organization.
+	AddMember(ctx context.Context, m *domain.Membership) error
+	FindMember(ctx context.Context, orgID, userID string) (*domain.Membership, error)
+	// ListMembers returns the organization's memberships in user ID order.
+	ListMembers(ctx context.Context, orgID string) ([]*domain.Membership, error)
+	// MembershipsOf returns the user's memberships in organization ID order.
+	MembershipsOf(ctx context.Context, userID string) ([]*domain.Membership, error)
+	UpdateMember(ctx context.Context, m *domain.Membership) error
+	RemoveMember(ctx context.Context, orgID, userID string) error
+	// RemoveUser removes every membership of the user and returns how
+	// many there were.
+	RemoveUser(ctx context.Context, userID string) (int64, error)
+}
+
+// OutboxRepository hands out outbox messages for publishing. Messages are
+// written by the UserRepository in the same transaction as the change.
+type OutboxRepository interface {
+	// Claim returns the next message that is due and hides it from other
+	// claims for lease. It returns domain.ErrNotFound when none is due.
+	Claim(ctx context.Context, lease time.Duration) (*domain.OutboxMessage, error)
+	MarkDelivered(ctx context.Context, id string) error
+	MarkFailed(ctx context.Context, id, reason string, retryAt time.Time) error
+}
+
+// WebhookRepository stores subscriptions and their deliveries, scoped to
+// the context's tenant: CreateSubscription sets the subscription's
+// TenantID to it and the other lookups match on it. ClaimDelivery and
+// UpdateDelivery span all tenants, for the dispatcher.
+type WebhookRepository interface {
+	CreateSubscription(ctx context.Context, s *domain.WebhookSubscription) error
+	FindSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	DeleteSubscription(ctx context.Context, id string) error
+
+	// CreateDelivery fails with domain.ErrAlreadyExists when the event was
+	// already queued for the subscription.
+	CreateDelivery(ctx context.Context, d *domain.WebhookDelivery) error
+	FindDelivery(ctx context.Context, id string) (*domain.WebhookDelivery, error)
+	ListDeliveries(ctx context.Context, q domain.WebhookDeliveryQuery) ([]*domain.WebhookDelivery, error)
+	// ClaimDelivery returns the next pending delivery that is due and hides
+	// it from other claims for lease. It returns domain.ErrNotFound when none is due.
+	ClaimDelivery(ctx context.Context, lease time.Duration) (*domain.WebhookDelivery, error)
+	UpdateDelivery(ctx context.Context, d *domain.WebhookDelivery) error
+	// RedactDeliveries strips the user snapshot from the payload of every
+	// delivery of an event about userID, and returns how many it changed.
+	RedactDeliveries(ctx context.Context, userID string) (int64, error)
+}
+
+// AuditRepository stores the audit hash chains, one per tenant. Every
+// method but Append works on the chain of the context's tenant; Append
+// stores the event in the chain of its TenantID. It never removes events
+// and only updates them through Redact.
+type AuditRepository interface {
+	// Append fails with domain.ErrAlreadyExists when an event with the
+	// same sequence number was stored first in the tenant's chain.
+	Append(ctx context.Context, e *domain.AuditEvent) error
+	// Last returns domain.ErrNotFound while the log is empty.
+	Last(ctx context.Context) (*domain.AuditEvent, error)
+	Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error)
+	// Redact applies domain.AuditEvent.Redact to every event about userID
+	// and returns the events it changed, in chain order.
+	Redact(ctx context.Context, userID, pseudonym string) ([]*domain.AuditEvent, error)
+}
@@ -2,6 +2,7 @@ package ports
 
 import (
 	"context"
+	"io"
 
 	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
 )
@@ -9,8 +10,114 @@ import (
 type UserService interface {
 	Register(ctx context.Context, name, email, password string) error
 	GetByID(ctx context.Context, id string) (*domain.User, error)
-	List(ctx context.Context) ([]*domain.User, error)
+	List(ctx context.Context, q domain.UserQuery) (*domain.UserPage, error)
+	Search(ctx context.Context, q domain.UserSearch) (*domain.UserPage, error)
 	Login(ctx context.Context, email, password string) (string, error)
-	Update(ctx context.Context, id, name, email string) error
+	Authenticate(ctx context.Context, token string) (*domain.User, error)
+	// Update fails with domain.ErrVersionConflict unless expectedVersion is
+	// zero or matches the stored version.
+	Update(ctx context.Context, id, name, email string, expectedVersion int64) (*domain.User, error)
+	// Patch changes only the fields set in patch, with the same version check as Update.
+	// A changed email is not applied but held pending until confirmed.
+	Patch(ctx context.Context, id string, patch domain.UserPatch, expectedVersion int64) (*domain.User, error)
+	// ConfirmEmailChange swaps in the pending email the token was issued for.
+	ConfirmEmailChange(ctx context.Context, token string) error
+	// AcceptInvitation sets the password of the invited user the token was
+	// issued to and activates the account.
+	AcceptInvitation(ctx context.Context, token, password string) error
+	// ChangeStatus moves the user along the status lifecycle; suspending
+	// or disabling requires a reason.
+	ChangeStatus(ctx context.Context, id string, to domain.UserStatus, reason string) (*domain.User, error)
 	Delete(ctx context.Context, id string) error
+	Restore(ctx context.Context, id string) error
+}
+
+// OrganizationService manages organizations on behalf of actorID, a user
+// of the context's tenant. Organizations actorID holds no membership in
+// are reported missing; actions their role does not allow fail with
+// domain.ErrForbidden.
+type OrganizationService interface {
+	// Create makes a new organization with actorID as its owner.
+	Create(ctx context.Context, actorID, name string) (*domain.Organization, error)
+	Get(ctx context.Context, actorID, orgID string) (*domain.Organization, error)
+	// List returns the organizations actorID is a member of or invited to.
+	List(ctx context.Context, actorID string) ([]*domain.Organization, error)
+	// Members lists the memberships of an organization, optionally only
+	// those in team.
+	Members(ctx context.Context, actorID, orgID, team string) ([]*domain.Membership, error)
+	// Invite adds the user with email as an invited member and mails them.
+	// Owners may invite with any role, admins with any but owner. So that
+	// it cannot tell which emails are registered, it succeeds alike for
+	// unknown emails and for users who are already members, leaving both
+	// as they are.
+	Invite(ctx context.Context, actorID, orgID, email string, role domain.OrgRole, teams []string) error
+	// Accept turns actorID's invitation into an active membership.
+	Accept(ctx context.Context, actorID, orgID string) (*domain.Membership, error)
+	// ChangeMember changes the role or teams of a member, under the same
+	// rules as Invite for both the member's current and new role. It
+	// fails with domain.ErrLastOwner rather than leave no active owner.
+	ChangeMember(ctx context.Context, actorID, orgID, userID string, patch domain.MembershipPatch) (*domain.Membership, error)
+	// Remove takes a member out of the organization. Members may always
+	// remove themselves, but the last active owner may not.
+	Remove(ctx context.Context, actorID, orgID, userID string) error
+	// VisibleUsers returns the IDs of the users actorID may see: the
+	// members of orgID, which actorID must be an active member of, or with
+	// orgID empty, actorID and every member of actorID's organizations.
+	VisibleUsers(ctx context.Context, actorID, orgID string) ([]string, error)
+}
+
+type UserTransferService interface {
+	// Import creates a user for every valid row read from r and reports
+	// the rows it did not import. The report covers the rows read so far
+	// when an error stops the import.
+	Import(ctx context.Context, r io.Reader, format domain.TransferFormat, opts domain.ImportOptions) (*domain.ImportReport, error)
+	// Export writes every user, without passwords, to w and returns how
+	// many it wrote.
+	Export(ctx context.Context, w io.Writer, format domain.TransferFormat) (int64, error)
+}
+
+// PrivacyService answers data subject requests.
+type PrivacyService interface {
+	// Export assembles everything held about the user.
+	Export(ctx context.Context, userID string) (*domain.SubjectArchive, error)
+	// Erase removes the user for good, deleted or not, along with their
+	// avatar, and strips their personal data from the audit log, outbox
+	// and webhook deliveries. A user.erased audit event records it under
+	// a pseudonym. Erasing a purged user still cleans up after them.
+	Erase(ctx context.Context, userID, reason string) (*domain.ErasureReport, error)
+}
+
+type AuditService interface {
+	// Record appends e to the chain of the context's tenant, filling in
+	// actor, IP and request ID from the context's domain.RequestMeta when
+	// unset.
+	Record(ctx context.Context, e domain.AuditEvent) error
+	Find(ctx context.Context, q domain.AuditQuery) ([]*domain.AuditEvent, error)
+	// Redact applies domain.AuditEvent.Redact to every event about userID
+	// and records an audit.redacted event listing their new hashes under
+	// pseudonym. It returns how many events it changed.
+	Redact(ctx context.Context, userID, pseudonym string) (int64, error)
+	// Verify walks the whole chain and reports the first broken link.
+	Verify(ctx context.Context) (*domain.AuditVerification, error)
+}
+
+type WebhookService interface {
+	// Subscribe returns the subscription with its secret, which is not
+	// shown again.
+	Subscribe(ctx context.Context, url string, events []domain.EventType) (*domain.WebhookSubscription, error)
+	GetSubscription(ctx context.Context, id string) (*domain.WebhookSubscription, error)
+	ListSubscriptions(ctx context.Context) ([]*domain.WebhookSubscription, error)
+	Unsubscribe(ctx context.Context, id string) error
+	Deliveries(ctx context.Context, q domain.WebhookDeliveryQuery) ([]*domain.WebhookDelivery, error)
+	// Redeliver queues a delivery again, including dead ones.
+	Redeliver(ctx context.Context, subscriptionID, deliveryID string) (*domain.WebhookDelivery, error)
+}
+
+type AvatarService interface {
+	// Upload validates the image, stores it with its thumbnails and
+	// points the user's avatar URL at it.
+	Upload(ctx context.Context, userID string, image io.Reader) (*domain.User, error)
+	// Open fails with domain.ErrNotFound when the user is deleted or has
+	// no avatar.
+	Open(ctx context.Context, userID string, size domain.AvatarSize) (io.ReadCloser, *BlobInfo, error)
 }
@@ -0,0 +1,13 @@
+package ports
+
+import "github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+
+// TenantRegistry knows the configured tenants. domain.DefaultTenant is
+// always among them.
+type TenantRegistry interface {
+	// Tenant returns domain.ErrUnknownTenant for an ID not configured.
+	Tenant(id string) (*domain.Tenant, error)
+	// TenantForHost returns the tenant serving host, or
+	// domain.ErrUnknownTenant when no tenant claims it.
+	TenantForHost(host string) (*domain.Tenant, error)
+}
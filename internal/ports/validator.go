@@ -0,0 +1,6 @@
+package ports
+
+// AttributeValidator checks custom user attributes against the configured schema.
+type AttributeValidator interface {
+	Validate(attrs map[string]interface{}) error
+}
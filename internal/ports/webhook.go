@@ -0,0 +1,13 @@
+package ports
+
+import (
+	"context"
+
+	"github.com/yimsoijoi/7s-backend-challenge/internal/domain"
+)
+
+// WebhookSender makes one signed delivery attempt. It returns the response
+// status code, or zero with an error when no response came back.
+type WebhookSender interface {
+	Send(ctx context.Context, sub *domain.WebhookSubscription, d *domain.WebhookDelivery) (int, error)
+}